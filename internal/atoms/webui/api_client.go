@@ -2,18 +2,27 @@ package webui
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"time"
 )
 
 // APIClient handles authenticated requests to the Canvus Server API.
+// Requests are rate limited by an optional token bucket and retried according
+// to the client's RetryPolicy.
 type APIClient struct {
-	baseURL   string
-	authToken string
-	httpClient *http.Client
+	baseURL      string
+	authToken    string
+	httpClient   *http.Client
+	uploadClient *http.Client // no overall timeout: uploads are bounded by their context
+	retryPolicy  RetryPolicy
+	limiter      *RateLimiter
 }
 
 // NewAPIClient creates a new API client for Canvus Server.
@@ -25,176 +34,237 @@ func NewAPIClient(baseURL, authToken string) *APIClient {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		uploadClient: &http.Client{},
+		retryPolicy:  DefaultRetryPolicy(),
 	}
 }
 
-// Get performs a GET request to the Canvus API.
-func (c *APIClient) Get(endpoint string) ([]byte, error) {
-	url := c.baseURL + endpoint
-	fmt.Printf("[APIClient] GET %s\n", url)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Printf("[APIClient] ERROR: Failed to create request: %v\n", err)
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// SetRetryPolicy replaces the client's retry policy.
+// It should be called before the client is shared between goroutines.
+func (c *APIClient) SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
 	}
+	c.retryPolicy = policy
+}
 
-	req.Header.Set("Private-Token", c.authToken)
-	req.Header.Set("Content-Type", "application/json")
-	fmt.Printf("[APIClient] Request headers: Private-Token=%s (length: %d)\n",
-		func() string {
-			if len(c.authToken) > 10 {
-				return c.authToken[:10] + "..."
-			}
-			return c.authToken
-		}(), len(c.authToken))
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		fmt.Printf("[APIClient] ERROR: Request failed: %v\n", err)
-		return nil, fmt.Errorf("request failed: %w", err)
+// SetRateLimit limits the client to requestsPerSecond sustained requests with
+// bursts of up to burst requests. A non-positive rate disables limiting.
+// It should be called before the client is shared between goroutines.
+func (c *APIClient) SetRateLimit(requestsPerSecond float64, burst int) {
+	if requestsPerSecond <= 0 {
+		c.limiter = nil
+		return
 	}
-	defer resp.Body.Close()
+	c.limiter = NewRateLimiter(requestsPerSecond, burst)
+}
 
-	fmt.Printf("[APIClient] Response status: %d %s\n", resp.StatusCode, resp.Status)
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("[APIClient] ERROR: Failed to read response: %v\n", err)
-		return nil, fmt.Errorf("failed to read response: %w", err)
+// Do performs a JSON request against the Canvus API using the given context.
+// payload is marshalled to JSON when non-nil. The raw response body is returned
+// on success; non-2xx responses are returned as *APIError.
+func (c *APIClient) Do(ctx context.Context, method, endpoint string, payload interface{}) ([]byte, error) {
+	var body []byte
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal data: %w", err)
+		}
+		body = jsonData
 	}
+	return c.send(ctx, method, endpoint, "application/json", body)
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		fmt.Printf("[APIClient] ERROR: API returned error status %d: %s\n", resp.StatusCode, string(body))
-		return nil, fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body))
+// send performs a request with rate limiting and retries. body is replayed
+// unchanged on every attempt.
+func (c *APIClient) send(ctx context.Context, method, endpoint, contentType string, body []byte) ([]byte, error) {
+	var newBody func() (io.Reader, error)
+	if body != nil {
+		newBody = func() (io.Reader, error) { return bytes.NewReader(body), nil }
 	}
-
-	fmt.Printf("[APIClient] Success: Received %d bytes\n", len(body))
-	return body, nil
+	return c.sendWith(ctx, c.httpClient, c.retryPolicy, method, endpoint, contentType, newBody)
 }
 
-// Post performs a POST request to the Canvus API.
-func (c *APIClient) Post(endpoint string, data interface{}) ([]byte, error) {
-	jsonData, err := json.Marshal(data)
+// sendWith performs a request with rate limiting and retries through client.
+// newBody returns the body of each attempt; nil sends no body.
+func (c *APIClient) sendWith(ctx context.Context, client *http.Client, policy RetryPolicy, method, endpoint, contentType string, newBody func() (io.Reader, error)) ([]byte, error) {
+	var respBody []byte
+	err := c.retry(ctx, policy, method, endpoint, func() (time.Duration, error) {
+		var body io.Reader
+		if newBody != nil {
+			var err error
+			if body, err = newBody(); err != nil {
+				return 0, errStopRetrying{err}
+			}
+		}
+		var retryAfter time.Duration
+		var err error
+		respBody, retryAfter, err = c.sendOnce(ctx, client, method, endpoint, contentType, body)
+		return retryAfter, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
+		return nil, err
 	}
+	return respBody, nil
+}
 
-	url := c.baseURL + endpoint
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+// errStopRetrying wraps an error that ends retry at once.
+type errStopRetrying struct{ err error }
 
-	req.Header.Set("Private-Token", c.authToken)
-	req.Header.Set("Content-Type", "application/json")
+func (e errStopRetrying) Error() string { return e.err.Error() }
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+// retry calls attempt, waiting for the rate limiter before every call, until
+// it succeeds, fails with an error policy does not retry, or runs out of
+// attempts. attempt returns the Retry-After delay of a failed response.
+func (c *APIClient) retry(ctx context.Context, policy RetryPolicy, method, endpoint string, attempt func() (time.Duration, error)) error {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body))
+	var lastErr error
+	for n := 1; n <= policy.MaxAttempts; n++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
+		countAttempt(ctx)
+		retryAfter, err := attempt()
+		if err == nil {
+			return nil
+		}
+		if stop, ok := err.(errStopRetrying); ok {
+			return stop.err
+		}
+		lastErr = err
+
+		if n == policy.MaxAttempts || !shouldRetry(method, err) {
+			break
+		}
+
+		delay := policy.backoff(n)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		fmt.Printf("[APIClient] Retrying %s %s in %v (attempt %d/%d): %v\n", method, endpoint, delay, n+1, policy.MaxAttempts, err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return lastErr
+		}
 	}
-
-	return body, nil
+	return lastErr
 }
 
-// Put performs a PUT request to the Canvus API.
-func (c *APIClient) Put(endpoint string, data interface{}) ([]byte, error) {
-	jsonData, err := json.Marshal(data)
+// OpenDownload performs a GET of endpoint, typically the download of a
+// widget file, and returns the response body for the caller to stream and
+// close. It is not subject to the client's request timeout, only to ctx, so
+// files of any size can be fetched without holding them in memory.
+// Requests that fail before the body is handed over are retried.
+func (c *APIClient) OpenDownload(ctx context.Context, endpoint string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := c.retry(ctx, c.retryPolicy, http.MethodGet, endpoint, func() (time.Duration, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+endpoint, nil)
+		if err != nil {
+			return 0, errStopRetrying{fmt.Errorf("failed to create request: %w", err)}
+		}
+		req.Header.Set("Private-Token", c.authToken)
+
+		resp, err := c.uploadClient.Do(req)
+		if err != nil {
+			fmt.Printf("[APIClient] ERROR: GET %s%s failed: %v\n", c.baseURL, endpoint, err)
+			return 0, fmt.Errorf("request failed: %w", err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			defer resp.Body.Close()
+			respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+			fmt.Printf("[APIClient] ERROR: GET %s%s returned status %d: %s\n", c.baseURL, endpoint, resp.StatusCode, string(respBody))
+			retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			return retryAfter, newAPIError(http.MethodGet, endpoint, resp.StatusCode, respBody)
+		}
+		body = resp.Body
+		return 0, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
+		return nil, err
 	}
+	return body, nil
+}
 
+// sendOnce performs a single HTTP request. The Retry-After delay of a failed
+// response is returned alongside its *APIError.
+func (c *APIClient) sendOnce(ctx context.Context, client *http.Client, method, endpoint, contentType string, body io.Reader) ([]byte, time.Duration, error) {
 	url := c.baseURL + endpoint
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		if closer, ok := body.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Private-Token", c.authToken)
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		fmt.Printf("[APIClient] ERROR: %s %s failed: %v\n", method, url, err)
+		return nil, 0, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body))
+		fmt.Printf("[APIClient] ERROR: %s %s returned status %d: %s\n", method, url, resp.StatusCode, string(respBody))
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return nil, retryAfter, newAPIError(method, endpoint, resp.StatusCode, respBody)
 	}
 
-	return body, nil
+	return respBody, 0, nil
 }
 
-// Patch performs a PATCH request to the Canvus API.
-func (c *APIClient) Patch(endpoint string, data interface{}) ([]byte, error) {
-	jsonData, err := json.Marshal(data)
+// DoJSON performs a request and decodes the JSON response into out (if non-nil).
+func (c *APIClient) DoJSON(ctx context.Context, method, endpoint string, payload, out interface{}) error {
+	data, err := c.Do(ctx, method, endpoint, payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
+		return err
 	}
-
-	url := c.baseURL + endpoint
-	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	if out == nil || len(data) == 0 {
+		return nil
 	}
-
-	req.Header.Set("Private-Token", c.authToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %w", endpoint, err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
+// Get performs a GET request to the Canvus API.
+func (c *APIClient) Get(endpoint string) ([]byte, error) {
+	fmt.Printf("[APIClient] GET %s%s\n", c.baseURL, endpoint)
+	return c.Do(context.Background(), http.MethodGet, endpoint, nil)
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body))
-	}
+// Post performs a POST request to the Canvus API.
+func (c *APIClient) Post(endpoint string, data interface{}) ([]byte, error) {
+	return c.Do(context.Background(), http.MethodPost, endpoint, data)
+}
 
-	return body, nil
+// Put performs a PUT request to the Canvus API.
+func (c *APIClient) Put(endpoint string, data interface{}) ([]byte, error) {
+	return c.Do(context.Background(), http.MethodPut, endpoint, data)
+}
+
+// Patch performs a PATCH request to the Canvus API.
+func (c *APIClient) Patch(endpoint string, data interface{}) ([]byte, error) {
+	return c.Do(context.Background(), http.MethodPatch, endpoint, data)
 }
 
 // Delete performs a DELETE request to the Canvus API.
 func (c *APIClient) Delete(endpoint string) error {
-	url := c.baseURL + endpoint
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Private-Token", c.authToken)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body))
-	}
-
-	return nil
+	_, err := c.Do(context.Background(), http.MethodDelete, endpoint, nil)
+	return err
 }
 
 // PostMultipart performs a multipart/form-data POST request to the Canvus API.
@@ -202,96 +272,125 @@ func (c *APIClient) Delete(endpoint string) error {
 // - json: JSON metadata as a form field
 // - data: File binary data as a form field
 func (c *APIClient) PostMultipart(endpoint string, jsonData map[string]interface{}, fileData io.Reader, fileName string) ([]byte, error) {
+	return c.PostMultipartContext(context.Background(), endpoint, jsonData, fileData, fileName)
+}
+
+// PostMultipartContext is PostMultipart with a caller-supplied context.
+// The body is streamed, so files of any size are never held in memory, and
+// the upload is not subject to the client's request timeout, only to ctx.
+// A failed upload is retried only when fileData is an io.Seeker, rewound
+// for every attempt.
+func (c *APIClient) PostMultipartContext(ctx context.Context, endpoint string, jsonData map[string]interface{}, fileData io.Reader, fileName string) ([]byte, error) {
 	url := c.baseURL + endpoint
 	fmt.Printf("[APIClient] PostMultipart: %s\n", url)
 
-	// Write JSON part
 	jsonBytes, err := json.Marshal(jsonData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal json data: %w", err)
 	}
 
-	// Build multipart form manually
-	boundary := "----WebKitFormBoundary" + fmt.Sprintf("%d", time.Now().UnixNano())
-
-	var buf bytes.Buffer
-	// JSON part
-	buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-	buf.WriteString("Content-Disposition: form-data; name=\"json\"\r\n")
-	buf.WriteString("Content-Type: application/json\r\n\r\n")
-	buf.Write(jsonBytes)
-	buf.WriteString("\r\n")
-
-	// File part
-	buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-	buf.WriteString(fmt.Sprintf("Content-Disposition: form-data; name=\"data\"; filename=\"%s\"\r\n", fileName))
-	buf.WriteString("Content-Type: application/octet-stream\r\n\r\n")
-
-	// Copy file data
-	if _, err := io.Copy(&buf, fileData); err != nil {
-		return nil, fmt.Errorf("failed to copy file data: %w", err)
+	policy := c.retryPolicy
+	seeker, seekable := fileData.(io.Seeker)
+	var start int64
+	if seekable {
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			seekable = false
+		}
+	}
+	if !seekable {
+		policy.MaxAttempts = 1
 	}
 
-	buf.WriteString("\r\n")
-	buf.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
+	// Every attempt streams fileData from its own goroutine. Before the file
+	// is rewound for the next attempt, or handed back to the caller, the
+	// previous goroutine is stopped and waited for, so that it never reads
+	// fileData concurrently with the Seek or the caller.
+	boundary := multipart.NewWriter(nil).Boundary()
+	var body *io.PipeReader
+	var written <-chan struct{}
+	stopWriter := func() {
+		if body != nil {
+			body.CloseWithError(errUploadAttemptDone)
+			<-written
+			body = nil
+		}
+	}
+	defer stopWriter()
+
+	attempt := 0
+	newBody := func() (io.Reader, error) {
+		attempt++
+		stopWriter()
+		if attempt > 1 {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("failed to rewind file data: %w", err)
+			}
+		}
+		body, written = streamMultipart(boundary, jsonBytes, fileData, fileName)
+		return body, nil
+	}
 
-	req, err := http.NewRequest("POST", url, &buf)
+	bodyBytes, err := c.sendWith(ctx, c.uploadClient, policy, http.MethodPost, endpoint, "multipart/form-data; boundary="+boundary, newBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		fmt.Printf("[APIClient] ERROR: PostMultipart failed: %v\n", err)
+		return nil, err
 	}
 
-	req.Header.Set("Private-Token", c.authToken)
-	req.Header.Set("Content-Type", fmt.Sprintf("multipart/form-data; boundary=%s", boundary))
+	fmt.Printf("[APIClient] PostMultipart success: Received %d bytes\n", len(bodyBytes))
+	return bodyBytes, nil
+}
+
+// errUploadAttemptDone stops the writer of an upload attempt that is over.
+var errUploadAttemptDone = errors.New("upload attempt finished")
+
+// streamMultipart returns a reader producing the json and data parts of an
+// upload as they are read, and a channel closed once the writing goroutine
+// stopped reading fileData. The writing side stops when the reader is
+// closed, which the HTTP client does once the request is done.
+func streamMultipart(boundary string, jsonBytes []byte, fileData io.Reader, fileName string) (*io.PipeReader, <-chan struct{}) {
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		form := multipart.NewWriter(writer)
+		form.SetBoundary(boundary)
+		writer.CloseWithError(writeMultipart(form, jsonBytes, fileData, fileName))
+	}()
+	return reader, done
+}
 
-	resp, err := c.httpClient.Do(req)
+// writeMultipart writes the json part, the data part and the closing
+// boundary of an upload to form.
+func writeMultipart(form *multipart.Writer, jsonBytes []byte, fileData io.Reader, fileName string) error {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="json"`)
+	header.Set("Content-Type", "application/json")
+	part, err := form.CreatePart(header)
 	if err != nil {
-		fmt.Printf("[APIClient] ERROR: PostMultipart request failed: %v\n", err)
-		return nil, fmt.Errorf("request failed: %w", err)
+		return err
+	}
+	if _, err := part.Write(jsonBytes); err != nil {
+		return err
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	part, err = form.CreateFormFile("data", fileName)
 	if err != nil {
-		fmt.Printf("[APIClient] ERROR: PostMultipart failed to read response: %v\n", err)
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return err
 	}
-
-	fmt.Printf("[APIClient] PostMultipart response status: %d %s\n", resp.StatusCode, resp.Status)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		fmt.Printf("[APIClient] ERROR: PostMultipart API returned error status %d: %s\n", resp.StatusCode, string(bodyBytes))
-		return nil, fmt.Errorf("API error: %d - %s", resp.StatusCode, string(bodyBytes))
+	if _, err := io.Copy(part, fileData); err != nil {
+		return fmt.Errorf("failed to copy file data: %w", err)
 	}
-
-	fmt.Printf("[APIClient] PostMultipart success: Received %d bytes\n", len(bodyBytes))
-	return bodyBytes, nil
-}
-
-// Client represents a Canvus client.
-type Client struct {
-	ID               string `json:"id"`
-	InstallationName string `json:"installation_name"`
+	return form.Close()
 }
 
 // GetClients fetches the list of clients from the Canvus API.
 func (c *APIClient) GetClients() ([]Client, error) {
-	url := c.baseURL + "/api/v1/clients"
-	fmt.Printf("[APIClient] GetClients: Calling %s\n", url)
-	body, err := c.Get("/api/v1/clients")
+	clients, err := c.Clients().List(context.Background())
 	if err != nil {
 		fmt.Printf("[APIClient] ERROR: GetClients failed: %v\n", err)
 		return nil, fmt.Errorf("failed to get clients: %w", err)
 	}
 
-	fmt.Printf("[APIClient] GetClients: Received %d bytes response\n", len(body))
-	var clients []Client
-	if err := json.Unmarshal(body, &clients); err != nil {
-		fmt.Printf("[APIClient] ERROR: Failed to unmarshal clients: %v\n", err)
-		fmt.Printf("[APIClient] Response body: %s\n", string(body))
-		return nil, fmt.Errorf("failed to unmarshal clients: %w", err)
-	}
-
 	fmt.Printf("[APIClient] GetClients: Successfully parsed %d clients\n", len(clients))
 	return clients, nil
 }
-
-
@@ -0,0 +1,73 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError is returned when the Canvus API responds with a non-2xx status.
+// It keeps the HTTP status code and the message reported by the server so
+// callers can react to specific failures (e.g. 404 vs 503).
+type APIError struct {
+	Method     string
+	Endpoint   string
+	StatusCode int
+	Message    string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: %d - %s", e.StatusCode, e.Body)
+}
+
+// IsNotFound reports whether the server returned 404 Not Found.
+func (e *APIError) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// newAPIError builds an APIError from a response status and body.
+// MTCS reports errors as {"msg": "..."}; other shapes fall back to the raw body.
+func newAPIError(method, endpoint string, statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		Method:     method,
+		Endpoint:   endpoint,
+		StatusCode: statusCode,
+		Body:       string(body),
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		for _, key := range []string{"msg", "message", "error"} {
+			if msg, ok := payload[key].(string); ok && msg != "" {
+				apiErr.Message = msg
+				break
+			}
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(statusCode)
+	}
+
+	return apiErr
+}
+
+// AsAPIError unwraps err looking for an *APIError.
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// IsNotFound reports whether err wraps a 404 response from the Canvus API.
+func IsNotFound(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && apiErr.IsNotFound()
+}
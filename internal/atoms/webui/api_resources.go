@@ -0,0 +1,184 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Resource is a typed view over one Canvus API collection
+// (e.g. /api/v1/canvases/{id}/notes). T is the item type returned by the server.
+type Resource[T any] struct {
+	client   *APIClient
+	basePath string
+}
+
+func newResource[T any](client *APIClient, basePath string) *Resource[T] {
+	return &Resource[T]{client: client, basePath: basePath}
+}
+
+// Path returns the collection endpoint, e.g. /api/v1/canvases/{id}/notes.
+func (r *Resource[T]) Path() string {
+	return r.basePath
+}
+
+// List returns every item in the collection.
+func (r *Resource[T]) List(ctx context.Context) ([]T, error) {
+	var items []T
+	if err := r.client.DoJSON(ctx, http.MethodGet, r.basePath, nil, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Get returns a single item by ID.
+func (r *Resource[T]) Get(ctx context.Context, id string) (*T, error) {
+	var item T
+	if err := r.client.DoJSON(ctx, http.MethodGet, r.itemPath(id), nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Create posts payload (a typed struct or a map) and returns the created item.
+func (r *Resource[T]) Create(ctx context.Context, payload interface{}) (*T, error) {
+	var item T
+	if err := r.client.DoJSON(ctx, http.MethodPost, r.basePath, payload, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Update patches an item. payload should contain only the fields to change;
+// a map is usually the right choice since zero values are otherwise omitted.
+func (r *Resource[T]) Update(ctx context.Context, id string, payload interface{}) (*T, error) {
+	var item T
+	if err := r.client.DoJSON(ctx, http.MethodPatch, r.itemPath(id), payload, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Delete removes an item by ID.
+func (r *Resource[T]) Delete(ctx context.Context, id string) error {
+	_, err := r.client.Do(ctx, http.MethodDelete, r.itemPath(id), nil)
+	return err
+}
+
+func (r *Resource[T]) itemPath(id string) string {
+	return fmt.Sprintf("%s/%s", r.basePath, id)
+}
+
+// AssetResource is a Resource whose items carry a binary asset
+// (images, videos and PDFs). Creation uses multipart upload.
+type AssetResource[T any] struct {
+	*Resource[T]
+}
+
+// Upload creates a new asset widget from fileData using multipart/form-data.
+func (r *AssetResource[T]) Upload(ctx context.Context, metadata map[string]interface{}, fileData io.Reader, fileName string) (*T, error) {
+	data, err := r.client.PostMultipartContext(ctx, r.basePath, metadata, fileData, fileName)
+	if err != nil {
+		return nil, err
+	}
+	var item T
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("failed to parse response from %s: %w", r.basePath, err)
+		}
+	}
+	return &item, nil
+}
+
+// Download returns the binary asset for an item.
+func (r *AssetResource[T]) Download(ctx context.Context, id string) ([]byte, error) {
+	return r.client.Do(ctx, http.MethodGet, r.itemPath(id)+"/download", nil)
+}
+
+// CanvasesAPI provides access to /api/v1/canvases.
+type CanvasesAPI struct {
+	*Resource[Canvas]
+}
+
+// ClientsAPI provides access to /api/v1/clients and their workspaces.
+type ClientsAPI struct {
+	*Resource[Client]
+}
+
+// Workspaces lists the workspaces of a client.
+func (c *ClientsAPI) Workspaces(ctx context.Context, clientID string) ([]Workspace, error) {
+	var workspaces []Workspace
+	endpoint := fmt.Sprintf("%s/%s/workspaces", c.basePath, clientID)
+	if err := c.client.DoJSON(ctx, http.MethodGet, endpoint, nil, &workspaces); err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+// Workspace returns a single workspace of a client by index.
+func (c *ClientsAPI) Workspace(ctx context.Context, clientID string, index int) (*Workspace, error) {
+	var workspace Workspace
+	endpoint := fmt.Sprintf("%s/%s/workspaces/%d", c.basePath, clientID, index)
+	if err := c.client.DoJSON(ctx, http.MethodGet, endpoint, nil, &workspace); err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// Canvases returns the typed canvases API.
+func (c *APIClient) Canvases() *CanvasesAPI {
+	return &CanvasesAPI{newResource[Canvas](c, "/api/v1/canvases")}
+}
+
+// Clients returns the typed clients API.
+func (c *APIClient) Clients() *ClientsAPI {
+	return &ClientsAPI{newResource[Client](c, "/api/v1/clients")}
+}
+
+// Widgets returns the read-only widgets collection of a canvas.
+// Widgets must be modified through their type-specific endpoint.
+func (c *APIClient) Widgets(canvasID string) *Resource[Widget] {
+	return newResource[Widget](c, canvasPath(canvasID, "widgets"))
+}
+
+// Notes returns the typed notes API for a canvas.
+func (c *APIClient) Notes(canvasID string) *Resource[Note] {
+	return newResource[Note](c, canvasPath(canvasID, "notes"))
+}
+
+// Browsers returns the typed browsers API for a canvas.
+func (c *APIClient) Browsers(canvasID string) *Resource[Browser] {
+	return newResource[Browser](c, canvasPath(canvasID, "browsers"))
+}
+
+// Connectors returns the typed connectors API for a canvas.
+func (c *APIClient) Connectors(canvasID string) *Resource[Connector] {
+	return newResource[Connector](c, canvasPath(canvasID, "connectors"))
+}
+
+// Anchors returns the typed anchors (zones) API for a canvas.
+func (c *APIClient) Anchors(canvasID string) *Resource[Anchor] {
+	return newResource[Anchor](c, canvasPath(canvasID, "anchors"))
+}
+
+// Images returns the typed images API for a canvas.
+func (c *APIClient) Images(canvasID string) *AssetResource[Image] {
+	return &AssetResource[Image]{newResource[Image](c, canvasPath(canvasID, "images"))}
+}
+
+// Videos returns the typed videos API for a canvas.
+func (c *APIClient) Videos(canvasID string) *AssetResource[Video] {
+	return &AssetResource[Video]{newResource[Video](c, canvasPath(canvasID, "videos"))}
+}
+
+// PDFs returns the typed PDFs API for a canvas.
+func (c *APIClient) PDFs(canvasID string) *AssetResource[PDF] {
+	return &AssetResource[PDF]{newResource[PDF](c, canvasPath(canvasID, "pdfs"))}
+}
+
+// canvasPath builds /api/v1/canvases/{canvasID}/{collection}.
+func canvasPath(canvasID, collection string) string {
+	return fmt.Sprintf("/api/v1/canvases/%s/%s", canvasID, collection)
+}
@@ -1,46 +1,116 @@
 package webui
 
 import (
+	"sort"
 	"sync"
 )
 
-// CanvasTracker manages the current canvas_id state.
+// CanvasTracker manages the canvas_id state of each workspace of a client.
+// One workspace is selected; the methods without a workspace argument act
+// on it. Workspace 0 is selected until SelectWorkspace is called.
 type CanvasTracker struct {
-	mu         sync.RWMutex
+	mu       sync.RWMutex
+	selected int
+	canvases map[int]trackedCanvas
+}
+
+// trackedCanvas is the canvas open in a workspace.
+type trackedCanvas struct {
 	canvasID   string
 	canvasName string
 }
 
 // NewCanvasTracker creates a new canvas tracker.
 func NewCanvasTracker() *CanvasTracker {
-	return &CanvasTracker{}
+	return &CanvasTracker{canvases: make(map[int]trackedCanvas)}
+}
+
+// SetWorkspaces records the workspaces of the client, keeping the canvases
+// of those already tracked and forgetting the others.
+func (ct *CanvasTracker) SetWorkspaces(workspaces []int) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	canvases := make(map[int]trackedCanvas, len(workspaces))
+	for _, workspace := range workspaces {
+		canvases[workspace] = ct.canvases[workspace]
+	}
+	ct.canvases = canvases
+}
+
+// Workspaces returns the tracked workspaces in ascending order.
+func (ct *CanvasTracker) Workspaces() []int {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	workspaces := make([]int, 0, len(ct.canvases))
+	for workspace := range ct.canvases {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Ints(workspaces)
+	return workspaces
+}
+
+// SelectWorkspace selects the workspace the methods without a workspace
+// argument act on.
+func (ct *CanvasTracker) SelectWorkspace(workspace int) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.selected = workspace
+}
+
+// SelectedWorkspace returns the selected workspace.
+func (ct *CanvasTracker) SelectedWorkspace() int {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.selected
 }
 
-// UpdateCanvas updates the current canvas_id and canvas_name.
+// UpdateCanvas updates the canvas_id and canvas_name of the selected workspace.
 func (ct *CanvasTracker) UpdateCanvas(canvasID, canvasName string) {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
-	ct.canvasID = canvasID
-	ct.canvasName = canvasName
+	ct.update(ct.selected, canvasID, canvasName)
+}
+
+// UpdateWorkspaceCanvas updates the canvas_id and canvas_name of workspace.
+func (ct *CanvasTracker) UpdateWorkspaceCanvas(workspace int, canvasID, canvasName string) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.update(workspace, canvasID, canvasName)
+}
+
+// update records the canvas of workspace. Callers must hold ct.mu.
+func (ct *CanvasTracker) update(workspace int, canvasID, canvasName string) {
+	if ct.canvases == nil {
+		ct.canvases = make(map[int]trackedCanvas)
+	}
+	ct.canvases[workspace] = trackedCanvas{canvasID: canvasID, canvasName: canvasName}
+}
+
+// GetWorkspaceCanvas returns the canvas_id and canvas_name of workspace.
+// ok is false when the workspace is not tracked.
+func (ct *CanvasTracker) GetWorkspaceCanvas(workspace int) (canvasID, canvasName string, ok bool) {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	canvas, ok := ct.canvases[workspace]
+	return canvas.canvasID, canvas.canvasName, ok
 }
 
 // GetCanvas returns the current canvas_id and canvas_name.
 func (ct *CanvasTracker) GetCanvas() (string, string) {
 	ct.mu.RLock()
 	defer ct.mu.RUnlock()
-	return ct.canvasID, ct.canvasName
+	canvas := ct.canvases[ct.selected]
+	return canvas.canvasID, canvas.canvasName
 }
 
 // GetCanvasID returns the current canvas_id.
 func (ct *CanvasTracker) GetCanvasID() string {
-	ct.mu.RLock()
-	defer ct.mu.RUnlock()
-	return ct.canvasID
+	canvasID, _ := ct.GetCanvas()
+	return canvasID
 }
 
 // GetCanvasName returns the current canvas_name.
 func (ct *CanvasTracker) GetCanvasName() string {
-	ct.mu.RLock()
-	defer ct.mu.RUnlock()
-	return ct.canvasName
+	_, canvasName := ct.GetCanvas()
+	return canvasName
 }
@@ -0,0 +1,132 @@
+package webui
+
+// Canvas represents a canvas from the Canvus API.
+type Canvas struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	FolderID  string `json:"folder_id,omitempty"`
+	Mode      string `json:"mode,omitempty"`
+	State     string `json:"state,omitempty"`
+	AssetSize int64  `json:"asset_size,omitempty"`
+}
+
+// Client represents a Canvus client.
+type Client struct {
+	ID               string `json:"id"`
+	InstallationName string `json:"installation_name"`
+	Name             string `json:"name,omitempty"`
+	State            string `json:"state,omitempty"`
+	Version          string `json:"version,omitempty"`
+	AccessLevel      string `json:"access,omitempty"`
+}
+
+// Workspace represents a workspace on a Canvus client.
+type Workspace struct {
+	Index          int             `json:"index"`
+	CanvasID       string          `json:"canvas_id"`
+	CanvasName     string          `json:"canvas_name,omitempty"`
+	ServerID       string          `json:"server_id,omitempty"`
+	User           string          `json:"user,omitempty"`
+	InfoPanel      bool            `json:"info_panel_visible,omitempty"`
+	Pinned         bool            `json:"pinned,omitempty"`
+	Location       *WidgetLocation `json:"location,omitempty"`
+	Size           *WidgetSize     `json:"size,omitempty"`
+	WorkspaceState string          `json:"workspace_state,omitempty"`
+}
+
+// WidgetBase holds the fields shared by every widget type.
+type WidgetBase struct {
+	ID         string          `json:"id"`
+	WidgetType string          `json:"widget_type,omitempty"`
+	ParentID   string          `json:"parent_id,omitempty"`
+	Location   *WidgetLocation `json:"location,omitempty"`
+	Size       *WidgetSize     `json:"size,omitempty"`
+	Scale      float64         `json:"scale,omitempty"`
+	Depth      float64         `json:"depth,omitempty"`
+	Pinned     bool            `json:"pinned,omitempty"`
+	State      string          `json:"state,omitempty"`
+}
+
+// Note represents a note widget.
+type Note struct {
+	WidgetBase
+	Title           string `json:"title,omitempty"`
+	Text            string `json:"text,omitempty"`
+	BackgroundColor string `json:"background_color,omitempty"`
+	AutoTextColor   bool   `json:"auto_text_color,omitempty"`
+	TextColor       string `json:"text_color,omitempty"`
+}
+
+// Image represents an image widget.
+type Image struct {
+	WidgetBase
+	Title            string `json:"title,omitempty"`
+	OriginalFilename string `json:"original_filename,omitempty"`
+	Hash             string `json:"hash,omitempty"`
+}
+
+// Video represents a video widget.
+type Video struct {
+	WidgetBase
+	Title            string  `json:"title,omitempty"`
+	OriginalFilename string  `json:"original_filename,omitempty"`
+	Hash             string  `json:"hash,omitempty"`
+	PlaybackState    string  `json:"playback_state,omitempty"`
+	PlaybackPosition float64 `json:"playback_position,omitempty"`
+}
+
+// PDF represents a PDF widget.
+type PDF struct {
+	WidgetBase
+	Title            string `json:"title,omitempty"`
+	OriginalFilename string `json:"original_filename,omitempty"`
+	Hash             string `json:"hash,omitempty"`
+	Index            int    `json:"index,omitempty"`
+}
+
+// Browser represents a browser widget.
+type Browser struct {
+	WidgetBase
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// ConnectorEnd describes one end of a connector.
+type ConnectorEnd struct {
+	ID           string          `json:"id"`
+	RelLocation  *WidgetLocation `json:"rel_location,omitempty"`
+	Tip          string          `json:"tip,omitempty"`
+	AutoLocation bool            `json:"auto_location,omitempty"`
+}
+
+// Connector represents a connector between two widgets.
+type Connector struct {
+	WidgetBase
+	Src       *ConnectorEnd `json:"src,omitempty"`
+	Dst       *ConnectorEnd `json:"dst,omitempty"`
+	LineColor string        `json:"line_color,omitempty"`
+	LineWidth float64       `json:"line_width,omitempty"`
+	Type      string        `json:"type,omitempty"`
+}
+
+// Anchor represents an anchor (zone) widget.
+type Anchor struct {
+	WidgetBase
+	AnchorName  string `json:"anchor_name,omitempty"`
+	AnchorIndex int    `json:"anchor_index,omitempty"`
+}
+
+// BoundingBox returns the anchor's zone bounding box, or nil if the anchor
+// has no location or size.
+func (a *Anchor) BoundingBox() *ZoneBoundingBox {
+	if a.Location == nil || a.Size == nil {
+		return nil
+	}
+	return &ZoneBoundingBox{
+		X:      a.Location.X,
+		Y:      a.Location.Y,
+		Width:  a.Size.Width,
+		Height: a.Size.Height,
+		Scale:  a.Scale,
+	}
+}
@@ -1,10 +1,8 @@
 package webui
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"time"
 
 	"github.com/jaypaulb/CanvusPowerToys/internal/atoms/config"
 	"github.com/jaypaulb/CanvusPowerToys/internal/organisms/services"
@@ -56,41 +54,10 @@ func (r *ClientResolver) GetInstallationName() (string, error) {
 
 // ResolveClientID queries the Canvus API to find client_id matching installation_name.
 func (r *ClientResolver) ResolveClientID(apiBaseURL, authToken, installationName string) (string, error) {
-	// Create HTTP client
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	// Query Canvus API for clients
-	// Base URL should already include /api/v1 if provided, but we'll be explicit
-	url := fmt.Sprintf("%s/api/v1/clients", apiBaseURL)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Private-Token", authToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
+	apiClient := NewAPIClient(apiBaseURL, authToken)
+	clients, err := apiClient.Clients().List(context.Background())
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("API error: %d", resp.StatusCode)
-	}
-
-	// Parse response
-	var clients []struct {
-		ID               string `json:"id"`
-		InstallationName string `json:"installation_name"`
-		Name             string `json:"name"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&clients); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return "", fmt.Errorf("failed to list clients: %w", err)
 	}
 
 	// Find matching client by installation_name
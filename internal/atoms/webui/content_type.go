@@ -0,0 +1,190 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// SniffLength is how much of a file SniffContentType and JPEGOrientation
+// should be given: enough for the EXIF block of a camera JPEG.
+const SniffLength = 64 << 10
+
+// asfHeader starts Windows Media (ASF) files.
+var asfHeader = []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11}
+
+// SniffContentType returns the MIME type of a file from its first bytes,
+// without parameters. The file name is only used for types without a
+// signature: Markdown, Matroska, and Windows .url and macOS .webloc
+// shortcuts.
+func SniffContentType(head []byte, filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	// ISO media: MP4, QuickTime, HEIC and AVIF by the brand of the ftyp box
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		switch string(head[8:12]) {
+		case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1":
+			return "image/heic"
+		case "avif", "avis":
+			return "image/avif"
+		case "qt  ":
+			return "video/quicktime"
+		case "M4A ", "M4B ":
+			return "audio/mp4"
+		default:
+			return "video/mp4"
+		}
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return "image/tiff"
+	case bytes.HasPrefix(head, asfHeader):
+		return "video/x-ms-wmv"
+	}
+
+	mimeType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	switch {
+	case mimeType == "video/webm" && ext == ".mkv":
+		return "video/x-matroska"
+	case strings.HasPrefix(mimeType, "text/"):
+		switch {
+		case ext == ".url" || strings.HasPrefix(strings.TrimSpace(string(head)), "[InternetShortcut]"):
+			return "application/x-url"
+		case ext == ".webloc":
+			return "application/x-webloc"
+		case mimeType == "text/plain" && (ext == ".md" || ext == ".markdown"):
+			return "text/markdown"
+		}
+	}
+	return mimeType
+}
+
+// JPEGOrientation returns the EXIF orientation (1-8) of a JPEG from its
+// first bytes, or 1 when it has none.
+func JPEGOrientation(head []byte) int {
+	if !bytes.HasPrefix(head, []byte{0xFF, 0xD8}) {
+		return 1
+	}
+	for pos := 2; pos+4 <= len(head) && head[pos] == 0xFF; {
+		marker := head[pos+1]
+		length := int(binary.BigEndian.Uint16(head[pos+2:]))
+		if marker == 0xDA || length < 2 { // image data follows: no more metadata
+			return 1
+		}
+		segment := head[pos+4 : min(pos+2+length, len(head))]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag of IFD0 of a TIFF structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// ApplyOrientation returns img turned upright according to its EXIF
+// orientation. Pixels are copied straight between the pixel buffers of
+// YCbCr (decoded JPEG), RGBA and Gray images; other images are converted to
+// RGBA first.
+func ApplyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 { // rotated by a quarter turn
+		dstW, dstH = h, w
+	}
+
+	pixel := rgbaPixels(img)
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		row := dst.Pix[y*dst.Stride:]
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored upside down
+				sx, sy = x, h-1-y
+			case 5: // mirrored, turned left
+				sx, sy = y, x
+			case 6: // turned left: rotate clockwise
+				sx, sy = y, h-1-x
+			case 7: // mirrored, turned right
+				sx, sy = w-1-y, h-1-x
+			case 8: // turned right: rotate counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			pixel(row[x*4:x*4+4], bounds.Min.X+sx, bounds.Min.Y+sy)
+		}
+	}
+	return dst
+}
+
+// rgbaPixels returns a function writing the premultiplied RGBA value of the
+// pixel of img at x, y to its 4-byte argument.
+func rgbaPixels(img image.Image) func(dst []byte, x, y int) {
+	switch src := img.(type) {
+	case *image.YCbCr:
+		return func(dst []byte, x, y int) {
+			yi, ci := src.YOffset(x, y), src.COffset(x, y)
+			dst[0], dst[1], dst[2] = color.YCbCrToRGB(src.Y[yi], src.Cb[ci], src.Cr[ci])
+			dst[3] = 0xff
+		}
+	case *image.Gray:
+		return func(dst []byte, x, y int) {
+			v := src.Pix[src.PixOffset(x, y)]
+			dst[0], dst[1], dst[2], dst[3] = v, v, v, 0xff
+		}
+	case *image.RGBA:
+		return func(dst []byte, x, y int) {
+			i := src.PixOffset(x, y)
+			copy(dst, src.Pix[i:i+4])
+		}
+	default:
+		converted := image.NewRGBA(img.Bounds())
+		draw.Draw(converted, converted.Bounds(), img, img.Bounds().Min, draw.Src)
+		return rgbaPixels(converted)
+	}
+}
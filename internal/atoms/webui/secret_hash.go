@@ -0,0 +1,77 @@
+package webui
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Secret hashes are stored as pbkdf2-sha256$<iterations>$<salt>$<key>, with
+// salt and key in unpadded base64.
+const (
+	secretHashScheme     = "pbkdf2-sha256"
+	secretHashIterations = 100000
+	secretSaltLength     = 16
+	secretKeyLength      = 32
+)
+
+// HashSecret hashes a PIN or password for storage with a random salt.
+func HashSecret(secret string) (string, error) {
+	if secret == "" {
+		return "", fmt.Errorf("secret is empty")
+	}
+	salt := make([]byte, secretSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, secret, salt, secretHashIterations, secretKeyLength)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s$%d$%s$%s", secretHashScheme, secretHashIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// IsHashedSecret reports whether value was produced by HashSecret, as
+// opposed to a secret typed into the config file in plain text.
+func IsHashedSecret(value string) bool {
+	_, _, _, err := parseSecretHash(value)
+	return err == nil
+}
+
+// VerifySecret reports whether secret matches hash. It is false for hashes
+// not produced by HashSecret.
+func VerifySecret(hash, secret string) bool {
+	iterations, salt, want, err := parseSecretHash(hash)
+	if err != nil || secret == "" {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, secret, salt, iterations, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// parseSecretHash splits a hash produced by HashSecret.
+func parseSecretHash(hash string) (iterations int, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != secretHashScheme {
+		return 0, nil, nil, fmt.Errorf("not a %s hash", secretHashScheme)
+	}
+	if iterations, err = strconv.Atoi(parts[1]); err != nil || iterations < 1 {
+		return 0, nil, nil, fmt.Errorf("invalid iteration count %q", parts[1])
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return 0, nil, nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil || len(key) == 0 {
+		return 0, nil, nil, fmt.Errorf("invalid key")
+	}
+	return iterations, salt, key, nil
+}
@@ -0,0 +1,173 @@
+package webui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WidgetSubscriber streams widget changes of a canvas from the Canvus
+// widgets endpoint. Like the workspace subscription, MTCS sends one JSON
+// block per line with \n as keepalive. The first block after connecting is
+// the full widget set; later blocks hold the widgets that changed, with
+// deleted widgets marked by state "deleted".
+type WidgetSubscriber struct {
+	apiBaseURL string
+	authToken  string
+	httpClient *http.Client
+}
+
+// WidgetEvent is one block of the widget stream.
+type WidgetEvent struct {
+	Initial   bool     // Widgets is the full widget set of the canvas
+	Widgets   []Widget // created or updated widgets
+	Deleted   []string // IDs of deleted widgets
+	Timestamp time.Time
+}
+
+// streamedWidget is a widget as sent on the stream, with its state.
+type streamedWidget struct {
+	Widget
+	State string `json:"state"`
+}
+
+// NewWidgetSubscriber creates a new widget subscriber.
+func NewWidgetSubscriber(apiBaseURL, authToken string) *WidgetSubscriber {
+	return &WidgetSubscriber{
+		apiBaseURL: strings.TrimSuffix(apiBaseURL, "/"),
+		authToken:  authToken,
+		// No timeout - TCP JSON streaming connection stays open indefinitely
+		httpClient: &http.Client{
+			Timeout: 0,
+		},
+	}
+}
+
+// Subscribe streams the widget changes of canvasID until ctx is done,
+// reconnecting after 5 seconds when the stream drops. Every connection
+// starts with an Initial event. An error is sent whenever the stream drops,
+// so receivers know events may have been missed until the next Initial event.
+func (ws *WidgetSubscriber) Subscribe(ctx context.Context, canvasID string) (<-chan WidgetEvent, <-chan error) {
+	eventChan := make(chan WidgetEvent, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(eventChan)
+		defer close(errChan)
+
+		baseURL := ws.apiBaseURL
+		if !strings.Contains(baseURL, "/api/v1") && !strings.Contains(baseURL, "/api") {
+			baseURL = baseURL + "/api/v1"
+		}
+		url := fmt.Sprintf("%s/canvases/%s/widgets?subscribe", baseURL, canvasID)
+
+		for {
+			err := ws.connectAndStream(ctx, url, eventChan)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				err = fmt.Errorf("widget stream closed")
+			}
+			select {
+			case errChan <- err:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+				// Retry connection
+			}
+		}
+	}()
+
+	return eventChan, errChan
+}
+
+// connectAndStream streams one connection, marking its first block Initial.
+func (ws *WidgetSubscriber) connectAndStream(ctx context.Context, url string, eventChan chan<- WidgetEvent) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Private-Token", ws.authToken)
+
+	resp, err := ws.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	// The initial block holds every widget of the canvas on one line.
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	initial := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		event, err := ParseWidgetEvent([]byte(line))
+		if err != nil {
+			fmt.Printf("[WidgetSubscriber] Failed to parse JSON line: %v\n", err)
+			if initial {
+				return err
+			}
+			continue
+		}
+		event.Initial = initial
+		initial = false
+
+		select {
+		case eventChan <- *event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanner error: %w", err)
+	}
+	return nil
+}
+
+// ParseWidgetEvent parses one block of the widget stream, which is either a
+// JSON array of widgets or a single widget object.
+func ParseWidgetEvent(data []byte) (*WidgetEvent, error) {
+	var widgets []streamedWidget
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		if err := json.Unmarshal(data, &widgets); err != nil {
+			return nil, fmt.Errorf("invalid widget array: %w", err)
+		}
+	} else {
+		var widget streamedWidget
+		if err := json.Unmarshal(data, &widget); err != nil {
+			return nil, fmt.Errorf("invalid widget: %w", err)
+		}
+		widgets = append(widgets, widget)
+	}
+
+	event := &WidgetEvent{Timestamp: time.Now()}
+	for _, widget := range widgets {
+		if widget.ID == "" {
+			continue
+		}
+		if widget.State == "deleted" {
+			event.Deleted = append(event.Deleted, widget.ID)
+		} else {
+			event.Widgets = append(event.Widgets, widget.Widget)
+		}
+	}
+	return event, nil
+}
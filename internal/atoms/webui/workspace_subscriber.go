@@ -8,26 +8,72 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // WorkspaceSubscriber handles TCP JSON streaming subscription to Canvus workspace endpoint.
 // The MTCS sends one JSON block per line, with \n as keepalive.
 type WorkspaceSubscriber struct {
-	clientID   string
-	apiBaseURL string
-	authToken  string
-	httpClient *http.Client
+	clientID        string
+	workspace       int
+	apiBaseURL      string
+	authToken       string
+	httpClient      *http.Client
+	reconnectPolicy ReconnectPolicy
+
+	mu    sync.Mutex
+	stats SubscriberStats
+}
+
+// ReconnectPolicy controls how WorkspaceSubscriber reconnects a dropped
+// stream. Consecutive failures back off exponentially from BaseDelay up to
+// MaxDelay; a connection that delivered data resets the backoff.
+type ReconnectPolicy struct {
+	BaseDelay   time.Duration // Delay before the first reconnect
+	MaxDelay    time.Duration // Upper bound for a single delay
+	Jitter      float64       // Fraction of the delay randomised (0-1)
+	IdleTimeout time.Duration // Connections silent this long are torn down (0 disables)
+}
+
+// DefaultReconnectPolicy returns the policy used by NewWorkspaceSubscriber.
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+		Jitter:      0.2,
+		IdleTimeout: 60 * time.Second,
+	}
+}
+
+// backoff returns the delay after the given number of consecutive failures.
+func (p ReconnectPolicy) backoff(failures int) time.Duration {
+	return RetryPolicy{BaseDelay: p.BaseDelay, MaxDelay: p.MaxDelay, Jitter: p.Jitter}.backoff(failures)
+}
+
+// SubscriberStats describes the connection history of a WorkspaceSubscriber.
+type SubscriberStats struct {
+	Connected           bool      `json:"connected"`
+	Connects            int       `json:"connects"`             // Successful connections
+	Reconnects          int       `json:"reconnects"`           // Connection attempts after the first
+	ConsecutiveFailures int       `json:"consecutive_failures"` // Failures since data was last received
+	LastFailure         string    `json:"last_failure,omitempty"`
+	LastFailureAt       time.Time `json:"last_failure_at,omitempty"`
+	LastDataAt          time.Time `json:"last_data_at,omitempty"` // Last event or keepalive
+	NextAttemptAt       time.Time `json:"next_attempt_at,omitempty"`
 }
 
 // CanvasEvent represents a canvas_id update event from the workspace subscription.
 type CanvasEvent struct {
+	Workspace  int
 	CanvasID   string
 	CanvasName string
 	Timestamp  time.Time
 }
 
-// NewWorkspaceSubscriber creates a new workspace subscriber.
+// NewWorkspaceSubscriber creates a new workspace subscriber for workspace 0
+// of the client.
 func NewWorkspaceSubscriber(clientID, apiBaseURL, authToken string) *WorkspaceSubscriber {
 	return &WorkspaceSubscriber{
 		clientID:   clientID,
@@ -37,12 +83,39 @@ func NewWorkspaceSubscriber(clientID, apiBaseURL, authToken string) *WorkspaceSu
 		httpClient: &http.Client{
 			Timeout: 0, // No timeout for streaming connections
 		},
+		reconnectPolicy: DefaultReconnectPolicy(),
 	}
 }
 
+// SetWorkspace selects the workspace of the client to subscribe to.
+// It should be called before Subscribe.
+func (ws *WorkspaceSubscriber) SetWorkspace(workspace int) {
+	ws.workspace = workspace
+}
+
+// Workspace returns the workspace the subscriber streams.
+func (ws *WorkspaceSubscriber) Workspace() int {
+	return ws.workspace
+}
+
+// SetReconnectPolicy replaces the subscriber's reconnect policy.
+// It should be called before Subscribe.
+func (ws *WorkspaceSubscriber) SetReconnectPolicy(policy ReconnectPolicy) {
+	ws.reconnectPolicy = policy
+}
+
+// Stats returns the connection history of the subscriber.
+func (ws *WorkspaceSubscriber) Stats() SubscriberStats {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.stats
+}
+
 // Subscribe connects to the workspace TCP JSON streaming endpoint and streams canvas_id updates.
 // MTCS sends one JSON block per line, with \n as keepalive.
-// Returns a channel of CanvasEvent and an error channel.
+// Returns a channel of CanvasEvent and an error channel. Every dropped
+// connection is reported on the error channel, which is never waited on:
+// errors nobody receives are dropped, and Stats keeps the last one.
 func (ws *WorkspaceSubscriber) Subscribe(ctx context.Context) (<-chan CanvasEvent, <-chan error) {
 	eventChan := make(chan CanvasEvent, 10)
 	errChan := make(chan error, 1)
@@ -56,23 +129,39 @@ func (ws *WorkspaceSubscriber) Subscribe(ctx context.Context) (<-chan CanvasEven
 		if !strings.Contains(baseURL, "/api/v1") && !strings.Contains(baseURL, "/api") {
 			baseURL = strings.TrimSuffix(baseURL, "/") + "/api/v1"
 		}
-		url := fmt.Sprintf("%s/clients/%s/workspaces/0/?subscribe", baseURL, ws.clientID)
+		url := fmt.Sprintf("%s/clients/%s/workspaces/%d/?subscribe", baseURL, ws.clientID, ws.workspace)
 
-		for {
-			select {
-			case <-ctx.Done():
+		for attempt := 0; ; attempt++ {
+			if attempt > 0 {
+				ws.updateStats(func(stats *SubscriberStats) { stats.Reconnects++ })
+			}
+			err := ws.connectAndStream(ctx, url, eventChan)
+			if ctx.Err() != nil {
 				return
+			}
+			if err == nil {
+				err = fmt.Errorf("workspace stream closed")
+			}
+
+			var delay time.Duration
+			ws.updateStats(func(stats *SubscriberStats) {
+				stats.Connected = false
+				stats.ConsecutiveFailures++
+				stats.LastFailure = err.Error()
+				stats.LastFailureAt = time.Now()
+				delay = ws.reconnectPolicy.backoff(stats.ConsecutiveFailures)
+				stats.NextAttemptAt = stats.LastFailureAt.Add(delay)
+			})
+			fmt.Printf("[WorkspaceSubscriber] Stream failed, reconnecting in %v: %v\n", delay, err)
+			select {
+			case errChan <- err:
 			default:
-				if err := ws.connectAndStream(ctx, url, eventChan); err != nil {
-					errChan <- err
-					// Wait before reconnecting
-					select {
-					case <-ctx.Done():
-						return
-					case <-time.After(5 * time.Second):
-						// Retry connection
-					}
-				}
+				// Nobody is receiving; do not stall the stream
+			}
+
+			// Wait before reconnecting
+			if sleepContext(ctx, delay) != nil {
+				return
 			}
 		}
 	}()
@@ -80,10 +169,40 @@ func (ws *WorkspaceSubscriber) Subscribe(ctx context.Context) (<-chan CanvasEven
 	return eventChan, errChan
 }
 
+// updateStats applies update to the subscriber stats under the lock.
+func (ws *WorkspaceSubscriber) updateStats(update func(stats *SubscriberStats)) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	update(&ws.stats)
+}
+
 // connectAndStream establishes TCP JSON streaming connection and streams events.
 // MTCS sends one JSON block per line, with \n as keepalive (empty lines).
+// The connection is torn down when no line arrives within the idle timeout.
 func (ws *WorkspaceSubscriber) connectAndStream(ctx context.Context, url string, eventChan chan<- CanvasEvent) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The watchdog cancels the connection unless touched by incoming data
+	idleTimeout := ws.reconnectPolicy.IdleTimeout
+	var idle atomic.Bool
+	touch := func() {}
+	if idleTimeout > 0 {
+		watchdog := time.AfterFunc(idleTimeout, func() {
+			idle.Store(true)
+			cancel()
+		})
+		defer watchdog.Stop()
+		touch = func() { watchdog.Reset(idleTimeout) }
+	}
+	streamErr := func(err error) error {
+		if idle.Load() && ctx.Err() == nil {
+			return fmt.Errorf("no data for %v, closing idle stream", idleTimeout)
+		}
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(connCtx, "GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -94,37 +213,47 @@ func (ws *WorkspaceSubscriber) connectAndStream(ctx context.Context, url string,
 
 	resp, err := ws.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to connect: %w", err)
+		return streamErr(fmt.Errorf("failed to connect: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
+	ws.updateStats(func(stats *SubscriberStats) {
+		stats.Connected = true
+		stats.Connects++
+	})
 
 	// Read line by line - each line is a JSON object, empty lines are keepalive
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			line := strings.TrimSpace(scanner.Text())
-			// Skip empty lines (keepalive - just \n)
-			if line == "" {
-				continue
-			}
+		touch()
+		ws.updateStats(func(stats *SubscriberStats) {
+			stats.LastDataAt = time.Now()
+			stats.ConsecutiveFailures = 0
+			stats.NextAttemptAt = time.Time{}
+		})
+
+		line := strings.TrimSpace(scanner.Text())
+		// Skip empty lines (keepalive - just \n)
+		if line == "" {
+			continue
+		}
 
-			// Each non-empty line is a JSON object
-			event := ws.parseEvent(line)
-			if event != nil {
-				eventChan <- *event
+		// Each non-empty line is a JSON object
+		event := ws.parseEvent(line)
+		if event != nil {
+			select {
+			case eventChan <- *event:
+			case <-connCtx.Done():
+				return streamErr(connCtx.Err())
 			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil && err != io.EOF {
-		return fmt.Errorf("scanner error: %w", err)
+		return streamErr(fmt.Errorf("scanner error: %w", err))
 	}
 
 	return nil
@@ -149,6 +278,7 @@ func (ws *WorkspaceSubscriber) parseEvent(jsonLine string) *CanvasEvent {
 	canvasName, _ := eventData["canvas_name"].(string)
 
 	return &CanvasEvent{
+		Workspace:  ws.workspace,
 		CanvasID:   canvasID,
 		CanvasName: canvasName,
 		Timestamp:  time.Now(),
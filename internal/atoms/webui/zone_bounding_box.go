@@ -1,7 +1,7 @@
 package webui
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 )
 
@@ -20,44 +20,19 @@ func GetZoneBoundingBox(apiClient *APIClient, canvasID, zoneID string) (*ZoneBou
 		return nil, fmt.Errorf("canvas not available")
 	}
 
-	endpoint := fmt.Sprintf("/api/v1/canvases/%s/anchors/%s", canvasID, zoneID)
-	fmt.Printf("[GetZoneBoundingBox] Fetching zone %s from %s\n", zoneID, endpoint)
-	data, err := apiClient.Get(endpoint)
+	fmt.Printf("[GetZoneBoundingBox] Fetching zone %s\n", zoneID)
+	anchor, err := apiClient.Anchors(canvasID).Get(context.Background(), zoneID)
 	if err != nil {
 		fmt.Printf("[GetZoneBoundingBox] ERROR: Failed to get anchor: %v\n", err)
 		return nil, fmt.Errorf("failed to get anchor: %w", err)
 	}
 
-	var anchor struct {
-		Location *struct {
-			X float64 `json:"x"`
-			Y float64 `json:"y"`
-		} `json:"location"`
-		Size *struct {
-			Width  float64 `json:"width"`
-			Height float64 `json:"height"`
-		} `json:"size"`
-		Scale float64 `json:"scale"`
-	}
-	if err := json.Unmarshal(data, &anchor); err != nil {
-		fmt.Printf("[GetZoneBoundingBox] ERROR: Failed to parse anchor JSON: %v\n", err)
-		fmt.Printf("[GetZoneBoundingBox] Raw response: %s\n", string(data))
-		return nil, fmt.Errorf("failed to parse anchor: %w", err)
-	}
-
-	if anchor.Location == nil || anchor.Size == nil {
+	bb := anchor.BoundingBox()
+	if bb == nil {
 		fmt.Printf("[GetZoneBoundingBox] ERROR: Invalid anchor data - Location=%v, Size=%v\n", anchor.Location, anchor.Size)
 		return nil, fmt.Errorf("invalid anchor data for zone ID: %s", zoneID)
 	}
 
-	bb := &ZoneBoundingBox{
-		X:      anchor.Location.X,
-		Y:      anchor.Location.Y,
-		Width:  anchor.Size.Width,
-		Height: anchor.Size.Height,
-		Scale:  anchor.Scale,
-	}
-
 	fmt.Printf("[GetZoneBoundingBox] Zone %s: X=%.2f, Y=%.2f, W=%.2f, H=%.2f, Scale=%.2f\n",
 		zoneID, bb.X, bb.Y, bb.Width, bb.Height, bb.Scale)
 
@@ -66,7 +41,8 @@ func GetZoneBoundingBox(apiClient *APIClient, canvasID, zoneID string) (*ZoneBou
 
 // WidgetIsInZone checks if a widget is within a zone bounding box.
 // Checks if widget's location point is within the zone bounds (with 2px margin).
-// Note: This checks the widget's location point, not the widget's bounding box.
+// Note: This checks the widget's location point, not the widget's bounding box;
+// use ZoneMembership to test by the widget's size and scale.
 func WidgetIsInZone(widget *Widget, zoneBB *ZoneBoundingBox) bool {
 	if widget.Location == nil {
 		return false
@@ -75,10 +51,10 @@ func WidgetIsInZone(widget *Widget, zoneBB *ZoneBoundingBox) bool {
 	wy := widget.Location.Y
 
 	// Zone bounds (with 2px margin to avoid edge cases)
-	zoneMinX := zoneBB.X + 2
-	zoneMaxX := zoneBB.X + zoneBB.Width - 2
-	zoneMinY := zoneBB.Y + 2
-	zoneMaxY := zoneBB.Y + zoneBB.Height - 2
+	zoneMinX := zoneBB.X + zoneEdgeMargin
+	zoneMaxX := zoneBB.X + zoneBB.Width - zoneEdgeMargin
+	zoneMinY := zoneBB.Y + zoneEdgeMargin
+	zoneMaxY := zoneBB.Y + zoneBB.Height - zoneEdgeMargin
 
 	withinX := wx >= zoneMinX && wx <= zoneMaxX
 	withinY := wy >= zoneMinY && wy <= zoneMaxY
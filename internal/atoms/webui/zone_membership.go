@@ -0,0 +1,153 @@
+package webui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ZoneMembershipMode selects how a widget is tested against a zone.
+type ZoneMembershipMode string
+
+const (
+	// MembershipPoint tests the widget's top-left location (the original behaviour).
+	MembershipPoint ZoneMembershipMode = "point"
+	// MembershipContained requires the whole widget to be inside the zone.
+	MembershipContained ZoneMembershipMode = "contained"
+	// MembershipCenter tests the center of the widget.
+	MembershipCenter ZoneMembershipMode = "center"
+	// MembershipOverlap requires a fraction of the widget's area to be inside the zone.
+	MembershipOverlap ZoneMembershipMode = "overlap"
+)
+
+// DefaultOverlapThreshold is the overlap ratio used when "overlap" is given
+// without a threshold.
+const DefaultOverlapThreshold = 0.5
+
+// zoneEdgeMargin keeps widgets sitting exactly on a zone edge from matching
+// both neighbouring zones.
+const zoneEdgeMargin = 2
+
+// ZoneMembership is a policy deciding whether a widget belongs to a zone.
+type ZoneMembership struct {
+	Mode      ZoneMembershipMode
+	Threshold float64 // Overlap only: fraction of the widget area, in (0, 1]
+}
+
+// DefaultZoneMembership returns the point policy used when none is configured.
+func DefaultZoneMembership() ZoneMembership {
+	return ZoneMembership{Mode: MembershipPoint}
+}
+
+// ParseZoneMembership parses a policy such as "center", "contained",
+// "overlap" or "overlap:0.75". An empty string selects the default policy.
+func ParseZoneMembership(value string) (ZoneMembership, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return DefaultZoneMembership(), nil
+	}
+
+	mode, threshold, hasThreshold := strings.Cut(value, ":")
+	switch ZoneMembershipMode(mode) {
+	case MembershipPoint, MembershipContained, MembershipCenter:
+		if hasThreshold {
+			return ZoneMembership{}, fmt.Errorf("zone membership %q does not take a threshold", mode)
+		}
+		return ZoneMembership{Mode: ZoneMembershipMode(mode)}, nil
+	case MembershipOverlap:
+		if !hasThreshold {
+			return ZoneMembership{Mode: MembershipOverlap, Threshold: DefaultOverlapThreshold}, nil
+		}
+		ratio, err := strconv.ParseFloat(threshold, 64)
+		if err != nil || ratio <= 0 || ratio > 1 {
+			return ZoneMembership{}, fmt.Errorf("invalid overlap threshold %q: must be greater than 0 and at most 1", threshold)
+		}
+		return ZoneMembership{Mode: MembershipOverlap, Threshold: ratio}, nil
+	default:
+		return ZoneMembership{}, fmt.Errorf("unknown zone membership %q (want point, contained, center or overlap[:ratio])", mode)
+	}
+}
+
+// String returns the policy in the form accepted by ParseZoneMembership.
+func (m ZoneMembership) String() string {
+	if m.Mode == MembershipOverlap {
+		return fmt.Sprintf("%s:%s", m.Mode, strconv.FormatFloat(m.Threshold, 'f', -1, 64))
+	}
+	if m.Mode == "" {
+		return string(MembershipPoint)
+	}
+	return string(m.Mode)
+}
+
+// Contains reports whether widget belongs to the zone under this policy.
+// Widgets without a size are tested by their location point.
+func (m ZoneMembership) Contains(widget *Widget, zoneBB *ZoneBoundingBox) bool {
+	if widget.Location == nil {
+		return false
+	}
+	bounds, ok := widget.Bounds()
+	if !ok || m.Mode == MembershipPoint || m.Mode == "" {
+		return WidgetIsInZone(widget, zoneBB)
+	}
+
+	zone := WidgetBounds{X: zoneBB.X, Y: zoneBB.Y, Width: zoneBB.Width, Height: zoneBB.Height}
+	switch m.Mode {
+	case MembershipContained:
+		return bounds.X >= zone.X-zoneEdgeMargin && bounds.MaxX() <= zone.MaxX()+zoneEdgeMargin &&
+			bounds.Y >= zone.Y-zoneEdgeMargin && bounds.MaxY() <= zone.MaxY()+zoneEdgeMargin
+	case MembershipCenter:
+		cx := bounds.X + bounds.Width/2
+		cy := bounds.Y + bounds.Height/2
+		return cx >= zone.X && cx < zone.MaxX() && cy >= zone.Y && cy < zone.MaxY()
+	case MembershipOverlap:
+		return bounds.Intersection(zone)/bounds.Area() >= m.Threshold
+	}
+	return false
+}
+
+// WidgetBounds is the rectangle a widget covers on the canvas.
+type WidgetBounds struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// MaxX returns the right edge of the rectangle.
+func (b WidgetBounds) MaxX() float64 { return b.X + b.Width }
+
+// MaxY returns the bottom edge of the rectangle.
+func (b WidgetBounds) MaxY() float64 { return b.Y + b.Height }
+
+// Area returns the area of the rectangle.
+func (b WidgetBounds) Area() float64 { return b.Width * b.Height }
+
+// Intersection returns the area shared by b and other.
+func (b WidgetBounds) Intersection(other WidgetBounds) float64 {
+	width := min(b.MaxX(), other.MaxX()) - max(b.X, other.X)
+	height := min(b.MaxY(), other.MaxY()) - max(b.Y, other.Y)
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+	return width * height
+}
+
+// Bounds returns the rectangle the widget covers: its size multiplied by its
+// scale, starting at its location. ok is false if the widget has no location
+// or no area.
+func (w *Widget) Bounds() (bounds WidgetBounds, ok bool) {
+	if w.Location == nil || w.Size == nil {
+		return WidgetBounds{}, false
+	}
+	scale := w.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	bounds = WidgetBounds{
+		X:      w.Location.X,
+		Y:      w.Location.Y,
+		Width:  w.Size.Width * scale,
+		Height: w.Size.Height * scale,
+	}
+	return bounds, bounds.Area() > 0
+}
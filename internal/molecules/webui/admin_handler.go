@@ -26,7 +26,8 @@ func NewAdminHandler(apiClient *webuiatoms.APIClient, canvasService *CanvasServi
 	}
 }
 
-// HandleCreateTargets handles POST /api/admin/create-targets - Create target notes for teams 1-7.
+// HandleCreateTargets handles POST /api/admin/create-targets - Create a target
+// note for each team of the RCU session, in the zone with its anchor_index.
 func (h *AdminHandler) HandleCreateTargets(w http.ResponseWriter, r *http.Request) {
 	// Panic recovery
 	defer func() {
@@ -40,7 +41,7 @@ func (h *AdminHandler) HandleCreateTargets(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
@@ -60,27 +61,12 @@ func (h *AdminHandler) HandleCreateTargets(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if len(zones) < 8 {
-		sendErrorResponse(w, "Not enough zones available (need at least 8 zones)", http.StatusBadRequest)
-		return
-	}
-
-	// Team colors (ROYGBIV)
-	teamColors := map[int]string{
-		1: "#FF0000FF", // Red
-		2: "#FF7F00FF", // Orange
-		3: "#FFFF00FF", // Yellow
-		4: "#00FF00FF", // Green
-		5: "#0000FFFF", // Blue
-		6: "#4B0082FF", // Indigo
-		7: "#8B00FFFF", // Violet
-	}
-
-	// Create notes for teams 1-7 (using zones with anchor_index 1-7, skipping index 0)
+	// Create a note for each team in the zone with the team's anchor_index
 	createdTeams := []int{}
 	var errors []string
-	for i := 1; i <= 7; i++ {
-		// Find zone with anchor_index == i
+	for _, team := range h.rcuHandler.session.Get().Teams {
+		i := team.ID
+		// Find zone with anchor_index == team.AnchorIndex
 		var zone map[string]interface{}
 		for _, z := range zones {
 			if z == nil {
@@ -98,14 +84,14 @@ func (h *AdminHandler) HandleCreateTargets(w http.ResponseWriter, r *http.Reques
 			default:
 				continue
 			}
-			if anchorIndex == i {
+			if anchorIndex == team.AnchorIndex {
 				zone = z
 				break
 			}
 		}
 
 		if zone == nil {
-			errors = append(errors, fmt.Sprintf("Team %d: zone with anchor_index %d not found", i, i))
+			errors = append(errors, fmt.Sprintf("Team %d: zone with anchor_index %d not found", i, team.AnchorIndex))
 			continue
 		}
 
@@ -126,8 +112,8 @@ func (h *AdminHandler) HandleCreateTargets(w http.ResponseWriter, r *http.Reques
 		}
 
 		noteTitle := fmt.Sprintf("Team_%d_Target", i)
-		noteText := fmt.Sprintf("Team %d", i)
-		noteColor := teamColors[i]
+		noteText := team.Name
+		noteColor := team.Color
 
 		// Create note widget at zone location
 		// Note: Use /notes endpoint, not /widgets (widgets is read-only)
@@ -182,7 +168,7 @@ func (h *AdminHandler) HandleDeleteTargets(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
@@ -254,27 +240,19 @@ func (h *AdminHandler) HandleTestTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Team < 1 || req.Team > 7 {
-		sendErrorResponse(w, "Team must be between 1 and 7", http.StatusBadRequest)
+	team, err := h.rcuHandler.session.Team(req.Team)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Use RCU handler's create note functionality with user "Admin"
-	// Get team color for Admin's note
-	teamColors := map[int]string{
-		1: "#FF0000FF", // Red
-		2: "#FF7F00FF", // Orange
-		3: "#FFFF00FF", // Yellow
-		4: "#00FF00FF", // Green
-		5: "#0000FFFF", // Blue
-		6: "#4B0082FF", // Indigo
-		7: "#8B00FFFF", // Violet
-	}
-	adminColor := teamColors[req.Team]
+	// in the team's color
+	adminColor := team.Color
 
 	noteText := req.Text
 	if noteText == "" {
-		noteText = fmt.Sprintf("Test note from Admin to Team %d", req.Team)
+		noteText = fmt.Sprintf("Test note from Admin to %s", team.Name)
 	}
 
 	// Create a new request body for HandleCreateNote
@@ -294,6 +272,34 @@ func (h *AdminHandler) HandleTestTeam(w http.ResponseWriter, r *http.Request) {
 	h.rcuHandler.HandleCreateNote(w, newReq)
 }
 
+// HandleSession handles GET/PUT /api/admin/rcu-session - Get or replace the
+// RCU session: its teams with their IDs, names, colors and target
+// anchor_index. Teams sent without an ID are added.
+func (h *AdminHandler) HandleSession(w http.ResponseWriter, r *http.Request) {
+	store := h.rcuHandler.session
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var session RCUSession
+		if err := json.NewDecoder(r.Body).Decode(&session); err != nil {
+			sendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := store.Set(session); err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"session": store.Get(),
+	}, http.StatusOK)
+}
+
 // HandleListUsers handles GET /api/admin/list-users - List RCU users.
 func (h *AdminHandler) HandleListUsers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -316,6 +322,10 @@ func (h *AdminHandler) HandleListUsers(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			continue
 		}
+		teamName := fmt.Sprintf("Team %d", team) // a team since removed from the session
+		if sessionTeam, err := h.rcuHandler.session.Team(team); err == nil {
+			teamName = sessionTeam.Name
+		}
 
 		for username, color := range teamUsersMap {
 			colorStr, ok := color.(string)
@@ -323,9 +333,10 @@ func (h *AdminHandler) HandleListUsers(w http.ResponseWriter, r *http.Request) {
 				continue
 			}
 			userList = append(userList, map[string]interface{}{
-				"team":  team,
-				"name":  username,
-				"color": colorStr,
+				"team":      team,
+				"team_name": teamName,
+				"name":      username,
+				"color":     colorStr,
 			})
 		}
 	}
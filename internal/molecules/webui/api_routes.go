@@ -12,56 +12,137 @@ import (
 
 // APIRoutes handles registration of API routes for the WebUI server.
 type APIRoutes struct {
-	canvasService *CanvasService
-	sseHandler    *SSEHandler
-	apiClient     *webuiatoms.APIClient
-	pagesHandler  *PagesHandler
-	macrosHandler *MacrosHandler
-	uploadHandler *UploadHandler
-	rcuHandler    *RCUHandler
-	adminHandler  *AdminHandler
+	canvasService   *CanvasService
+	clients         *ClientRegistry
+	events          *EventBus
+	sseHandler      *SSEHandler
+	apiClient       *webuiatoms.APIClient
+	pagesHandler    *PagesHandler
+	macrosHandler   *MacrosHandler
+	snapshotHandler *SnapshotHandler
+	jobsHandler     *JobsHandler
+	uploadHandler   *UploadHandler
+	staging         *UploadStaging
+	pipeline        *UploadPipeline
+	watchFolder     *WatchFolder
+	rcuHandler      *RCUHandler
+	adminHandler    *AdminHandler
+	auth            *Authenticator
+	audit           *AuditLog
 }
 
 // NewAPIRoutes creates a new API routes handler.
 func NewAPIRoutes(canvasService *CanvasService, apiClient *webuiatoms.APIClient, uploadDir string) *APIRoutes {
-	sseHandler := NewSSEHandler(canvasService)
+	// Event bus shared by every subsystem that publishes to SSE clients
+	events := NewEventBus()
+	if canvasService != nil {
+		canvasService.SetEventBus(events)
+	}
+	// Other clients are tracked on demand, selected by the client_id parameter
+	clients := NewClientRegistry(canvasService, apiClient)
+	clients.SetEventBus(events)
+
+	sseHandler := NewSSEHandler(canvasService, events)
 	pagesHandler := NewPagesHandler(apiClient, canvasService)
 	macrosHandler := NewMacrosHandler(apiClient, canvasService)
+	macrosHandler.SetEventBus(events)
+	macrosHandler.SetClients(clients)
+	snapshotHandler := NewSnapshotHandler(apiClient, canvasService)
+	jobsHandler := NewJobsHandler(NewMacroScheduler(macrosHandler, ""))
+	// Resumable uploads, placed by the remote upload and RCU handlers and
+	// sniffed and converted by one pipeline
+	staging := NewUploadStaging("", DefaultMaxUploadSize)
+	pipeline := NewUploadPipeline()
 	uploadHandler := NewUploadHandler(apiClient, canvasService, uploadDir)
+	uploadHandler.SetEventBus(events)
+	uploadHandler.SetStaging(staging)
+	uploadHandler.SetPipeline(pipeline)
 	rcuHandler := NewRCUHandler(apiClient, canvasService)
+	rcuHandler.SetEventBus(events)
+	rcuHandler.SetStaging(staging)
+	rcuHandler.SetPipeline(pipeline)
 	adminHandler := NewAdminHandler(apiClient, canvasService, rcuHandler)
 
 	return &APIRoutes{
-		canvasService: canvasService,
-		sseHandler:    sseHandler,
-		apiClient:     apiClient,
-		pagesHandler:  pagesHandler,
-		macrosHandler: macrosHandler,
-		uploadHandler: uploadHandler,
-		rcuHandler:    rcuHandler,
-		adminHandler:  adminHandler,
+		canvasService:   canvasService,
+		clients:         clients,
+		events:          events,
+		sseHandler:      sseHandler,
+		apiClient:       apiClient,
+		pagesHandler:    pagesHandler,
+		macrosHandler:   macrosHandler,
+		snapshotHandler: snapshotHandler,
+		jobsHandler:     jobsHandler,
+		uploadHandler:   uploadHandler,
+		staging:         staging,
+		pipeline:        pipeline,
+		rcuHandler:      rcuHandler,
+		adminHandler:    adminHandler,
+	}
+}
+
+// SetAuthenticator sets the authenticator serving the login endpoints.
+func (ar *APIRoutes) SetAuthenticator(auth *Authenticator) {
+	ar.auth = auth
+}
+
+// SetUploadStaging sets where resumable uploads are received, and the size
+// limit of every upload.
+func (ar *APIRoutes) SetUploadStaging(staging *UploadStaging) {
+	ar.staging = staging
+	ar.uploadHandler.SetStaging(staging)
+	ar.rcuHandler.SetStaging(staging)
+}
+
+// UploadPipeline returns the pipeline sniffing and converting uploads, to
+// register more converters with.
+func (ar *APIRoutes) UploadPipeline() *UploadPipeline {
+	return ar.pipeline
+}
+
+// SetWatchFolder sets the watch folder served by /api/watch-folder, placing
+// files through the shared upload pipeline, history and event bus.
+func (ar *APIRoutes) SetWatchFolder(watchFolder *WatchFolder) {
+	ar.watchFolder = watchFolder
+	if watchFolder != nil {
+		watchFolder.SetPipeline(ar.pipeline)
+		watchFolder.SetHistory(ar.uploadHandler.history)
+		watchFolder.SetEventBus(ar.events)
+	}
+}
+
+// SetAuditLog sets the audit log served by /api/admin/audit, resolving the
+// canvas of each request through the client registry.
+func (ar *APIRoutes) SetAuditLog(audit *AuditLog) {
+	ar.audit = audit
+	if audit != nil {
+		audit.SetCanvasResolver(ar.clients.canvasIDOf)
 	}
 }
 
 // RegisterRoutes registers all API routes with the given mux.
+// Routes serving a canvas accept a client_id parameter selecting the client
+// whose canvas they act on; without it they use the primary client.
 func (ar *APIRoutes) RegisterRoutes(mux *http.ServeMux) {
+	forClient := ar.clients.forClient
+
 	// SSE endpoint for canvas_id updates
-	mux.HandleFunc("/api/subscribe-workspace", ar.sseHandler.HandleSubscribe)
+	mux.HandleFunc("/api/subscribe-workspace", forClient(ar.sseHandler.HandleSubscribe))
 
 	// Canvas info endpoint (current canvas_id and canvas_name)
-	mux.HandleFunc("/api/canvas/info", ar.handleCanvasInfo)
+	mux.HandleFunc("/api/canvas/info", forClient(ar.handleCanvasInfo))
 
 	// Installation info endpoint
-	mux.HandleFunc("/api/installation/info", ar.handleInstallationInfo)
+	mux.HandleFunc("/api/installation/info", forClient(ar.handleInstallationInfo))
 
 	// Health check endpoint
-	mux.HandleFunc("/api/health", ar.handleHealth)
+	mux.HandleFunc("/api/health", forClient(ar.handleHealth))
 
 	// Server info endpoint (for IP detection)
 	mux.HandleFunc("/api/server-info", ar.handleServerInfo)
 
 	// Pages endpoints
-	mux.HandleFunc("/api/pages", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/pages", forClient(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			ar.pagesHandler.HandleList(w, r)
 		} else if r.Method == http.MethodPost {
@@ -69,42 +150,67 @@ func (ar *APIRoutes) RegisterRoutes(mux *http.ServeMux) {
 		} else {
 			sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	})
+	}))
 
 	// Zones endpoints (for pages.js compatibility)
-	mux.HandleFunc("/get-zones", ar.pagesHandler.HandleGetZones)
-	mux.HandleFunc("/create-zones", ar.pagesHandler.HandleCreateZones)
-	mux.HandleFunc("/delete-zones", ar.pagesHandler.HandleDeleteZones)
+	mux.HandleFunc("/get-zones", forClient(ar.pagesHandler.HandleGetZones))
+	mux.HandleFunc("/create-zones", forClient(ar.pagesHandler.HandleCreateZones))
+	mux.HandleFunc("/delete-zones", forClient(ar.pagesHandler.HandleDeleteZones))
 
 	// Macros endpoints
-	mux.HandleFunc("/api/macros/groups", ar.macrosHandler.HandleGroups)
-	mux.HandleFunc("/api/macros/pinned", ar.macrosHandler.HandlePinned)
-	mux.HandleFunc("/api/macros/move", ar.macrosHandler.HandleMove)
-	mux.HandleFunc("/api/macros/copy", ar.macrosHandler.HandleCopy)
-	mux.HandleFunc("/api/macros/pin-all", ar.macrosHandler.HandlePinAll)
-	mux.HandleFunc("/api/macros/unpin-all", ar.macrosHandler.HandleUnpin)
-	mux.HandleFunc("/api/macros/auto-grid", ar.macrosHandler.HandleAutoGrid)
-	mux.HandleFunc("/api/macros/group-color", ar.macrosHandler.HandleGroupColor)
-	mux.HandleFunc("/api/macros/group-title", ar.macrosHandler.HandleGroupTitle)
+	mux.HandleFunc("/api/macros/groups", forClient(ar.macrosHandler.HandleGroups))
+	mux.HandleFunc("/api/macros/pinned", forClient(ar.macrosHandler.HandlePinned))
+	mux.HandleFunc("/api/macros/move", forClient(ar.macrosHandler.HandleMove))
+	mux.HandleFunc("/api/macros/copy", forClient(ar.macrosHandler.HandleCopy))
+	mux.HandleFunc("/api/macros/zone-diff", forClient(ar.macrosHandler.HandleZoneDiff))
+	mux.HandleFunc("/api/macros/zone-sync", forClient(ar.macrosHandler.HandleZoneSync))
+	mux.HandleFunc("/api/macros/pin-all", forClient(ar.macrosHandler.HandlePinAll))
+	mux.HandleFunc("/api/macros/unpin-all", forClient(ar.macrosHandler.HandleUnpin))
+	mux.HandleFunc("/api/macros/auto-grid", forClient(ar.macrosHandler.HandleAutoGrid))
+	mux.HandleFunc("/api/macros/group-color", forClient(ar.macrosHandler.HandleGroupColor))
+	mux.HandleFunc("/api/macros/group-title", forClient(ar.macrosHandler.HandleGroupTitle))
+	mux.HandleFunc("/api/macros/undo", forClient(ar.macrosHandler.HandleUndo))
+	mux.HandleFunc("/api/macros/redo", forClient(ar.macrosHandler.HandleRedo))
+	mux.HandleFunc("/api/macros/definitions", forClient(ar.macrosHandler.HandleDefinitions))
+	mux.HandleFunc("/api/macros/run", forClient(ar.macrosHandler.HandleRun))
+	mux.HandleFunc("/api/macros/jobs", forClient(ar.jobsHandler.HandleJobs))
+	mux.HandleFunc("/api/macros/jobs/pause", forClient(ar.jobsHandler.HandlePause))
+
+	// Snapshot endpoints
+	mux.HandleFunc("/api/snapshots", forClient(ar.snapshotHandler.HandleSnapshots))
+	mux.HandleFunc("/api/snapshots/diff", forClient(ar.snapshotHandler.HandleDiff))
+	mux.HandleFunc("/api/snapshots/restore", forClient(ar.snapshotHandler.HandleRestore))
+	mux.HandleFunc("/api/snapshots/canvases", forClient(ar.snapshotHandler.HandleCanvases))
 
 	// Remote upload endpoints
-	mux.HandleFunc("/api/remote-upload", ar.uploadHandler.HandleUpload)
-	mux.HandleFunc("/api/remote-upload/history", ar.uploadHandler.HandleHistory)
+	mux.HandleFunc("/api/remote-upload", forClient(ar.uploadHandler.HandleUpload))
+	mux.HandleFunc("/api/remote-upload/history", forClient(ar.uploadHandler.HandleHistory))
+
+	// Resumable upload endpoints
+	mux.HandleFunc("/api/uploads", ar.staging.HandleUploads)
+	mux.HandleFunc("/api/uploads/", ar.staging.HandleUploads)
+	mux.HandleFunc("/api/uploads/types", ar.pipeline.HandleTypes)
+
+	// Watch folder endpoint
+	mux.HandleFunc("/api/watch-folder", ar.watchFolder.HandleStatus)
 
 	// RCU endpoints
-	mux.HandleFunc("/api/rcu/config", ar.rcuHandler.HandleConfig)
-	mux.HandleFunc("/api/rcu/status", ar.rcuHandler.HandleStatus)
-	mux.HandleFunc("/api/rcu/test", ar.rcuHandler.HandleTest)
-	mux.HandleFunc("/identify-user", ar.rcuHandler.HandleIdentifyUser)
-	mux.HandleFunc("/create-note", ar.rcuHandler.HandleCreateNote)
-	mux.HandleFunc("/upload-item", ar.rcuHandler.HandleUploadItem)
+	mux.HandleFunc("/api/rcu/config", forClient(ar.rcuHandler.HandleConfig))
+	mux.HandleFunc("/api/rcu/status", forClient(ar.rcuHandler.HandleStatus))
+	mux.HandleFunc("/api/rcu/test", forClient(ar.rcuHandler.HandleTest))
+	mux.HandleFunc("/rcu-teams", ar.rcuHandler.HandleTeams)
+	mux.HandleFunc("/identify-user", forClient(ar.rcuHandler.HandleIdentifyUser))
+	mux.HandleFunc("/create-note", forClient(ar.rcuHandler.HandleCreateNote))
+	mux.HandleFunc("/upload-item", forClient(ar.rcuHandler.HandleUploadItem))
 
 	// Admin endpoints
-	mux.HandleFunc("/api/admin/create-targets", ar.adminHandler.HandleCreateTargets)
-	mux.HandleFunc("/api/admin/delete-targets", ar.adminHandler.HandleDeleteTargets)
-	mux.HandleFunc("/api/admin/test-team", ar.adminHandler.HandleTestTeam)
-	mux.HandleFunc("/api/admin/list-users", ar.adminHandler.HandleListUsers)
-	mux.HandleFunc("/api/admin/delete-users", ar.adminHandler.HandleDeleteUsers)
+	mux.HandleFunc("/api/admin/create-targets", forClient(ar.adminHandler.HandleCreateTargets))
+	mux.HandleFunc("/api/admin/delete-targets", forClient(ar.adminHandler.HandleDeleteTargets))
+	mux.HandleFunc("/api/admin/test-team", forClient(ar.adminHandler.HandleTestTeam))
+	mux.HandleFunc("/api/admin/rcu-session", ar.adminHandler.HandleSession)
+	mux.HandleFunc("/api/admin/list-users", forClient(ar.adminHandler.HandleListUsers))
+	mux.HandleFunc("/api/admin/delete-users", forClient(ar.adminHandler.HandleDeleteUsers))
+	mux.HandleFunc("/api/admin/audit", ar.audit.HandleAudit)
 
 	// Client override endpoint
 	mux.HandleFunc("/api/client/override", ar.handleClientOverride)
@@ -113,7 +219,12 @@ func (ar *APIRoutes) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/clients", ar.handleClientList)
 
 	// Restart canvas service endpoint
-	mux.HandleFunc("/api/canvas/restart", ar.handleCanvasRestart)
+	mux.HandleFunc("/api/canvas/restart", forClient(ar.handleCanvasRestart))
+
+	// Login endpoints
+	mux.HandleFunc("/api/auth/login", ar.auth.HandleLogin)
+	mux.HandleFunc("/api/auth/logout", ar.auth.HandleLogout)
+	mux.HandleFunc("/api/auth/session", ar.auth.HandleSession)
 }
 
 // contains checks if a string contains a substring.
@@ -131,13 +242,18 @@ func (ar *APIRoutes) handleCanvasInfo(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	canvasService := requestCanvas(r, ar.canvasService)
 	response := map[string]interface{}{
-		"canvas_id":      ar.canvasService.GetCanvasID(),
-		"canvas_name":    ar.canvasService.GetCanvasName(),
-		"client_id":      ar.canvasService.GetClientID(),
-		"client_name":    ar.canvasService.GetClientName(),
-		"installation_name": ar.canvasService.GetInstallationName(),
-		"connected":      ar.canvasService.IsConnected(),
+		"canvas_id":      requestCanvasID(r, ar.canvasService),
+		"canvas_name":    requestCanvasName(r, ar.canvasService),
+		"workspace":      requestWorkspace(r, canvasService),
+		"workspaces":     canvasService.Workspaces(),
+		"client_id":      canvasService.GetClientID(),
+		"client_name":    canvasService.GetClientName(),
+		"installation_name": canvasService.GetInstallationName(),
+		"connected":      canvasService.IsConnected(),
+		"connection":     canvasService.ConnectionStatus(),
+		"widget_mirror":  canvasService.WidgetMirrorStatus(),
 	}
 
 	jsonResponse, err := json.Marshal(response)
@@ -161,16 +277,19 @@ func (ar *APIRoutes) handleInstallationInfo(w http.ResponseWriter, r *http.Reque
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Get canvas info (this will trigger fetch if name is missing)
-	canvasID := ar.canvasService.GetCanvasID()
-	canvasName := ar.canvasService.GetCanvasName()
+	canvasService := requestCanvas(r, ar.canvasService)
+	canvasID := requestCanvasID(r, ar.canvasService)
+	canvasName := requestCanvasName(r, ar.canvasService)
 
 	response := map[string]interface{}{
-		"installation_name": ar.canvasService.GetInstallationName(),
-		"client_id":         ar.canvasService.GetClientID(),
-		"client_name":       ar.canvasService.GetClientName(),
+		"installation_name": canvasService.GetInstallationName(),
+		"client_id":         canvasService.GetClientID(),
+		"client_name":       canvasService.GetClientName(),
 		"canvas_id":         canvasID,
 		"canvas_name":       canvasName,
-		"connected":         ar.canvasService.IsConnected(),
+		"workspace":         requestWorkspace(r, canvasService),
+		"workspaces":        canvasService.Workspaces(),
+		"connected":         canvasService.IsConnected(),
 	}
 
 	jsonResponse, err := json.Marshal(response)
@@ -195,7 +314,7 @@ func (ar *APIRoutes) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 	response := map[string]interface{}{
 		"status":    "ok",
-		"connected": ar.canvasService.IsConnected(),
+		"connected": requestCanvas(r, ar.canvasService).IsConnected(),
 	}
 
 	jsonResponse, err := json.Marshal(response)
@@ -293,10 +412,16 @@ func (ar *APIRoutes) handleClientList(w http.ResponseWriter, r *http.Request) {
 	validClients := make([]map[string]interface{}, 0, len(clients))
 	for _, client := range clients {
 		fmt.Printf("[API] Client: ID=%s, InstallationName='%s'\n", client.ID, client.InstallationName)
-		validClients = append(validClients, map[string]interface{}{
-			"id":   client.ID,
-			"name": client.InstallationName,
-		})
+		entry := map[string]interface{}{
+			"id":      client.ID,
+			"name":    client.InstallationName,
+			"tracked": ar.clients.IsTracked(client.ID),
+		}
+		if cs, ok := ar.clients.lookup(client.ID); ok && entry["tracked"] == true {
+			entry["canvas_id"] = cs.GetCanvasID()
+			entry["connection"] = cs.ConnectionStatus().State
+		}
+		validClients = append(validClients, entry)
 	}
 
 	fmt.Printf("[API] Returning %d valid clients (with names)\n", len(validClients))
@@ -426,7 +551,7 @@ func (ar *APIRoutes) handleCanvasRestart(w http.ResponseWriter, r *http.Request)
 	fmt.Printf("[API] handleCanvasRestart called\n")
 
 	// Restart canvas service
-	if err := ar.canvasService.Restart(); err != nil {
+	if err := requestCanvas(r, ar.canvasService).Restart(); err != nil {
 		fmt.Printf("[API] ERROR: Restart failed: %v\n", err)
 		response := map[string]interface{}{
 			"success": false,
@@ -0,0 +1,420 @@
+package webui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxAuditLogBytes is the size at which the audit log is rotated.
+	MaxAuditLogBytes = 5 << 20
+	// AuditLogBackups is how many rotated audit logs are kept.
+	AuditLogBackups = 5
+
+	// maxAuditBody is how much of a request body is kept for its parameters.
+	maxAuditBody = 64 << 10
+	// maxAuditValue is how much of a form value is kept.
+	maxAuditValue = 1 << 10
+)
+
+// AuditEntry is one mutating request to the WebUI.
+type AuditEntry struct {
+	Time     time.Time              `json:"time"`
+	RemoteIP string                 `json:"remote_ip"`
+	Role     string                 `json:"role,omitempty"`
+	User     string                 `json:"user,omitempty"`
+	ClientID string                 `json:"client_id,omitempty"`
+	CanvasID string                 `json:"canvas_id,omitempty"`
+	Method   string                 `json:"method"`
+	Action   string                 `json:"action"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+	Status   int                    `json:"status"`
+	Outcome  string                 `json:"outcome"` // success or failure
+	Error    string                 `json:"error,omitempty"`
+	Duration int64                  `json:"duration_ms"`
+}
+
+// AuditFilter selects audit entries. Empty fields match every entry.
+type AuditFilter struct {
+	Since    time.Time
+	Until    time.Time
+	Action   string // substring of the action
+	Role     string
+	User     string // substring of the user, case-insensitive
+	RemoteIP string
+	CanvasID string
+	Outcome  string
+	Limit    int
+}
+
+// matches reports whether entry passes the filter.
+func (f AuditFilter) matches(entry AuditEntry) bool {
+	switch {
+	case !f.Since.IsZero() && entry.Time.Before(f.Since),
+		!f.Until.IsZero() && entry.Time.After(f.Until),
+		f.Action != "" && !strings.Contains(entry.Action, f.Action),
+		f.Role != "" && entry.Role != f.Role,
+		f.User != "" && !strings.Contains(strings.ToLower(entry.User), strings.ToLower(f.User)),
+		f.RemoteIP != "" && entry.RemoteIP != f.RemoteIP,
+		f.CanvasID != "" && entry.CanvasID != f.CanvasID,
+		f.Outcome != "" && entry.Outcome != f.Outcome:
+		return false
+	}
+	return true
+}
+
+// AuditLog records every mutating WebUI request as a line of JSON, rotating
+// the file at MaxAuditLogBytes. An AuditLog without a path, or a nil one,
+// records nothing.
+type AuditLog struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	backups  int
+	auth     *Authenticator
+	canvasID func(*http.Request) string
+}
+
+// NewAuditLog creates an audit log appending to path.
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path, maxBytes: MaxAuditLogBytes, backups: AuditLogBackups}
+}
+
+// SetAuthenticator sets the authenticator the role of a request is read from.
+func (l *AuditLog) SetAuthenticator(auth *Authenticator) {
+	l.auth = auth
+}
+
+// SetCanvasResolver sets how the canvas a request acts on is found.
+func (l *AuditLog) SetCanvasResolver(canvasID func(*http.Request) string) {
+	l.canvasID = canvasID
+}
+
+// Record appends entry to the log.
+func (l *AuditLog) Record(entry AuditEntry) {
+	if l == nil || l.path == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Printf("[AuditLog] Failed to encode entry: %v\n", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		fmt.Printf("[AuditLog] Failed to create audit directory: %v\n", err)
+		return
+	}
+	if info, err := os.Stat(l.path); err == nil && info.Size()+int64(len(data)) >= l.maxBytes {
+		l.rotate()
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Printf("[AuditLog] Failed to open %s: %v\n", l.path, err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		fmt.Printf("[AuditLog] Failed to write %s: %v\n", l.path, err)
+	}
+}
+
+// rotate shifts path to path.1, path.1 to path.2 and so on, dropping the
+// oldest. Must be called with l.mu held.
+func (l *AuditLog) rotate() {
+	os.Remove(l.backupPath(l.backups))
+	for i := l.backups - 1; i >= 1; i-- {
+		os.Rename(l.backupPath(i), l.backupPath(i+1))
+	}
+	if err := os.Rename(l.path, l.backupPath(1)); err != nil {
+		fmt.Printf("[AuditLog] Failed to rotate %s: %v\n", l.path, err)
+	}
+}
+
+// backupPath returns the path of the i-th rotated log; 0 is the current one.
+func (l *AuditLog) backupPath(i int) string {
+	if i == 0 {
+		return l.path
+	}
+	return fmt.Sprintf("%s.%d", l.path, i)
+}
+
+// Query returns the entries passing filter, newest first, across the current
+// and rotated logs.
+func (l *AuditLog) Query(filter AuditFilter) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+	if l == nil || l.path == "" {
+		return entries, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := 0; i <= l.backups; i++ {
+		file, err := os.Open(l.backupPath(i))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		var fileEntries []AuditEntry
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64<<10), 2*maxAuditBody)
+		for scanner.Scan() {
+			var entry AuditEntry
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil && filter.matches(entry) {
+				fileEntries = append(fileEntries, entry)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		for j := len(fileEntries) - 1; j >= 0; j-- {
+			entries = append(entries, fileEntries[j])
+			if filter.Limit > 0 && len(entries) >= filter.Limit {
+				return entries, nil
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Audit wraps next so that every request other than GET, HEAD and OPTIONS
+// is recorded with its parameters and outcome, including requests refused
+// for lack of a role. Chunks of resumable uploads are not: the upload is,
+// when it starts and when it is placed.
+func (l *AuditLog) Audit(next http.Handler) http.Handler {
+	if l == nil || l.path == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet, r.Method == http.MethodHead, r.Method == http.MethodOptions,
+			r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/api/uploads/"):
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		entry := AuditEntry{
+			Time:     time.Now(),
+			RemoteIP: remoteIP(r),
+			ClientID: r.URL.Query().Get("client_id"),
+			Method:   r.Method,
+			Action:   strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/"), "/"),
+		}
+		if l.auth != nil {
+			if role, ok := l.auth.sessionRole(r); ok {
+				entry.Role = string(role)
+			}
+		}
+		if l.canvasID != nil {
+			entry.CanvasID = l.canvasID(r)
+		}
+
+		body := &auditBody{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+		recorder := &auditResponse{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		entry.Params = auditParams(r, body)
+		if name, ok := entry.Params["name"].(string); ok && isRCUAction(entry.Action) {
+			entry.User = name
+		}
+		entry.Status = recorder.status
+		entry.Outcome = "success"
+		if recorder.status >= http.StatusBadRequest {
+			entry.Outcome = "failure"
+			var response struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(recorder.body.Bytes(), &response) == nil {
+				entry.Error = response.Error
+			}
+		}
+		entry.Duration = time.Since(entry.Time).Milliseconds()
+		l.Record(entry)
+	})
+}
+
+// isRCUAction reports whether action is a participant action carrying the
+// participant's name.
+func isRCUAction(action string) bool {
+	return action == "identify-user" || action == "create-note" || action == "upload-item"
+}
+
+// remoteIP returns the address of the client of r without its port.
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// auditParams returns the query and body parameters of r, with secrets
+// redacted. Files uploaded are recorded by name only.
+func auditParams(r *http.Request, body *auditBody) map[string]interface{} {
+	params := make(map[string]interface{})
+	for key, values := range r.URL.Query() {
+		if key != "client_id" && len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+
+	mediaType, mediaParams, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case body.buf.Len() == 0:
+	case mediaType == "multipart/form-data":
+		// Part headers and small values precede the file contents
+		reader := multipart.NewReader(bytes.NewReader(body.buf.Bytes()), mediaParams["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FileName() != "" {
+				params[part.FormName()] = part.FileName()
+				continue
+			}
+			value, err := io.ReadAll(io.LimitReader(part, maxAuditValue))
+			if err != nil {
+				break
+			}
+			params[part.FormName()] = string(value)
+		}
+	case body.truncated:
+		params["body_truncated"] = true
+	default:
+		var fields map[string]interface{}
+		if json.Unmarshal(body.buf.Bytes(), &fields) == nil {
+			for key, value := range fields {
+				params[key] = value
+			}
+		}
+	}
+
+	for key := range params {
+		lower := strings.ToLower(key)
+		for _, secret := range []string{"secret", "pin", "password", "token"} {
+			if strings.Contains(lower, secret) {
+				params[key] = "[redacted]"
+			}
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// auditBody keeps the first maxAuditBody bytes of a request body as the
+// handler reads it.
+type auditBody struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if keep := maxAuditBody - b.buf.Len(); keep > 0 {
+		b.buf.Write(p[:min(n, keep)])
+	}
+	if n > 0 && b.buf.Len() >= maxAuditBody {
+		b.truncated = true
+	}
+	return n, err
+}
+
+// auditResponse records the status of a response and the body of errors.
+type auditResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditResponse) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponse) Write(p []byte) (int, error) {
+	if w.status >= http.StatusBadRequest && w.body.Len() < maxAuditValue {
+		w.body.Write(p[:min(len(p), maxAuditValue-w.body.Len())])
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *auditResponse) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// HandleAudit handles GET /api/admin/audit - List audit entries, newest first.
+// Query: since, until (RFC 3339), action, role, user, remote_ip, canvas_id,
+// outcome and limit (default 200, at most 1000).
+func (l *AuditLog) HandleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := AuditFilter{
+		Action:   query.Get("action"),
+		Role:     query.Get("role"),
+		User:     query.Get("user"),
+		RemoteIP: query.Get("remote_ip"),
+		CanvasID: query.Get("canvas_id"),
+		Outcome:  query.Get("outcome"),
+		Limit:    200,
+	}
+	for param, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				sendErrorResponse(w, fmt.Sprintf("invalid %s %q: use RFC 3339", param, value), http.StatusBadRequest)
+				return
+			}
+			*t = parsed
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			sendErrorResponse(w, fmt.Sprintf("invalid limit %q", value), http.StatusBadRequest)
+			return
+		}
+		filter.Limit = min(limit, 1000)
+	}
+
+	entries, err := l.Query(filter)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"enabled": l != nil && l.path != "",
+		"entries": entries,
+	}, http.StatusOK)
+}
@@ -0,0 +1,128 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestAuditLog_RecordsMutatingRequests audits requests through the login
+// layer and lists them back through /api/admin/audit.
+func TestAuditLog_RecordsMutatingRequests(t *testing.T) {
+	operatorHash, _ := webuiatoms.HashSecret("1234")
+	auth, _ := NewAuthenticator(map[string]string{"operator": operatorHash})
+	token, _, err := auth.Login("1234")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	session := &http.Cookie{Name: SessionCookie, Value: token}
+
+	audit := NewAuditLog(filepath.Join(t.TempDir(), "audit", "audit.jsonl"))
+	audit.SetAuthenticator(auth)
+	audit.SetCanvasResolver(func(r *http.Request) string { return "canvas-1" })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/macros/pin-all", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		sendJSONResponse(w, map[string]interface{}{"success": true}, http.StatusOK)
+	})
+	mux.HandleFunc("/upload-item", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sendErrorResponse(w, "canvas not reachable", http.StatusBadGateway)
+	})
+	mux.HandleFunc("/api/admin/audit", audit.HandleAudit)
+	handler := audit.Audit(auth.Protect(mux))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		t.Helper()
+		req.RemoteAddr = "192.168.1.20:51000"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	pinAll := httptest.NewRequest(http.MethodPost, "/api/macros/pin-all?client_id=client-1", strings.NewReader(`{"zone":"A1","token":"abc"}`))
+	pinAll.Header.Set("Content-Type", "application/json")
+	pinAll.AddCookie(session)
+	serve(pinAll)
+
+	var upload bytes.Buffer
+	form := multipart.NewWriter(&upload)
+	form.WriteField("name", "Alice")
+	file, _ := form.CreateFormFile("file", "photo.jpg")
+	file.Write(bytes.Repeat([]byte("x"), 200<<10))
+	form.Close()
+	uploadItem := httptest.NewRequest(http.MethodPost, "/upload-item", &upload)
+	uploadItem.Header.Set("Content-Type", form.FormDataContentType())
+	uploadItem.AddCookie(session)
+	serve(uploadItem)
+
+	// Refused for lack of a session, still recorded
+	serve(httptest.NewRequest(http.MethodPost, "/api/macros/pin-all", strings.NewReader(`{}`)))
+	// Reads are not recorded
+	serve(httptest.NewRequest(http.MethodGet, "/api/macros/pin-all", nil))
+
+	entries, err := audit.Query(AuditFilter{})
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("entries = %+v, %v; want 3", entries, err)
+	}
+	refused, uploaded, pinned := entries[0], entries[1], entries[2]
+	if pinned.Action != "macros/pin-all" || pinned.Role != "operator" || pinned.ClientID != "client-1" || pinned.CanvasID != "canvas-1" ||
+		pinned.RemoteIP != "192.168.1.20" || pinned.Outcome != "success" || pinned.Params["zone"] != "A1" || pinned.Params["token"] != "[redacted]" {
+		t.Errorf("pin-all entry = %+v", pinned)
+	}
+	if uploaded.User != "Alice" || uploaded.Params["file"] != "photo.jpg" || uploaded.Outcome != "failure" || uploaded.Error != "canvas not reachable" {
+		t.Errorf("upload entry = %+v", uploaded)
+	}
+	if refused.Status != http.StatusUnauthorized || refused.Role != "" || refused.Outcome != "failure" {
+		t.Errorf("refused entry = %+v", refused)
+	}
+
+	list := httptest.NewRequest(http.MethodGet, "/api/admin/audit?outcome=failure&user=ali&since="+time.Now().Add(-time.Minute).Format(time.RFC3339), nil)
+	list.AddCookie(session)
+	var response struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+	json.Unmarshal(serve(list).Body.Bytes(), &response)
+	if len(response.Entries) != 1 || response.Entries[0].Action != "upload-item" {
+		t.Errorf("filtered entries = %+v, want the upload", response.Entries)
+	}
+}
+
+// TestAuditLog_Rotates keeps the newest entries across rotated files.
+func TestAuditLog_Rotates(t *testing.T) {
+	audit := NewAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	audit.maxBytes = 400
+	audit.backups = 2
+	for i := 0; i < 30; i++ {
+		audit.Record(AuditEntry{Time: time.Unix(int64(i), 0), Action: "macros/pin-all", Outcome: "success"})
+	}
+
+	entries, err := audit.Query(AuditFilter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(entries) == 0 || len(entries) >= 30 || entries[0].Time.Unix() != 29 {
+		t.Fatalf("%d entries, newest %v; want the newest kept and the oldest dropped", len(entries), entries)
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Time.Unix() != entries[i-1].Time.Unix()-1 {
+			t.Fatalf("entries out of order at %d: %v", i, entries)
+		}
+	}
+	if limited, _ := audit.Query(AuditFilter{Limit: 3}); len(limited) != 3 {
+		t.Errorf("limited query = %d entries, want 3", len(limited))
+	}
+}
@@ -0,0 +1,451 @@
+package webui
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// Role is what a WebUI session may do. Each role may do everything the
+// roles below it may: admin > operator > participant.
+type Role string
+
+// Roles, lowest first.
+const (
+	RoleParticipant Role = "participant" // RCU identify, create-note and upload
+	RoleOperator    Role = "operator"    // macros, pages, snapshots and uploads
+	RoleAdmin       Role = "admin"       // admin tools, client override and restart
+)
+
+// Roles lists the roles, lowest first.
+var Roles = []Role{RoleParticipant, RoleOperator, RoleAdmin}
+
+// rank orders the roles; unknown roles rank below participant.
+func (r Role) rank() int {
+	for i, role := range Roles {
+		if role == r {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// routeRole is the role a path, or every path under a prefix ending in "/",
+// needs. An empty role marks a public path.
+type routeRole struct {
+	path string
+	role Role
+}
+
+// routeRoles lists the paths with their role. The first match wins; API
+// paths not listed need RoleOperator, other paths are public assets.
+var routeRoles = []routeRole{
+	{"/api/auth/", ""},
+	{"/api/health", ""},
+	{"/health", ""},
+	{"/api/server-info", RoleParticipant},
+	{"/api/canvas/info", RoleParticipant},
+	{"/api/installation/info", RoleParticipant},
+	{"/identify-user", RoleParticipant},
+	{"/rcu-teams", RoleParticipant},
+	{"/create-note", RoleParticipant},
+	{"/upload-item", RoleParticipant},
+	{"/api/uploads", RoleParticipant},
+	{"/api/uploads/", RoleParticipant},
+	{"/api/admin/", RoleAdmin},
+	{"/api/client/override", RoleAdmin},
+	{"/api/canvas/restart", RoleAdmin},
+	{"/api/rcu/", RoleAdmin},
+	{"/debug/", RoleAdmin},
+	{"/get-zones", RoleOperator},
+	{"/create-zones", RoleOperator},
+	{"/delete-zones", RoleOperator},
+	{"/api/", RoleOperator},
+}
+
+// pageRoles lists the role each WebUI page needs; pages not listed need
+// RoleOperator, the login page is public.
+var pageRoles = map[string]Role{
+	"login": "",
+	"rcu":   RoleParticipant,
+	"audit": RoleAdmin,
+}
+
+// SessionCookie is the name of the WebUI session cookie.
+const SessionCookie = "powertoys_session"
+
+// DefaultSessionTTL is how long a login lasts.
+const DefaultSessionTTL = 12 * time.Hour
+
+// failedLoginDelay slows down guessing PINs.
+const failedLoginDelay = time.Second
+
+// maxFailedLogins failed logins in a row from one address lock that address
+// out for loginLockout, doubling with each further failure up to
+// maxLoginLockout.
+const (
+	maxFailedLogins = 5
+	loginLockout    = 30 * time.Second
+	maxLoginLockout = 15 * time.Minute
+)
+
+// session is a logged-in browser.
+type session struct {
+	role    Role
+	expires time.Time
+}
+
+// loginFailures counts the failed logins of one address.
+type loginFailures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// Authenticator guards the WebUI with a PIN or password per role and
+// session cookies. A path needing a role is locked by the lowest role at or
+// above it that has a secret (see lockingRole). A nil Authenticator, or one
+// without secrets, allows everything.
+type Authenticator struct {
+	mu          sync.Mutex
+	secrets     map[Role]string // role -> hash from webuiatoms.HashSecret
+	sessions    map[string]session
+	failures    map[string]*loginFailures // remote address -> failed logins
+	ttl         time.Duration
+	now         func() time.Time
+	failedDelay time.Duration
+}
+
+// NewAuthenticator creates an authenticator from the hashed secrets of the
+// PowerToys config, keyed by role name.
+func NewAuthenticator(secrets map[string]string) (*Authenticator, error) {
+	a := &Authenticator{
+		sessions:    make(map[string]session),
+		failures:    make(map[string]*loginFailures),
+		ttl:         DefaultSessionTTL,
+		now:         time.Now,
+		failedDelay: failedLoginDelay,
+	}
+	if err := a.SetSecrets(secrets); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SetSecrets replaces the hashed secrets, keyed by role name. Sessions of
+// roles whose secret changed end.
+func (a *Authenticator) SetSecrets(secrets map[string]string) error {
+	parsed := make(map[Role]string)
+	for name, hash := range secrets {
+		role := Role(name)
+		if role.rank() == 0 {
+			return fmt.Errorf("unknown role %q", name)
+		}
+		if hash == "" {
+			continue
+		}
+		if !webuiatoms.IsHashedSecret(hash) {
+			return fmt.Errorf("secret of role %s is not hashed", name)
+		}
+		parsed[role] = hash
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for token, s := range a.sessions {
+		if parsed[s.role] != a.secrets[s.role] {
+			delete(a.sessions, token)
+		}
+	}
+	a.secrets = parsed
+	return nil
+}
+
+// Enabled reports whether any role needs a login.
+func (a *Authenticator) Enabled() bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.secrets) > 0
+}
+
+// Login returns a new session token for the highest role whose secret
+// matches.
+func (a *Authenticator) Login(secret string) (string, Role, error) {
+	a.mu.Lock()
+	secrets := a.secrets
+	a.mu.Unlock()
+
+	var role Role
+	for i := len(Roles) - 1; i >= 0 && role == ""; i-- {
+		if hash, ok := secrets[Roles[i]]; ok && webuiatoms.VerifySecret(hash, secret) {
+			role = Roles[i]
+		}
+	}
+	if role == "" {
+		time.Sleep(a.failedDelay)
+		return "", "", fmt.Errorf("wrong PIN or password")
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to create session: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	for t, s := range a.sessions {
+		if now.After(s.expires) {
+			delete(a.sessions, t)
+		}
+	}
+	a.sessions[token] = session{role: role, expires: now.Add(a.ttl)}
+	return token, role, nil
+}
+
+// beginLogin counts a login attempt of remote as failed until
+// endLogin clears it, so that concurrent guesses count too. It returns how
+// long remote is still locked out instead when it failed maxFailedLogins
+// times in a row.
+func (a *Authenticator) beginLogin(remote string) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	for addr, f := range a.failures {
+		if now.Sub(f.last) > maxLoginLockout && now.After(f.lockedUntil) {
+			delete(a.failures, addr)
+		}
+	}
+	f, ok := a.failures[remote]
+	if !ok {
+		f = &loginFailures{}
+		a.failures[remote] = f
+	}
+	if wait := f.lockedUntil.Sub(now); wait > 0 {
+		return wait
+	}
+
+	f.count++
+	f.last = now
+	if over := f.count - maxFailedLogins; over >= 0 {
+		lockout := maxLoginLockout
+		if over < 10 {
+			lockout = min(loginLockout<<over, maxLoginLockout)
+		}
+		f.lockedUntil = now.Add(lockout)
+	}
+	return 0
+}
+
+// endLogin forgets the failed logins of remote after a successful login.
+func (a *Authenticator) endLogin(remote string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.failures, remote)
+}
+
+// Logout ends the session of token.
+func (a *Authenticator) Logout(token string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, token)
+}
+
+// sessionRole returns the role of the session cookie of r, if any.
+func (a *Authenticator) sessionRole(r *http.Request) (Role, bool) {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return "", false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, ok := a.sessions[cookie.Value]
+	if !ok || a.now().After(s.expires) {
+		return "", false
+	}
+	return s.role, true
+}
+
+// lockingRole returns the role a session needs for the paths needing role:
+// the lowest role at or above role that has a secret or, when none has, the
+// highest role below it that has one, so that no path is more open than
+// the paths of lower roles. It returns "" when the paths are open.
+func (a *Authenticator) lockingRole(role Role) Role {
+	if role == "" {
+		return ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var below Role
+	for _, candidate := range Roles {
+		if _, ok := a.secrets[candidate]; !ok {
+			continue
+		}
+		if candidate.rank() >= role.rank() {
+			return candidate
+		}
+		below = candidate
+	}
+	return below
+}
+
+// allows reports whether r may access a path needing role.
+func (a *Authenticator) allows(r *http.Request, role Role) bool {
+	locking := a.lockingRole(role)
+	if locking == "" {
+		return true
+	}
+	sessionRole, ok := a.sessionRole(r)
+	return ok && sessionRole.rank() >= locking.rank()
+}
+
+// requiredRole returns the role path needs and whether path is a WebUI page.
+func requiredRole(path string) (Role, bool) {
+	for _, route := range routeRoles {
+		if path == route.path || (strings.HasSuffix(route.path, "/") && strings.HasPrefix(path, route.path)) {
+			return route.role, false
+		}
+	}
+	if path != "/" && !strings.HasSuffix(path, ".html") {
+		return "", false
+	}
+
+	page := strings.TrimSuffix(path[strings.LastIndex(path, "/")+1:], ".html")
+	if role, ok := pageRoles[page]; ok {
+		return role, true
+	}
+	return RoleOperator, true
+}
+
+// Protect wraps next so that every path needs its role. Pages redirect to
+// the login page, other paths answer 401 without a session and 403 with a
+// session of a lower role.
+func (a *Authenticator) Protect(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, page := requiredRole(r.URL.Path)
+		if a.allows(r, role) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		_, loggedIn := a.sessionRole(r)
+		switch {
+		case page:
+			http.Redirect(w, r, "/login.html?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+		case loggedIn:
+			sendErrorResponse(w, fmt.Sprintf("requires the %s role", a.lockingRole(role)), http.StatusForbidden)
+		default:
+			sendErrorResponse(w, "login required", http.StatusUnauthorized)
+		}
+	})
+}
+
+// HandleLogin handles POST /api/auth/login - Start a session.
+// Body: {"secret": "<PIN or password>"}
+func (a *Authenticator) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !a.Enabled() {
+		sendErrorResponse(w, "login is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	remote := remoteIP(r)
+	if wait := a.beginLogin(remote); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		sendErrorResponse(w, "too many failed logins, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	token, role, err := a.Login(req.Secret)
+	if err != nil {
+		fmt.Printf("[Auth] Failed login from %s\n", r.RemoteAddr)
+		sendErrorResponse(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	a.endLogin(remote)
+	fmt.Printf("[Auth] %s logged in from %s\n", role, r.RemoteAddr)
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(a.ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"role":    role,
+	}, http.StatusOK)
+}
+
+// HandleLogout handles POST /api/auth/logout - End the session.
+func (a *Authenticator) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if cookie, err := r.Cookie(SessionCookie); err == nil && a != nil {
+		a.Logout(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+	}, http.StatusOK)
+}
+
+// HandleSession handles GET /api/auth/session - Report whether login is
+// enabled and the role of the current session.
+func (a *Authenticator) HandleSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	response := map[string]interface{}{
+		"success":       true,
+		"enabled":       a.Enabled(),
+		"authenticated": false,
+	}
+	if a != nil {
+		if role, ok := a.sessionRole(r); ok {
+			response["authenticated"] = true
+			response["role"] = role
+		}
+	}
+	sendJSONResponse(w, response, http.StatusOK)
+}
@@ -0,0 +1,198 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestAuthenticator_ProtectsRoutesByRole logs in as operator and checks which
+// routes the session reaches, with the participant role locked by the
+// operator PIN.
+func TestAuthenticator_ProtectsRoutesByRole(t *testing.T) {
+	adminHash, _ := webuiatoms.HashSecret("9999")
+	operatorHash, _ := webuiatoms.HashSecret("1234")
+	auth, err := NewAuthenticator(map[string]string{"admin": adminHash, "operator": operatorHash})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	auth.failedDelay = 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/login", auth.HandleLogin)
+	mux.HandleFunc("/api/auth/session", auth.HandleSession)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	handler := auth.Protect(mux)
+
+	serve := func(method, target, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Without a session only public routes answer
+	for target, code := range map[string]int{
+		"/api/macros/pin-all":    http.StatusUnauthorized,
+		"/api/admin/list-users":  http.StatusUnauthorized,
+		"/macros.html":           http.StatusFound,
+		"/create-note":           http.StatusUnauthorized, // locked by the operator PIN
+		"/rcu.html":              http.StatusFound,
+		"/login.html":            http.StatusOK,
+		"/css/design-system.css": http.StatusOK,
+		"/api/auth/session":      http.StatusOK,
+	} {
+		if got := serve(http.MethodGet, target, "", nil).Code; got != code {
+			t.Errorf("%s without session = %d, want %d", target, got, code)
+		}
+	}
+	if location := serve(http.MethodGet, "/macros.html?client_id=c1", "", nil).Header().Get("Location"); location != "/login.html?next=%2Fmacros.html%3Fclient_id%3Dc1" {
+		t.Errorf("login redirect = %q", location)
+	}
+
+	if got := serve(http.MethodPost, "/api/auth/login", `{"secret":"0000"}`, nil).Code; got != http.StatusUnauthorized {
+		t.Errorf("wrong PIN = %d, want 401", got)
+	}
+	login := serve(http.MethodPost, "/api/auth/login", `{"secret":"1234"}`, nil)
+	cookies := login.Result().Cookies()
+	if login.Code != http.StatusOK || len(cookies) != 1 || !cookies[0].HttpOnly || !strings.Contains(login.Body.String(), `"role":"operator"`) {
+		t.Fatalf("login = %d %s, cookies %v", login.Code, login.Body.String(), cookies)
+	}
+	session := cookies[0]
+
+	for target, code := range map[string]int{
+		"/api/macros/pin-all":   http.StatusOK,
+		"/macros.html":          http.StatusOK,
+		"/create-note":          http.StatusOK,
+		"/api/admin/list-users": http.StatusForbidden,
+		"/api/canvas/restart":   http.StatusForbidden,
+	} {
+		if got := serve(http.MethodGet, target, "", session).Code; got != code {
+			t.Errorf("%s as operator = %d, want %d", target, got, code)
+		}
+	}
+	if body := serve(http.MethodGet, "/api/auth/session", "", session).Body.String(); !strings.Contains(body, `"role":"operator"`) {
+		t.Errorf("session = %s", body)
+	}
+
+	// Changing the operator PIN ends the session
+	newHash, _ := webuiatoms.HashSecret("4321")
+	if err := auth.SetSecrets(map[string]string{"admin": adminHash, "operator": newHash}); err != nil {
+		t.Fatalf("SetSecrets: %v", err)
+	}
+	if got := serve(http.MethodGet, "/api/macros/pin-all", "", session).Code; got != http.StatusUnauthorized {
+		t.Errorf("session after PIN change = %d, want 401", got)
+	}
+
+	if _, err := NewAuthenticator(map[string]string{"admin": "9999"}); err == nil {
+		t.Error("plain text PIN accepted")
+	}
+	if _, err := NewAuthenticator(map[string]string{"guest": adminHash}); err == nil {
+		t.Error("unknown role accepted")
+	}
+}
+
+// TestAuthenticator_LocksHigherRolesWithoutSecret sets only the operator PIN
+// and checks that admin routes need it too, while participant routes stay
+// locked by it and nothing is open.
+func TestAuthenticator_LocksHigherRolesWithoutSecret(t *testing.T) {
+	operatorHash, _ := webuiatoms.HashSecret("1234")
+	auth, err := NewAuthenticator(map[string]string{"operator": operatorHash})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	auth.failedDelay = 0
+	handler := auth.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	serve := func(target string, cookie *http.Cookie) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	for _, target := range []string{"/api/admin/list-users", "/api/client/override", "/api/canvas/restart", "/api/macros/pin-all", "/create-note"} {
+		if got := serve(target, nil); got != http.StatusUnauthorized {
+			t.Errorf("%s without session = %d, want 401", target, got)
+		}
+	}
+
+	token, role, err := auth.Login("1234")
+	if err != nil || role != RoleOperator {
+		t.Fatalf("Login = %q, %v", role, err)
+	}
+	session := &http.Cookie{Name: SessionCookie, Value: token}
+	for _, target := range []string{"/api/admin/list-users", "/api/canvas/restart", "/api/macros/pin-all", "/create-note"} {
+		if got := serve(target, session); got != http.StatusOK {
+			t.Errorf("%s as operator = %d, want 200", target, got)
+		}
+	}
+}
+
+// TestAuthenticator_LocksOutRepeatedFailedLogins checks that an address is
+// locked out after maxFailedLogins wrong PINs, even with the right one, that
+// other addresses are not, and that the lockout doubles.
+func TestAuthenticator_LocksOutRepeatedFailedLogins(t *testing.T) {
+	operatorHash, _ := webuiatoms.HashSecret("1234")
+	auth, err := NewAuthenticator(map[string]string{"operator": operatorHash})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	auth.failedDelay = 0
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	auth.now = func() time.Time { return now }
+
+	login := func(remote, secret string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"secret":"`+secret+`"}`))
+		req.RemoteAddr = remote
+		recorder := httptest.NewRecorder()
+		auth.HandleLogin(recorder, req)
+		return recorder
+	}
+
+	for i := 0; i < maxFailedLogins; i++ {
+		if got := login("10.0.0.5:4000", "0000").Code; got != http.StatusUnauthorized {
+			t.Fatalf("failed login %d = %d, want 401", i+1, got)
+		}
+	}
+	locked := login("10.0.0.5:4001", "1234")
+	if locked.Code != http.StatusTooManyRequests || locked.Header().Get("Retry-After") != "30" {
+		t.Errorf("login while locked out = %d, Retry-After %q", locked.Code, locked.Header().Get("Retry-After"))
+	}
+	if got := login("10.0.0.6:4000", "1234").Code; got != http.StatusOK {
+		t.Errorf("login from another address = %d, want 200", got)
+	}
+
+	now = now.Add(loginLockout)
+	if got := login("10.0.0.5:4000", "0000").Code; got != http.StatusUnauthorized {
+		t.Errorf("failed login after lockout = %d, want 401", got)
+	}
+	if got := login("10.0.0.5:4000", "1234").Header().Get("Retry-After"); got != "60" {
+		t.Errorf("second lockout Retry-After = %q, want 60", got)
+	}
+
+	now = now.Add(2 * loginLockout)
+	if got := login("10.0.0.5:4000", "1234").Code; got != http.StatusOK {
+		t.Errorf("login after lockout = %d, want 200", got)
+	}
+	if got := login("10.0.0.5:4000", "0000").Code; got != http.StatusUnauthorized {
+		t.Errorf("failed login after success = %d, want 401 (counter reset)", got)
+	}
+}
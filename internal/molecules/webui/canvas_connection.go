@@ -0,0 +1,117 @@
+package webui
+
+import (
+	"fmt"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// ConnectionState is a state of the CanvasService connection to the
+// workspace of its client.
+type ConnectionState string
+
+// Connection states. A service moves resolving -> subscribing -> live, drops
+// to reconnecting when the workspace stream fails and to degraded when a new
+// subscription stays silent; stopped is the state before Start and after Stop.
+const (
+	StateResolving    ConnectionState = "resolving"    // looking up the client ID
+	StateSubscribing  ConnectionState = "subscribing"  // waiting for the first workspace event
+	StateLive         ConnectionState = "live"         // workspace events are arriving
+	StateDegraded     ConnectionState = "degraded"     // subscribed, but no event within subscribeGracePeriod
+	StateReconnecting ConnectionState = "reconnecting" // workspace stream failed, retrying
+	StateStopped      ConnectionState = "stopped"
+)
+
+// subscribeGracePeriod is how long a new subscription may go without a
+// workspace event before the connection is considered degraded.
+const subscribeGracePeriod = 30 * time.Second
+
+// ConnectionStatus describes the current connection state, why it was
+// entered and the last connection error. The state follows the subscription
+// to the selected Workspace; Stream holds its reconnect history.
+type ConnectionStatus struct {
+	ClientID    string                      `json:"client_id,omitempty"`
+	Workspace   int                         `json:"workspace"`
+	State       ConnectionState             `json:"state"`
+	Reason      string                      `json:"reason"`
+	LastError   string                      `json:"last_error,omitempty"`
+	Since       time.Time                   `json:"since"`
+	LastEventAt *time.Time                  `json:"last_event_at,omitempty"`
+	Stream      *webuiatoms.SubscriberStats `json:"stream,omitempty"`
+}
+
+// ConnectionStatus returns the current connection state.
+func (cs *CanvasService) ConnectionStatus() ConnectionStatus {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.connectionStatus()
+}
+
+// connectionStatus returns the current connection state. Callers hold cs.mu.
+func (cs *CanvasService) connectionStatus() ConnectionStatus {
+	status := ConnectionStatus{
+		ClientID:  cs.clientID,
+		Workspace: cs.SelectedWorkspace(),
+		State:     cs.state,
+		Reason:    cs.stateReason,
+		LastError: cs.lastError,
+		Since:     cs.stateSince,
+	}
+	if status.State == "" {
+		status.State = StateStopped
+	}
+	if !cs.lastEventTime.IsZero() {
+		lastEventAt := cs.lastEventTime
+		status.LastEventAt = &lastEventAt
+	}
+	if subscriber, ok := cs.workspaceSubscribers[status.Workspace]; ok {
+		stream := subscriber.Stats()
+		status.Stream = &stream
+	}
+	return status
+}
+
+// transition moves the connection of session to state and publishes a
+// connection_state event. It does nothing when session is no longer the
+// current one, so goroutines of a replaced subscription cannot change the
+// state, or when from is given and the current state is not in it.
+// A non-nil err becomes the last error.
+func (cs *CanvasService) transition(session uint64, state ConnectionState, reason string, err error, from ...ConnectionState) {
+	cs.mu.Lock()
+	if session != cs.session || (len(from) > 0 && !containsState(from, cs.state)) {
+		cs.mu.Unlock()
+		return
+	}
+	status := cs.setState(state, reason, err)
+	events := cs.events
+	cs.mu.Unlock()
+
+	events.Publish(TopicConnection, "connection_state", status)
+}
+
+func containsState(states []ConnectionState, state ConnectionState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// setState records a transition and returns the new status. Callers hold
+// cs.mu and publish the status once they release it.
+func (cs *CanvasService) setState(state ConnectionState, reason string, err error) ConnectionStatus {
+	previous := cs.state
+	if previous == "" {
+		previous = StateStopped
+	}
+	cs.state = state
+	cs.stateReason = reason
+	cs.stateSince = time.Now()
+	if err != nil {
+		cs.lastError = err.Error()
+	}
+	fmt.Printf("[CanvasService] Connection %s -> %s: %s\n", previous, state, reason)
+	return cs.connectionStatus()
+}
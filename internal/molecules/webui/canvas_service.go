@@ -2,9 +2,10 @@ package webui
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
@@ -12,21 +13,31 @@ import (
 )
 
 // CanvasService manages canvas tracking by composing client resolver and workspace subscriber.
+// Its connection follows the states in canvas_connection.go.
 type CanvasService struct {
-	clientResolver      *webuiatoms.ClientResolver
-	workspaceSubscriber *webuiatoms.WorkspaceSubscriber
-	canvasTracker       *webuiatoms.CanvasTracker
-	ctx                 context.Context
-	cancel              context.CancelFunc
-	apiBaseURL          string
-	authToken           string
-	clientID            string
-	clientName          string // Actual client name from server
-	installationName    string
-	overrideClientName  string // Manual override for client name to monitor
-	hasReceivedEvents   bool   // Track if we've received events (proves subscription works)
-	lastEventTime       time.Time
-	subscriptionStartTime time.Time // Track when subscription started
+	clientResolver *webuiatoms.ClientResolver
+	canvasTracker  *webuiatoms.CanvasTracker
+	apiBaseURL     string
+	authToken      string
+
+	startMu sync.Mutex // serializes Start and restarts
+
+	mu                   sync.Mutex // guards the fields below
+	workspaceSubscribers map[int]*webuiatoms.WorkspaceSubscriber
+	widgetMirror         *WidgetMirror
+	events               *EventBus
+	ctx                  context.Context
+	cancel               context.CancelFunc
+	session              uint64 // incremented by every (re)start and Stop
+	state                ConnectionState
+	stateReason          string
+	stateSince           time.Time
+	lastError            string
+	lastEventTime        time.Time
+	clientID             string
+	clientName           string // Actual client name from server
+	installationName     string
+	overrideClientName   string // Manual override for client name to monitor
 }
 
 // NewCanvasService creates a new canvas service.
@@ -43,22 +54,31 @@ func NewCanvasService(fileService *services.FileService, apiBaseURL, authToken s
 	}
 	fmt.Printf("[CanvasService] Installation name: '%s'\n", installationName)
 
-	ctx, cancel := context.WithCancel(context.Background())
-
 	cs := &CanvasService{
-		clientResolver:      clientResolver,
-		canvasTracker:      canvasTracker,
-		ctx:                 ctx,
-		cancel:              cancel,
-		apiBaseURL:          apiBaseURL,
-		authToken:           authToken,
-		installationName:    installationName,
-		workspaceSubscriber: nil, // Will be created after client_id resolution
+		clientResolver:   clientResolver,
+		canvasTracker:    canvasTracker,
+		widgetMirror:     NewWidgetMirror(),
+		apiBaseURL:       apiBaseURL,
+		authToken:        authToken,
+		installationName: installationName,
+		// Workspace subscribers are created after client_id resolution
 	}
 	fmt.Printf("[CanvasService] Created CanvasService with apiBaseURL: '%s'\n", cs.apiBaseURL)
 	return cs, nil
 }
 
+// newClientCanvasService creates a CanvasService for a client whose ID is
+// already known. Call follow to start tracking it.
+func newClientCanvasService(apiBaseURL, authToken string, client webuiatoms.Client) *CanvasService {
+	return &CanvasService{
+		canvasTracker:    webuiatoms.NewCanvasTracker(),
+		widgetMirror:     NewWidgetMirror(),
+		apiBaseURL:       apiBaseURL,
+		authToken:        authToken,
+		installationName: client.InstallationName,
+	}
+}
+
 // Start initializes client_id resolution and starts workspace subscription.
 // Returns error if resolution fails, but service can still be used for manual override.
 func (cs *CanvasService) Start() error {
@@ -69,58 +89,160 @@ func (cs *CanvasService) Start() error {
 		return nil // Not an error - just needs manual override
 	}
 
+	cs.startMu.Lock()
+	defer cs.startMu.Unlock()
+
 	// Resolve client_id from installation_name
-	clientID, err := cs.clientResolver.ResolveClientID(cs.apiBaseURL, cs.authToken, cs.installationName)
+	session, ctx := cs.beginSession("resolving client from installation name")
+	clientID, err := cs.clientResolver.ResolveClientID(cs.apiBaseURL, cs.authToken, cs.GetInstallationName())
 	if err != nil {
+		cs.transition(session, StateStopped, "client resolution failed", err)
 		return fmt.Errorf("failed to resolve client_id: %w", err)
 	}
 
+	cs.mu.Lock()
 	cs.clientID = clientID
+	cs.mu.Unlock()
 
 	// Fetch client name from server to verify it exists
 	cs.fetchClientName()
 
-	// Create workspace subscriber
-	cs.workspaceSubscriber = webuiatoms.NewWorkspaceSubscriber(
-		cs.clientID,
-		cs.apiBaseURL,
-		cs.authToken,
-	)
+	cs.subscribe(session, ctx, clientID)
+	return nil
+}
+
+// follow starts tracking client without resolving an installation name.
+func (cs *CanvasService) follow(client webuiatoms.Client) {
+	cs.startMu.Lock()
+	defer cs.startMu.Unlock()
 
-	// Start subscription
-	cs.subscriptionStartTime = time.Now()
-	eventChan, errChan := cs.workspaceSubscriber.Subscribe(cs.ctx)
+	session, ctx := cs.beginSession(fmt.Sprintf("following client %s", client.ID))
+	cs.mu.Lock()
+	cs.clientID = client.ID
+	cs.clientName = client.InstallationName
+	cs.mu.Unlock()
+
+	cs.subscribe(session, ctx, client.ID)
+}
 
-	// Process events in background
-	go cs.processEvents(eventChan, errChan)
+// Stop stops the canvas service and cancels subscriptions.
+func (cs *CanvasService) Stop() {
+	cs.mu.Lock()
+	if cs.cancel != nil {
+		cs.cancel()
+	}
+	// Ends the current session so its goroutines can no longer change state
+	cs.session++
+	if cs.state == "" || cs.state == StateStopped {
+		cs.mu.Unlock()
+		return
+	}
+	status := cs.setState(StateStopped, "stopped", nil)
+	events := cs.events
+	cs.mu.Unlock()
+
+	events.Publish(TopicConnection, "connection_state", status)
+}
+
+// beginSession cancels the current subscription and starts a new session in
+// the resolving state. It returns the session and the context its goroutines
+// run under.
+func (cs *CanvasService) beginSession(reason string) (uint64, context.Context) {
+	cs.mu.Lock()
+	if cs.cancel != nil {
+		cs.cancel()
+	}
+	cs.ctx, cs.cancel = context.WithCancel(context.Background())
+	ctx := cs.ctx
+	cs.session++
+	session := cs.session
+	cs.lastEventTime = time.Time{}
+	status := cs.setState(StateResolving, reason, nil)
+	events := cs.events
+	cs.mu.Unlock()
+
+	events.Publish(TopicConnection, "connection_state", status)
+	return session, ctx
+}
+
+// subscribe starts a subscription to every workspace of clientID for
+// session and the goroutines that track their canvases until ctx is
+// cancelled. The selected workspace is kept if the client still has it,
+// otherwise its first workspace is selected.
+func (cs *CanvasService) subscribe(session uint64, ctx context.Context, clientID string) {
+	workspaces := cs.discoverWorkspaces(ctx, clientID)
+	cs.canvasTracker.SetWorkspaces(workspaces)
+	if _, _, ok := cs.canvasTracker.GetWorkspaceCanvas(cs.canvasTracker.SelectedWorkspace()); !ok {
+		cs.canvasTracker.SelectWorkspace(workspaces[0])
+	}
+
+	// Create a workspace subscriber per workspace
+	subscribers := make(map[int]*webuiatoms.WorkspaceSubscriber, len(workspaces))
+	for _, workspace := range workspaces {
+		subscriber := webuiatoms.NewWorkspaceSubscriber(
+			clientID,
+			cs.apiBaseURL,
+			cs.authToken,
+		)
+		subscriber.SetWorkspace(workspace)
+		subscribers[workspace] = subscriber
+	}
+	cs.mu.Lock()
+	cs.workspaceSubscribers = subscribers
+	cs.mu.Unlock()
+
+	// Start subscriptions
+	cs.transition(session, StateSubscribing, fmt.Sprintf("subscribing to %d workspace(s) of client %s", len(workspaces), clientID), nil)
+	for workspace, subscriber := range subscribers {
+		eventChan, errChan := subscriber.Subscribe(ctx)
+
+		// Process events in background
+		go cs.processEvents(session, ctx, workspace, eventChan, errChan)
+	}
+
+	// Mirror the widgets of whichever canvas is tracked
+	cs.followWidgets(ctx)
 
 	// Also start polling fallback - fetch canvas_id directly from workspace API
 	// This ensures we get canvas_id even if SSE subscription has issues
-	go cs.pollWorkspaceCanvasID()
+	go cs.pollWorkspaceCanvasID(ctx, clientID)
 
 	// Fetch initial canvas name if we have a canvas_id but no canvas_name
 	go func() {
 		// Wait a moment for initial events to arrive
 		time.Sleep(2 * time.Second)
-		canvasID := cs.canvasTracker.GetCanvasID()
-		canvasName := cs.canvasTracker.GetCanvasName()
-		if canvasID != "" && canvasName == "" {
-			// Fetch canvas name from API
-			fetchedName, err := cs.fetchCanvasName(canvasID)
-			if err == nil && fetchedName != "" {
-				cs.canvasTracker.UpdateCanvas(canvasID, fetchedName)
+		if ctx.Err() != nil {
+			return
+		}
+		for _, workspace := range workspaces {
+			canvasID, canvasName, _ := cs.canvasTracker.GetWorkspaceCanvas(workspace)
+			if canvasID != "" && canvasName == "" {
+				// Fetch canvas name from API
+				fetchedName, err := cs.fetchCanvasName(canvasID)
+				if err == nil && fetchedName != "" {
+					cs.updateWorkspaceCanvas(workspace, canvasID, fetchedName)
+				}
 			}
 		}
 	}()
-
-	return nil
 }
 
-// Stop stops the canvas service and cancels subscriptions.
-func (cs *CanvasService) Stop() {
-	if cs.cancel != nil {
-		cs.cancel()
-	}
+// discoverWorkspaces returns the workspaces of clientID in ascending order,
+// or just workspace 0 when they cannot be listed.
+func (cs *CanvasService) discoverWorkspaces(ctx context.Context, clientID string) []int {
+	apiClient := webuiatoms.NewAPIClient(cs.apiBaseURL, cs.authToken)
+	found, err := apiClient.Clients().Workspaces(ctx, clientID)
+	if err != nil || len(found) == 0 {
+		fmt.Printf("[CanvasService] Could not list workspaces of client %s, using workspace 0: %v\n", clientID, err)
+		return []int{0}
+	}
+	workspaces := make([]int, 0, len(found))
+	for _, workspace := range found {
+		workspaces = append(workspaces, workspace.Index)
+	}
+	sort.Ints(workspaces)
+	fmt.Printf("[CanvasService] Client %s has workspaces %v\n", clientID, workspaces)
+	return workspaces
 }
 
 // Restart restarts the canvas service by stopping current subscription,
@@ -134,21 +256,20 @@ func (cs *CanvasService) Restart() error {
 	// Wait a moment for cleanup
 	time.Sleep(500 * time.Millisecond)
 
-	// Reset state
-	cs.hasReceivedEvents = false
-	cs.lastEventTime = time.Time{}
-	cs.subscriptionStartTime = time.Time{}
+	cs.mu.Lock()
+	overrideClientName, installationName := cs.overrideClientName, cs.installationName
+	cs.mu.Unlock()
 
 	// If we have an override client name, use that
-	if cs.overrideClientName != "" {
-		fmt.Printf("[CanvasService] Restart: Using override client name: '%s'\n", cs.overrideClientName)
-		return cs.restartWithClientName(cs.overrideClientName)
+	if overrideClientName != "" {
+		fmt.Printf("[CanvasService] Restart: Using override client name: '%s'\n", overrideClientName)
+		return cs.restartWithClientName(overrideClientName)
 	}
 
 	// Otherwise, restart with installation name
-	if cs.installationName != "" && cs.installationName != "Unknown" {
-		fmt.Printf("[CanvasService] Restart: Using installation name: '%s'\n", cs.installationName)
-		return cs.restartWithClientName(cs.installationName)
+	if installationName != "" && installationName != "Unknown" {
+		fmt.Printf("[CanvasService] Restart: Using installation name: '%s'\n", installationName)
+		return cs.restartWithClientName(installationName)
 	}
 
 	// If no installation name, try to re-resolve
@@ -156,7 +277,9 @@ func (cs *CanvasService) Restart() error {
 		fmt.Printf("[CanvasService] Restart: Re-resolving client ID from installation name\n")
 		installationName, err := cs.clientResolver.GetInstallationName()
 		if err == nil && installationName != "" {
+			cs.mu.Lock()
 			cs.installationName = installationName
+			cs.mu.Unlock()
 			return cs.restartWithClientName(installationName)
 		}
 	}
@@ -164,23 +287,38 @@ func (cs *CanvasService) Restart() error {
 	return fmt.Errorf("cannot restart: no client name or installation name available")
 }
 
-// processEvents processes canvas events from the workspace subscription.
+// processEvents processes canvas events from the workspace subscription of
+// session and drives its connection state.
 // Only processes updates when canvasName or canvasID actually changes.
-func (cs *CanvasService) processEvents(eventChan <-chan webuiatoms.CanvasEvent, errChan <-chan error) {
+func (cs *CanvasService) processEvents(session uint64, ctx context.Context, workspace int, eventChan <-chan webuiatoms.CanvasEvent, errChan <-chan error) {
+	grace := time.NewTimer(subscribeGracePeriod)
+	defer grace.Stop()
+
 	for {
 		select {
-		case <-cs.ctx.Done():
+		case <-ctx.Done():
 			return
+		case <-grace.C:
+			if cs.isSelected(workspace) {
+				cs.transition(session, StateDegraded, fmt.Sprintf("no workspace event within %s", subscribeGracePeriod), nil, StateSubscribing)
+			}
 		case event, ok := <-eventChan:
 			if !ok {
 				return
 			}
-			// Mark that we've received events (proves subscription is working)
-			cs.hasReceivedEvents = true
-			cs.lastEventTime = time.Now()
+			// Receiving events proves the subscription is working; the
+			// connection state follows the selected workspace
+			if cs.isSelected(workspace) {
+				cs.mu.Lock()
+				if session == cs.session {
+					cs.lastEventTime = time.Now()
+				}
+				cs.mu.Unlock()
+				cs.transition(session, StateLive, "receiving workspace events", nil, StateSubscribing, StateDegraded, StateReconnecting)
+			}
 
 			// Get current canvas state to compare
-			currentCanvasID, currentCanvasName := cs.canvasTracker.GetCanvas()
+			currentCanvasID, currentCanvasName, _ := cs.canvasTracker.GetWorkspaceCanvas(workspace)
 
 			// If canvas_name is empty, fetch it from the API
 			canvasName := event.CanvasName
@@ -194,9 +332,9 @@ func (cs *CanvasService) processEvents(eventChan <-chan webuiatoms.CanvasEvent,
 
 			// Only update if canvasName or canvasID has actually changed
 			if event.CanvasID != currentCanvasID || canvasName != currentCanvasName {
-				cs.canvasTracker.UpdateCanvas(event.CanvasID, canvasName)
-				fmt.Printf("[CanvasService] Canvas updated - ID: %s -> %s, Name: '%s' -> '%s'\n",
-					currentCanvasID, event.CanvasID, currentCanvasName, canvasName)
+				cs.updateWorkspaceCanvas(workspace, event.CanvasID, canvasName)
+				fmt.Printf("[CanvasService] Canvas of workspace %d updated - ID: %s -> %s, Name: '%s' -> '%s'\n",
+					workspace, currentCanvasID, event.CanvasID, currentCanvasName, canvasName)
 			} else {
 				// Ignore update - no change to canvasName or canvasID
 				fmt.Printf("[CanvasService] Ignoring update - no change to canvasName or canvasID\n")
@@ -206,12 +344,66 @@ func (cs *CanvasService) processEvents(eventChan <-chan webuiatoms.CanvasEvent,
 				return
 			}
 			// Log error (will be handled by error handling system)
-			fmt.Printf("Canvas service error: %v\n", err)
+			fmt.Printf("Canvas service error (workspace %d): %v\n", workspace, err)
+			cs.eventBus().Publish(TopicConnection, "connection_error", map[string]interface{}{
+				"client_id": cs.GetClientID(),
+				"workspace": workspace,
+				"error":     err.Error(),
+			})
 			// Reconnection is handled by workspace_subscriber
+			if cs.isSelected(workspace) {
+				cs.transition(session, StateReconnecting, "workspace stream failed", err)
+			}
 		}
 	}
 }
 
+// SetEventBus sets where canvas, connection and widget events are published.
+func (cs *CanvasService) SetEventBus(events *EventBus) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.events = events
+	if cs.widgetMirror != nil {
+		cs.widgetMirror.SetEventBus(events)
+	}
+}
+
+// eventBus returns the bus events are published on.
+func (cs *CanvasService) eventBus() *EventBus {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.events
+}
+
+// updateCanvas records the tracked canvas and publishes a canvas_update
+// event when it changed.
+func (cs *CanvasService) updateCanvas(canvasID, canvasName string) {
+	cs.updateWorkspaceCanvas(cs.SelectedWorkspace(), canvasID, canvasName)
+}
+
+// updateWorkspaceCanvas records the canvas of workspace and publishes a
+// canvas_update event when it changed.
+func (cs *CanvasService) updateWorkspaceCanvas(workspace int, canvasID, canvasName string) {
+	currentCanvasID, currentCanvasName, _ := cs.canvasTracker.GetWorkspaceCanvas(workspace)
+	cs.canvasTracker.UpdateWorkspaceCanvas(workspace, canvasID, canvasName)
+	if canvasID != currentCanvasID || canvasName != currentCanvasName {
+		cs.eventBus().Publish(TopicCanvas, "canvas_update", cs.canvasUpdate(workspace))
+	}
+}
+
+// canvasUpdate returns the payload of a canvas_update event for workspace.
+func (cs *CanvasService) canvasUpdate(workspace int) map[string]interface{} {
+	canvasID, _, _ := cs.WorkspaceCanvas(workspace)
+	return map[string]interface{}{
+		"canvas_id":   canvasID,
+		"canvas_name": cs.WorkspaceCanvasName(workspace),
+		"client_name": cs.GetClientName(),
+		"client_id":   cs.GetClientID(),
+		"workspace":   workspace,
+		"timestamp":   time.Now().Unix(),
+	}
+}
+
 // fetchCanvasName fetches the canvas name from the Canvus API using canvas_id.
 // Endpoint: GET /api/v1/canvases/{canvasID}
 func (cs *CanvasService) fetchCanvasName(canvasID string) (string, error) {
@@ -221,38 +413,19 @@ func (cs *CanvasService) fetchCanvasName(canvasID string) (string, error) {
 
 	fmt.Printf("[CanvasService] fetchCanvasName: Fetching canvas name for canvasID: %s\n", canvasID)
 	apiClient := webuiatoms.NewAPIClient(cs.apiBaseURL, cs.authToken)
-	endpoint := fmt.Sprintf("/api/v1/canvases/%s", canvasID)
-	fmt.Printf("[CanvasService] fetchCanvasName: Calling endpoint: %s\n", endpoint)
-
-	data, err := apiClient.Get(endpoint)
+	canvas, err := apiClient.Canvases().Get(context.Background(), canvasID)
 	if err != nil {
 		fmt.Printf("[CanvasService] fetchCanvasName: ERROR - Failed to fetch canvas: %v\n", err)
 		return "", fmt.Errorf("failed to fetch canvas: %w", err)
 	}
 
-	var canvas map[string]interface{}
-	if err := json.Unmarshal(data, &canvas); err != nil {
-		fmt.Printf("[CanvasService] fetchCanvasName: ERROR - Failed to parse canvas JSON: %v\n", err)
-		fmt.Printf("[CanvasService] fetchCanvasName: Response body: %s\n", string(data))
-		return "", fmt.Errorf("failed to parse canvas: %w", err)
+	if canvas.Name == "" {
+		fmt.Printf("[CanvasService] fetchCanvasName: ERROR - Canvas name not found in response\n")
+		return "", fmt.Errorf("canvas name not found in response")
 	}
 
-	if name, ok := canvas["name"].(string); ok && name != "" {
-		fmt.Printf("[CanvasService] fetchCanvasName: Success - Found canvas name: '%s'\n", name)
-		return name, nil
-	}
-
-	fmt.Printf("[CanvasService] fetchCanvasName: ERROR - Canvas name not found in response. Response keys: %v\n", getMapKeys(canvas))
-	return "", fmt.Errorf("canvas name not found in response")
-}
-
-// getMapKeys returns the keys of a map as a slice (for debugging)
-func getMapKeys(m map[string]interface{}) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	return keys
+	fmt.Printf("[CanvasService] fetchCanvasName: Success - Found canvas name: '%s'\n", canvas.Name)
+	return canvas.Name, nil
 }
 
 // GetCanvasID returns the current canvas_id.
@@ -266,12 +439,44 @@ func (cs *CanvasService) GetCanvasID() string {
 // GetCanvasName returns the current canvas_name.
 // If canvas_name is empty but we have a canvas_id, attempts to fetch it from the API.
 func (cs *CanvasService) GetCanvasName() string {
+	return cs.WorkspaceCanvasName(cs.SelectedWorkspace())
+}
+
+// SelectedWorkspace returns the workspace GetCanvasID and GetCanvasName
+// report on.
+func (cs *CanvasService) SelectedWorkspace() int {
 	if cs.canvasTracker == nil {
-		return ""
+		return 0
 	}
+	return cs.canvasTracker.SelectedWorkspace()
+}
 
-	canvasName := cs.canvasTracker.GetCanvasName()
-	canvasID := cs.canvasTracker.GetCanvasID()
+// isSelected reports whether workspace is the selected workspace.
+func (cs *CanvasService) isSelected(workspace int) bool {
+	return cs.SelectedWorkspace() == workspace
+}
+
+// Workspaces returns the workspaces of the client, in ascending order.
+func (cs *CanvasService) Workspaces() []int {
+	if cs.canvasTracker == nil {
+		return nil
+	}
+	return cs.canvasTracker.Workspaces()
+}
+
+// WorkspaceCanvas returns the canvas_id and canvas_name of workspace.
+// ok is false when the client has no such workspace.
+func (cs *CanvasService) WorkspaceCanvas(workspace int) (canvasID, canvasName string, ok bool) {
+	if cs == nil || cs.canvasTracker == nil {
+		return "", "", false
+	}
+	return cs.canvasTracker.GetWorkspaceCanvas(workspace)
+}
+
+// WorkspaceCanvasName returns the canvas_name of workspace.
+// If canvas_name is empty but we have a canvas_id, attempts to fetch it from the API.
+func (cs *CanvasService) WorkspaceCanvasName(workspace int) string {
+	canvasID, canvasName, _ := cs.WorkspaceCanvas(workspace)
 
 	// If we have a canvas_id but no canvas_name, try to fetch it
 	if canvasID != "" && canvasName == "" {
@@ -280,7 +485,7 @@ func (cs *CanvasService) GetCanvasName() string {
 		go func() {
 			fetchedName, err := cs.fetchCanvasName(canvasID)
 			if err == nil && fetchedName != "" {
-				cs.canvasTracker.UpdateCanvas(canvasID, fetchedName)
+				cs.updateWorkspaceCanvas(workspace, canvasID, fetchedName)
 				fmt.Printf("[CanvasService] GetCanvasName: Updated canvas name to: '%s'\n", fetchedName)
 			}
 		}()
@@ -289,49 +494,85 @@ func (cs *CanvasService) GetCanvasName() string {
 	return canvasName
 }
 
+// followWidgets starts mirroring the widgets of the tracked canvas until ctx
+// is cancelled.
+func (cs *CanvasService) followWidgets(ctx context.Context) {
+	subscriber := webuiatoms.NewWidgetSubscriber(cs.apiBaseURL, cs.authToken)
+	go cs.mirror().Follow(ctx, subscriber, cs.GetCanvasID)
+}
+
+// mirror returns the widget mirror, creating it for services built without
+// NewCanvasService.
+func (cs *CanvasService) mirror() *WidgetMirror {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.widgetMirror == nil {
+		cs.widgetMirror = NewWidgetMirror()
+		cs.widgetMirror.SetEventBus(cs.events)
+	}
+	return cs.widgetMirror
+}
+
+// MirroredWidgets returns the widgets of canvasID from the widget mirror.
+// ok is false when the mirror is not in sync with canvasID.
+func (cs *CanvasService) MirroredWidgets(canvasID string) ([]webuiatoms.Widget, bool) {
+	if cs == nil {
+		return nil, false
+	}
+	return cs.mirror().Widgets(canvasID)
+}
+
+// MirroredWidget returns widget id of canvasID from the widget mirror.
+// ok is false when the mirror is not in sync with canvasID or lacks the widget.
+func (cs *CanvasService) MirroredWidget(canvasID, id string) (webuiatoms.Widget, bool) {
+	if cs == nil {
+		return webuiatoms.Widget{}, false
+	}
+	return cs.mirror().Widget(canvasID, id)
+}
+
+// WidgetMirrorStatus returns the state of the widget mirror.
+func (cs *CanvasService) WidgetMirrorStatus() WidgetMirrorStatus {
+	return cs.mirror().Status()
+}
+
 // GetInstallationName returns the installation name.
 func (cs *CanvasService) GetInstallationName() string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
 	return cs.installationName
 }
 
 // GetClientID returns the resolved client_id.
 func (cs *CanvasService) GetClientID() string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
 	return cs.clientID
 }
 
 // GetClientName returns the actual client name from the server (if available).
 func (cs *CanvasService) GetClientName() string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
 	return cs.clientName
 }
 
-// IsConnected returns whether the service is connected and tracking.
-// This checks if we have a clientID and workspace subscriber.
-// Having a clientID means we've successfully resolved a client, which is sufficient for connection.
+// IsConnected returns whether the service is connected and tracking: a
+// client is resolved and its subscription is live or still within its
+// grace period. ConnectionStatus tells why it is not.
 // Note: clientName may be empty if the client exists but has no name, or if fetchClientName hasn't run yet.
 func (cs *CanvasService) IsConnected() bool {
-	// Must have a clientID and workspace subscriber to be considered connected
-	if cs.clientID == "" || cs.workspaceSubscriber == nil {
-		return false
-	}
-	// If we've received events, we're definitely connected
-	if cs.hasReceivedEvents {
-		return true
-	}
-	// If no events received yet, allow grace period of 30 seconds after subscription start
-	// (allows for initial connection time)
-	if !cs.subscriptionStartTime.IsZero() {
-		return time.Since(cs.subscriptionStartTime) < 30*time.Second
-	}
-	// If we have a clientID and subscriber, consider connected (even if no events yet)
-	// This handles cases where events are slow or subscription is still establishing
-	return true
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.clientID != "" && (cs.state == StateSubscribing || cs.state == StateLive)
 }
 
 // fetchClientName fetches the client name from the server to verify it exists.
 // This is informational only - doesn't affect connection status.
 // A client may exist but have an empty name, which is valid.
 func (cs *CanvasService) fetchClientName() {
-	if cs.clientID == "" {
+	clientID := cs.GetClientID()
+	if clientID == "" {
 		return
 	}
 
@@ -345,23 +586,25 @@ func (cs *CanvasService) fetchClientName() {
 
 	// Find client by ID
 	for _, client := range clients {
-		if client.ID == cs.clientID {
+		if client.ID == clientID {
+			cs.mu.Lock()
 			cs.clientName = client.InstallationName
+			cs.mu.Unlock()
 			return
 		}
 	}
 
 	// Client not found by ID - log warning but don't clear clientName
 	// The clientID might be valid but the API call might have failed or client list might be stale
-	fmt.Printf("[CanvasService] WARNING: Client ID %s not found in clients list. This may be temporary. Available clients: %v\n", cs.clientID, clients)
+	fmt.Printf("[CanvasService] WARNING: Client ID %s not found in clients list. This may be temporary. Available clients: %v\n", clientID, clients)
 	// Don't clear clientName - keep it if we had it before, as the clientID is still valid
 }
 
 // pollWorkspaceCanvasID polls the workspace API to get canvas_id directly (fallback to subscription).
 // This ensures we get canvas_id even if SSE subscription has issues.
-// Only polls if we don't have canvas_id yet, then stops.
-func (cs *CanvasService) pollWorkspaceCanvasID() {
-	if cs.clientID == "" {
+// Only polls the workspaces without a canvas_id yet, then stops.
+func (cs *CanvasService) pollWorkspaceCanvasID(ctx context.Context, clientID string) {
+	if clientID == "" {
 		return
 	}
 
@@ -370,48 +613,45 @@ func (cs *CanvasService) pollWorkspaceCanvasID() {
 
 	apiClient := webuiatoms.NewAPIClient(cs.apiBaseURL, cs.authToken)
 
-	// Poll up to 6 times (30 seconds total) or until we get canvas_id
+	// Poll up to 6 times (30 seconds total) or until every workspace has a canvas_id
 	maxAttempts := 6
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		select {
-		case <-cs.ctx.Done():
+		if ctx.Err() != nil {
 			return
-		default:
-			// Check if we already have canvas_id (from SSE subscription)
-			if cs.canvasTracker.GetCanvasID() != "" {
-				fmt.Printf("[CanvasService] Polling stopped - canvas_id obtained from subscription\n")
-				return
-			}
+		}
 
-			// Fetch workspace 0 data directly from API
-			endpoint := fmt.Sprintf("/api/v1/clients/%s/workspaces/0", cs.clientID)
-			fmt.Printf("[CanvasService] Polling workspace (attempt %d/%d)...\n", attempt+1, maxAttempts)
-			body, err := apiClient.Get(endpoint)
-			if err != nil {
-				fmt.Printf("[CanvasService] Polling workspace failed: %v\n", err)
-				time.Sleep(5 * time.Second)
-				continue
+		// Check which workspaces still lack a canvas_id (from SSE subscription)
+		var missing []int
+		for _, workspace := range cs.canvasTracker.Workspaces() {
+			if canvasID, _, _ := cs.canvasTracker.GetWorkspaceCanvas(workspace); canvasID == "" {
+				missing = append(missing, workspace)
 			}
+		}
+		if len(missing) == 0 {
+			fmt.Printf("[CanvasService] Polling stopped - canvas_id obtained from subscription\n")
+			return
+		}
 
-			// Parse workspace data to extract canvas_id
-			var workspaceData map[string]interface{}
-			if err := json.Unmarshal(body, &workspaceData); err != nil {
-				fmt.Printf("[CanvasService] Failed to parse workspace data: %v\n", err)
-				time.Sleep(5 * time.Second)
+		// Fetch workspace data directly from API
+		fmt.Printf("[CanvasService] Polling workspaces %v (attempt %d/%d)...\n", missing, attempt+1, maxAttempts)
+		for _, index := range missing {
+			workspace, err := apiClient.Clients().Workspace(ctx, clientID, index)
+			if err != nil {
+				fmt.Printf("[CanvasService] Polling workspace %d failed: %v\n", index, err)
 				continue
 			}
-
-			canvasID, ok := workspaceData["canvas_id"].(string)
-			if !ok || canvasID == "" {
-				fmt.Printf("[CanvasService] No canvas_id in workspace data yet\n")
-				time.Sleep(5 * time.Second)
+			if workspace.CanvasID == "" {
+				fmt.Printf("[CanvasService] No canvas_id in workspace %d data yet\n", index)
 				continue
 			}
-
-			canvasName, _ := workspaceData["canvas_name"].(string)
-			cs.canvasTracker.UpdateCanvas(canvasID, canvasName)
-			fmt.Printf("[CanvasService] Polling fallback: Found canvas_id=%s, canvas_name=%s\n", canvasID, canvasName)
-			return // Success, stop polling
+			cs.updateWorkspaceCanvas(index, workspace.CanvasID, workspace.CanvasName)
+			fmt.Printf("[CanvasService] Polling fallback: Found canvas_id=%s, canvas_name=%s in workspace %d\n",
+				workspace.CanvasID, workspace.CanvasName, index)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
 		}
 	}
 	fmt.Printf("[CanvasService] Polling stopped after %d attempts - canvas_id not found\n", maxAttempts)
@@ -420,17 +660,20 @@ func (cs *CanvasService) pollWorkspaceCanvasID() {
 // OverrideClient manually sets a client name to monitor instead of using installation name.
 func (cs *CanvasService) OverrideClient(clientName string) error {
 	fmt.Printf("[CanvasService] OverrideClient called with clientName: '%s'\n", clientName)
+	cs.mu.Lock()
+	cs.overrideClientName = clientName
+	installationName := cs.installationName
+	cs.mu.Unlock()
+
 	if clientName == "" {
 		// Clear override - use installation name again
-		cs.overrideClientName = ""
 		// Restart with installation name (if available)
-		if cs.installationName != "" && cs.installationName != "Unknown" {
-			return cs.restartWithClientName(cs.installationName)
+		if installationName != "" && installationName != "Unknown" {
+			return cs.restartWithClientName(installationName)
 		}
 		return fmt.Errorf("cannot clear override: no installation name available")
 	}
 
-	cs.overrideClientName = clientName
 	// Restart subscription with new client name
 	return cs.restartWithClientName(clientName)
 }
@@ -440,14 +683,11 @@ func (cs *CanvasService) restartWithClientName(clientName string) error {
 	fmt.Printf("[CanvasService] restartWithClientName called with clientName: '%s'\n", clientName)
 	fmt.Printf("[CanvasService] API Base URL: %s\n", cs.apiBaseURL)
 
-	// Stop current subscription
-	if cs.workspaceSubscriber != nil {
-		fmt.Printf("[CanvasService] Stopping current subscription\n")
-		cs.Stop()
-	}
+	cs.startMu.Lock()
+	defer cs.startMu.Unlock()
 
-	// Create new context
-	cs.ctx, cs.cancel = context.WithCancel(context.Background())
+	// Stop current subscription and start a new session
+	session, ctx := cs.beginSession(fmt.Sprintf("looking up client '%s'", clientName))
 
 	// Always use direct API lookup for manual override (matches by client name, not installation_name)
 	// This ensures we can override to any client by name, regardless of installation_name
@@ -458,6 +698,7 @@ func (cs *CanvasService) restartWithClientName(clientName string) error {
 	clients, err := apiClient.GetClients()
 	if err != nil {
 		fmt.Printf("[CanvasService] ERROR: Failed to get clients list: %v\n", err)
+		cs.transition(session, StateStopped, "client lookup failed", err)
 		return fmt.Errorf("failed to get clients list: %w", err)
 	}
 	fmt.Printf("[CanvasService] Successfully fetched %d clients from API\n", len(clients))
@@ -493,69 +734,30 @@ func (cs *CanvasService) restartWithClientName(clientName string) error {
 			availableNames = append(availableNames, client.InstallationName)
 		}
 		fmt.Printf("[CanvasService] ERROR: No client found with installation_name '%s'. Available clients: %v\n", clientName, availableNames)
+		cs.transition(session, StateStopped, "client not found", fmt.Errorf("no client with installation_name '%s'", clientName))
 		if len(availableNames) > 0 {
 			return fmt.Errorf("client not found: no client with installation_name '%s'. Available clients: %v", clientName, availableNames)
 		}
 		return fmt.Errorf("client not found: no client with installation_name '%s'", clientName)
 	}
 
+	cs.mu.Lock()
 	cs.clientID = clientID
-	fmt.Printf("[CanvasService] Set clientID to: %s\n", cs.clientID)
 	// Update clientName to the actual name from server (in case we matched by installation_name)
 	if foundClientName != "" {
 		cs.clientName = foundClientName
-		fmt.Printf("[CanvasService] Set clientName to: '%s'\n", cs.clientName)
 	}
-
-	// Reset event tracking
-	cs.hasReceivedEvents = false
-	cs.lastEventTime = time.Time{}
-	fmt.Printf("[CanvasService] Reset event tracking\n")
+	cs.mu.Unlock()
+	fmt.Printf("[CanvasService] Set clientID to: %s, clientName to: '%s'\n", clientID, foundClientName)
 
 	// Fetch client name from server to verify it exists
 	fmt.Printf("[CanvasService] Fetching client name from server...\n")
 	cs.fetchClientName()
-	fmt.Printf("[CanvasService] Client name after fetch: '%s'\n", cs.clientName)
-
-	// Create new workspace subscriber
-	fmt.Printf("[CanvasService] Creating workspace subscriber for clientID: %s\n", cs.clientID)
-	cs.workspaceSubscriber = webuiatoms.NewWorkspaceSubscriber(
-		cs.clientID,
-		cs.apiBaseURL,
-		cs.authToken,
-	)
-
-	// Start subscription
-	cs.subscriptionStartTime = time.Now()
-	fmt.Printf("[CanvasService] Starting workspace subscription...\n")
-	eventChan, errChan := cs.workspaceSubscriber.Subscribe(cs.ctx)
+	fmt.Printf("[CanvasService] Client name after fetch: '%s'\n", cs.GetClientName())
 
-	// Process events in background
-	fmt.Printf("[CanvasService] Starting event processing goroutine\n")
-	go cs.processEvents(eventChan, errChan)
-
-	// Also start polling fallback - fetch canvas_id directly from workspace API
-	// This ensures we get canvas_id even if SSE subscription has issues
-	fmt.Printf("[CanvasService] Starting polling fallback goroutine\n")
-	go cs.pollWorkspaceCanvasID()
+	fmt.Printf("[CanvasService] Starting workspace subscription for clientID: %s\n", clientID)
+	cs.subscribe(session, ctx, clientID)
 
 	fmt.Printf("[CanvasService] restartWithClientName completed successfully\n")
-
-	// Fetch initial canvas name if we have a canvas_id but no canvas_name
-	go func() {
-		// Wait a moment for initial events to arrive
-		time.Sleep(2 * time.Second)
-		canvasID := cs.canvasTracker.GetCanvasID()
-		canvasName := cs.canvasTracker.GetCanvasName()
-		if canvasID != "" && canvasName == "" {
-			// Fetch canvas name from API
-			fetchedName, err := cs.fetchCanvasName(canvasID)
-			if err == nil && fetchedName != "" {
-				cs.canvasTracker.UpdateCanvas(canvasID, fetchedName)
-			}
-		}
-	}()
-
 	return nil
 }
-
@@ -1,7 +1,16 @@
 package webui
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
 )
 
 // TestCanvasService_MinimalMode tests that a minimal canvas service can be created
@@ -48,3 +57,94 @@ func TestCanvasService_GetCanvasID_WithNilTracker(t *testing.T) {
 	}
 }
 
+// TestCanvasService_ConnectionStates follows a service from resolving its
+// client to a live subscription, a failed stream and Stop.
+func TestCanvasService_ConnectionStates(t *testing.T) {
+	release := make(chan struct{})
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/clients":
+			w.Write([]byte(`[{"id":"client-1","installation_name":"Wall"}]`))
+		case r.URL.Path == "/api/v1/clients/client-1/workspaces/0/" && r.URL.Query().Has("subscribe"):
+			if atomic.AddInt32(&connections, 1) > 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprintln(w, `{"canvas_id":"canvas-1","canvas_name":"Demo"}`)
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	events := NewEventBus()
+	sub, _ := events.Subscribe([]string{TopicConnection}, 0)
+	cs := &CanvasService{
+		apiBaseURL:    server.URL,
+		authToken:     "test-token",
+		canvasTracker: webuiatoms.NewCanvasTracker(),
+	}
+	cs.SetEventBus(events)
+	if status := cs.ConnectionStatus(); status.State != StateStopped {
+		t.Fatalf("initial state = %s, want stopped", status.State)
+	}
+
+	nextState := func() ConnectionStatus {
+		t.Helper()
+		for {
+			select {
+			case event := <-sub.C:
+				if event.Type == "connection_state" {
+					return event.Data.(ConnectionStatus)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("no state transition, state = %+v", cs.ConnectionStatus())
+			}
+		}
+	}
+
+	if err := cs.OverrideClient("wall"); err != nil {
+		t.Fatalf("OverrideClient: %v", err)
+	}
+	for _, want := range []ConnectionState{StateResolving, StateSubscribing, StateLive} {
+		if got := nextState(); got.State != want {
+			t.Fatalf("state = %+v, want %s", got, want)
+		}
+	}
+	if !cs.IsConnected() || cs.GetClientID() != "client-1" || cs.GetCanvasID() != "canvas-1" {
+		t.Errorf("live service: connected=%v client=%s canvas=%s", cs.IsConnected(), cs.GetClientID(), cs.GetCanvasID())
+	}
+
+	// The stream ends; the subscriber backs off before reconnecting
+	close(release)
+	got := nextState()
+	if got.State != StateReconnecting || !strings.Contains(got.LastError, "closed") {
+		t.Fatalf("state = %+v, want reconnecting after the stream closed", got)
+	}
+	if cs.IsConnected() {
+		t.Error("connected while reconnecting")
+	}
+	if got.Stream == nil || got.Stream.Connects != 1 || got.Stream.ConsecutiveFailures != 1 {
+		t.Errorf("stream stats = %+v, want one connection and one failure", got.Stream)
+	}
+
+	recorder := httptest.NewRecorder()
+	(&APIRoutes{canvasService: cs}).handleCanvasInfo(recorder, httptest.NewRequest(http.MethodGet, "/api/canvas/info", nil))
+	var info struct {
+		Connection ConnectionStatus `json:"connection"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &info); err != nil || info.Connection.State != StateReconnecting {
+		t.Errorf("/api/canvas/info connection = %+v, %v", info.Connection, err)
+	}
+
+	cs.Stop()
+	if got := nextState(); got.State != StateStopped || got.LastError == "" {
+		t.Errorf("state after Stop = %+v, want stopped keeping the last error", got)
+	}
+}
@@ -0,0 +1,284 @@
+package webui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// ErrClientNotFound is returned for a client_id that is not on the server.
+var ErrClientNotFound = errors.New("client not found")
+
+// ClientRegistry tracks the canvases of several Canvus clients at once. The
+// primary CanvasService follows the installation's own client (or its
+// override); every other client gets its own CanvasService on first use.
+type ClientRegistry struct {
+	mu        sync.Mutex
+	primary   *CanvasService
+	apiClient *webuiatoms.APIClient
+	events    *EventBus
+	services  map[string]*CanvasService
+	order     []string
+}
+
+// NewClientRegistry creates a registry around the primary CanvasService,
+// looking up other clients through apiClient.
+func NewClientRegistry(primary *CanvasService, apiClient *webuiatoms.APIClient) *ClientRegistry {
+	return &ClientRegistry{
+		primary:   primary,
+		apiClient: apiClient,
+		services:  make(map[string]*CanvasService),
+	}
+}
+
+// SetEventBus sets where the CanvasServices of other clients publish.
+func (cr *ClientRegistry) SetEventBus(events *EventBus) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.events = events
+	for _, cs := range cr.services {
+		cs.SetEventBus(events)
+	}
+}
+
+// Service returns the CanvasService of clientID, starting to track the client
+// if needed. An empty clientID or the primary's client selects the primary.
+func (cr *ClientRegistry) Service(clientID string) (*CanvasService, error) {
+	if cs, ok := cr.lookup(clientID); ok {
+		return cs, nil
+	}
+	if cr.primary == nil || cr.apiClient == nil {
+		return nil, fmt.Errorf("canvas service not available")
+	}
+
+	clients, err := cr.apiClient.GetClients()
+	if err != nil {
+		return nil, err
+	}
+	for _, client := range clients {
+		if client.ID == clientID {
+			return cr.track(client), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrClientNotFound, clientID)
+}
+
+// TrackAll starts tracking every client on the server.
+func (cr *ClientRegistry) TrackAll() error {
+	if cr.primary == nil || cr.apiClient == nil {
+		return fmt.Errorf("canvas service not available")
+	}
+	clients, err := cr.apiClient.GetClients()
+	if err != nil {
+		return err
+	}
+	for _, client := range clients {
+		if _, ok := cr.lookup(client.ID); !ok {
+			cr.track(client)
+		}
+	}
+	return nil
+}
+
+// lookup returns the CanvasService of clientID if it is already tracked.
+func (cr *ClientRegistry) lookup(clientID string) (*CanvasService, bool) {
+	if clientID == "" || (cr.primary != nil && cr.primary.GetClientID() == clientID) {
+		return cr.primary, true
+	}
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cs, ok := cr.services[clientID]
+	return cs, ok
+}
+
+// track starts a CanvasService following client, unless one already does.
+func (cr *ClientRegistry) track(client webuiatoms.Client) *CanvasService {
+	cr.mu.Lock()
+	if cs, ok := cr.services[client.ID]; ok {
+		cr.mu.Unlock()
+		return cs
+	}
+	cs := newClientCanvasService(cr.primary.apiBaseURL, cr.primary.authToken, client)
+	cs.SetEventBus(cr.events)
+	cr.services[client.ID] = cs
+	cr.order = append(cr.order, client.ID)
+	cr.mu.Unlock()
+
+	fmt.Printf("[ClientRegistry] Tracking client %s ('%s')\n", client.ID, client.InstallationName)
+	cs.follow(client)
+	return cs
+}
+
+// IsTracked reports whether the canvas of clientID is being tracked.
+func (cr *ClientRegistry) IsTracked(clientID string) bool {
+	_, ok := cr.lookup(clientID)
+	return ok && clientID != ""
+}
+
+// Services returns the tracked CanvasServices, the primary first.
+func (cr *ClientRegistry) Services() []*CanvasService {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	var services []*CanvasService
+	if cr.primary != nil {
+		services = append(services, cr.primary)
+	}
+	for _, clientID := range cr.order {
+		services = append(services, cr.services[clientID])
+	}
+	return services
+}
+
+// Stop stops tracking every client except the primary's.
+func (cr *ClientRegistry) Stop() {
+	cr.mu.Lock()
+	services := cr.services
+	cr.services = make(map[string]*CanvasService)
+	cr.order = nil
+	cr.mu.Unlock()
+
+	for _, cs := range services {
+		cs.Stop()
+	}
+}
+
+// MirroredWidgets returns the widgets of canvasID from the widget mirror of
+// whichever tracked client shows it.
+func (cr *ClientRegistry) MirroredWidgets(canvasID string) ([]webuiatoms.Widget, bool) {
+	for _, cs := range cr.Services() {
+		if widgets, ok := cs.MirroredWidgets(canvasID); ok {
+			return widgets, true
+		}
+	}
+	return nil, false
+}
+
+// MirroredWidget returns widget id of canvasID from the widget mirror of
+// whichever tracked client shows it.
+func (cr *ClientRegistry) MirroredWidget(canvasID, id string) (webuiatoms.Widget, bool) {
+	for _, cs := range cr.Services() {
+		if widget, ok := cs.MirroredWidget(canvasID, id); ok {
+			return widget, true
+		}
+	}
+	return webuiatoms.Widget{}, false
+}
+
+type (
+	canvasServiceKey struct{}
+	workspaceKey     struct{}
+)
+
+// forClient wraps next so that it serves the client selected by the
+// client_id query parameter and the workspace selected by the workspace
+// query parameter. Handlers read them with requestCanvas, requestWorkspace
+// and requestCanvasID.
+func (cr *ClientRegistry) forClient(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		clientID, workspaceParam := query.Get("client_id"), query.Get("workspace")
+		if clientID == "" && workspaceParam == "" {
+			next(w, r)
+			return
+		}
+
+		cs, err := cr.Service(clientID)
+		if err != nil {
+			status := http.StatusBadGateway
+			if errors.Is(err, ErrClientNotFound) {
+				status = http.StatusNotFound
+			}
+			sendErrorResponse(w, err.Error(), status)
+			return
+		}
+		ctx := context.WithValue(r.Context(), canvasServiceKey{}, cs)
+
+		if workspaceParam != "" {
+			workspace, err := strconv.Atoi(workspaceParam)
+			if err != nil {
+				sendErrorResponse(w, fmt.Sprintf("invalid workspace %q", workspaceParam), http.StatusBadRequest)
+				return
+			}
+			if _, _, ok := cs.WorkspaceCanvas(workspace); !ok {
+				sendErrorResponse(w, fmt.Sprintf("workspace %d not found", workspace), http.StatusNotFound)
+				return
+			}
+			ctx = context.WithValue(ctx, workspaceKey{}, workspace)
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// requestCanvas returns the CanvasService selected for r by its client_id,
+// or fallback when r does not select one.
+func requestCanvas(r *http.Request, fallback *CanvasService) *CanvasService {
+	if cs, ok := r.Context().Value(canvasServiceKey{}).(*CanvasService); ok {
+		return cs
+	}
+	return fallback
+}
+
+// requestClientID returns the client_id of the client selected for r,
+// falling back to fallback.
+func requestClientID(r *http.Request, fallback *CanvasService) string {
+	cs := requestCanvas(r, fallback)
+	if cs == nil {
+		return ""
+	}
+	return cs.GetClientID()
+}
+
+// canvasIDOf returns the canvas a request selects with its client_id and
+// workspace parameters, without tracking a client not tracked yet.
+func (cr *ClientRegistry) canvasIDOf(r *http.Request) string {
+	query := r.URL.Query()
+	cs, ok := cr.lookup(query.Get("client_id"))
+	if !ok || cs == nil {
+		return ""
+	}
+	workspace := cs.SelectedWorkspace()
+	if param := query.Get("workspace"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil {
+			return ""
+		}
+		workspace = parsed
+	}
+	canvasID, _, _ := cs.WorkspaceCanvas(workspace)
+	return canvasID
+}
+
+// requestWorkspace returns the workspace selected for r by its workspace
+// parameter, or the selected workspace of cs.
+func requestWorkspace(r *http.Request, cs *CanvasService) int {
+	if workspace, ok := r.Context().Value(workspaceKey{}).(int); ok {
+		return workspace
+	}
+	return cs.SelectedWorkspace()
+}
+
+// requestCanvasID returns the canvas_id of the client and workspace selected
+// for r, falling back to the selected workspace of fallback.
+func requestCanvasID(r *http.Request, fallback *CanvasService) string {
+	cs := requestCanvas(r, fallback)
+	if cs == nil {
+		return ""
+	}
+	canvasID, _, _ := cs.WorkspaceCanvas(requestWorkspace(r, cs))
+	return canvasID
+}
+
+// requestCanvasName returns the canvas_name of the client and workspace
+// selected for r, falling back to the selected workspace of fallback.
+func requestCanvasName(r *http.Request, fallback *CanvasService) string {
+	cs := requestCanvas(r, fallback)
+	if cs == nil {
+		return ""
+	}
+	return cs.WorkspaceCanvasName(requestWorkspace(r, cs))
+}
@@ -0,0 +1,170 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestClientRegistry_SelectsClientPerRequest serves the canvas info of two
+// walls side by side through the client_id parameter.
+func TestClientRegistry_SelectsClientPerRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/clients":
+			w.Write([]byte(`[{"id":"client-1","installation_name":"Wall"},{"id":"client-2","installation_name":"Lobby"}]`))
+		case strings.HasSuffix(r.URL.Path, "/workspaces/0/") && r.URL.Query().Has("subscribe"):
+			clientID := strings.Split(r.URL.Path, "/")[4]
+			fmt.Fprintf(w, "{\"canvas_id\":\"canvas-of-%s\"}\n", clientID)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	primary := &CanvasService{
+		apiBaseURL:    server.URL,
+		authToken:     "test-token",
+		canvasTracker: webuiatoms.NewCanvasTracker(),
+	}
+	primary.follow(webuiatoms.Client{ID: "client-1", InstallationName: "Wall"})
+	defer primary.Stop()
+	clients := NewClientRegistry(primary, webuiatoms.NewAPIClient(server.URL, "test-token"))
+	defer clients.Stop()
+
+	routes := &APIRoutes{canvasService: primary, clients: clients}
+	handler := clients.forClient(routes.handleCanvasInfo)
+	canvasInfo := func(query string) (int, map[string]interface{}) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, "/api/canvas/info"+query, nil))
+		var info map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &info)
+		return recorder.Code, info
+	}
+	waitForCanvas := func(query, want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			_, info := canvasInfo(query)
+			if info["canvas_id"] == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("canvas info for %q = %v, want canvas %s", query, info, want)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	waitForCanvas("", "canvas-of-client-1")
+	waitForCanvas("?client_id=client-1", "canvas-of-client-1")
+	if clients.IsTracked("client-2") {
+		t.Fatal("client-2 tracked before it was selected")
+	}
+	waitForCanvas("?client_id=client-2", "canvas-of-client-2")
+	if !clients.IsTracked("client-2") || len(clients.Services()) != 2 {
+		t.Errorf("tracked services = %d, want the primary and client-2", len(clients.Services()))
+	}
+	// The primary still follows its own wall
+	waitForCanvas("", "canvas-of-client-1")
+
+	if code, _ := canvasInfo("?client_id=client-9"); code != http.StatusNotFound {
+		t.Errorf("unknown client status = %d, want 404", code)
+	}
+
+	lobby, _ := clients.Service("client-2")
+	update := Event{Type: "canvas_update", Data: lobby.canvasUpdate(0)}
+	if concernsClient(update, primary, 0) || !concernsClient(update, lobby, 0) {
+		t.Error("canvas update of client-2 not routed to client-2 only")
+	}
+	if !concernsClient(Event{Type: "macro_started", Data: map[string]interface{}{"macro": "pin-all"}}, primary, 0) {
+		t.Error("event without a client left out")
+	}
+	for _, event := range []Event{
+		{Type: "macro_progress", Data: map[string]interface{}{"macro": "pin-all", "client_id": "client-2"}},
+		{Type: "upload_progress", Data: map[string]interface{}{"upload_id": "1", "client_id": "client-2"}},
+		{Type: "rcu_note_created", Data: map[string]interface{}{"team": 1, "client_id": "client-2"}},
+		{Type: "job_run", Data: JobRun{Macro: "pin-all", ClientID: "client-2"}},
+	} {
+		if concernsClient(event, primary, 0) || !concernsClient(event, lobby, 0) {
+			t.Errorf("%s of client-2 not routed to client-2 only", event.Type)
+		}
+	}
+}
+
+// TestClientRegistry_SelectsWorkspacePerRequest follows both workspaces of a
+// wall and serves either through the workspace parameter.
+func TestClientRegistry_SelectsWorkspacePerRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/clients/client-1/workspaces":
+			w.Write([]byte(`[{"index":1,"canvas_id":"canvas-1"},{"index":0,"canvas_id":"canvas-0"}]`))
+		case strings.HasPrefix(r.URL.Path, "/api/v1/clients/client-1/workspaces/") && r.URL.Query().Has("subscribe"):
+			workspace := strings.Split(r.URL.Path, "/")[6]
+			fmt.Fprintf(w, "{\"canvas_id\":\"canvas-%s\",\"canvas_name\":\"Board %s\"}\n", workspace, workspace)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	primary := &CanvasService{
+		apiBaseURL:    server.URL,
+		authToken:     "test-token",
+		canvasTracker: webuiatoms.NewCanvasTracker(),
+	}
+	primary.follow(webuiatoms.Client{ID: "client-1", InstallationName: "Wall"})
+	defer primary.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		second, _, _ := primary.WorkspaceCanvas(1)
+		if primary.GetCanvasID() == "canvas-0" && second == "canvas-1" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("workspaces %v: canvas %q, second workspace %q", primary.Workspaces(), primary.GetCanvasID(), second)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	clients := NewClientRegistry(primary, webuiatoms.NewAPIClient(server.URL, "test-token"))
+	routes := &APIRoutes{canvasService: primary, clients: clients}
+	handler := clients.forClient(routes.handleCanvasInfo)
+	for query, want := range map[string]string{"": "canvas-0", "?workspace=0": "canvas-0", "?workspace=1": "canvas-1"} {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, "/api/canvas/info"+query, nil))
+		var info struct {
+			CanvasID   string `json:"canvas_id"`
+			CanvasName string `json:"canvas_name"`
+			Workspaces []int  `json:"workspaces"`
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &info)
+		if info.CanvasID != want || !strings.HasSuffix(info.CanvasName, want[len(want)-1:]) || len(info.Workspaces) != 2 {
+			t.Errorf("canvas info for %q = %+v, want %s of workspaces [0 1]", query, info, want)
+		}
+	}
+	for query, code := range map[string]int{"?workspace=5": http.StatusNotFound, "?workspace=x": http.StatusBadRequest} {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, "/api/canvas/info"+query, nil))
+		if recorder.Code != code {
+			t.Errorf("status for %q = %d, want %d", query, recorder.Code, code)
+		}
+	}
+
+	update := Event{Type: "canvas_update", Data: primary.canvasUpdate(1)}
+	if concernsClient(update, primary, 0) || !concernsClient(update, primary, 1) {
+		t.Error("canvas update of workspace 1 not routed to workspace 1 only")
+	}
+}
@@ -0,0 +1,139 @@
+package webui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Event topics. SSE clients subscribe with ?topics=canvas,macro and receive
+// the event types listed for each topic.
+const (
+	TopicCanvas     = "canvas"     // canvas_update
+	TopicConnection = "connection" // connection_state, connection_error
+	TopicMacro      = "macro"      // macro_started, macro_progress, macro_finished, job_run
+	TopicWidgets    = "widgets"    // widgets_synced, widgets_changed
+	TopicUpload     = "upload"     // upload_progress, upload_finished
+	TopicRCU        = "rcu"        // rcu_note_created, rcu_item_uploaded
+)
+
+// MaxEventHistory is how many recent events the EventBus keeps for clients
+// resuming with Last-Event-ID.
+const MaxEventHistory = 500
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// it is dropped.
+const subscriberBuffer = 64
+
+// Event is one event published on the EventBus.
+type Event struct {
+	ID        uint64      `json:"id"`
+	Topic     string      `json:"topic"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// EventSubscription receives the events of its topics on C. C is closed when
+// the subscription ends, including when the subscriber fell too far behind;
+// it can then resubscribe from the last event ID it saw.
+type EventSubscription struct {
+	C      <-chan Event
+	ch     chan Event
+	topics map[string]bool // nil for all topics
+}
+
+func (s *EventSubscription) matches(topic string) bool {
+	return s.topics == nil || s.topics[topic]
+}
+
+// EventBus is a publish/subscribe bus for events inside the WebUI server.
+// Publishing never blocks. All methods are safe on a nil bus, which drops
+// every event, so subsystems work without one.
+type EventBus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	subscribers map[*EventSubscription]struct{}
+}
+
+// NewEventBus creates an empty event bus.
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[*EventSubscription]struct{})}
+}
+
+// Publish sends an event of eventType with data to the subscribers of topic.
+func (b *EventBus) Publish(topic, eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	event := Event{ID: b.lastID, Topic: topic, Type: eventType, Data: data, Timestamp: time.Now()}
+	b.history = append(b.history, event)
+	if len(b.history) > MaxEventHistory {
+		b.history = b.history[len(b.history)-MaxEventHistory:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.matches(topic) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			fmt.Printf("[EventBus] Dropping subscriber that fell %d events behind\n", subscriberBuffer)
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe subscribes to topics, or to all topics when topics is empty.
+// With a non-zero lastEventID it also returns the kept events after that ID,
+// so nothing is missed or repeated between the replay and C.
+func (b *EventBus) Subscribe(topics []string, lastEventID uint64) (*EventSubscription, []Event) {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &EventSubscription{C: ch, ch: ch}
+	if len(topics) > 0 {
+		sub.topics = make(map[string]bool, len(topics))
+		for _, topic := range topics {
+			sub.topics[topic] = true
+		}
+	}
+	if b == nil {
+		close(ch)
+		return sub, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var replay []Event
+	// An ID from before a server restart may be ahead of lastID; there is
+	// nothing to replay then.
+	if lastEventID > 0 && lastEventID < b.lastID {
+		for _, event := range b.history {
+			if event.ID > lastEventID && sub.matches(event.Topic) {
+				replay = append(replay, event)
+			}
+		}
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub, replay
+}
+
+// Unsubscribe ends sub and closes its channel.
+func (b *EventBus) Unsubscribe(sub *EventSubscription) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
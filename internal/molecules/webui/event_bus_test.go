@@ -0,0 +1,103 @@
+package webui
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventBus_TopicsAndReplay(t *testing.T) {
+	bus := NewEventBus()
+	macros, _ := bus.Subscribe([]string{TopicMacro}, 0)
+	all, _ := bus.Subscribe(nil, 0)
+
+	bus.Publish(TopicCanvas, "canvas_update", "c1")
+	bus.Publish(TopicMacro, "macro_started", "m1")
+	bus.Publish(TopicMacro, "macro_finished", "m2")
+
+	if event := <-macros.C; event.Type != "macro_started" || event.ID != 2 {
+		t.Errorf("first macro event = %+v", event)
+	}
+	if len(macros.C) != 1 || len(all.C) != 3 {
+		t.Errorf("buffered = %d macro, %d all events; want 1, 3", len(macros.C), len(all.C))
+	}
+
+	_, replay := bus.Subscribe([]string{TopicMacro}, 1)
+	if len(replay) != 2 || replay[0].ID != 2 || replay[1].ID != 3 {
+		t.Errorf("replay after 1 = %+v", replay)
+	}
+	if _, replay := bus.Subscribe(nil, 3); len(replay) != 0 {
+		t.Errorf("replay after last ID = %+v", replay)
+	}
+	// IDs from before a server restart are ahead of the bus
+	if _, replay := bus.Subscribe(nil, 99); len(replay) != 0 {
+		t.Errorf("replay after unknown ID = %+v", replay)
+	}
+
+	bus.Unsubscribe(macros)
+	if _, ok := <-macros.C; !ok {
+		t.Fatal("buffered event lost on Unsubscribe")
+	}
+	if _, ok := <-macros.C; ok {
+		t.Error("channel still open after Unsubscribe")
+	}
+}
+
+func TestEventBus_DropsSlowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	slow, _ := bus.Subscribe(nil, 0)
+	for i := 0; i <= subscriberBuffer; i++ {
+		bus.Publish(TopicWidgets, "widgets_changed", i)
+	}
+
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("received %d events before the drop, want %d", received, subscriberBuffer)
+	}
+	bus.Unsubscribe(slow) // already dropped; must not close twice
+}
+
+func TestSSEHandler_ResumesFromLastEventID(t *testing.T) {
+	bus := NewEventBus()
+	bus.Publish(TopicMacro, "macro_started", map[string]string{"macro": "pin-all"})
+	bus.Publish(TopicUpload, "upload_finished", map[string]string{"file": "a.png"})
+	bus.Publish(TopicMacro, "macro_finished", map[string]string{"macro": "pin-all"})
+
+	server := httptest.NewServer(http.HandlerFunc(NewSSEHandler(nil, bus).HandleSubscribe))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?topics=macro", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Replayed events are followed by live ones
+	bus.Publish(TopicMacro, "job_run", map[string]string{"job": "nightly"})
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && len(lines) < 6 {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	want := []string{
+		"id: 3", "event: macro_finished", `data: {"macro":"pin-all"}`,
+		"id: 4", "event: job_run", `data: {"job":"nightly"}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("stream =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,354 @@
+package webui
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// macroRun is a user-defined macro being planned. Steps act on a simulated
+// copy of the canvas, so later steps see where earlier ones put the
+// widgets, and their changes are merged into a single MacroPlan.
+type macroRun struct {
+	h          *MacrosHandler
+	params     map[string]string
+	membership webuiatoms.ZoneMembership
+	plan       *MacroPlan
+
+	order     []string                      // widget IDs in canvas order
+	original  map[string]webuiatoms.Widget  // state before the macro
+	current   map[string]*webuiatoms.Widget // state after the steps so far
+	payloads  map[string]map[string]interface{}
+	updated   []string // widgets with a payload, in the order first changed
+	deleted   map[string]bool
+	selection []string
+	zone      *webuiatoms.ZoneBoundingBox // zone of the last select, if any
+
+	anchors []webuiatoms.Anchor
+	colors  map[string]string
+}
+
+// planUserMacro plans def on canvasID. params maps the names of the
+// macro's parameters to zone IDs.
+func (h *MacrosHandler) planUserMacro(canvasID string, def *MacroDefinition, params map[string]string, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+	fmt.Printf("[MacrosHandler] planUserMacro - canvasID: %s, macro: %s, %d steps\n", canvasID, def.ID, len(def.Steps))
+	if err := def.CheckParams(params); err != nil {
+		return nil, err
+	}
+
+	widgets, err := listWidgets(h.apiClient, h.mirrors(), canvasID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widgets: %w", err)
+	}
+
+	run := &macroRun{
+		h:          h,
+		params:     params,
+		membership: membership,
+		plan:       &MacroPlan{Macro: def.ID, CanvasID: canvasID},
+		original:   make(map[string]webuiatoms.Widget, len(widgets)),
+		current:    make(map[string]*webuiatoms.Widget, len(widgets)),
+		payloads:   make(map[string]map[string]interface{}),
+		deleted:    make(map[string]bool),
+		colors:     make(map[string]string),
+	}
+	for _, widget := range widgets {
+		cloned := cloneWidget(widget)
+		run.order = append(run.order, widget.ID)
+		run.original[widget.ID] = widget
+		run.current[widget.ID] = &cloned
+	}
+
+	for i, step := range def.Steps {
+		if err := run.apply(step); err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", i+1, step.Action, err)
+		}
+		fmt.Printf("[MacrosHandler] planUserMacro: step %d (%s) - %d widgets selected\n", i+1, step.Action, len(run.selection))
+	}
+
+	plan := run.plan
+	for _, id := range run.updated {
+		if run.deleted[id] {
+			continue
+		}
+		widget := run.original[id]
+		plan.Widgets = append(plan.Widgets, widget)
+		plan.Updates = append(plan.Updates, WidgetUpdate{WidgetID: id, WidgetType: widget.WidgetType, Payload: run.payloads[id]})
+	}
+	for _, id := range run.order {
+		if run.deleted[id] {
+			plan.Deletes = append(plan.Deletes, run.original[id])
+		}
+	}
+
+	fmt.Printf("[MacrosHandler] planUserMacro: %d updates, %d copies, %d deletes\n", len(plan.Updates), len(plan.Copies), len(plan.Deletes))
+	return plan, nil
+}
+
+// apply runs one step against the simulated canvas.
+func (run *macroRun) apply(step MacroStep) error {
+	switch step.Action {
+	case StepSelect:
+		return run.selectWidgets(step)
+	case StepTransform:
+		run.transform(step)
+	case StepPin:
+		pinned := step.Pinned == nil || *step.Pinned
+		for _, id := range run.selection {
+			run.current[id].Pinned = pinned
+			run.set(id, "pinned", pinned)
+		}
+	case StepGrid:
+		zoneBB, err := run.stepZone(step)
+		if err != nil {
+			return err
+		}
+		run.applyUpdates(PlanGrid(run.selected(), zoneBB))
+	case StepGroup:
+		return run.group(step)
+	case StepCopy:
+		return run.copySelection(step)
+	case StepDelete:
+		for _, id := range run.selection {
+			run.deleted[id] = true
+		}
+		run.selection = nil
+	default:
+		return fmt.Errorf("unknown action %q", step.Action)
+	}
+	return nil
+}
+
+// selectWidgets replaces the selection with the widgets matching every
+// filter of step. Anchors and connectors are never selected.
+func (run *macroRun) selectWidgets(step MacroStep) error {
+	var candidates []webuiatoms.Widget
+	for _, id := range run.order {
+		if !run.deleted[id] && run.current[id].WidgetType != "SharedCanvas" {
+			candidates = append(candidates, *run.current[id])
+		}
+	}
+
+	run.zone = nil
+	if step.Zone != "" {
+		zoneID, zoneBB, err := run.resolveZone(step.Zone)
+		if err != nil {
+			return err
+		}
+		run.zone = zoneBB
+		run.plan.Zone = zoneBB
+		candidates = FilterWidgetsInZone(candidates, zoneBB, zoneID, run.membership)
+	} else {
+		onCanvas := candidates[:0]
+		for _, widget := range candidates {
+			wt := strings.ToLower(widget.WidgetType)
+			if widget.Location != nil && wt != "anchor" && wt != "connector" {
+				onCanvas = append(onCanvas, widget)
+			}
+		}
+		candidates = onCanvas
+	}
+
+	types := make(map[string]bool, len(step.Types))
+	for _, t := range step.Types {
+		types[strings.ToLower(t)] = true
+	}
+	var title *regexp.Regexp
+	if step.Title != "" {
+		title = regexp.MustCompile(step.Title) // checked by Validate
+	}
+
+	run.selection = nil
+	for _, widget := range candidates {
+		if len(types) > 0 && !types[strings.ToLower(widget.WidgetType)] {
+			continue
+		}
+		if title != nil && !title.MatchString(widget.Title) {
+			continue
+		}
+		if step.Color != "" {
+			color, err := run.noteColor(widget)
+			if err != nil {
+				return err
+			}
+			if !strings.EqualFold(color, step.Color) {
+				continue
+			}
+		}
+		run.selection = append(run.selection, widget.ID)
+	}
+	return nil
+}
+
+// transform offsets the selection and multiplies its scale.
+func (run *macroRun) transform(step MacroStep) {
+	for _, id := range run.selection {
+		widget := run.current[id]
+		if widget.Location != nil && (step.DX != 0 || step.DY != 0) {
+			widget.Location.X += step.DX
+			widget.Location.Y += step.DY
+			run.set(id, "location", map[string]float64{"x": widget.Location.X, "y": widget.Location.Y})
+		}
+		if step.Scale != 0 {
+			widget.Scale = scaleOf(*widget) * step.Scale
+			run.set(id, "scale", widget.Scale)
+		}
+	}
+}
+
+// group lays the selection out in groups of the same note color or title.
+// Grouping by color leaves widgets other than colored notes where they are.
+func (run *macroRun) group(step MacroStep) error {
+	zoneBB, err := run.stepZone(step)
+	if err != nil {
+		return err
+	}
+	groups := make(map[string][]webuiatoms.Widget)
+	for _, widget := range run.selected() {
+		key := widget.Title
+		if step.By == "color" {
+			if key, err = run.noteColor(widget); err != nil {
+				return err
+			}
+			if key == "" {
+				continue
+			}
+		} else if key == "" {
+			key = "untitled"
+		}
+		groups[key] = append(groups[key], widget)
+	}
+	run.applyUpdates(PlanWidgetGroups(groups, zoneBB))
+	return nil
+}
+
+// copySelection plans copies of the selection in the target zone, placed
+// as move and copy do. The selection itself is unchanged.
+func (run *macroRun) copySelection(step MacroStep) error {
+	if run.zone == nil {
+		return fmt.Errorf("copy needs a select step with a zone")
+	}
+	_, targetBB, err := run.resolveZone(step.To)
+	if err != nil {
+		return err
+	}
+	run.plan.SourceZone = run.zone
+	run.plan.Zone = targetBB
+
+	copied := make(map[string]bool)
+	for _, id := range run.selection {
+		widget := run.original[id]
+		if !copySupported(widget.WidgetType) {
+			run.plan.skip(widget, fmt.Sprintf("widget type %s cannot be copied", widget.WidgetType))
+			continue
+		}
+		cloned := cloneWidget(*run.current[id])
+		webuiatoms.TransformWidgetLocationAndScale(&cloned, run.zone, targetBB)
+		run.plan.Copies = append(run.plan.Copies, PlannedCopy{Source: widget, Target: cloned})
+		copied[id] = true
+	}
+	if len(copied) == 0 {
+		return nil
+	}
+	return run.h.planConnectorCopies(run.plan, copied)
+}
+
+// selected returns the current state of the selected widgets.
+func (run *macroRun) selected() []webuiatoms.Widget {
+	widgets := make([]webuiatoms.Widget, 0, len(run.selection))
+	for _, id := range run.selection {
+		widgets = append(widgets, *run.current[id])
+	}
+	return widgets
+}
+
+// applyUpdates applies location updates to the simulated canvas and merges
+// them into the plan.
+func (run *macroRun) applyUpdates(updates []WidgetUpdate) {
+	for _, update := range updates {
+		location, _ := update.Payload["location"].(map[string]float64)
+		run.current[update.WidgetID].Location = &webuiatoms.WidgetLocation{X: location["x"], Y: location["y"]}
+		run.set(update.WidgetID, "location", location)
+	}
+}
+
+// set records a field of the PATCH payload for widget id.
+func (run *macroRun) set(id, field string, value interface{}) {
+	payload, ok := run.payloads[id]
+	if !ok {
+		payload = make(map[string]interface{})
+		run.payloads[id] = payload
+		run.updated = append(run.updated, id)
+	}
+	payload[field] = value
+}
+
+// stepZone returns the zone a grid or group step lays widgets out in: its
+// own zone if set, otherwise the zone of the last select.
+func (run *macroRun) stepZone(step MacroStep) (*webuiatoms.ZoneBoundingBox, error) {
+	if step.Zone == "" {
+		if run.zone == nil {
+			return nil, fmt.Errorf("%s needs a zone or a select step with a zone", step.Action)
+		}
+		return run.zone, nil
+	}
+	_, zoneBB, err := run.resolveZone(step.Zone)
+	if err != nil {
+		return nil, err
+	}
+	run.plan.Zone = zoneBB
+	return zoneBB, nil
+}
+
+// resolveZone returns the anchor ID and bounding box of a zone reference:
+// a $param, an anchor ID or an anchor name.
+func (run *macroRun) resolveZone(ref string) (string, *webuiatoms.ZoneBoundingBox, error) {
+	if name, ok := strings.CutPrefix(ref, "$"); ok {
+		ref = run.params[name]
+	}
+	if widget, ok := run.original[ref]; !ok || !strings.EqualFold(widget.WidgetType, "anchor") {
+		if run.anchors == nil {
+			anchors, err := run.h.apiClient.Anchors(run.plan.CanvasID).List(context.Background())
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to get zones: %w", err)
+			}
+			run.anchors = anchors
+		}
+		found := false
+		for _, anchor := range run.anchors {
+			if strings.EqualFold(anchor.AnchorName, ref) {
+				ref, found = anchor.ID, true
+				break
+			}
+		}
+		if !found {
+			return "", nil, fmt.Errorf("zone %q not found", ref)
+		}
+	}
+	zoneBB, err := zoneBoundingBox(run.h.apiClient, run.h.mirrors(), run.plan.CanvasID, ref)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get zone %s: %w", ref, err)
+	}
+	return ref, zoneBB, nil
+}
+
+// noteColor returns the background color of a note, or "" for other
+// widgets. Colors are fetched once per widget.
+func (run *macroRun) noteColor(widget webuiatoms.Widget) (string, error) {
+	if !strings.EqualFold(widget.WidgetType, "note") {
+		return "", nil
+	}
+	if color, ok := run.colors[widget.ID]; ok {
+		return color, nil
+	}
+	data, err := fetchWidgetData(context.Background(), run.h.apiClient, run.plan.CanvasID, widget)
+	if err != nil && !webuiatoms.IsNotFound(err) {
+		return "", fmt.Errorf("failed to fetch note %s: %w", widget.ID, err)
+	}
+	color, _ := data["background_color"].(string)
+	run.colors[widget.ID] = color
+	return color, nil
+}
@@ -0,0 +1,131 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+const tidyMacroYAML = `
+name: Tidy inbox
+description: Pin the red TODO notes and grid them, drop the rest.
+params:
+  - name: inbox
+    label: Inbox zone
+steps:
+  - action: select
+    zone: $inbox
+    types: [note]
+    color: "#FF0000"
+    title: "^TODO"
+  - action: transform
+    scale: 2
+  - action: pin
+  - action: grid
+  - action: select
+    zone: $inbox
+    title: "^DONE"
+  - action: delete
+`
+
+func TestMacroLibrary_ListAndValidate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"tidy.yaml":      tidyMacroYAML,
+		"unpin.json":     `{"name":"Unpin everything","steps":[{"action":"select"},{"action":"pin","pinned":false}]}`,
+		"typo.yml":       "steps:\n  - action: select\n    zoen: Inbox\n",
+		"bad-param.yaml": "steps:\n  - action: select\n    zone: $missing\n",
+		"no-select.yaml": "steps:\n  - action: grid\n",
+		"notes.txt":      "not a macro",
+		"bad-group.json": `{"steps":[{"action":"select"},{"action":"group","by":"size"}]}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	library := NewMacroLibrary(dir)
+	defs, loadErrors, err := library.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(defs) != 2 || defs[0].ID != "tidy" || defs[1].ID != "unpin" {
+		t.Fatalf("defs = %+v", defs)
+	}
+	if len(loadErrors) != 4 {
+		t.Errorf("loadErrors = %+v, want 4", loadErrors)
+	}
+
+	if _, err := library.Get("../tidy"); err != ErrMacroNotFound {
+		t.Errorf("Get(../tidy) error = %v, want ErrMacroNotFound", err)
+	}
+	def, err := library.Get("tidy")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if err := def.CheckParams(map[string]string{}); err == nil {
+		t.Error("CheckParams accepted a missing parameter")
+	}
+}
+
+// TestUserMacro_Plan runs the tidy macro against a fake canvas and checks
+// that its steps are merged into one plan.
+func TestUserMacro_Plan(t *testing.T) {
+	const canvas = "/api/v1/canvases/canvas-1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, canvas) {
+		case "/anchors":
+			w.Write([]byte(`[{"id":"inbox-zone","anchor_name":"Inbox"}]`))
+		case "/anchors/inbox-zone":
+			w.Write([]byte(`{"id":"inbox-zone","location":{"x":0,"y":0},"size":{"width":1000,"height":1000},"scale":1}`))
+		case "/widgets":
+			w.Write([]byte(`[
+				{"id":"inbox-zone","widget_type":"Anchor","location":{"x":0,"y":0},"size":{"width":1000,"height":1000}},
+				{"id":"todo-red-1","widget_type":"Note","title":"TODO a","location":{"x":500,"y":500}},
+				{"id":"todo-blue-1","widget_type":"Note","title":"TODO b","location":{"x":600,"y":600}},
+				{"id":"done-note-1","widget_type":"Note","title":"DONE c","location":{"x":700,"y":700}},
+				{"id":"todo-outside","widget_type":"Note","title":"TODO d","location":{"x":5000,"y":5000}}
+			]`))
+		case "/notes/todo-red-1":
+			w.Write([]byte(`{"background_color":"#ff0000"}`))
+		case "/notes/todo-blue-1":
+			w.Write([]byte(`{"background_color":"#0000ff"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	def, err := ParseMacroDefinition("tidy", []byte(tidyMacroYAML), ".yaml")
+	if err != nil {
+		t.Fatalf("ParseMacroDefinition: %v", err)
+	}
+	handler := NewMacrosHandler(webuiatoms.NewAPIClient(server.URL, "test-token"), nil)
+
+	// The zone may be given by anchor name as well as by ID.
+	plan, err := handler.planUserMacro("canvas-1", def, map[string]string{"inbox": "inbox"}, webuiatoms.DefaultZoneMembership())
+	if err != nil {
+		t.Fatalf("planUserMacro: %v", err)
+	}
+
+	if len(plan.Updates) != 1 || plan.Updates[0].WidgetID != "todo-red-1" {
+		t.Fatalf("updates = %+v", plan.Updates)
+	}
+	payload, _ := json.Marshal(plan.Updates[0].Payload)
+	if got := string(payload); got != `{"location":{"x":100,"y":100},"pinned":true,"scale":2}` {
+		t.Errorf("payload = %s", got)
+	}
+	if len(plan.Deletes) != 1 || plan.Deletes[0].ID != "done-note-1" {
+		t.Errorf("deletes = %+v", plan.Deletes)
+	}
+	if plan.Macro != "tidy" || plan.Zone == nil {
+		t.Errorf("plan = %+v", plan)
+	}
+}
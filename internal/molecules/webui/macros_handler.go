@@ -1,10 +1,10 @@
 package webui
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"sort"
-	"strings"
 
 	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
 )
@@ -13,18 +13,79 @@ import (
 // NOTE: Macros are server-side operations that operate on widgets via the widgets API.
 // They are NOT a Canvus API resource - they're abstractions built on top of widgets/anchors.
 type MacrosHandler struct {
-	apiClient     *webuiatoms.APIClient
-	canvasService *CanvasService
+	apiClient        *webuiatoms.APIClient
+	canvasService    *CanvasService
+	batchConcurrency int
+	journal          *MacroJournal
+	membership       webuiatoms.ZoneMembership
+	library          *MacroLibrary
+	events           *EventBus
+	clients          *ClientRegistry
 }
 
+// zonePlanner plans a single-zone macro.
+type zonePlanner func(canvasID, zoneID string, membership webuiatoms.ZoneMembership) (*MacroPlan, error)
+
 // NewMacrosHandler creates a new macros handler.
 func NewMacrosHandler(apiClient *webuiatoms.APIClient, canvasService *CanvasService) *MacrosHandler {
 	return &MacrosHandler{
-		apiClient:     apiClient,
-		canvasService: canvasService,
+		apiClient:        apiClient,
+		canvasService:    canvasService,
+		batchConcurrency: DefaultBatchConcurrency,
+		journal:          NewMacroJournal(""),
+		membership:       webuiatoms.DefaultZoneMembership(),
+		library:          NewMacroLibrary(""),
 	}
 }
 
+// SetBatchConcurrency sets how many widget updates a macro runs in parallel.
+func (h *MacrosHandler) SetBatchConcurrency(n int) {
+	h.batchConcurrency = n
+}
+
+// SetJournal replaces the in-memory undo journal, e.g. with a persisted one.
+func (h *MacrosHandler) SetJournal(journal *MacroJournal) {
+	h.journal = journal
+}
+
+// SetZoneMembership sets the policy deciding which widgets belong to a zone
+// when a request does not choose one.
+func (h *MacrosHandler) SetZoneMembership(membership webuiatoms.ZoneMembership) {
+	h.membership = membership
+}
+
+// SetLibrary sets where user-defined macros are loaded from.
+func (h *MacrosHandler) SetLibrary(library *MacroLibrary) {
+	h.library = library
+}
+
+// SetEventBus sets where macro progress is published.
+func (h *MacrosHandler) SetEventBus(events *EventBus) {
+	h.events = events
+}
+
+// SetClients sets the registry of tracked clients whose widget mirrors
+// macros read from.
+func (h *MacrosHandler) SetClients(clients *ClientRegistry) {
+	h.clients = clients
+}
+
+// mirrors returns the widget mirrors macros read from: those of every
+// tracked client when a registry is set.
+func (h *MacrosHandler) mirrors() widgetMirrors {
+	if h.clients != nil {
+		return h.clients
+	}
+	return h.canvasService
+}
+
+// newOperations creates a MacrosOperations configured for this handler.
+func (h *MacrosHandler) newOperations() *MacrosOperations {
+	ops := NewMacrosOperations(h.apiClient, h.mirrors())
+	ops.SetConcurrency(h.batchConcurrency)
+	return ops
+}
+
 // HandleMove handles POST /api/macros/move - Move widgets from source zone to target zone.
 func (h *MacrosHandler) HandleMove(w http.ResponseWriter, r *http.Request) {
 	canvasID, ok := h.validateZoneRequest(w, r, http.MethodPost)
@@ -38,16 +99,25 @@ func (h *MacrosHandler) HandleMove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	movedCount, err := h.moveWidgets(canvasID, sourceZoneID, targetZoneID)
+	membership, err := h.zoneMembership(r)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.planMove(canvasID, sourceZoneID, targetZoneID, membership)
 	if err != nil {
 		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	plan.Membership = membership
 
-	sendJSONResponse(w, map[string]interface{}{
-		"success": true,
-		"message": fmt.Sprintf("%d widgets moved", movedCount),
-	}, http.StatusOK)
+	if isDryRun(r) {
+		sendPlanResponse(w, plan)
+		return
+	}
+
+	sendBatchResponse(w, h.applyPlan(plan, requestClientID(r, h.canvasService)), "moved")
 }
 
 // HandleCopy handles POST /api/macros/copy - Copy widgets from source zone to target zone.
@@ -63,170 +133,254 @@ func (h *MacrosHandler) HandleCopy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	copiedCount, err := h.copyWidgets(canvasID, sourceZoneID, targetZoneID)
+	membership, err := h.zoneMembership(r)
 	if err != nil {
-		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	sendJSONResponse(w, map[string]interface{}{
-		"success": true,
-		"message": fmt.Sprintf("%d widgets copied", copiedCount),
-	}, http.StatusOK)
-}
+	plan, err := h.planCopy(canvasID, sourceZoneID, targetZoneID, membership)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	plan.Membership = membership
 
-// HandleGroups handles GET /api/macros/groups - List widget groups (computed from widgets).
-func (h *MacrosHandler) HandleGroups(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if isDryRun(r) {
+		sendPlanResponse(w, plan)
 		return
 	}
 
-	// Groups are computed from widgets - for now return empty array
-	// Full implementation would group widgets by color, title, etc.
-	sendJSONResponse(w, []interface{}{}, http.StatusOK)
+	sendBatchResponse(w, h.applyPlan(plan, requestClientID(r, h.canvasService)), "copied")
 }
 
-// HandlePinned handles GET /api/macros/pinned - List pinned widgets.
-func (h *MacrosHandler) HandlePinned(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+// HandleZoneDiff handles POST /api/macros/zone-diff - Compare the widgets of two zones.
+func (h *MacrosHandler) HandleZoneDiff(w http.ResponseWriter, r *http.Request) {
+	canvasID, ok := h.validateZoneRequest(w, r, http.MethodPost)
+	if !ok {
 		return
 	}
 
-	canvasID, ok := h.validateZoneRequest(w, r, http.MethodGet)
-	if !ok {
+	sourceZoneID, targetZoneID, err := parseZonePairRequest(r)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Get all widgets and filter pinned ones
-	allWidgets, err := webuiatoms.GetAllWidgets(h.apiClient, canvasID)
+	membership, err := h.zoneMembership(r)
 	if err != nil {
-		sendErrorResponse(w, fmt.Sprintf("Failed to get widgets: %v", err), http.StatusInternalServerError)
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var pinned []webuiatoms.Widget
-	for _, w := range allWidgets {
-		if w.Pinned {
-			pinned = append(pinned, w)
-		}
+	comparison, err := h.compareZones(canvasID, sourceZoneID, targetZoneID, membership)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	sendJSONResponse(w, pinned, http.StatusOK)
+	diff := comparison.Diff()
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Target zone: %d missing, %d extra, %d changed, %d unchanged", len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged),
+		"diff":    diff,
+	}, http.StatusOK)
 }
 
-// HandleUnpin handles POST /api/macros/unpin-all - Unpin all widgets in a zone.
-func (h *MacrosHandler) HandleUnpin(w http.ResponseWriter, r *http.Request) {
+// HandleZoneSync handles POST /api/macros/zone-sync - Make the target zone match the source zone.
+func (h *MacrosHandler) HandleZoneSync(w http.ResponseWriter, r *http.Request) {
 	canvasID, ok := h.validateZoneRequest(w, r, http.MethodPost)
 	if !ok {
 		return
 	}
 
-	zoneID, err := parseZoneIDRequest(r)
+	sourceZoneID, targetZoneID, err := parseZonePairRequest(r)
 	if err != nil {
 		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	unpinnedCount, err := h.pinWidgetsInZone(canvasID, zoneID, false)
+	membership, err := h.zoneMembership(r)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.planZoneSync(canvasID, sourceZoneID, targetZoneID, membership)
 	if err != nil {
 		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	plan.Membership = membership
 
-	sendJSONResponse(w, map[string]interface{}{
-		"success": true,
-		"message": fmt.Sprintf("%d widgets unpinned", unpinnedCount),
-	}, http.StatusOK)
-}
+	if isDryRun(r) {
+		sendPlanResponse(w, plan)
+		return
+	}
 
-// HandlePinAll handles POST /api/macros/pin-all - Pin all widgets in a zone.
-func (h *MacrosHandler) HandlePinAll(w http.ResponseWriter, r *http.Request) {
-	canvasID, ok := h.validateZoneRequest(w, r, http.MethodPost)
-	if !ok {
+	if plan.Empty() {
+		sendJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Zones are already in sync",
+		}, http.StatusOK)
 		return
 	}
 
-	zoneID, err := parseZoneIDRequest(r)
-	if err != nil {
-		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+	sendBatchResponse(w, h.applyPlan(plan, requestClientID(r, h.canvasService)), "synced")
+}
+
+// HandleDefinitions handles GET /api/macros/definitions - List the user-defined macros.
+func (h *MacrosHandler) HandleDefinitions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	pinnedCount, err := h.pinWidgetsInZone(canvasID, zoneID, true)
+	defs, loadErrors, err := h.library.List()
 	if err != nil {
 		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
 	sendJSONResponse(w, map[string]interface{}{
 		"success": true,
-		"message": fmt.Sprintf("%d widgets pinned", pinnedCount),
+		"macros":  defs,
+		"errors":  loadErrors,
 	}, http.StatusOK)
 }
 
-// HandleAutoGrid handles POST /api/macros/auto-grid - Organize widgets in a grid within a zone.
-func (h *MacrosHandler) HandleAutoGrid(w http.ResponseWriter, r *http.Request) {
+// HandleRun handles POST /api/macros/run - Run a user-defined macro with its zone parameters.
+func (h *MacrosHandler) HandleRun(w http.ResponseWriter, r *http.Request) {
 	canvasID, ok := h.validateZoneRequest(w, r, http.MethodPost)
 	if !ok {
 		return
 	}
 
-	zoneID, err := parseZoneIDRequest(r)
+	var req struct {
+		Macro  string            `json:"macro"`
+		Params map[string]string `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	def, err := h.library.Get(req.Macro)
+	if errors.Is(err, ErrMacroNotFound) {
+		sendErrorResponse(w, fmt.Sprintf("%v: %s", err, req.Macro), http.StatusNotFound)
+		return
+	}
+	if err == nil {
+		err = def.CheckParams(req.Params)
+	}
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	membership, err := h.zoneMembership(r)
 	if err != nil {
 		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	griddedCount, err := h.organizeWidgetsInGrid(canvasID, zoneID)
+	plan, err := h.planUserMacro(canvasID, def, req.Params, membership)
 	if err != nil {
 		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	plan.Membership = membership
+
+	if isDryRun(r) {
+		sendPlanResponse(w, plan)
+		return
+	}
 
-	if griddedCount == 0 {
+	if plan.Empty() {
 		sendJSONResponse(w, map[string]interface{}{
 			"success": true,
-			"message": "No widgets found to auto-grid",
+			"message": "No widgets matched",
 		}, http.StatusOK)
 		return
 	}
 
-	sendJSONResponse(w, map[string]interface{}{
-		"success": true,
-		"message": fmt.Sprintf("%d widgets organized in grid", griddedCount),
-	}, http.StatusOK)
+	sendBatchResponse(w, h.applyPlan(plan, requestClientID(r, h.canvasService)), "changed")
 }
 
-// HandleGroupColor handles POST /api/macros/group-color - Group widgets by color.
-func (h *MacrosHandler) HandleGroupColor(w http.ResponseWriter, r *http.Request) {
-	canvasID, ok := h.validateZoneRequest(w, r, http.MethodPost)
-	if !ok {
+// HandleGroups handles GET /api/macros/groups - List widget groups (computed from widgets).
+func (h *MacrosHandler) HandleGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	zoneID, err := parseZoneIDRequest(r)
-	if err != nil {
-		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+	// Groups are computed from widgets - for now return empty array
+	// Full implementation would group widgets by color, title, etc.
+	sendJSONResponse(w, []interface{}{}, http.StatusOK)
+}
+
+// HandlePinned handles GET /api/macros/pinned - List pinned widgets.
+func (h *MacrosHandler) HandlePinned(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	canvasID, ok := h.validateZoneRequest(w, r, http.MethodGet)
+	if !ok {
 		return
 	}
 
-	// Group by background_color - only for Note widgets that have background_color
-	groupedCount, err := h.groupWidgetsByColor(canvasID, zoneID)
+	// Get all widgets and filter pinned ones
+	allWidgets, err := listWidgets(h.apiClient, h.mirrors(), canvasID)
 	if err != nil {
-		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		sendErrorResponse(w, fmt.Sprintf("Failed to get widgets: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	sendJSONResponse(w, map[string]interface{}{
-		"success": true,
-		"message": fmt.Sprintf("%d widgets grouped by color", groupedCount),
-	}, http.StatusOK)
+	var pinned []webuiatoms.Widget
+	for _, w := range allWidgets {
+		if w.Pinned {
+			pinned = append(pinned, w)
+		}
+	}
+
+	sendJSONResponse(w, pinned, http.StatusOK)
+}
+
+// HandleUnpin handles POST /api/macros/unpin-all - Unpin all widgets in a zone.
+func (h *MacrosHandler) HandleUnpin(w http.ResponseWriter, r *http.Request) {
+	h.handleZoneMacro(w, r, "unpinned", "", func(canvasID, zoneID string, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+		return h.planPin(canvasID, zoneID, false, membership)
+	})
+}
+
+// HandlePinAll handles POST /api/macros/pin-all - Pin all widgets in a zone.
+func (h *MacrosHandler) HandlePinAll(w http.ResponseWriter, r *http.Request) {
+	h.handleZoneMacro(w, r, "pinned", "", func(canvasID, zoneID string, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+		return h.planPin(canvasID, zoneID, true, membership)
+	})
+}
+
+// HandleAutoGrid handles POST /api/macros/auto-grid - Organize widgets in a grid within a zone.
+func (h *MacrosHandler) HandleAutoGrid(w http.ResponseWriter, r *http.Request) {
+	h.handleZoneMacro(w, r, "organized in grid", "No widgets found to auto-grid", h.planAutoGrid)
+}
+
+// HandleGroupColor handles POST /api/macros/group-color - Group widgets by color.
+// Only Note widgets that have a background_color are grouped.
+func (h *MacrosHandler) HandleGroupColor(w http.ResponseWriter, r *http.Request) {
+	h.handleZoneMacro(w, r, "grouped by color", "", h.planGroupByColor)
 }
 
 // HandleGroupTitle handles POST /api/macros/group-title - Group widgets by title.
 func (h *MacrosHandler) HandleGroupTitle(w http.ResponseWriter, r *http.Request) {
+	h.handleZoneMacro(w, r, "grouped by title", "No widgets found to group by title", h.planGroupByTitle)
+}
+
+// handleZoneMacro runs a single-zone macro: it plans the changes, returns the
+// plan for dry runs, and otherwise applies it. emptyMessage, when set, is
+// sent instead of a report if the plan touches no widgets.
+func (h *MacrosHandler) handleZoneMacro(w http.ResponseWriter, r *http.Request, action, emptyMessage string, plan zonePlanner) {
 	canvasID, ok := h.validateZoneRequest(w, r, http.MethodPost)
 	if !ok {
 		return
@@ -238,45 +392,75 @@ func (h *MacrosHandler) HandleGroupTitle(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get widgets for sorting
-	ops := NewMacrosOperations(h.apiClient, h.canvasService)
-	zoneBB, allWidgets, err := ops.GetZoneAndWidgets(zoneID)
+	membership, err := h.zoneMembership(r)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	macroPlan, err := plan(canvasID, zoneID, membership)
 	if err != nil {
 		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	macroPlan.Membership = membership
 
-	// Filter widgets in zone
-	inZone := FilterWidgetsInZone(allWidgets, zoneBB, zoneID)
+	if isDryRun(r) {
+		sendPlanResponse(w, macroPlan)
+		return
+	}
 
-	if len(inZone) == 0 {
+	if macroPlan.Empty() && emptyMessage != "" {
 		sendJSONResponse(w, map[string]interface{}{
 			"success": true,
-			"message": "No widgets found to group by title",
+			"message": emptyMessage,
 		}, http.StatusOK)
 		return
 	}
 
-	// Sort by title
-	sort.Slice(inZone, func(i, j int) bool {
-		return strings.ToLower(inZone[i].Title) < strings.ToLower(inZone[j].Title)
+	sendBatchResponse(w, h.applyPlan(macroPlan, requestClientID(r, h.canvasService)), action)
+}
+
+// HandleUndo handles POST /api/macros/undo - Revert the most recent macro run
+// on the canvas of the request.
+func (h *MacrosHandler) HandleUndo(w http.ResponseWriter, r *http.Request) {
+	canvasID, ok := h.validateZoneRequest(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+
+	var report *BatchReport
+	entry, err := h.journal.Undo(canvasID, func(entry JournalEntry) (JournalEntry, error) {
+		var err error
+		report, entry, err = h.revertEntry(entry)
+		return entry, err
 	})
+	if err != nil {
+		sendJournalError(w, err)
+		return
+	}
 
-	// Group by title
-	titleGroups := make(map[string][]webuiatoms.Widget)
-	for _, w := range inZone {
-		title := w.Title
-		if title == "" {
-			title = "untitled"
-		}
-		titleGroups[title] = append(titleGroups[title], w)
+	sendJournalResponse(w, h.journal, canvasID, report, fmt.Sprintf("Undid %s", entry.Macro))
+}
+
+// HandleRedo handles POST /api/macros/redo - Re-apply the most recently undone
+// macro run on the canvas of the request.
+func (h *MacrosHandler) HandleRedo(w http.ResponseWriter, r *http.Request) {
+	canvasID, ok := h.validateZoneRequest(w, r, http.MethodPost)
+	if !ok {
+		return
 	}
 
-	// Position widgets by title groups
-	groupedCount := ops.PositionWidgetGroups(titleGroups, zoneBB, canvasID)
+	var report *BatchReport
+	entry, err := h.journal.Redo(canvasID, func(entry JournalEntry) (JournalEntry, error) {
+		var err error
+		report, entry, err = h.replayEntry(entry)
+		return entry, err
+	})
+	if err != nil {
+		sendJournalError(w, err)
+		return
+	}
 
-	sendJSONResponse(w, map[string]interface{}{
-		"success": true,
-		"message": fmt.Sprintf("%d widgets grouped by title", groupedCount),
-	}, http.StatusOK)
+	sendJournalResponse(w, h.journal, canvasID, report, fmt.Sprintf("Redid %s", entry.Macro))
 }
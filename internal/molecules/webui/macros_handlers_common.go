@@ -2,9 +2,12 @@ package webui
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
 	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
@@ -17,10 +20,11 @@ func (h *MacrosHandler) validateZoneRequest(w http.ResponseWriter, r *http.Reque
 		return "", false
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasService := requestCanvas(r, h.canvasService)
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		fmt.Printf("[MacrosHandler] ERROR: Canvas ID is empty - canvas not available yet\n")
-		fmt.Printf("[MacrosHandler] ClientID: %s, Connected: %v\n", h.canvasService.GetClientID(), h.canvasService.IsConnected())
+		fmt.Printf("[MacrosHandler] ClientID: %s, Connected: %v\n", canvasService.GetClientID(), canvasService.IsConnected())
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return "", false
 	}
@@ -43,6 +47,16 @@ func parseZoneIDRequest(r *http.Request) (string, error) {
 	return req.ZoneID, nil
 }
 
+// zoneMembership returns the zone membership policy for a request: the
+// ?membership= query parameter if given, otherwise the configured policy.
+func (h *MacrosHandler) zoneMembership(r *http.Request) (webuiatoms.ZoneMembership, error) {
+	value := r.URL.Query().Get("membership")
+	if value == "" {
+		return h.membership, nil
+	}
+	return webuiatoms.ParseZoneMembership(value)
+}
+
 // parseZonePairRequest parses a request body expecting sourceZoneId and targetZoneId fields.
 func parseZonePairRequest(r *http.Request) (sourceZoneID, targetZoneID string, err error) {
 	var req struct {
@@ -77,31 +91,165 @@ func sendErrorResponse(w http.ResponseWriter, message string, statusCode int) {
 	})
 }
 
-// moveWidgets moves widgets from source zone to target zone.
-func (h *MacrosHandler) moveWidgets(canvasID, sourceZoneID, targetZoneID string) (int, error) {
-	fmt.Printf("[MacrosHandler] moveWidgets - canvasID: %s, sourceZoneID: %s, targetZoneID: %s\n", canvasID, sourceZoneID, targetZoneID)
-	ops := NewMacrosOperations(h.apiClient, h.canvasService)
+// sendBatchResponse sends the result of a batch macro, including the
+// per-widget report so the UI can list failed widgets.
+func sendBatchResponse(w http.ResponseWriter, report *BatchReport, action string) {
+	message := fmt.Sprintf("%d widgets %s", report.Succeeded, action)
+	if report.Failed > 0 {
+		message = fmt.Sprintf("%s, %d failed", message, report.Failed)
+	}
+	if len(report.Skipped) > 0 {
+		message = fmt.Sprintf("%s, %d skipped", message, len(report.Skipped))
+	}
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"message": message,
+		"report":  report,
+	}, http.StatusOK)
+}
+
+// sendJournalResponse sends the result of an undo or redo along with how
+// many entries of canvasID remain on each stack.
+func sendJournalResponse(w http.ResponseWriter, journal *MacroJournal, canvasID string, report *BatchReport, action string) {
+	message := fmt.Sprintf("%s: %d widgets restored", action, report.Succeeded)
+	if report.Failed > 0 {
+		message = fmt.Sprintf("%s, %d failed", message, report.Failed)
+	}
+	undo, redo := journal.Len(canvasID)
+	sendJSONResponse(w, map[string]interface{}{
+		"success":        true,
+		"message":        message,
+		"report":         report,
+		"undo_available": undo,
+		"redo_available": redo,
+	}, http.StatusOK)
+}
+
+// sendJournalError maps undo/redo errors to HTTP responses.
+func sendJournalError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNothingToUndo) || errors.Is(err, ErrNothingToRedo) {
+		sendErrorResponse(w, err.Error(), http.StatusConflict)
+		return
+	}
+	sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+}
+
+// revertEntry restores the prior state of every widget in entry, deletes the
+// widgets it created and recreates the widgets it deleted, so the returned
+// entry carries their new IDs. It fails only if nothing could be reverted.
+func (h *MacrosHandler) revertEntry(entry JournalEntry) (*BatchReport, JournalEntry, error) {
+	fmt.Printf("[MacrosHandler] Undoing %s on canvas %s (%d changes, %d creations, %d deletions)\n",
+		entry.Macro, entry.CanvasID, len(entry.Changes), len(entry.Creations), len(entry.Deletions))
+	ops := h.newOperations()
+
+	var updates []WidgetUpdate
+	for _, change := range entry.Changes {
+		updates = append(updates, WidgetUpdate{WidgetID: change.WidgetID, WidgetType: change.WidgetType, Payload: change.Before})
+	}
+	report := ops.BatchUpdateWidgets(entry.CanvasID, updates)
+
+	for _, created := range entry.Creations {
+		result := WidgetUpdateResult{WidgetID: created.WidgetID, WidgetType: created.WidgetType, Attempts: 1}
+		err := h.apiClient.Delete(widgetEndpoint(entry.CanvasID, created.WidgetType, created.WidgetID))
+		if err != nil && !webuiatoms.IsNotFound(err) {
+			fmt.Printf("[MacrosHandler] ERROR: Failed to delete copied widget %s: %v\n", created.WidgetID, err)
+			result.Error = err.Error()
+		} else {
+			result.Succeeded = true
+		}
+		report.add(result)
+	}
+	entry.Deletions = h.restoreDeletions(entry, report)
+
+	if report.Total > 0 && report.Succeeded == 0 {
+		return report, entry, fmt.Errorf("undo of %s failed for all %d widgets", entry.Macro, report.Total)
+	}
+	return report, entry, nil
+}
+
+// replayEntry re-applies entry after it was undone. Widgets recreated by the
+// undo are deleted again and copies are made again from their source
+// widgets, so the returned entry carries the new IDs.
+func (h *MacrosHandler) replayEntry(entry JournalEntry) (*BatchReport, JournalEntry, error) {
+	fmt.Printf("[MacrosHandler] Redoing %s on canvas %s (%d changes, %d creations, %d deletions)\n",
+		entry.Macro, entry.CanvasID, len(entry.Changes), len(entry.Creations), len(entry.Deletions))
+	ops := h.newOperations()
+
+	var updates []WidgetUpdate
+	for _, change := range entry.Changes {
+		updates = append(updates, WidgetUpdate{WidgetID: change.WidgetID, WidgetType: change.WidgetType, Payload: change.After})
+	}
+	report := ops.BatchUpdateWidgets(entry.CanvasID, updates)
+
+	for _, deletion := range entry.Deletions {
+		result := WidgetUpdateResult{WidgetID: deletion.WidgetID, WidgetType: deletion.WidgetType, Attempts: 1}
+		err := h.apiClient.Delete(widgetEndpoint(entry.CanvasID, deletion.WidgetType, deletion.WidgetID))
+		if err != nil && !webuiatoms.IsNotFound(err) {
+			fmt.Printf("[MacrosHandler] ERROR: Failed to delete widget %s again: %v\n", deletion.WidgetID, err)
+			result.Error = err.Error()
+		} else {
+			result.Succeeded = true
+		}
+		report.add(result)
+	}
+
+	creations := make([]JournalCreation, len(entry.Creations))
+	copy(creations, entry.Creations)
+	copiedIDs := make(map[string]string)
+	for i, created := range creations {
+		source := webuiatoms.Widget{ID: created.SourceID, WidgetType: created.WidgetType}
+		cloned := webuiatoms.Widget{
+			ID:         created.SourceID,
+			WidgetType: created.WidgetType,
+			Location:   created.Location,
+			Size:       created.Size,
+			Scale:      created.Scale,
+		}
+		result := WidgetUpdateResult{WidgetID: created.SourceID, WidgetType: created.WidgetType, Attempts: 1}
+		newID, err := h.copyWidget(entry.CanvasID, source, &cloned, copiedIDs)
+		if err != nil {
+			fmt.Printf("[MacrosHandler] ERROR: Failed to re-copy widget %s: %v\n", created.SourceID, err)
+			result.Error = err.Error()
+		} else {
+			result.Succeeded = true
+			result.WidgetID = newID
+			creations[i].WidgetID = newID
+			copiedIDs[created.SourceID] = newID
+		}
+		report.add(result)
+	}
+	entry.Creations = creations
+
+	if report.Total > 0 && report.Succeeded == 0 {
+		return report, entry, fmt.Errorf("redo of %s failed for all %d widgets", entry.Macro, report.Total)
+	}
+	return report, entry, nil
+}
+
+// planMove plans moving widgets from source zone to target zone.
+func (h *MacrosHandler) planMove(canvasID, sourceZoneID, targetZoneID string, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+	fmt.Printf("[MacrosHandler] planMove - canvasID: %s, sourceZoneID: %s, targetZoneID: %s\n", canvasID, sourceZoneID, targetZoneID)
 
 	// Get zone bounding boxes
-	sourceBB, err := webuiatoms.GetZoneBoundingBox(h.apiClient, canvasID, sourceZoneID)
+	sourceBB, err := zoneBoundingBox(h.apiClient, h.mirrors(), canvasID, sourceZoneID)
 	if err != nil {
 		fmt.Printf("[MacrosHandler] ERROR: Failed to get source zone: %v\n", err)
-		return 0, fmt.Errorf("failed to get source zone: %w", err)
+		return nil, fmt.Errorf("failed to get source zone: %w", err)
 	}
 	fmt.Printf("[MacrosHandler] Source zone BB: X=%.2f, Y=%.2f, W=%.2f, H=%.2f, Scale=%.2f\n", sourceBB.X, sourceBB.Y, sourceBB.Width, sourceBB.Height, sourceBB.Scale)
 
-	targetBB, err := webuiatoms.GetZoneBoundingBox(h.apiClient, canvasID, targetZoneID)
+	targetBB, err := zoneBoundingBox(h.apiClient, h.mirrors(), canvasID, targetZoneID)
 	if err != nil {
 		fmt.Printf("[MacrosHandler] ERROR: Failed to get target zone: %v\n", err)
-		return 0, fmt.Errorf("failed to get target zone: %w", err)
+		return nil, fmt.Errorf("failed to get target zone: %w", err)
 	}
 	fmt.Printf("[MacrosHandler] Target zone BB: X=%.2f, Y=%.2f, W=%.2f, H=%.2f, Scale=%.2f\n", targetBB.X, targetBB.Y, targetBB.Width, targetBB.Height, targetBB.Scale)
 
 	// Get all widgets
-	allWidgets, err := webuiatoms.GetAllWidgets(h.apiClient, canvasID)
+	allWidgets, err := listWidgets(h.apiClient, h.mirrors(), canvasID)
 	if err != nil {
 		fmt.Printf("[MacrosHandler] ERROR: Failed to get widgets: %v\n", err)
-		return 0, fmt.Errorf("failed to get widgets: %w", err)
+		return nil, fmt.Errorf("failed to get widgets: %w", err)
 	}
 	fmt.Printf("[MacrosHandler] Retrieved %d total widgets\n", len(allWidgets))
 
@@ -120,13 +268,13 @@ func (h *MacrosHandler) moveWidgets(canvasID, sourceZoneID, targetZoneID string)
 	}
 
 	// Filter widgets in source zone
-	toMove := FilterWidgetsInZone(allWidgets, sourceBB, "")
+	toMove := FilterWidgetsInZone(allWidgets, sourceBB, "", membership)
 	fmt.Printf("[MacrosHandler] Found %d widgets in source zone to move\n", len(toMove))
 
 	// Transform and update each widget
 	var updates []WidgetUpdate
 	for _, widget := range toMove {
-		cloned := widget
+		cloned := cloneWidget(widget)
 		webuiatoms.TransformWidgetLocationAndScale(&cloned, sourceBB, targetBB)
 		updates = append(updates, WidgetUpdate{
 			WidgetID:   widget.ID,
@@ -140,85 +288,398 @@ func (h *MacrosHandler) moveWidgets(canvasID, sourceZoneID, targetZoneID string)
 			widget.ID[:8], widget.WidgetType, cloned.Location.X, cloned.Location.Y, cloned.Scale)
 	}
 
-	movedCount := ops.BatchUpdateWidgets(canvasID, updates)
-	fmt.Printf("[MacrosHandler] moveWidgets completed: %d widgets moved\n", movedCount)
-	return movedCount, nil
+	fmt.Printf("[MacrosHandler] planMove completed: %d widgets to move\n", len(updates))
+	return &MacroPlan{
+		Macro:      "move",
+		CanvasID:   canvasID,
+		SourceZone: sourceBB,
+		Zone:       targetBB,
+		Widgets:    toMove,
+		Updates:    updates,
+	}, nil
 }
 
-// copyWidgets copies widgets from source zone to target zone.
-func (h *MacrosHandler) copyWidgets(canvasID, sourceZoneID, targetZoneID string) (int, error) {
-	fmt.Printf("[MacrosHandler] copyWidgets - canvasID: %s, sourceZoneID: %s, targetZoneID: %s\n", canvasID, sourceZoneID, targetZoneID)
+// planCopy plans copying widgets from source zone to target zone.
+// Every widget in the source zone is planned, including browsers and nested
+// anchors. Connectors are copied when both of their ends are copied and are
+// re-linked to the copies. Anything left out is listed in plan.Skipped.
+func (h *MacrosHandler) planCopy(canvasID, sourceZoneID, targetZoneID string, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+	fmt.Printf("[MacrosHandler] planCopy - canvasID: %s, sourceZoneID: %s, targetZoneID: %s\n", canvasID, sourceZoneID, targetZoneID)
 
 	// Get zone bounding boxes
-	sourceBB, err := webuiatoms.GetZoneBoundingBox(h.apiClient, canvasID, sourceZoneID)
+	sourceBB, err := zoneBoundingBox(h.apiClient, h.mirrors(), canvasID, sourceZoneID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get source zone: %w", err)
+		return nil, fmt.Errorf("failed to get source zone: %w", err)
 	}
 
-	targetBB, err := webuiatoms.GetZoneBoundingBox(h.apiClient, canvasID, targetZoneID)
+	targetBB, err := zoneBoundingBox(h.apiClient, h.mirrors(), canvasID, targetZoneID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get target zone: %w", err)
+		return nil, fmt.Errorf("failed to get target zone: %w", err)
 	}
 
 	// Get all widgets
-	allWidgets, err := webuiatoms.GetAllWidgets(h.apiClient, canvasID)
+	allWidgets, err := listWidgets(h.apiClient, h.mirrors(), canvasID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get widgets: %w", err)
+		return nil, fmt.Errorf("failed to get widgets: %w", err)
 	}
 
-	// Filter widgets in source zone
-	toCopy := FilterWidgetsInZone(allWidgets, sourceBB, "")
-	fmt.Printf("[MacrosHandler] Found %d widgets in source zone to copy\n", len(toCopy))
-
-	// Copy widgets (create new widgets with transformed locations)
-	copiedCount := 0
-	for _, widget := range toCopy {
-		cloned := widget
+	// Plan new widgets with transformed locations. Connectors have no
+	// location of their own and are planned once their ends are known.
+	plan := &MacroPlan{Macro: "copy", CanvasID: canvasID, SourceZone: sourceBB, Zone: targetBB}
+	copied := make(map[string]bool)
+	hasConnectors := false
+	for _, widget := range allWidgets {
+		if widget.ID == sourceZoneID || widget.ID == targetZoneID || widget.WidgetType == "SharedCanvas" {
+			continue
+		}
+		if strings.ToLower(widget.WidgetType) == "connector" {
+			hasConnectors = true
+			continue
+		}
+		if !membership.Contains(&widget, sourceBB) {
+			continue
+		}
+		if !copySupported(widget.WidgetType) {
+			fmt.Printf("[MacrosHandler] Skipping unsupported widget type: %s\n", widget.WidgetType)
+			plan.skip(widget, fmt.Sprintf("widget type %s cannot be copied", widget.WidgetType))
+			continue
+		}
+		cloned := cloneWidget(widget)
 		webuiatoms.TransformWidgetLocationAndScale(&cloned, sourceBB, targetBB)
-		widgetType := strings.ToLower(widget.WidgetType)
+		plan.Copies = append(plan.Copies, PlannedCopy{Source: widget, Target: cloned})
+		copied[widget.ID] = true
+	}
 
-		var err error
-		switch widgetType {
-		case "note":
-			err = h.copyNote(canvasID, widget.ID, &cloned)
-		case "image":
-			err = h.copyImage(canvasID, widget.ID, &cloned)
-		case "video":
-			err = h.copyVideo(canvasID, widget.ID, &cloned)
-		case "pdf":
-			err = h.copyPDF(canvasID, widget.ID, &cloned)
-		default:
-			fmt.Printf("[MacrosHandler] Skipping unsupported widget type: %s\n", widget.WidgetType)
+	if hasConnectors && len(copied) > 0 {
+		if err := h.planConnectorCopies(plan, copied); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Printf("[MacrosHandler] planCopy completed: %d widgets to copy, %d skipped\n", len(plan.Copies), len(plan.Skipped))
+	return plan, nil
+}
+
+// planConnectorCopies adds the connectors between copied widgets to plan.
+// Connectors with only one end in the copied set are skipped.
+func (h *MacrosHandler) planConnectorCopies(plan *MacroPlan, copied map[string]bool) error {
+	connectors, err := h.apiClient.Connectors(plan.CanvasID).List(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get connectors: %w", err)
+	}
+
+	for _, connector := range connectors {
+		if connector.Src == nil || connector.Dst == nil {
 			continue
 		}
+		srcCopied, dstCopied := copied[connector.Src.ID], copied[connector.Dst.ID]
+		widget := webuiatoms.Widget{ID: connector.ID, WidgetType: "Connector"}
+		switch {
+		case srcCopied && dstCopied:
+			plan.Copies = append(plan.Copies, PlannedCopy{Source: widget, Target: widget})
+		case srcCopied || dstCopied:
+			plan.skip(widget, "connector has one end outside the source zone")
+		}
+	}
+	return nil
+}
 
+// applyCopies creates the copies in plan, adds a result per copy to report
+// and returns the created widgets for the undo journal. Copies are made in
+// plan order, so connectors are re-linked to the copies of their ends made
+// earlier in the same run.
+func (h *MacrosHandler) applyCopies(plan *MacroPlan, report *BatchReport) []JournalCreation {
+	copiedIDs := make(map[string]string)
+	var creations []JournalCreation
+	for _, planned := range plan.Copies {
+		widget := planned.Source
+		cloned := planned.Target
+		result := WidgetUpdateResult{WidgetID: widget.ID, WidgetType: widget.WidgetType, Attempts: 1}
+
+		newID, err := h.copyWidget(plan.CanvasID, widget, &cloned, copiedIDs)
 		if err == nil {
-			copiedCount++
+			result.Succeeded = true
+			copiedIDs[widget.ID] = newID
+			creations = append(creations, JournalCreation{
+				SourceID:   widget.ID,
+				WidgetID:   newID,
+				WidgetType: widget.WidgetType,
+				Location:   cloned.Location,
+				Size:       cloned.Size,
+				Scale:      cloned.Scale,
+			})
 			fmt.Printf("[MacrosHandler] Successfully copied widget %s (%s)\n", widget.ID[:8], widget.WidgetType)
 		} else {
+			result.Error = err.Error()
 			fmt.Printf("[MacrosHandler] ERROR: Failed to copy widget %s (%s): %v\n", widget.ID[:8], widget.WidgetType, err)
 		}
+		report.add(result)
 	}
 
-	fmt.Printf("[MacrosHandler] copyWidgets completed: %d widgets copied\n", copiedCount)
-	return copiedCount, nil
+	fmt.Printf("[MacrosHandler] applyCopies completed: %d widgets copied\n", len(creations))
+	return creations
 }
 
-// copyNote copies a note widget, fetching full note data to get text and background_color.
-func (h *MacrosHandler) copyNote(canvasID, noteID string, cloned *webuiatoms.Widget) error {
-	// Fetch full note data to get text and background_color
-	endpoint := fmt.Sprintf("/api/v1/canvases/%s/notes/%s", canvasID, noteID)
-	data, err := h.apiClient.Get(endpoint)
+// applyDeletes deletes the widgets in plan.Deletes, adds a result per widget
+// to report and returns the deleted widgets for the undo journal. Each
+// widget is saved with its file before it is deleted; a widget that cannot
+// be saved is left in place so that undo can always recreate what was
+// deleted. Widgets that are already gone count as deleted.
+func (h *MacrosHandler) applyDeletes(plan *MacroPlan, report *BatchReport) []JournalDeletion {
+	ctx := context.Background()
+	var deletions []JournalDeletion
+	for _, widget := range plan.Deletes {
+		result := WidgetUpdateResult{WidgetID: widget.ID, WidgetType: widget.WidgetType, Attempts: 1}
+		deletion, err := h.captureDeletion(ctx, plan.CanvasID, widget)
+		if webuiatoms.IsNotFound(err) {
+			result.Succeeded = true
+			report.add(result)
+			continue
+		}
+		if err != nil {
+			result.Error = fmt.Sprintf("not deleted, failed to save it for undo: %v", err)
+			fmt.Printf("[MacrosHandler] ERROR: Failed to save widget %s (%s) for undo: %v\n", widget.ID, widget.WidgetType, err)
+			report.add(result)
+			continue
+		}
+
+		_, err = h.apiClient.Do(ctx, http.MethodDelete, widgetEndpoint(plan.CanvasID, widget.WidgetType, widget.ID), nil)
+		switch {
+		case err == nil:
+			result.Succeeded = true
+			deletions = append(deletions, deletion)
+		case webuiatoms.IsNotFound(err):
+			result.Succeeded = true
+			h.journal.DiscardAsset(deletion.Asset)
+		default:
+			result.Error = err.Error()
+			h.journal.DiscardAsset(deletion.Asset)
+			fmt.Printf("[MacrosHandler] ERROR: Failed to delete widget %s (%s): %v\n", widget.ID, widget.WidgetType, err)
+		}
+		report.add(result)
+	}
+	return deletions
+}
+
+// captureDeletion fetches the full state of widget, and the file of an
+// image, video or PDF, so that undo can recreate it after it is deleted.
+func (h *MacrosHandler) captureDeletion(ctx context.Context, canvasID string, widget webuiatoms.Widget) (JournalDeletion, error) {
+	deletion := JournalDeletion{WidgetID: widget.ID, WidgetType: widget.WidgetType}
+	if _, ok := snapshotTypeFields[strings.ToLower(widget.WidgetType)]; !ok {
+		return deletion, fmt.Errorf("widget type %s cannot be recreated", widget.WidgetType)
+	}
+
+	data, err := fetchWidgetData(ctx, h.apiClient, canvasID, widget)
 	if err != nil {
-		return fmt.Errorf("failed to fetch note: %w", err)
+		return deletion, err
 	}
+	deletion.Data = data
 
-	var noteData map[string]interface{}
-	if err := json.Unmarshal(data, &noteData); err != nil {
-		return fmt.Errorf("failed to parse note: %w", err)
+	if snapshotHasAsset(widget.WidgetType) {
+		download, err := h.apiClient.OpenDownload(ctx, widgetEndpoint(canvasID, widget.WidgetType, widget.ID)+"/download")
+		if err != nil {
+			return deletion, fmt.Errorf("failed to download %s: %w", strings.ToLower(widget.WidgetType), err)
+		}
+		deletion.Asset, err = h.journal.SaveAsset(widget.ID, download)
+		download.Close()
+		if err != nil {
+			return deletion, err
+		}
 	}
+	return deletion, nil
+}
 
-	// Build payload with all note fields
+// restoreDeletions recreates the deleted widgets of entry, connectors last so
+// they can be linked to recreated ends, adds a result per widget to report
+// and returns the deletions with the IDs of the recreated widgets.
+func (h *MacrosHandler) restoreDeletions(entry JournalEntry, report *BatchReport) []JournalDeletion {
+	ctx := context.Background()
+	deletions := make([]JournalDeletion, len(entry.Deletions))
+	copy(deletions, entry.Deletions)
+	restoredIDs := make(map[string]string)
+
+	restore := func(i int) {
+		deletion := deletions[i]
+		widget := SnapshotWidget{ID: deletion.WidgetID, WidgetType: deletion.WidgetType, Data: deletion.Data, Asset: deletion.Asset}
+		result := WidgetUpdateResult{WidgetID: deletion.WidgetID, WidgetType: deletion.WidgetType, Attempts: 1}
+		var newID string
+		var err error
+		if strings.EqualFold(deletion.WidgetType, "connector") {
+			newID, err = h.restoreConnector(ctx, entry.CanvasID, widget, restoredIDs)
+		} else {
+			newID, err = createWidget(ctx, h.apiClient, entry.CanvasID, widget, h.journal.OpenAsset)
+		}
+		if err != nil {
+			fmt.Printf("[MacrosHandler] ERROR: Failed to recreate deleted widget %s: %v\n", deletion.WidgetID, err)
+			result.Error = err.Error()
+		} else {
+			result.Succeeded = true
+			result.WidgetID = newID
+			restoredIDs[deletion.WidgetID] = newID
+			deletions[i].WidgetID = newID
+		}
+		report.add(result)
+	}
+	for i, deletion := range deletions {
+		if !strings.EqualFold(deletion.WidgetType, "connector") {
+			restore(i)
+		}
+	}
+	for i, deletion := range deletions {
+		if strings.EqualFold(deletion.WidgetType, "connector") {
+			restore(i)
+		}
+	}
+	return deletions
+}
+
+// restoreConnector recreates a deleted connector. Ends that were recreated in
+// the same undo are linked to their new IDs; other ends are assumed to still
+// exist.
+func (h *MacrosHandler) restoreConnector(ctx context.Context, canvasID string, connector SnapshotWidget, restoredIDs map[string]string) (string, error) {
+	ends := make(map[string]string, len(restoredIDs)+2)
+	for oldID, newID := range restoredIDs {
+		ends[oldID] = newID
+	}
+	for _, end := range []string{"src", "dst"} {
+		original, _ := connector.Data[end].(map[string]interface{})
+		if id, _ := original["id"].(string); id != "" && ends[id] == "" {
+			ends[id] = id
+		}
+	}
+	payload, ok := relinkConnector(connector, ends)
+	if !ok {
+		return "", fmt.Errorf("connector %s has no ends", connector.ID)
+	}
+	data, err := h.apiClient.Do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/canvases/%s/connectors", canvasID), payload)
+	if err != nil {
+		return "", err
+	}
+	return createdWidgetID(data)
+}
+
+// copySupported reports whether copyWidget can copy widgets of widgetType.
+func copySupported(widgetType string) bool {
+	switch strings.ToLower(widgetType) {
+	case "note", "image", "video", "pdf", "browser", "anchor", "connector":
+		return true
+	}
+	return false
+}
+
+// errUnsupportedCopy is returned by copyWidget for widget types it cannot copy.
+var errUnsupportedCopy = errors.New("unsupported widget type for copy")
+
+// copyWidget creates a copy of widget at the location, size and scale of
+// cloned and returns the ID of the new widget. copiedIDs maps source widget
+// IDs to their copies and is used to re-link connectors.
+func (h *MacrosHandler) copyWidget(canvasID string, widget webuiatoms.Widget, cloned *webuiatoms.Widget, copiedIDs map[string]string) (string, error) {
+	switch strings.ToLower(widget.WidgetType) {
+	case "note":
+		return h.copyNote(canvasID, widget.ID, cloned)
+	case "image":
+		return h.copyImage(canvasID, widget.ID, cloned)
+	case "video":
+		return h.copyVideo(canvasID, widget.ID, cloned)
+	case "pdf":
+		return h.copyPDF(canvasID, widget.ID, cloned)
+	case "browser":
+		return h.copyBrowser(canvasID, widget.ID, cloned)
+	case "anchor":
+		return h.copyAnchor(canvasID, widget.ID, cloned)
+	case "connector":
+		return h.copyConnector(canvasID, widget.ID, copiedIDs)
+	default:
+		return "", fmt.Errorf("%w: %s", errUnsupportedCopy, widget.WidgetType)
+	}
+}
+
+// copyBrowser copies a browser widget with its URL and title.
+func (h *MacrosHandler) copyBrowser(canvasID, browserID string, cloned *webuiatoms.Widget) (string, error) {
+	ctx := context.Background()
+	browser, err := h.apiClient.Browsers(canvasID).Get(ctx, browserID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch browser: %w", err)
+	}
+
+	payload := placementPayload(cloned)
+	payload["url"] = browser.URL
+	if browser.Title != "" {
+		payload["title"] = browser.Title
+	}
+
+	created, err := h.apiClient.Browsers(canvasID).Create(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// copyAnchor copies an anchor (zone) nested inside the source zone.
+func (h *MacrosHandler) copyAnchor(canvasID, anchorID string, cloned *webuiatoms.Widget) (string, error) {
+	ctx := context.Background()
+	anchor, err := h.apiClient.Anchors(canvasID).Get(ctx, anchorID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch anchor: %w", err)
+	}
+
+	payload := placementPayload(cloned)
+	if anchor.AnchorName != "" {
+		payload["anchor_name"] = anchor.AnchorName
+	}
+
+	created, err := h.apiClient.Anchors(canvasID).Create(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// copyConnector copies a connector, pointing its ends at the copies of the
+// widgets it connects. Both ends must already have been copied.
+func (h *MacrosHandler) copyConnector(canvasID, connectorID string, copiedIDs map[string]string) (string, error) {
+	ctx := context.Background()
+	connector, err := h.apiClient.Connectors(canvasID).Get(ctx, connectorID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch connector: %w", err)
+	}
+	if connector.Src == nil || connector.Dst == nil {
+		return "", fmt.Errorf("connector %s has no ends", connectorID)
+	}
+
+	src, dst := *connector.Src, *connector.Dst
+	var ok bool
+	if src.ID, ok = copiedIDs[connector.Src.ID]; !ok {
+		return "", fmt.Errorf("connector source %s was not copied", connector.Src.ID)
+	}
+	if dst.ID, ok = copiedIDs[connector.Dst.ID]; !ok {
+		return "", fmt.Errorf("connector destination %s was not copied", connector.Dst.ID)
+	}
+
+	payload := map[string]interface{}{
+		"src": src,
+		"dst": dst,
+	}
+	if connector.LineColor != "" {
+		payload["line_color"] = connector.LineColor
+	}
+	if connector.LineWidth != 0 {
+		payload["line_width"] = connector.LineWidth
+	}
+	if connector.Type != "" {
+		payload["type"] = connector.Type
+	}
+
+	created, err := h.apiClient.Connectors(canvasID).Create(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// placementPayload returns the location, scale, size and pinned state of
+// cloned as a create payload.
+func placementPayload(cloned *webuiatoms.Widget) map[string]interface{} {
 	payload := map[string]interface{}{
 		"location": cloned.Location,
 		"scale":    cloned.Scale,
@@ -226,6 +687,54 @@ func (h *MacrosHandler) copyNote(canvasID, noteID string, cloned *webuiatoms.Wid
 	if cloned.Size != nil {
 		payload["size"] = cloned.Size
 	}
+	if cloned.Pinned {
+		payload["pinned"] = true
+	}
+	return payload
+}
+
+// cloneWidget returns a copy of widget that does not share its location or
+// size with the original, so it can be transformed without side effects.
+func cloneWidget(widget webuiatoms.Widget) webuiatoms.Widget {
+	cloned := widget
+	if widget.Location != nil {
+		location := *widget.Location
+		cloned.Location = &location
+	}
+	if widget.Size != nil {
+		size := *widget.Size
+		cloned.Size = &size
+	}
+	return cloned
+}
+
+// createdWidgetID extracts the ID from the response to a create request.
+func createdWidgetID(data []byte) (string, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &created); err != nil {
+		return "", fmt.Errorf("failed to parse created widget: %w", err)
+	}
+	return created.ID, nil
+}
+
+// copyNote copies a note widget, fetching full note data to get text and background_color.
+func (h *MacrosHandler) copyNote(canvasID, noteID string, cloned *webuiatoms.Widget) (string, error) {
+	// Fetch full note data to get text and background_color
+	endpoint := fmt.Sprintf("/api/v1/canvases/%s/notes/%s", canvasID, noteID)
+	data, err := h.apiClient.Get(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	var noteData map[string]interface{}
+	if err := json.Unmarshal(data, &noteData); err != nil {
+		return "", fmt.Errorf("failed to parse note: %w", err)
+	}
+
+	// Build payload with all note fields
+	payload := placementPayload(cloned)
 	if title, ok := noteData["title"].(string); ok {
 		payload["title"] = title
 	}
@@ -238,39 +747,36 @@ func (h *MacrosHandler) copyNote(canvasID, noteID string, cloned *webuiatoms.Wid
 
 	// Create new note
 	createEndpoint := fmt.Sprintf("/api/v1/canvases/%s/notes", canvasID)
-	_, err = h.apiClient.Post(createEndpoint, payload)
-	return err
+	data, err = h.apiClient.Post(createEndpoint, payload)
+	if err != nil {
+		return "", err
+	}
+	return createdWidgetID(data)
 }
 
 // copyImage copies an image widget by downloading and re-uploading the file.
-func (h *MacrosHandler) copyImage(canvasID, imageID string, cloned *webuiatoms.Widget) error {
+func (h *MacrosHandler) copyImage(canvasID, imageID string, cloned *webuiatoms.Widget) (string, error) {
 	// Download image file
 	downloadEndpoint := fmt.Sprintf("/api/v1/canvases/%s/images/%s/download", canvasID, imageID)
 	fileData, err := h.apiClient.Get(downloadEndpoint)
 	if err != nil {
-		return fmt.Errorf("failed to download image: %w", err)
+		return "", fmt.Errorf("failed to download image: %w", err)
 	}
 
 	// Get image metadata
 	metaEndpoint := fmt.Sprintf("/api/v1/canvases/%s/images/%s", canvasID, imageID)
 	metaData, err := h.apiClient.Get(metaEndpoint)
 	if err != nil {
-		return fmt.Errorf("failed to fetch image metadata: %w", err)
+		return "", fmt.Errorf("failed to fetch image metadata: %w", err)
 	}
 
 	var imageMeta map[string]interface{}
 	if err := json.Unmarshal(metaData, &imageMeta); err != nil {
-		return fmt.Errorf("failed to parse image metadata: %w", err)
+		return "", fmt.Errorf("failed to parse image metadata: %w", err)
 	}
 
 	// Build JSON payload
-	jsonPayload := map[string]interface{}{
-		"location": cloned.Location,
-		"scale":    cloned.Scale,
-	}
-	if cloned.Size != nil {
-		jsonPayload["size"] = cloned.Size
-	}
+	jsonPayload := placementPayload(cloned)
 	if title, ok := imageMeta["title"].(string); ok {
 		jsonPayload["title"] = title
 	}
@@ -287,39 +793,36 @@ func (h *MacrosHandler) copyImage(canvasID, imageID string, cloned *webuiatoms.W
 	// Upload image using multipart
 	createEndpoint := fmt.Sprintf("/api/v1/canvases/%s/images", canvasID)
 	fileReader := bytes.NewReader(fileData)
-	_, err = h.apiClient.PostMultipart(createEndpoint, jsonPayload, fileReader, fileName)
-	return err
+	created, err := h.apiClient.PostMultipart(createEndpoint, jsonPayload, fileReader, fileName)
+	if err != nil {
+		return "", err
+	}
+	return createdWidgetID(created)
 }
 
 // copyVideo copies a video widget by downloading and re-uploading the file.
-func (h *MacrosHandler) copyVideo(canvasID, videoID string, cloned *webuiatoms.Widget) error {
+func (h *MacrosHandler) copyVideo(canvasID, videoID string, cloned *webuiatoms.Widget) (string, error) {
 	// Download video file
 	downloadEndpoint := fmt.Sprintf("/api/v1/canvases/%s/videos/%s/download", canvasID, videoID)
 	fileData, err := h.apiClient.Get(downloadEndpoint)
 	if err != nil {
-		return fmt.Errorf("failed to download video: %w", err)
+		return "", fmt.Errorf("failed to download video: %w", err)
 	}
 
 	// Get video metadata
 	metaEndpoint := fmt.Sprintf("/api/v1/canvases/%s/videos/%s", canvasID, videoID)
 	metaData, err := h.apiClient.Get(metaEndpoint)
 	if err != nil {
-		return fmt.Errorf("failed to fetch video metadata: %w", err)
+		return "", fmt.Errorf("failed to fetch video metadata: %w", err)
 	}
 
 	var videoMeta map[string]interface{}
 	if err := json.Unmarshal(metaData, &videoMeta); err != nil {
-		return fmt.Errorf("failed to parse video metadata: %w", err)
+		return "", fmt.Errorf("failed to parse video metadata: %w", err)
 	}
 
 	// Build JSON payload
-	jsonPayload := map[string]interface{}{
-		"location": cloned.Location,
-		"scale":    cloned.Scale,
-	}
-	if cloned.Size != nil {
-		jsonPayload["size"] = cloned.Size
-	}
+	jsonPayload := placementPayload(cloned)
 	if title, ok := videoMeta["title"].(string); ok {
 		jsonPayload["title"] = title
 	}
@@ -336,39 +839,36 @@ func (h *MacrosHandler) copyVideo(canvasID, videoID string, cloned *webuiatoms.W
 	// Upload video using multipart
 	createEndpoint := fmt.Sprintf("/api/v1/canvases/%s/videos", canvasID)
 	fileReader := bytes.NewReader(fileData)
-	_, err = h.apiClient.PostMultipart(createEndpoint, jsonPayload, fileReader, fileName)
-	return err
+	created, err := h.apiClient.PostMultipart(createEndpoint, jsonPayload, fileReader, fileName)
+	if err != nil {
+		return "", err
+	}
+	return createdWidgetID(created)
 }
 
 // copyPDF copies a PDF widget by downloading and re-uploading the file.
-func (h *MacrosHandler) copyPDF(canvasID, pdfID string, cloned *webuiatoms.Widget) error {
+func (h *MacrosHandler) copyPDF(canvasID, pdfID string, cloned *webuiatoms.Widget) (string, error) {
 	// Download PDF file
 	downloadEndpoint := fmt.Sprintf("/api/v1/canvases/%s/pdfs/%s/download", canvasID, pdfID)
 	fileData, err := h.apiClient.Get(downloadEndpoint)
 	if err != nil {
-		return fmt.Errorf("failed to download PDF: %w", err)
+		return "", fmt.Errorf("failed to download PDF: %w", err)
 	}
 
 	// Get PDF metadata
 	metaEndpoint := fmt.Sprintf("/api/v1/canvases/%s/pdfs/%s", canvasID, pdfID)
 	metaData, err := h.apiClient.Get(metaEndpoint)
 	if err != nil {
-		return fmt.Errorf("failed to fetch PDF metadata: %w", err)
+		return "", fmt.Errorf("failed to fetch PDF metadata: %w", err)
 	}
 
 	var pdfMeta map[string]interface{}
 	if err := json.Unmarshal(metaData, &pdfMeta); err != nil {
-		return fmt.Errorf("failed to parse PDF metadata: %w", err)
+		return "", fmt.Errorf("failed to parse PDF metadata: %w", err)
 	}
 
 	// Build JSON payload
-	jsonPayload := map[string]interface{}{
-		"location": cloned.Location,
-		"scale":    cloned.Scale,
-	}
-	if cloned.Size != nil {
-		jsonPayload["size"] = cloned.Size
-	}
+	jsonPayload := placementPayload(cloned)
 	if title, ok := pdfMeta["title"].(string); ok {
 		jsonPayload["title"] = title
 	}
@@ -388,24 +888,27 @@ func (h *MacrosHandler) copyPDF(canvasID, pdfID string, cloned *webuiatoms.Widge
 	// Upload PDF using multipart
 	createEndpoint := fmt.Sprintf("/api/v1/canvases/%s/pdfs", canvasID)
 	fileReader := bytes.NewReader(fileData)
-	_, err = h.apiClient.PostMultipart(createEndpoint, jsonPayload, fileReader, fileName)
-	return err
+	created, err := h.apiClient.PostMultipart(createEndpoint, jsonPayload, fileReader, fileName)
+	if err != nil {
+		return "", err
+	}
+	return createdWidgetID(created)
 }
 
-// pinWidgetsInZone pins or unpins all widgets in a zone.
-func (h *MacrosHandler) pinWidgetsInZone(canvasID, zoneID string, pinned bool) (int, error) {
-	fmt.Printf("[MacrosHandler] pinWidgetsInZone - canvasID: %s, zoneID: %s, pinned: %v\n", canvasID, zoneID, pinned)
-	ops := NewMacrosOperations(h.apiClient, h.canvasService)
+// planPin plans pinning or unpinning all widgets in a zone.
+func (h *MacrosHandler) planPin(canvasID, zoneID string, pinned bool, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+	fmt.Printf("[MacrosHandler] planPin - canvasID: %s, zoneID: %s, pinned: %v\n", canvasID, zoneID, pinned)
+	ops := h.newOperations()
 
-	zoneBB, allWidgets, err := ops.GetZoneAndWidgets(zoneID)
+	zoneBB, allWidgets, err := ops.GetZoneAndWidgets(canvasID, zoneID)
 	if err != nil {
-		fmt.Printf("[MacrosHandler] ERROR: pinWidgetsInZone failed to get zone/widgets: %v\n", err)
-		return 0, err
+		fmt.Printf("[MacrosHandler] ERROR: planPin failed to get zone/widgets: %v\n", err)
+		return nil, err
 	}
 
 	// Filter widgets in zone
-	inZone := FilterWidgetsInZone(allWidgets, zoneBB, zoneID)
-	fmt.Printf("[MacrosHandler] pinWidgetsInZone: Found %d widgets in zone\n", len(inZone))
+	inZone := FilterWidgetsInZone(allWidgets, zoneBB, zoneID, membership)
+	fmt.Printf("[MacrosHandler] planPin: Found %d widgets in zone\n", len(inZone))
 
 	// Update widgets
 	var updates []WidgetUpdate
@@ -415,86 +918,62 @@ func (h *MacrosHandler) pinWidgetsInZone(canvasID, zoneID string, pinned bool) (
 			WidgetType: widget.WidgetType,
 			Payload:    map[string]interface{}{"pinned": pinned},
 		})
-		fmt.Printf("[MacrosHandler] pinWidgetsInZone: Prepared update for widget %s (%s) - pinned: %v\n",
+		fmt.Printf("[MacrosHandler] planPin: Prepared update for widget %s (%s) - pinned: %v\n",
 			widget.ID[:8], widget.WidgetType, pinned)
 	}
 
-	fmt.Printf("[MacrosHandler] pinWidgetsInZone: Prepared %d updates, calling BatchUpdateWidgets\n", len(updates))
-	pinnedCount := ops.BatchUpdateWidgets(canvasID, updates)
-	fmt.Printf("[MacrosHandler] pinWidgetsInZone completed: %d widgets updated\n", pinnedCount)
-	return pinnedCount, nil
+	fmt.Printf("[MacrosHandler] planPin: Prepared %d updates\n", len(updates))
+	macro := "unpin-all"
+	if pinned {
+		macro = "pin-all"
+	}
+	return &MacroPlan{Macro: macro, CanvasID: canvasID, Zone: zoneBB, Widgets: inZone, Updates: updates}, nil
 }
 
-// organizeWidgetsInGrid organizes widgets in a grid within a zone.
-func (h *MacrosHandler) organizeWidgetsInGrid(canvasID, zoneID string) (int, error) {
-	fmt.Printf("[MacrosHandler] organizeWidgetsInGrid - canvasID: %s, zoneID: %s\n", canvasID, zoneID)
-	ops := NewMacrosOperations(h.apiClient, h.canvasService)
+// planAutoGrid plans organizing widgets in a grid within a zone.
+func (h *MacrosHandler) planAutoGrid(canvasID, zoneID string, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+	fmt.Printf("[MacrosHandler] planAutoGrid - canvasID: %s, zoneID: %s\n", canvasID, zoneID)
+	ops := h.newOperations()
 
-	zoneBB, allWidgets, err := ops.GetZoneAndWidgets(zoneID)
+	zoneBB, allWidgets, err := ops.GetZoneAndWidgets(canvasID, zoneID)
 	if err != nil {
-		fmt.Printf("[MacrosHandler] ERROR: organizeWidgetsInGrid failed to get zone/widgets: %v\n", err)
-		return 0, err
+		fmt.Printf("[MacrosHandler] ERROR: planAutoGrid failed to get zone/widgets: %v\n", err)
+		return nil, err
 	}
 
 	// Filter widgets in zone
-	inZone := FilterWidgetsInZone(allWidgets, zoneBB, zoneID)
-	fmt.Printf("[MacrosHandler] organizeWidgetsInGrid: Found %d widgets in zone\n", len(inZone))
+	inZone := FilterWidgetsInZone(allWidgets, zoneBB, zoneID, membership)
+	fmt.Printf("[MacrosHandler] planAutoGrid: Found %d widgets in zone\n", len(inZone))
 
 	if len(inZone) == 0 {
-		fmt.Printf("[MacrosHandler] organizeWidgetsInGrid: No widgets to organize\n")
-		return 0, nil
-	}
-
-	// Determine optimal grid size
-	bestRows, bestCols := CalculateOptimalGrid(len(inZone), zoneBB)
-	cellWidth, cellHeight := CalculateCellDimensions(zoneBB, bestRows, bestCols)
-	fmt.Printf("[MacrosHandler] organizeWidgetsInGrid: Grid layout: %d rows x %d cols, cell size: %.2f x %.2f\n", bestRows, bestCols, cellWidth, cellHeight)
-
-	// Position widgets in grid
-	var updates []WidgetUpdate
-	buffer := 100.0
-	for i, widget := range inZone {
-		row := i / bestCols
-		col := i % bestCols
-		x := zoneBB.X + buffer + float64(col)*(cellWidth+buffer)
-		y := zoneBB.Y + buffer + float64(row)*(cellHeight+buffer)
-
-		updates = append(updates, WidgetUpdate{
-			WidgetID:   widget.ID,
-			WidgetType: widget.WidgetType,
-			Payload: map[string]interface{}{
-				"location": map[string]float64{"x": x, "y": y},
-			},
-		})
-		fmt.Printf("[MacrosHandler] organizeWidgetsInGrid: Prepared update for widget %s (%s) at (%.2f, %.2f)\n",
-			widget.ID[:8], widget.WidgetType, x, y)
+		fmt.Printf("[MacrosHandler] planAutoGrid: No widgets to organize\n")
+		return &MacroPlan{Macro: "auto-grid", CanvasID: canvasID, Zone: zoneBB}, nil
 	}
 
-	fmt.Printf("[MacrosHandler] organizeWidgetsInGrid: Prepared %d updates, calling BatchUpdateWidgets\n", len(updates))
-	griddedCount := ops.BatchUpdateWidgets(canvasID, updates)
-	fmt.Printf("[MacrosHandler] organizeWidgetsInGrid completed: %d widgets organized\n", griddedCount)
-	return griddedCount, nil
+	updates := PlanGrid(inZone, zoneBB)
+	fmt.Printf("[MacrosHandler] planAutoGrid: Prepared %d updates\n", len(updates))
+	return &MacroPlan{Macro: "auto-grid", CanvasID: canvasID, Zone: zoneBB, Widgets: inZone, Updates: updates}, nil
 }
 
-// groupWidgetsByAttribute groups widgets by an attribute (color or title) and positions them.
+// planGroupByAttribute plans grouping widgets by an attribute (color or title) and positioning them.
 // Uses bounding boxes to filter widgets within the zone before grouping.
-func (h *MacrosHandler) groupWidgetsByAttribute(canvasID, zoneID string, getAttribute func(webuiatoms.Widget) string) (int, error) {
-	fmt.Printf("[MacrosHandler] groupWidgetsByAttribute - canvasID: %s, zoneID: %s\n", canvasID, zoneID)
-	ops := NewMacrosOperations(h.apiClient, h.canvasService)
+func (h *MacrosHandler) planGroupByAttribute(canvasID, zoneID string, membership webuiatoms.ZoneMembership, getAttribute func(webuiatoms.Widget) string) (*MacroPlan, error) {
+	fmt.Printf("[MacrosHandler] planGroupByAttribute - canvasID: %s, zoneID: %s\n", canvasID, zoneID)
+	ops := h.newOperations()
 
-	zoneBB, allWidgets, err := ops.GetZoneAndWidgets(zoneID)
+	zoneBB, allWidgets, err := ops.GetZoneAndWidgets(canvasID, zoneID)
 	if err != nil {
-		fmt.Printf("[MacrosHandler] ERROR: groupWidgetsByAttribute failed to get zone/widgets: %v\n", err)
-		return 0, err
+		fmt.Printf("[MacrosHandler] ERROR: planGroupByAttribute failed to get zone/widgets: %v\n", err)
+		return nil, err
 	}
 
 	// Filter widgets in zone using bounding box (excludes anchors/connectors)
-	inZone := FilterWidgetsInZone(allWidgets, zoneBB, zoneID)
-	fmt.Printf("[MacrosHandler] groupWidgetsByAttribute: Found %d widgets in zone (using bounding box)\n", len(inZone))
+	inZone := FilterWidgetsInZone(allWidgets, zoneBB, zoneID, membership)
+	fmt.Printf("[MacrosHandler] planGroupByAttribute: Found %d widgets in zone (using bounding box)\n", len(inZone))
 
 	if len(inZone) == 0 {
-		fmt.Printf("[MacrosHandler] groupWidgetsByAttribute: No widgets in zone to group\n")
-		return 0, nil
+		fmt.Printf("[MacrosHandler] planGroupByAttribute: No widgets in zone to group\n")
+		return &MacroPlan{Macro: "group", CanvasID: canvasID, Zone: zoneBB}, nil
 	}
 
 	// Group filtered widgets by attribute
@@ -507,29 +986,27 @@ func (h *MacrosHandler) groupWidgetsByAttribute(canvasID, zoneID string, getAttr
 		groups[attr] = append(groups[attr], w)
 	}
 
-	fmt.Printf("[MacrosHandler] groupWidgetsByAttribute: Created %d groups with total %d widgets\n", len(groups), len(inZone))
+	fmt.Printf("[MacrosHandler] planGroupByAttribute: Created %d groups with total %d widgets\n", len(groups), len(inZone))
 
-	groupedCount := ops.PositionWidgetGroups(groups, zoneBB, canvasID)
-	fmt.Printf("[MacrosHandler] groupWidgetsByAttribute completed: %d widgets grouped\n", groupedCount)
-	return groupedCount, nil
+	return &MacroPlan{Macro: "group", CanvasID: canvasID, Zone: zoneBB, Widgets: inZone, Updates: PlanWidgetGroups(groups, zoneBB)}, nil
 }
 
-// groupWidgetsByColor groups Note widgets by their background_color.
+// planGroupByColor plans grouping Note widgets by their background_color.
 // Only includes Note widgets that have a background_color field.
 // Skips PDFs, images, videos, and notes without background_color.
-func (h *MacrosHandler) groupWidgetsByColor(canvasID, zoneID string) (int, error) {
-	fmt.Printf("[MacrosHandler] groupWidgetsByColor - canvasID: %s, zoneID: %s\n", canvasID, zoneID)
-	ops := NewMacrosOperations(h.apiClient, h.canvasService)
+func (h *MacrosHandler) planGroupByColor(canvasID, zoneID string, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+	fmt.Printf("[MacrosHandler] planGroupByColor - canvasID: %s, zoneID: %s\n", canvasID, zoneID)
+	ops := h.newOperations()
 
-	zoneBB, allWidgets, err := ops.GetZoneAndWidgets(zoneID)
+	zoneBB, allWidgets, err := ops.GetZoneAndWidgets(canvasID, zoneID)
 	if err != nil {
-		fmt.Printf("[MacrosHandler] ERROR: groupWidgetsByColor failed to get zone/widgets: %v\n", err)
-		return 0, err
+		fmt.Printf("[MacrosHandler] ERROR: planGroupByColor failed to get zone/widgets: %v\n", err)
+		return nil, err
 	}
 
 	// Filter widgets in zone using bounding box
-	inZone := FilterWidgetsInZone(allWidgets, zoneBB, zoneID)
-	fmt.Printf("[MacrosHandler] groupWidgetsByColor: Found %d widgets in zone\n", len(inZone))
+	inZone := FilterWidgetsInZone(allWidgets, zoneBB, zoneID, membership)
+	fmt.Printf("[MacrosHandler] planGroupByColor: Found %d widgets in zone\n", len(inZone))
 
 	// Filter to only Note widgets and fetch their background_color
 	type noteWithColor struct {
@@ -541,7 +1018,7 @@ func (h *MacrosHandler) groupWidgetsByColor(canvasID, zoneID string) (int, error
 	for _, widget := range inZone {
 		// Only process Note widgets
 		if strings.ToLower(widget.WidgetType) != "note" {
-			fmt.Printf("[MacrosHandler] groupWidgetsByColor: Skipping non-note widget %s (%s)\n", widget.ID[:8], widget.WidgetType)
+			fmt.Printf("[MacrosHandler] planGroupByColor: Skipping non-note widget %s (%s)\n", widget.ID[:8], widget.WidgetType)
 			continue
 		}
 
@@ -549,20 +1026,20 @@ func (h *MacrosHandler) groupWidgetsByColor(canvasID, zoneID string) (int, error
 		noteEndpoint := fmt.Sprintf("/api/v1/canvases/%s/notes/%s", canvasID, widget.ID)
 		noteData, err := h.apiClient.Get(noteEndpoint)
 		if err != nil {
-			fmt.Printf("[MacrosHandler] groupWidgetsByColor: ERROR - Failed to fetch note %s: %v\n", widget.ID[:8], err)
+			fmt.Printf("[MacrosHandler] planGroupByColor: ERROR - Failed to fetch note %s: %v\n", widget.ID[:8], err)
 			continue
 		}
 
 		var note map[string]interface{}
 		if err := json.Unmarshal(noteData, &note); err != nil {
-			fmt.Printf("[MacrosHandler] groupWidgetsByColor: ERROR - Failed to parse note %s: %v\n", widget.ID[:8], err)
+			fmt.Printf("[MacrosHandler] planGroupByColor: ERROR - Failed to parse note %s: %v\n", widget.ID[:8], err)
 			continue
 		}
 
 		// Get background_color if it exists
 		bgColor, ok := note["background_color"].(string)
 		if !ok || bgColor == "" {
-			fmt.Printf("[MacrosHandler] groupWidgetsByColor: Skipping note %s (no background_color)\n", widget.ID[:8])
+			fmt.Printf("[MacrosHandler] planGroupByColor: Skipping note %s (no background_color)\n", widget.ID[:8])
 			continue
 		}
 
@@ -570,14 +1047,14 @@ func (h *MacrosHandler) groupWidgetsByColor(canvasID, zoneID string) (int, error
 			widget:          widget,
 			backgroundColor: bgColor,
 		})
-		fmt.Printf("[MacrosHandler] groupWidgetsByColor: Note %s has background_color: %s\n", widget.ID[:8], bgColor)
+		fmt.Printf("[MacrosHandler] planGroupByColor: Note %s has background_color: %s\n", widget.ID[:8], bgColor)
 	}
 
-	fmt.Printf("[MacrosHandler] groupWidgetsByColor: Found %d notes with background_color\n", len(notesWithColor))
+	fmt.Printf("[MacrosHandler] planGroupByColor: Found %d notes with background_color\n", len(notesWithColor))
 
 	if len(notesWithColor) == 0 {
-		fmt.Printf("[MacrosHandler] groupWidgetsByColor: No notes with background_color to group\n")
-		return 0, nil
+		fmt.Printf("[MacrosHandler] planGroupByColor: No notes with background_color to group\n")
+		return &MacroPlan{Macro: "group-color", CanvasID: canvasID, Zone: zoneBB}, nil
 	}
 
 	// Group by background_color
@@ -586,11 +1063,44 @@ func (h *MacrosHandler) groupWidgetsByColor(canvasID, zoneID string) (int, error
 		groups[nwc.backgroundColor] = append(groups[nwc.backgroundColor], nwc.widget)
 	}
 
-	fmt.Printf("[MacrosHandler] groupWidgetsByColor: Created %d color groups\n", len(groups))
+	fmt.Printf("[MacrosHandler] planGroupByColor: Created %d color groups\n", len(groups))
 
 	// Position groups
-	groupedCount := ops.PositionWidgetGroups(groups, zoneBB, canvasID)
-	fmt.Printf("[MacrosHandler] groupWidgetsByColor completed: %d notes grouped by color\n", groupedCount)
-	return groupedCount, nil
+	return &MacroPlan{Macro: "group-color", CanvasID: canvasID, Zone: zoneBB, Widgets: inZone, Updates: PlanWidgetGroups(groups, zoneBB)}, nil
 }
 
+// planGroupByTitle plans grouping widgets in a zone by title.
+// Widgets without a title are grouped together as "untitled".
+func (h *MacrosHandler) planGroupByTitle(canvasID, zoneID string, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+	fmt.Printf("[MacrosHandler] planGroupByTitle - canvasID: %s, zoneID: %s\n", canvasID, zoneID)
+	ops := h.newOperations()
+
+	zoneBB, allWidgets, err := ops.GetZoneAndWidgets(canvasID, zoneID)
+	if err != nil {
+		fmt.Printf("[MacrosHandler] ERROR: planGroupByTitle failed to get zone/widgets: %v\n", err)
+		return nil, err
+	}
+
+	// Filter widgets in zone
+	inZone := FilterWidgetsInZone(allWidgets, zoneBB, zoneID, membership)
+	if len(inZone) == 0 {
+		return &MacroPlan{Macro: "group-title", CanvasID: canvasID, Zone: zoneBB}, nil
+	}
+
+	// Sort by title
+	sort.Slice(inZone, func(i, j int) bool {
+		return strings.ToLower(inZone[i].Title) < strings.ToLower(inZone[j].Title)
+	})
+
+	// Group by title
+	titleGroups := make(map[string][]webuiatoms.Widget)
+	for _, w := range inZone {
+		title := w.Title
+		if title == "" {
+			title = "untitled"
+		}
+		titleGroups[title] = append(titleGroups[title], w)
+	}
+
+	return &MacroPlan{Macro: "group-title", CanvasID: canvasID, Zone: zoneBB, Widgets: inZone, Updates: PlanWidgetGroups(titleGroups, zoneBB)}, nil
+}
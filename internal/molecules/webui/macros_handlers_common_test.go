@@ -0,0 +1,110 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestCopy_BrowsersAnchorsAndConnectors checks that copy covers browsers and
+// nested anchors, re-links connectors to the copies, and reports connectors
+// that cannot be copied.
+func TestCopy_BrowsersAnchorsAndConnectors(t *testing.T) {
+	const canvas = "/api/v1/canvases/canvas-1"
+	var mu sync.Mutex
+	created := make(map[string]map[string]interface{})
+	nextID := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, canvas)
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			var payload map[string]interface{}
+			json.Unmarshal(body, &payload)
+			nextID++
+			id := fmt.Sprintf("new-%05d", nextID)
+			created[id] = payload
+			created[id]["collection"] = path
+			fmt.Fprintf(w, `{"id":%q}`, id)
+			return
+		}
+		switch path {
+		case "/anchors/source-zone":
+			w.Write([]byte(`{"id":"source-zone","location":{"x":0,"y":0},"size":{"width":1000,"height":1000},"scale":1}`))
+		case "/anchors/target-zone":
+			w.Write([]byte(`{"id":"target-zone","location":{"x":2000,"y":0},"size":{"width":1000,"height":1000},"scale":1}`))
+		case "/anchors/nested-anchor":
+			w.Write([]byte(`{"id":"nested-anchor","anchor_name":"Nested","location":{"x":500,"y":500},"size":{"width":100,"height":100}}`))
+		case "/widgets":
+			w.Write([]byte(`[
+				{"id":"source-zone","widget_type":"Anchor","location":{"x":0,"y":0},"size":{"width":1000,"height":1000}},
+				{"id":"target-zone","widget_type":"Anchor","location":{"x":2000,"y":0},"size":{"width":1000,"height":1000}},
+				{"id":"browser-0001","widget_type":"Browser","location":{"x":100,"y":100}},
+				{"id":"nested-anchor","widget_type":"Anchor","location":{"x":500,"y":500}},
+				{"id":"outside-0001","widget_type":"Browser","location":{"x":5000,"y":5000}},
+				{"id":"connector-in","widget_type":"Connector"},
+				{"id":"connector-out","widget_type":"Connector"}
+			]`))
+		case "/browsers/browser-0001":
+			w.Write([]byte(`{"id":"browser-0001","url":"https://example.com","title":"Example"}`))
+		case "/connectors":
+			w.Write([]byte(`[
+				{"id":"connector-in","src":{"id":"browser-0001","tip":"none"},"dst":{"id":"nested-anchor","tip":"solid-equilateral-triangle"}},
+				{"id":"connector-out","src":{"id":"browser-0001"},"dst":{"id":"outside-0001"}}
+			]`))
+		case "/connectors/connector-in":
+			w.Write([]byte(`{"id":"connector-in","src":{"id":"browser-0001","tip":"none"},"dst":{"id":"nested-anchor","tip":"solid-equilateral-triangle"},"line_width":5}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	handler := NewMacrosHandler(webuiatoms.NewAPIClient(server.URL, "test-token"), nil)
+	plan, err := handler.planCopy("canvas-1", "source-zone", "target-zone", webuiatoms.DefaultZoneMembership())
+	if err != nil {
+		t.Fatalf("planCopy: %v", err)
+	}
+	if len(plan.Copies) != 3 {
+		t.Fatalf("planned %d copies, want 3 (browser, anchor, connector)", len(plan.Copies))
+	}
+	if len(plan.Skipped) != 1 || plan.Skipped[0].WidgetID != "connector-out" {
+		t.Errorf("skipped = %+v, want connector-out", plan.Skipped)
+	}
+
+	report := handler.applyPlan(plan, "")
+	if report.Succeeded != 3 || report.Failed != 0 || len(report.Skipped) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	// The connector must point at the copies, not the originals.
+	var connector map[string]interface{}
+	for _, payload := range created {
+		if payload["collection"] == "/connectors" {
+			connector = payload
+		}
+	}
+	if connector == nil {
+		t.Fatal("connector was not created")
+	}
+	src := connector["src"].(map[string]interface{})
+	dst := connector["dst"].(map[string]interface{})
+	if !strings.HasPrefix(src["id"].(string), "new-") || !strings.HasPrefix(dst["id"].(string), "new-") {
+		t.Errorf("connector ends = %v -> %v, want copied widgets", src["id"], dst["id"])
+	}
+	if created[src["id"].(string)]["url"] != "https://example.com" {
+		t.Errorf("connector source is not the copied browser: %v", created[src["id"].(string)])
+	}
+	if created[dst["id"].(string)]["anchor_name"] != "Nested" {
+		t.Errorf("connector destination is not the copied anchor: %v", created[dst["id"].(string)])
+	}
+}
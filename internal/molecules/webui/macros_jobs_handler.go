@@ -0,0 +1,114 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// JobsHandler handles the scheduled macro job API endpoints.
+type JobsHandler struct {
+	scheduler *MacroScheduler
+}
+
+// NewJobsHandler creates a new jobs handler for scheduler.
+func NewJobsHandler(scheduler *MacroScheduler) *JobsHandler {
+	return &JobsHandler{scheduler: scheduler}
+}
+
+// SetScheduler replaces the scheduler whose jobs are managed.
+func (h *JobsHandler) SetScheduler(scheduler *MacroScheduler) {
+	h.scheduler = scheduler
+}
+
+// HandleJobs handles /api/macros/jobs:
+// GET lists jobs and the run history, POST creates a job and DELETE ?id= removes one.
+func (h *JobsHandler) HandleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		jobs, history := h.scheduler.List()
+		sendJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"jobs":    jobs,
+			"history": history,
+		}, http.StatusOK)
+	case http.MethodPost:
+		h.handleCreate(w, r)
+	case http.MethodDelete:
+		if err := h.scheduler.Delete(r.URL.Query().Get("id")); err != nil {
+			sendJobError(w, err)
+			return
+		}
+		sendJSONResponse(w, map[string]interface{}{
+			"success": true,
+			"message": "Job deleted",
+		}, http.StatusOK)
+	default:
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *JobsHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var job MacroJob
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if job.ClientID == "" {
+		job.ClientID = r.URL.Query().Get("client_id")
+	}
+
+	created, err := h.scheduler.Create(job)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Job %s scheduled", created.Name),
+		"job":     created,
+	}, http.StatusOK)
+}
+
+// HandlePause handles POST /api/macros/jobs/pause - Pause or resume a job.
+// Body: {"id": "...", "paused": true}
+func (h *JobsHandler) HandlePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID     string `json:"id"`
+		Paused bool   `json:"paused"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.scheduler.SetPaused(req.ID, req.Paused)
+	if err != nil {
+		sendJobError(w, err)
+		return
+	}
+	message := fmt.Sprintf("Job %s resumed", job.Name)
+	if job.Paused {
+		message = fmt.Sprintf("Job %s paused", job.Name)
+	}
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"message": message,
+		"job":     job,
+	}, http.StatusOK)
+}
+
+// sendJobError maps scheduler errors to HTTP status codes.
+func sendJobError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrJobNotFound) {
+		status = http.StatusNotFound
+	}
+	sendErrorResponse(w, err.Error(), status)
+}
@@ -0,0 +1,404 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// MaxJournalEntries is how many macro runs are kept for undo.
+const MaxJournalEntries = 50
+
+var (
+	// ErrNothingToUndo is returned by MacroJournal.Undo when the undo stack
+	// holds no entry of the canvas.
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrNothingToRedo is returned by MacroJournal.Redo when the redo stack
+	// holds no entry of the canvas.
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// JournalChange records one widget modified by a macro. Before and After
+// hold only the fields the macro changed (location, scale, pinned) in the
+// form they are sent to the PATCH endpoint.
+type JournalChange struct {
+	WidgetID   string                 `json:"widget_id"`
+	WidgetType string                 `json:"widget_type"`
+	Before     map[string]interface{} `json:"before"`
+	After      map[string]interface{} `json:"after"`
+}
+
+// JournalCreation records a widget created by a copy macro. Undo deletes
+// WidgetID; redo copies SourceID again to the recorded location.
+type JournalCreation struct {
+	SourceID   string                     `json:"source_id"`
+	WidgetID   string                     `json:"widget_id"`
+	WidgetType string                     `json:"widget_type"`
+	Location   *webuiatoms.WidgetLocation `json:"location,omitempty"`
+	Size       *webuiatoms.WidgetSize     `json:"size,omitempty"`
+	Scale      float64                    `json:"scale"`
+}
+
+// JournalDeletion records a widget deleted by a macro. Data holds the widget
+// as returned by its endpoint and Asset names the journal file of an image,
+// video or PDF, so undo can recreate it. Redo deletes WidgetID, which undo
+// sets to the ID of the recreated widget.
+type JournalDeletion struct {
+	WidgetID   string                 `json:"widget_id"`
+	WidgetType string                 `json:"widget_type"`
+	Data       map[string]interface{} `json:"data"`
+	Asset      string                 `json:"asset,omitempty"`
+}
+
+// JournalEntry is one macro run.
+type JournalEntry struct {
+	ID        string            `json:"id"`
+	Macro     string            `json:"macro"`
+	CanvasID  string            `json:"canvas_id"`
+	CreatedAt time.Time         `json:"created_at"`
+	Changes   []JournalChange   `json:"changes,omitempty"`
+	Creations []JournalCreation `json:"creations,omitempty"`
+	Deletions []JournalDeletion `json:"deletions,omitempty"`
+}
+
+// empty reports whether the entry touched no widgets.
+func (e JournalEntry) empty() bool {
+	return len(e.Changes) == 0 && len(e.Creations) == 0 && len(e.Deletions) == 0
+}
+
+// journalFile is the on-disk layout of the journal.
+type journalFile struct {
+	Undo []JournalEntry `json:"undo"`
+	Redo []JournalEntry `json:"redo"`
+}
+
+// MacroJournal is the undo/redo history of macro runs. Entries of all
+// canvases share the stacks, but undo and redo only ever take the most
+// recent entry of the canvas they are asked for, so each canvas has its own
+// history. When created with a path it is persisted as JSON after every
+// change, so history survives a restart of the WebUI server, and the files
+// of deleted widgets are kept in a directory next to it.
+type MacroJournal struct {
+	stepMu   sync.Mutex // held while undo or redo applies an entry
+	mu       sync.Mutex
+	path     string
+	assetDir string
+	assets   map[string][]byte // files of deleted widgets of an in-memory journal
+	undo     []JournalEntry
+	redo     []JournalEntry
+	assetSeq uint64
+}
+
+// NewMacroJournal creates a journal persisted at path, loading any existing
+// history. An empty path keeps the journal in memory only.
+func NewMacroJournal(path string) *MacroJournal {
+	j := &MacroJournal{path: path}
+	if path == "" {
+		j.assets = make(map[string][]byte)
+		return j
+	}
+	j.assetDir = strings.TrimSuffix(path, filepath.Ext(path)) + "_assets"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("[MacroJournal] Failed to read %s: %v\n", path, err)
+		}
+		return j
+	}
+
+	var file journalFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		fmt.Printf("[MacroJournal] Failed to parse %s, starting with empty history: %v\n", path, err)
+		return j
+	}
+	j.undo = file.Undo
+	j.redo = file.Redo
+	fmt.Printf("[MacroJournal] Loaded %d undo and %d redo entries from %s\n", len(j.undo), len(j.redo), path)
+	return j
+}
+
+// Record adds a macro run to the undo stack and clears the redo entries of
+// its canvas. Runs that touched no widgets are ignored.
+func (j *MacroJournal) Record(entry JournalEntry) {
+	if entry.empty() {
+		return
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if entry.ID == "" {
+		entry.ID = fmt.Sprintf("%d", entry.CreatedAt.UnixNano())
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.undo = append(j.undo, entry)
+	if len(j.undo) > MaxJournalEntries {
+		j.discardAssets(j.undo[:len(j.undo)-MaxJournalEntries])
+		j.undo = j.undo[len(j.undo)-MaxJournalEntries:]
+	}
+	var redo []JournalEntry
+	for _, undone := range j.redo {
+		if undone.CanvasID != entry.CanvasID {
+			redo = append(redo, undone)
+		} else {
+			j.discardAssets([]JournalEntry{undone})
+		}
+	}
+	j.redo = redo
+	j.save()
+}
+
+// Undo calls apply with the most recent entry of canvasID. If apply
+// succeeds, the entry it returns is moved to the redo stack; otherwise the
+// history is unchanged.
+func (j *MacroJournal) Undo(canvasID string, apply func(JournalEntry) (JournalEntry, error)) (JournalEntry, error) {
+	return j.step(canvasID, &j.undo, &j.redo, ErrNothingToUndo, apply)
+}
+
+// Redo calls apply with the most recently undone entry of canvasID. If apply
+// succeeds, the entry it returns is moved back to the undo stack.
+func (j *MacroJournal) Redo(canvasID string, apply func(JournalEntry) (JournalEntry, error)) (JournalEntry, error) {
+	return j.step(canvasID, &j.redo, &j.undo, ErrNothingToRedo, apply)
+}
+
+// Len returns the number of entries of canvasID that can be undone and
+// redone. An empty canvasID counts the entries of every canvas.
+func (j *MacroJournal) Len(canvasID string) (undo, redo int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	count := func(entries []JournalEntry) int {
+		n := 0
+		for _, entry := range entries {
+			if canvasID == "" || entry.CanvasID == canvasID {
+				n++
+			}
+		}
+		return n
+	}
+	return count(j.undo), count(j.redo)
+}
+
+// step takes the most recent entry of canvasID from one stack, applies it
+// and pushes the result onto the other; if apply fails the entry is put
+// back. Steps never interleave, but j.mu is released while apply runs, which
+// reads and saves widget files through the journal.
+func (j *MacroJournal) step(canvasID string, from, to *[]JournalEntry, emptyErr error, apply func(JournalEntry) (JournalEntry, error)) (JournalEntry, error) {
+	j.stepMu.Lock()
+	defer j.stepMu.Unlock()
+
+	j.mu.Lock()
+	index := -1
+	for i := len(*from) - 1; i >= 0; i-- {
+		if (*from)[i].CanvasID == canvasID {
+			index = i
+			break
+		}
+	}
+	if canvasID == "" || index < 0 {
+		j.mu.Unlock()
+		return JournalEntry{}, emptyErr
+	}
+	entry := (*from)[index]
+	*from = append((*from)[:index], (*from)[index+1:]...)
+	j.mu.Unlock()
+
+	applied, err := apply(entry)
+	if err == nil && applied.CanvasID != canvasID {
+		err = fmt.Errorf("journal entry %s belongs to canvas %s, not %s", entry.ID, applied.CanvasID, canvasID)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		if index > len(*from) {
+			index = len(*from)
+		}
+		*from = append((*from)[:index], append([]JournalEntry{entry}, (*from)[index:]...)...)
+		return entry, err
+	}
+	*to = append(*to, applied)
+	j.save()
+	return applied, nil
+}
+
+// SaveAsset streams the file of a widget about to be deleted from data into
+// the journal so that undo can recreate it, and returns the name to record in
+// its JournalDeletion.
+func (j *MacroJournal) SaveAsset(widgetID string, data io.Reader) (string, error) {
+	j.mu.Lock()
+	j.assetSeq++
+	name := fmt.Sprintf("%d-%d-%s", time.Now().UnixNano(), j.assetSeq, filepath.Base(widgetID))
+	assetDir := j.assetDir
+	j.mu.Unlock()
+
+	if assetDir == "" {
+		contents, err := io.ReadAll(data)
+		if err != nil {
+			return "", fmt.Errorf("failed to save widget file: %w", err)
+		}
+		j.mu.Lock()
+		j.assets[name] = contents
+		j.mu.Unlock()
+		return name, nil
+	}
+	if err := os.MkdirAll(assetDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create journal asset directory: %w", err)
+	}
+	assetPath := filepath.Join(assetDir, name)
+	file, err := os.Create(assetPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to save widget file: %w", err)
+	}
+	_, err = io.Copy(file, data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(assetPath)
+		return "", fmt.Errorf("failed to save widget file: %w", err)
+	}
+	return name, nil
+}
+
+// OpenAsset opens the file saved as name by SaveAsset.
+func (j *MacroJournal) OpenAsset(name string) (io.ReadCloser, error) {
+	if name == "" || filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid journal asset %q", name)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.assetDir == "" {
+		data, ok := j.assets[name]
+		if !ok {
+			return nil, fmt.Errorf("journal asset %s not found", name)
+		}
+		return nopCloseReadSeeker{bytes.NewReader(data)}, nil
+	}
+	file, err := os.Open(filepath.Join(j.assetDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal asset: %w", err)
+	}
+	return file, nil
+}
+
+// DiscardAsset removes a file saved by SaveAsset that ended up unused.
+func (j *MacroJournal) DiscardAsset(name string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.discardAsset(name)
+}
+
+// discardAssets removes the files of the deletions of entries, which are
+// leaving the journal. Must be called with j.mu held.
+func (j *MacroJournal) discardAssets(entries []JournalEntry) {
+	for _, entry := range entries {
+		for _, deletion := range entry.Deletions {
+			j.discardAsset(deletion.Asset)
+		}
+	}
+}
+
+// discardAsset removes the file saved as name. Must be called with j.mu held.
+func (j *MacroJournal) discardAsset(name string) {
+	if name == "" || filepath.Base(name) != name {
+		return
+	}
+	if j.assetDir == "" {
+		delete(j.assets, name)
+		return
+	}
+	if err := os.Remove(filepath.Join(j.assetDir, name)); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("[MacroJournal] Failed to remove %s: %v\n", name, err)
+	}
+}
+
+// save writes the journal to disk. Must be called with j.mu held.
+func (j *MacroJournal) save() {
+	if j.path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(journalFile{Undo: j.undo, Redo: j.redo}, "", "  ")
+	if err != nil {
+		fmt.Printf("[MacroJournal] Failed to encode journal: %v\n", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		fmt.Printf("[MacroJournal] Failed to create journal directory: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(j.path, data, 0644); err != nil {
+		fmt.Printf("[MacroJournal] Failed to write %s: %v\n", j.path, err)
+	}
+}
+
+// priorState returns the fields of payload as they are on widget before the
+// update is applied. Fields other than the placement are read from
+// widget.Data, which must hold the full widget for content updates.
+func priorState(widget webuiatoms.Widget, payload map[string]interface{}) map[string]interface{} {
+	before := make(map[string]interface{}, len(payload))
+	for key := range payload {
+		switch key {
+		case "location":
+			if widget.Location != nil {
+				before["location"] = map[string]float64{"x": widget.Location.X, "y": widget.Location.Y}
+			}
+		case "scale":
+			scale := widget.Scale
+			if scale == 0 {
+				scale = 1
+			}
+			before["scale"] = scale
+		case "pinned":
+			before["pinned"] = widget.Pinned
+		case "size":
+			if widget.Size != nil {
+				before["size"] = map[string]float64{"width": widget.Size.Width, "height": widget.Size.Height}
+			}
+		default:
+			if value, ok := widget.Data[key]; ok {
+				before[key] = value
+			}
+		}
+	}
+	return before
+}
+
+// journalChanges pairs every successful update with the prior state of its
+// widget. widgets must hold the state read before the updates were applied.
+func journalChanges(widgets []webuiatoms.Widget, updates []WidgetUpdate, report *BatchReport) []JournalChange {
+	byID := make(map[string]webuiatoms.Widget, len(widgets))
+	for _, w := range widgets {
+		byID[w.ID] = w
+	}
+
+	var changes []JournalChange
+	for i, update := range updates {
+		if i >= len(report.Results) || !report.Results[i].Succeeded {
+			continue
+		}
+		widget, ok := byID[update.WidgetID]
+		if !ok {
+			continue
+		}
+		changes = append(changes, JournalChange{
+			WidgetID:   update.WidgetID,
+			WidgetType: update.WidgetType,
+			Before:     priorState(widget, update.Payload),
+			After:      update.Payload,
+		})
+	}
+	return changes
+}
@@ -0,0 +1,202 @@
+package webui
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestMacroJournal_UndoRedoPersisted checks that undo and redo replay the
+// recorded states and that the history survives reloading from disk.
+func TestMacroJournal_UndoRedoPersisted(t *testing.T) {
+	var mu sync.Mutex
+	var patches []map[string]interface{}
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPatch:
+			body, _ := io.ReadAll(r.Body)
+			var payload map[string]interface{}
+			json.Unmarshal(body, &payload)
+			patches = append(patches, payload)
+		case http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "journal.json")
+	handler := NewMacrosHandler(webuiatoms.NewAPIClient(server.URL, "test-token"), journalTestCanvas("canvas-1"))
+	handler.SetJournal(NewMacroJournal(path))
+
+	widgets := []webuiatoms.Widget{{
+		ID:         "widget-00000001",
+		WidgetType: "Note",
+		Location:   &webuiatoms.WidgetLocation{X: 10, Y: 20},
+		Scale:      2,
+	}}
+	updates := []WidgetUpdate{{
+		WidgetID:   "widget-00000001",
+		WidgetType: "Note",
+		Payload: map[string]interface{}{
+			"location": map[string]float64{"x": 500, "y": 600},
+			"scale":    4.0,
+		},
+	}}
+	handler.applyPlan(&MacroPlan{Macro: "move", CanvasID: "canvas-1", Widgets: widgets, Updates: updates}, "")
+	handler.journal.Record(JournalEntry{
+		Macro:     "copy",
+		CanvasID:  "canvas-1",
+		Creations: []JournalCreation{{SourceID: "widget-00000001", WidgetID: "copy-00000001", WidgetType: "Note"}},
+	})
+
+	// Reload from disk: both entries must survive.
+	handler.SetJournal(NewMacroJournal(path))
+	if undo, redo := handler.journal.Len(""); undo != 2 || redo != 0 {
+		t.Fatalf("after reload Len() = %d, %d; want 2, 0", undo, redo)
+	}
+
+	// First undo removes the copy.
+	req := httptest.NewRequest(http.MethodPost, "/api/macros/undo", nil)
+	rec := httptest.NewRecorder()
+	handler.HandleUndo(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("undo copy status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(deleted) != 1 || deleted[0] != "/api/v1/canvases/canvas-1/notes/copy-00000001" {
+		t.Errorf("deleted = %v", deleted)
+	}
+
+	// Second undo restores the original location and scale.
+	rec = httptest.NewRecorder()
+	handler.HandleUndo(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("undo move status = %d: %s", rec.Code, rec.Body.String())
+	}
+	last := patches[len(patches)-1]
+	location, _ := last["location"].(map[string]interface{})
+	if location["x"] != 10.0 || location["y"] != 20.0 || last["scale"] != 2.0 {
+		t.Errorf("undo payload = %v, want location (10, 20) scale 2", last)
+	}
+
+	// Nothing left to undo.
+	rec = httptest.NewRecorder()
+	handler.HandleUndo(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("empty undo status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	// Redo re-applies the move.
+	rec = httptest.NewRecorder()
+	handler.HandleRedo(rec, httptest.NewRequest(http.MethodPost, "/api/macros/redo", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("redo status = %d: %s", rec.Code, rec.Body.String())
+	}
+	last = patches[len(patches)-1]
+	location, _ = last["location"].(map[string]interface{})
+	if location["x"] != 500.0 || last["scale"] != 4.0 {
+		t.Errorf("redo payload = %v, want location x 500 scale 4", last)
+	}
+	if undo, redo := NewMacroJournal(path).Len(""); undo != 1 || redo != 1 {
+		t.Errorf("persisted Len() = %d, %d; want 1, 1", undo, redo)
+	}
+}
+
+// TestMacroJournal_UndoPerCanvas checks that undo and redo only take entries
+// of the canvas they are asked for, and that recording on one canvas keeps
+// the redo history of another.
+func TestMacroJournal_UndoPerCanvas(t *testing.T) {
+	journal := NewMacroJournal("")
+	change := []JournalChange{{WidgetID: "widget-1", WidgetType: "Note"}}
+	journal.Record(JournalEntry{Macro: "move", CanvasID: "canvas-a", Changes: change})
+	journal.Record(JournalEntry{Macro: "pin-all", CanvasID: "canvas-b", Changes: change})
+
+	var applied []JournalEntry
+	apply := func(entry JournalEntry) (JournalEntry, error) {
+		applied = append(applied, entry)
+		return entry, nil
+	}
+	if entry, err := journal.Undo("canvas-a", apply); err != nil || entry.Macro != "move" {
+		t.Fatalf("undo on canvas-a = %+v, %v; want the move", entry, err)
+	}
+	if _, err := journal.Undo("canvas-a", apply); err != ErrNothingToUndo {
+		t.Errorf("second undo on canvas-a = %v, want ErrNothingToUndo", err)
+	}
+	if _, err := journal.Undo("canvas-c", apply); err != ErrNothingToUndo {
+		t.Errorf("undo on canvas-c = %v, want ErrNothingToUndo", err)
+	}
+	if undo, redo := journal.Len("canvas-b"); undo != 1 || redo != 0 {
+		t.Errorf("canvas-b Len = %d, %d; want 1, 0", undo, redo)
+	}
+
+	journal.Record(JournalEntry{Macro: "unpin-all", CanvasID: "canvas-b", Changes: change})
+	if entry, err := journal.Redo("canvas-a", apply); err != nil || entry.Macro != "move" {
+		t.Errorf("redo on canvas-a after recording on canvas-b = %+v, %v", entry, err)
+	}
+	if len(applied) != 2 || applied[0].CanvasID != "canvas-a" || applied[1].CanvasID != "canvas-a" {
+		t.Errorf("applied = %+v, want only canvas-a entries", applied)
+	}
+
+	refused, err := journal.Undo("canvas-b", func(entry JournalEntry) (JournalEntry, error) {
+		entry.CanvasID = "canvas-a"
+		return entry, nil
+	})
+	if err == nil {
+		t.Errorf("undo moving entry %s to another canvas succeeded", refused.Macro)
+	}
+}
+
+// journalTestCanvas returns a CanvasService showing canvasID.
+func journalTestCanvas(canvasID string) *CanvasService {
+	tracker := webuiatoms.NewCanvasTracker()
+	tracker.UpdateCanvas(canvasID, "")
+	return &CanvasService{canvasTracker: tracker}
+}
+
+// TestMacroJournal_DeletedWidgetAssets checks that the files of deleted
+// widgets are kept next to a persisted journal and removed once their entry
+// leaves the history.
+func TestMacroJournal_DeletedWidgetAssets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	journal := NewMacroJournal(path)
+	asset, err := journal.SaveAsset("image-1", strings.NewReader("png"))
+	if err != nil {
+		t.Fatalf("SaveAsset: %v", err)
+	}
+	journal.Record(JournalEntry{
+		Macro:     "zone-sync",
+		CanvasID:  "canvas-1",
+		Deletions: []JournalDeletion{{WidgetID: "image-1", WidgetType: "Image", Asset: asset}},
+	})
+
+	reloaded := NewMacroJournal(path)
+	file, err := reloaded.OpenAsset(asset)
+	if err != nil {
+		t.Fatalf("OpenAsset after reload: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "png" {
+		t.Fatalf("asset after reload = %q, want png", data)
+	}
+	if _, err := reloaded.OpenAsset("../journal.json"); err == nil {
+		t.Error("OpenAsset outside the asset directory succeeded")
+	}
+
+	// Undo, then record a new run: the undone entry and its file are dropped.
+	reloaded.Undo("canvas-1", func(entry JournalEntry) (JournalEntry, error) { return entry, nil })
+	reloaded.Record(JournalEntry{Macro: "pin-all", CanvasID: "canvas-1", Changes: []JournalChange{{WidgetID: "note-1"}}})
+	if _, err := reloaded.OpenAsset(asset); err == nil {
+		t.Error("asset of a dropped entry still readable")
+	}
+}
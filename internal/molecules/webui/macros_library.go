@@ -0,0 +1,259 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Step actions of a user-defined macro.
+const (
+	StepSelect    = "select"    // replace the selection: zone, types, color, title
+	StepTransform = "transform" // offset the selection by dx/dy and multiply its scale
+	StepPin       = "pin"       // pin the selection, or unpin with pinned: false
+	StepGroup     = "group"     // group the selection by color or title within a zone
+	StepGrid      = "grid"      // lay the selection out in a grid within a zone
+	StepCopy      = "copy"      // copy the selection into the zone given by to
+	StepDelete    = "delete"    // delete the selection
+)
+
+// ErrMacroNotFound is returned when no macro file has the requested ID.
+var ErrMacroNotFound = errors.New("macro not found")
+
+// macroIDPattern matches the IDs of macro files, which are their file names
+// without extension.
+var macroIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// MacroDefinition is a user-defined macro loaded from a YAML or JSON file in
+// the macros directory. Its steps run in order: select replaces the current
+// selection and every other step acts on it.
+type MacroDefinition struct {
+	ID          string       `json:"id" yaml:"-"`
+	Name        string       `json:"name" yaml:"name"`
+	Description string       `json:"description,omitempty" yaml:"description"`
+	Params      []MacroParam `json:"params,omitempty" yaml:"params"`
+	Steps       []MacroStep  `json:"steps" yaml:"steps"`
+}
+
+// MacroParam is a zone chosen when the macro is run. Steps refer to it as
+// $name wherever a zone is expected.
+type MacroParam struct {
+	Name  string `json:"name" yaml:"name"`
+	Label string `json:"label,omitempty" yaml:"label"`
+}
+
+// MacroStep is one step of a user-defined macro. Which fields apply depends
+// on Action. Zones are anchor IDs, anchor names or $param references.
+type MacroStep struct {
+	Action string   `json:"action" yaml:"action"`
+	Zone   string   `json:"zone,omitempty" yaml:"zone"`     // select, group, grid
+	Types  []string `json:"types,omitempty" yaml:"types"`   // select
+	Color  string   `json:"color,omitempty" yaml:"color"`   // select: note background color
+	Title  string   `json:"title,omitempty" yaml:"title"`   // select: regular expression
+	Pinned *bool    `json:"pinned,omitempty" yaml:"pinned"` // pin, defaults to true
+	By     string   `json:"by,omitempty" yaml:"by"`         // group: color or title
+	DX     float64  `json:"dx,omitempty" yaml:"dx"`         // transform
+	DY     float64  `json:"dy,omitempty" yaml:"dy"`         // transform
+	Scale  float64  `json:"scale,omitempty" yaml:"scale"`   // transform
+	To     string   `json:"to,omitempty" yaml:"to"`         // copy
+}
+
+// MacroLoadError describes a macro file that could not be loaded.
+type MacroLoadError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// ParseMacroDefinition parses a macro file. JSON is used for .json files
+// and YAML for anything else. Unknown fields are rejected so typos do not
+// silently change what a macro does.
+func ParseMacroDefinition(id string, data []byte, ext string) (*MacroDefinition, error) {
+	def := &MacroDefinition{}
+	if strings.EqualFold(ext, ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(def); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(def); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	}
+	def.ID = id
+	if def.Name == "" {
+		def.Name = id
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// Validate checks that every step has a known action with the fields it
+// needs and only refers to declared parameters.
+func (d *MacroDefinition) Validate() error {
+	if len(d.Steps) == 0 {
+		return fmt.Errorf("macro has no steps")
+	}
+	params := make(map[string]bool, len(d.Params))
+	for _, param := range d.Params {
+		if param.Name == "" {
+			return fmt.Errorf("parameter without a name")
+		}
+		if params[param.Name] {
+			return fmt.Errorf("parameter %s is declared twice", param.Name)
+		}
+		params[param.Name] = true
+	}
+	checkZone := func(zone string) error {
+		if name, ok := strings.CutPrefix(zone, "$"); ok && !params[name] {
+			return fmt.Errorf("undeclared parameter $%s", name)
+		}
+		return nil
+	}
+
+	for i, step := range d.Steps {
+		var err error
+		switch step.Action {
+		case StepSelect:
+			if step.Title != "" {
+				if _, compileErr := regexp.Compile(step.Title); compileErr != nil {
+					err = fmt.Errorf("invalid title pattern: %w", compileErr)
+				}
+			}
+		case StepTransform:
+			if step.DX == 0 && step.DY == 0 && step.Scale == 0 {
+				err = fmt.Errorf("transform needs dx, dy or scale")
+			} else if step.Scale < 0 {
+				err = fmt.Errorf("scale must be positive")
+			}
+		case StepGroup:
+			if step.By != "color" && step.By != "title" {
+				err = fmt.Errorf("group by must be color or title, got %q", step.By)
+			}
+		case StepCopy:
+			if step.To == "" {
+				err = fmt.Errorf("copy needs a target zone in to")
+			}
+		case StepPin, StepGrid, StepDelete:
+		default:
+			err = fmt.Errorf("unknown action %q", step.Action)
+		}
+		if err == nil && i == 0 && step.Action != StepSelect {
+			err = fmt.Errorf("the first step must be select")
+		}
+		if err == nil {
+			err = checkZone(step.Zone)
+		}
+		if err == nil {
+			err = checkZone(step.To)
+		}
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// CheckParams checks that params has a zone for every parameter.
+func (d *MacroDefinition) CheckParams(params map[string]string) error {
+	for _, param := range d.Params {
+		if params[param.Name] == "" {
+			return fmt.Errorf("parameter %s is required", param.Name)
+		}
+	}
+	return nil
+}
+
+// MacroLibrary loads user-defined macros from a directory. Files are read on
+// every call, so macros added or edited there show up without a restart.
+type MacroLibrary struct {
+	dir string
+}
+
+// NewMacroLibrary creates a library reading from dir. An empty dir gives an
+// empty library.
+func NewMacroLibrary(dir string) *MacroLibrary {
+	return &MacroLibrary{dir: dir}
+}
+
+// List returns the valid macros sorted by name, and an error for every file
+// that could not be loaded.
+func (l *MacroLibrary) List() ([]MacroDefinition, []MacroLoadError, error) {
+	defs := []MacroDefinition{}
+	loadErrors := []MacroLoadError{}
+	if l.dir == "" {
+		return defs, loadErrors, nil
+	}
+
+	entries, err := os.ReadDir(l.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return defs, loadErrors, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read macros directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		id, ext, ok := macroFileID(entry.Name())
+		if entry.IsDir() || !ok {
+			continue
+		}
+		def, err := l.load(id, entry.Name(), ext)
+		if err != nil {
+			loadErrors = append(loadErrors, MacroLoadError{File: entry.Name(), Error: err.Error()})
+			continue
+		}
+		defs = append(defs, *def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return strings.ToLower(defs[i].Name) < strings.ToLower(defs[j].Name)
+	})
+	return defs, loadErrors, nil
+}
+
+// Get loads the macro with the given ID.
+func (l *MacroLibrary) Get(id string) (*MacroDefinition, error) {
+	if l.dir == "" || !macroIDPattern.MatchString(id) {
+		return nil, ErrMacroNotFound
+	}
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		name := id + ext
+		if _, err := os.Stat(filepath.Join(l.dir, name)); err == nil {
+			return l.load(id, name, ext)
+		}
+	}
+	return nil, ErrMacroNotFound
+}
+
+func (l *MacroLibrary) load(id, name, ext string) (*MacroDefinition, error) {
+	data, err := os.ReadFile(filepath.Join(l.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return ParseMacroDefinition(id, data, ext)
+}
+
+// macroFileID returns the macro ID and extension of a macro file name. ok is
+// false for files that are not macros.
+func macroFileID(name string) (id, ext string, ok bool) {
+	ext = strings.ToLower(filepath.Ext(name))
+	switch ext {
+	case ".yaml", ".yml", ".json":
+	default:
+		return "", "", false
+	}
+	id = strings.TrimSuffix(name, filepath.Ext(name))
+	return id, ext, macroIDPattern.MatchString(id)
+}
@@ -1,39 +1,59 @@
 package webui
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"sort"
 	"strings"
+	"sync"
 
 	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
 )
 
+// DefaultBatchConcurrency is the number of widget updates run in parallel by
+// BatchUpdateWidgets unless configured otherwise.
+const DefaultBatchConcurrency = 8
+
 // MacrosOperations provides reusable operations for macros functionality.
 type MacrosOperations struct {
-	apiClient     *webuiatoms.APIClient
-	canvasService *CanvasService
+	apiClient   *webuiatoms.APIClient
+	mirrors     widgetMirrors
+	concurrency int
 }
 
-// NewMacrosOperations creates a new macros operations helper.
-func NewMacrosOperations(apiClient *webuiatoms.APIClient, canvasService *CanvasService) *MacrosOperations {
+// NewMacrosOperations creates a new macros operations helper reading widgets
+// from mirrors when they are in sync.
+func NewMacrosOperations(apiClient *webuiatoms.APIClient, mirrors widgetMirrors) *MacrosOperations {
 	return &MacrosOperations{
-		apiClient:     apiClient,
-		canvasService: canvasService,
+		apiClient:   apiClient,
+		mirrors:     mirrors,
+		concurrency: DefaultBatchConcurrency,
+	}
+}
+
+// SetConcurrency sets the maximum number of parallel updates in BatchUpdateWidgets.
+func (mo *MacrosOperations) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
 	}
+	mo.concurrency = n
 }
 
-// GetZoneAndWidgets gets zone bounding box and all widgets in that zone.
-func (mo *MacrosOperations) GetZoneAndWidgets(zoneID string) (*webuiatoms.ZoneBoundingBox, []webuiatoms.Widget, error) {
-	canvasID := mo.canvasService.GetCanvasID()
+// GetZoneAndWidgets gets the bounding box of zone zoneID of canvasID and all
+// widgets of the canvas.
+func (mo *MacrosOperations) GetZoneAndWidgets(canvasID, zoneID string) (*webuiatoms.ZoneBoundingBox, []webuiatoms.Widget, error) {
 	if canvasID == "" {
 		return nil, nil, fmt.Errorf("canvas not available")
 	}
 
-	zoneBB, err := webuiatoms.GetZoneBoundingBox(mo.apiClient, canvasID, zoneID)
+	zoneBB, err := zoneBoundingBox(mo.apiClient, mo.mirrors, canvasID, zoneID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get zone: %w", err)
 	}
 
-	allWidgets, err := webuiatoms.GetAllWidgets(mo.apiClient, canvasID)
+	allWidgets, err := listWidgets(mo.apiClient, mo.mirrors, canvasID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get widgets: %w", err)
 	}
@@ -41,10 +61,11 @@ func (mo *MacrosOperations) GetZoneAndWidgets(zoneID string) (*webuiatoms.ZoneBo
 	return zoneBB, allWidgets, nil
 }
 
-// FilterWidgetsInZone filters widgets that are within a zone, excluding anchors and connectors.
-func FilterWidgetsInZone(widgets []webuiatoms.Widget, zoneBB *webuiatoms.ZoneBoundingBox, excludeZoneID string) []webuiatoms.Widget {
-	fmt.Printf("[FilterWidgetsInZone] Filtering %d widgets, zoneBB: X=%.2f, Y=%.2f, W=%.2f, H=%.2f\n",
-		len(widgets), zoneBB.X, zoneBB.Y, zoneBB.Width, zoneBB.Height)
+// FilterWidgetsInZone filters widgets that belong to a zone under membership,
+// excluding anchors and connectors.
+func FilterWidgetsInZone(widgets []webuiatoms.Widget, zoneBB *webuiatoms.ZoneBoundingBox, excludeZoneID string, membership webuiatoms.ZoneMembership) []webuiatoms.Widget {
+	fmt.Printf("[FilterWidgetsInZone] Filtering %d widgets, zoneBB: X=%.2f, Y=%.2f, W=%.2f, H=%.2f, membership: %s\n",
+		len(widgets), zoneBB.X, zoneBB.Y, zoneBB.Width, zoneBB.Height, membership)
 
 	var filtered []webuiatoms.Widget
 	checkedCount := 0
@@ -65,7 +86,7 @@ func FilterWidgetsInZone(widgets []webuiatoms.Widget, zoneBB *webuiatoms.ZoneBou
 			continue
 		}
 		checkedCount++
-		if webuiatoms.WidgetIsInZone(&w, zoneBB) {
+		if membership.Contains(&w, zoneBB) {
 			filtered = append(filtered, w)
 			fmt.Printf("[FilterWidgetsInZone] Widget %s (%s) is IN zone\n", w.ID[:8], w.WidgetType)
 		}
@@ -75,43 +96,162 @@ func FilterWidgetsInZone(widgets []webuiatoms.Widget, zoneBB *webuiatoms.ZoneBou
 	return filtered
 }
 
-// UpdateWidgetWithRetry updates a widget with retry logic (up to 3 attempts).
-// Uses type-specific endpoints (not /widgets which is read-only).
+// UpdateWidgetWithRetry updates a widget via its type-specific endpoint
+// (not /widgets which is read-only). Retries, backoff and rate limiting are
+// handled by the APIClient's retry policy.
 func (mo *MacrosOperations) UpdateWidgetWithRetry(canvasID, widgetID, widgetType string, payload map[string]interface{}) error {
-	// Get type-specific endpoint
-	baseEndpoint := webuiatoms.GetWidgetPatchEndpoint(widgetType)
-	endpoint := fmt.Sprintf("/api/v1/canvases/%s%s/%s", canvasID, baseEndpoint, widgetID)
+	return mo.updateWidget(context.Background(), canvasID, widgetID, widgetType, payload)
+}
+
+func (mo *MacrosOperations) updateWidget(ctx context.Context, canvasID, widgetID, widgetType string, payload map[string]interface{}) error {
+	endpoint := widgetEndpoint(canvasID, widgetType, widgetID)
 
 	fmt.Printf("[UpdateWidgetWithRetry] Updating widget %s (type: %s) via %s\n", widgetID[:8], widgetType, endpoint)
 
-	var lastErr error
-	for tries := 0; tries < 3; tries++ {
-		_, err := mo.apiClient.Patch(endpoint, payload)
-		if err == nil {
-			fmt.Printf("[UpdateWidgetWithRetry] Successfully updated widget %s\n", widgetID[:8])
-			return nil
+	if _, err := mo.apiClient.Do(ctx, http.MethodPatch, endpoint, payload); err != nil {
+		fmt.Printf("[UpdateWidgetWithRetry] ERROR: Failed to update widget %s: %v\n", widgetID[:8], err)
+		return err
+	}
+	fmt.Printf("[UpdateWidgetWithRetry] Successfully updated widget %s\n", widgetID[:8])
+	return nil
+}
+
+// widgetEndpoint returns the type-specific endpoint for a single widget.
+func widgetEndpoint(canvasID, widgetType, widgetID string) string {
+	return fmt.Sprintf("/api/v1/canvases/%s%s/%s", canvasID, webuiatoms.GetWidgetPatchEndpoint(widgetType), widgetID)
+}
+
+// fetchWidgetData returns the full widget from its type-specific endpoint.
+func fetchWidgetData(ctx context.Context, apiClient *webuiatoms.APIClient, canvasID string, widget webuiatoms.Widget) (map[string]interface{}, error) {
+	data, err := apiClient.Do(ctx, http.MethodGet, widgetEndpoint(canvasID, widget.WidgetType, widget.ID), nil)
+	if err != nil {
+		return nil, err
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse %s %s: %w", widget.WidgetType, widget.ID, err)
+	}
+	return metadata, nil
+}
+
+// WidgetUpdateResult is the outcome of one widget update in a batch.
+type WidgetUpdateResult struct {
+	WidgetID   string `json:"widget_id"`
+	WidgetType string `json:"widget_type"`
+	Succeeded  bool   `json:"succeeded"`
+	Attempts   int    `json:"attempts"`
+	Retried    bool   `json:"retried"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BatchReport summarises a batch of widget updates. Results are in the same
+// order as the updates that produced them.
+type BatchReport struct {
+	Total     int                  `json:"total"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Retried   int                  `json:"retried"`
+	Results   []WidgetUpdateResult `json:"results"`
+	Skipped   []SkippedWidget      `json:"skipped,omitempty"`
+}
+
+// SkippedWidget is a widget a macro left alone, with the reason why.
+type SkippedWidget struct {
+	WidgetID   string `json:"widget_id"`
+	WidgetType string `json:"widget_type"`
+	Reason     string `json:"reason"`
+}
+
+// Failures returns the results of the updates that failed.
+func (r *BatchReport) Failures() []WidgetUpdateResult {
+	var failures []WidgetUpdateResult
+	for _, result := range r.Results {
+		if !result.Succeeded {
+			failures = append(failures, result)
 		}
-		fmt.Printf("[UpdateWidgetWithRetry] Attempt %d failed for widget %s: %v\n", tries+1, widgetID[:8], err)
-		lastErr = err
-	}
-	fmt.Printf("[UpdateWidgetWithRetry] ERROR: Failed to update widget %s after 3 attempts: %v\n", widgetID[:8], lastErr)
-	return fmt.Errorf("failed after 3 attempts: %w", lastErr)
-}
-
-// BatchUpdateWidgets updates multiple widgets and returns count of successful updates.
-func (mo *MacrosOperations) BatchUpdateWidgets(canvasID string, updates []WidgetUpdate) int {
-	fmt.Printf("[BatchUpdateWidgets] Updating %d widgets\n", len(updates))
-	successCount := 0
-	for i, update := range updates {
-		if err := mo.UpdateWidgetWithRetry(canvasID, update.WidgetID, update.WidgetType, update.Payload); err == nil {
-			successCount++
-		} else {
-			fmt.Printf("[BatchUpdateWidgets] Failed to update widget %d/%d (ID: %s, Type: %s): %v\n",
-				i+1, len(updates), update.WidgetID[:8], update.WidgetType, err)
+	}
+	return failures
+}
+
+// add appends a result and updates the counters.
+func (r *BatchReport) add(result WidgetUpdateResult) {
+	r.Total++
+	r.Results = append(r.Results, result)
+	if result.Succeeded {
+		r.Succeeded++
+	} else {
+		r.Failed++
+	}
+	if result.Retried {
+		r.Retried++
+	}
+}
+
+// BatchUpdateWidgets updates multiple widgets through a bounded worker pool
+// and returns a per-widget report.
+func (mo *MacrosOperations) BatchUpdateWidgets(canvasID string, updates []WidgetUpdate) *BatchReport {
+	return mo.BatchUpdateWidgetsContext(context.Background(), canvasID, updates)
+}
+
+// BatchUpdateWidgetsContext is BatchUpdateWidgets with a context; updates
+// not yet done when ctx is cancelled fail.
+func (mo *MacrosOperations) BatchUpdateWidgetsContext(ctx context.Context, canvasID string, updates []WidgetUpdate) *BatchReport {
+	workers := mo.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(updates) {
+		workers = len(updates)
+	}
+	fmt.Printf("[BatchUpdateWidgets] Updating %d widgets with %d workers\n", len(updates), workers)
+
+	results := make([]WidgetUpdateResult, len(updates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = mo.runUpdate(ctx, canvasID, updates[idx])
+			}
+		}()
+	}
+	for i := range updates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	report := &BatchReport{Results: make([]WidgetUpdateResult, 0, len(results))}
+	for i, result := range results {
+		report.add(result)
+		if !result.Succeeded {
+			fmt.Printf("[BatchUpdateWidgets] Failed to update widget %d/%d (ID: %s, Type: %s): %s\n",
+				i+1, len(updates), result.WidgetID[:8], result.WidgetType, result.Error)
 		}
 	}
-	fmt.Printf("[BatchUpdateWidgets] Successfully updated %d/%d widgets\n", successCount, len(updates))
-	return successCount
+	fmt.Printf("[BatchUpdateWidgets] Successfully updated %d/%d widgets (%d retried)\n", report.Succeeded, report.Total, report.Retried)
+	return report
+}
+
+// runUpdate performs a single update and records how many attempts it took.
+func (mo *MacrosOperations) runUpdate(ctx context.Context, canvasID string, update WidgetUpdate) WidgetUpdateResult {
+	var attempts int32
+	ctx = webuiatoms.WithAttemptCounter(ctx, &attempts)
+	err := mo.updateWidget(ctx, canvasID, update.WidgetID, update.WidgetType, update.Payload)
+
+	result := WidgetUpdateResult{
+		WidgetID:   update.WidgetID,
+		WidgetType: update.WidgetType,
+		Succeeded:  err == nil,
+		Attempts:   int(attempts),
+		Retried:    attempts > 1,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
 }
 
 // WidgetUpdate represents a widget update operation.
@@ -152,36 +292,94 @@ func CalculateCellDimensions(zoneBB *webuiatoms.ZoneBoundingBox, rows, cols int)
 	return width, height
 }
 
+// PlanGrid returns the location updates that lay widgets out in a grid
+// filling zoneBB, in the order given.
+func PlanGrid(widgets []webuiatoms.Widget, zoneBB *webuiatoms.ZoneBoundingBox) []WidgetUpdate {
+	if len(widgets) == 0 {
+		return nil
+	}
+
+	// Position widgets in grid
+	var updates []WidgetUpdate
+	for i, location := range GridLocations(len(widgets), zoneBB) {
+		widget := widgets[i]
+		updates = append(updates, WidgetUpdate{
+			WidgetID:   widget.ID,
+			WidgetType: widget.WidgetType,
+			Payload: map[string]interface{}{
+				"location": location,
+			},
+		})
+		fmt.Printf("[PlanGrid] Prepared update for widget %s (%s) at (%.2f, %.2f)\n",
+			widget.ID[:8], widget.WidgetType, location["x"], location["y"])
+	}
+	return updates
+}
+
+// GridLocations returns the top left corners of the cells of n widgets laid
+// out in a grid filling zoneBB, row by row.
+func GridLocations(n int, zoneBB *webuiatoms.ZoneBoundingBox) []map[string]float64 {
+	if n == 0 {
+		return nil
+	}
+
+	// Determine optimal grid size
+	bestRows, bestCols := CalculateOptimalGrid(n, zoneBB)
+	cellWidth, cellHeight := CalculateCellDimensions(zoneBB, bestRows, bestCols)
+	fmt.Printf("[PlanGrid] Grid layout: %d rows x %d cols, cell size: %.2f x %.2f\n", bestRows, bestCols, cellWidth, cellHeight)
+
+	locations := make([]map[string]float64, n)
+	buffer := 100.0
+	for i := range locations {
+		row := i / bestCols
+		col := i % bestCols
+		locations[i] = map[string]float64{
+			"x": zoneBB.X + buffer + float64(col)*(cellWidth+buffer),
+			"y": zoneBB.Y + buffer + float64(row)*(cellHeight+buffer),
+		}
+	}
+	return locations
+}
+
 // PositionWidgetGroups positions widget groups horizontally with vertical stacking within groups.
-func (mo *MacrosOperations) PositionWidgetGroups(groups map[string][]webuiatoms.Widget, zoneBB *webuiatoms.ZoneBoundingBox, canvasID string) int {
+func (mo *MacrosOperations) PositionWidgetGroups(groups map[string][]webuiatoms.Widget, zoneBB *webuiatoms.ZoneBoundingBox, canvasID string) *BatchReport {
+	report := mo.BatchUpdateWidgets(canvasID, PlanWidgetGroups(groups, zoneBB))
+	fmt.Printf("[PositionWidgetGroups] Completed: %d widgets positioned\n", report.Succeeded)
+	return report
+}
+
+// PlanWidgetGroups returns the location updates that lay widget groups out
+// horizontally, in order of their keys, with vertical stacking within each
+// group.
+func PlanWidgetGroups(groups map[string][]webuiatoms.Widget, zoneBB *webuiatoms.ZoneBoundingBox) []WidgetUpdate {
 	fmt.Printf("[PositionWidgetGroups] Positioning %d groups in zone\n", len(groups))
-	groupedCount := 0
+	groupKeys := make([]string, 0, len(groups))
+	for groupKey := range groups {
+		groupKeys = append(groupKeys, groupKey)
+	}
+	sort.Strings(groupKeys)
+
+	var updates []WidgetUpdate
 	xOffset := zoneBB.X + 100
-	for groupKey, widgets := range groups {
+	for _, groupKey := range groupKeys {
+		widgets := groups[groupKey]
 		fmt.Printf("[PositionWidgetGroups] Group '%s': %d widgets\n", groupKey, len(widgets))
 		yOffset := zoneBB.Y + 100
 		for _, widget := range widgets {
-			// Use type-specific endpoint (not /widgets which is read-only)
-			baseEndpoint := webuiatoms.GetWidgetPatchEndpoint(widget.WidgetType)
-			endpoint := fmt.Sprintf("/api/v1/canvases/%s%s/%s", canvasID, baseEndpoint, widget.ID)
-			payload := map[string]interface{}{
-				"location": map[string]float64{"x": xOffset, "y": yOffset},
-			}
-			fmt.Printf("[PositionWidgetGroups] Patching widget %s (%s) to (%.2f, %.2f) via %s\n",
-				widget.ID[:8], widget.WidgetType, xOffset, yOffset, endpoint)
-			_, err := mo.apiClient.Patch(endpoint, payload)
-			if err == nil {
-				groupedCount++
-				fmt.Printf("[PositionWidgetGroups] Successfully positioned widget %s\n", widget.ID[:8])
-			} else {
-				fmt.Printf("[PositionWidgetGroups] ERROR: Failed to position widget %s: %v\n", widget.ID[:8], err)
-			}
+			updates = append(updates, WidgetUpdate{
+				WidgetID:   widget.ID,
+				WidgetType: widget.WidgetType,
+				Payload: map[string]interface{}{
+					"location": map[string]float64{"x": xOffset, "y": yOffset},
+				},
+			})
+			fmt.Printf("[PositionWidgetGroups] Prepared widget %s (%s) at (%.2f, %.2f)\n",
+				widget.ID[:8], widget.WidgetType, xOffset, yOffset)
 			yOffset += 200
 		}
 		xOffset += 300
 	}
-	fmt.Printf("[PositionWidgetGroups] Completed: %d widgets positioned\n", groupedCount)
-	return groupedCount
+	return updates
 }
 
 // abs returns absolute value of a float64.
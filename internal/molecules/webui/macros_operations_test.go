@@ -0,0 +1,83 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestBatchUpdateWidgets_Report checks that every update gets a result in
+// input order and that failures and retries are reported per widget.
+func TestBatchUpdateWidgets_Report(t *testing.T) {
+	var flakyCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/broken-widget"):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"msg":"invalid location"}`))
+		case strings.HasSuffix(r.URL.Path, "/flaky-widget") && atomic.AddInt32(&flakyCalls, 1) == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	apiClient := webuiatoms.NewAPIClient(server.URL, "test-token")
+	apiClient.SetRetryPolicy(webuiatoms.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	ops := NewMacrosOperations(apiClient, nil)
+	ops.SetConcurrency(2)
+
+	updates := []WidgetUpdate{
+		{WidgetID: "good-widget-1", WidgetType: "Note", Payload: map[string]interface{}{"pinned": true}},
+		{WidgetID: "broken-widget", WidgetType: "Image", Payload: map[string]interface{}{"pinned": true}},
+		{WidgetID: "flaky-widget", WidgetType: "Note", Payload: map[string]interface{}{"pinned": true}},
+		{WidgetID: "good-widget-2", WidgetType: "PDF", Payload: map[string]interface{}{"pinned": true}},
+	}
+	report := ops.BatchUpdateWidgets("canvas-1", updates)
+
+	if report.Total != 4 || report.Succeeded != 3 || report.Failed != 1 || report.Retried != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	for i, result := range report.Results {
+		if result.WidgetID != updates[i].WidgetID {
+			t.Errorf("Results[%d].WidgetID = %s, want %s", i, result.WidgetID, updates[i].WidgetID)
+		}
+	}
+
+	failures := report.Failures()
+	if len(failures) != 1 || failures[0].WidgetID != "broken-widget" || !strings.Contains(failures[0].Error, "400") {
+		t.Errorf("Failures() = %+v", failures)
+	}
+	if flaky := report.Results[2]; !flaky.Succeeded || !flaky.Retried || flaky.Attempts != 2 {
+		t.Errorf("flaky result = %+v", flaky)
+	}
+}
+
+// TestPlanWidgetGroups_OrdersGroupsByKey checks that groups get the same
+// columns on every run, in order of their keys.
+func TestPlanWidgetGroups_OrdersGroupsByKey(t *testing.T) {
+	groups := map[string][]webuiatoms.Widget{
+		"yellow": {{ID: "yellow-note-1", WidgetType: "Note"}},
+		"blue":   {{ID: "blue-note-01", WidgetType: "Note"}, {ID: "blue-note-02", WidgetType: "Note"}},
+		"red":    {{ID: "red-note-001", WidgetType: "Note"}},
+	}
+	zoneBB := &webuiatoms.ZoneBoundingBox{X: 0, Y: 0, Width: 2000, Height: 2000}
+	for run := 0; run < 10; run++ {
+		var order []string
+		for _, update := range PlanWidgetGroups(groups, zoneBB) {
+			location := update.Payload["location"].(map[string]float64)
+			order = append(order, fmt.Sprintf("%s@%.0f,%.0f", update.WidgetID, location["x"], location["y"]))
+		}
+		want := "blue-note-01@100,100 blue-note-02@100,300 red-note-001@400,100 yellow-note-1@700,100"
+		if got := strings.Join(order, " "); got != want {
+			t.Fatalf("run %d: updates = %s, want %s", run, got, want)
+		}
+	}
+}
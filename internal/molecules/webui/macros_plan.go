@@ -0,0 +1,231 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// MacroPlan is everything a macro will do, computed before any widget is
+// touched. Handlers either apply it or, with ?dry_run=1, return its preview.
+type MacroPlan struct {
+	Macro      string
+	CanvasID   string
+	SourceZone *webuiatoms.ZoneBoundingBox // move/copy/sync only
+	Zone       *webuiatoms.ZoneBoundingBox // zone the widgets end up in
+	Membership webuiatoms.ZoneMembership   // policy used to select the widgets
+	Widgets    []webuiatoms.Widget         // state before the macro
+	Updates    []WidgetUpdate
+	Copies     []PlannedCopy
+	Deletes    []webuiatoms.Widget
+	Skipped    []SkippedWidget
+}
+
+// PlannedCopy is a widget a copy macro will create: Source placed at the
+// location, size and scale of Target.
+type PlannedCopy struct {
+	Source webuiatoms.Widget
+	Target webuiatoms.Widget
+}
+
+// WidgetPlacement is where a widget sits on the canvas.
+type WidgetPlacement struct {
+	Location *webuiatoms.WidgetLocation `json:"location,omitempty"`
+	Size     *webuiatoms.WidgetSize     `json:"size,omitempty"`
+	Scale    float64                    `json:"scale"`
+	Pinned   bool                       `json:"pinned"`
+}
+
+// PlannedChange is one widget in a plan preview. For creations WidgetID is
+// the widget being copied and Before is its current placement.
+type PlannedChange struct {
+	WidgetID   string          `json:"widget_id"`
+	WidgetType string          `json:"widget_type"`
+	Title      string          `json:"title,omitempty"`
+	Before     WidgetPlacement `json:"before"`
+	After      WidgetPlacement `json:"after"`
+}
+
+// PlanPreview is the JSON form of a MacroPlan returned for dry runs.
+type PlanPreview struct {
+	Macro      string                      `json:"macro"`
+	CanvasID   string                      `json:"canvas_id"`
+	SourceZone *webuiatoms.ZoneBoundingBox `json:"source_zone,omitempty"`
+	Zone       *webuiatoms.ZoneBoundingBox `json:"zone,omitempty"`
+	Membership string                      `json:"membership"`
+	Changes    []PlannedChange             `json:"changes"`
+	Creations  []PlannedChange             `json:"creations"`
+	Deletions  []PlannedChange             `json:"deletions"`
+	Skipped    []SkippedWidget             `json:"skipped,omitempty"`
+}
+
+// skip records that widget was left out of the plan.
+func (p *MacroPlan) skip(widget webuiatoms.Widget, reason string) {
+	p.Skipped = append(p.Skipped, SkippedWidget{WidgetID: widget.ID, WidgetType: widget.WidgetType, Reason: reason})
+}
+
+// Empty reports whether the plan touches no widgets.
+func (p *MacroPlan) Empty() bool {
+	return len(p.Updates) == 0 && len(p.Copies) == 0 && len(p.Deletes) == 0
+}
+
+// Preview returns the before and after placement of every widget in the plan.
+func (p *MacroPlan) Preview() PlanPreview {
+	preview := PlanPreview{
+		Macro:      p.Macro,
+		CanvasID:   p.CanvasID,
+		SourceZone: p.SourceZone,
+		Zone:       p.Zone,
+		Membership: p.Membership.String(),
+		Changes:    []PlannedChange{},
+		Creations:  []PlannedChange{},
+		Deletions:  []PlannedChange{},
+		Skipped:    p.Skipped,
+	}
+
+	byID := make(map[string]webuiatoms.Widget, len(p.Widgets))
+	for _, w := range p.Widgets {
+		byID[w.ID] = w
+	}
+	for _, update := range p.Updates {
+		widget := byID[update.WidgetID]
+		before := placementOf(widget)
+		preview.Changes = append(preview.Changes, PlannedChange{
+			WidgetID:   update.WidgetID,
+			WidgetType: update.WidgetType,
+			Title:      widget.Title,
+			Before:     before,
+			After:      applyPayload(before, update.Payload),
+		})
+	}
+
+	for _, planned := range p.Copies {
+		preview.Creations = append(preview.Creations, PlannedChange{
+			WidgetID:   planned.Source.ID,
+			WidgetType: planned.Source.WidgetType,
+			Title:      planned.Source.Title,
+			Before:     placementOf(planned.Source),
+			After:      placementOf(planned.Target),
+		})
+	}
+
+	for _, widget := range p.Deletes {
+		preview.Deletions = append(preview.Deletions, PlannedChange{
+			WidgetID:   widget.ID,
+			WidgetType: widget.WidgetType,
+			Title:      widget.Title,
+			Before:     placementOf(widget),
+		})
+	}
+
+	return preview
+}
+
+// placementOf returns the placement of widget.
+func placementOf(widget webuiatoms.Widget) WidgetPlacement {
+	placement := WidgetPlacement{Scale: widget.Scale, Pinned: widget.Pinned}
+	if placement.Scale == 0 {
+		placement.Scale = 1
+	}
+	if widget.Location != nil {
+		location := *widget.Location
+		placement.Location = &location
+	}
+	if widget.Size != nil {
+		size := *widget.Size
+		placement.Size = &size
+	}
+	return placement
+}
+
+// applyPayload returns placement with the fields of a PATCH payload applied.
+func applyPayload(placement WidgetPlacement, payload map[string]interface{}) WidgetPlacement {
+	switch location := payload["location"].(type) {
+	case *webuiatoms.WidgetLocation:
+		if location != nil {
+			copied := *location
+			placement.Location = &copied
+		}
+	case map[string]float64:
+		placement.Location = &webuiatoms.WidgetLocation{X: location["x"], Y: location["y"]}
+	}
+	if scale, ok := payload["scale"].(float64); ok {
+		placement.Scale = scale
+	}
+	if pinned, ok := payload["pinned"].(bool); ok {
+		placement.Pinned = pinned
+	}
+	return placement
+}
+
+// isDryRun reports whether the request asks for a preview (?dry_run=1).
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	return dryRun
+}
+
+// sendPlanResponse sends the preview of a plan without applying it.
+func sendPlanResponse(w http.ResponseWriter, plan *MacroPlan) {
+	preview := plan.Preview()
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"dry_run": true,
+		"message": fmt.Sprintf("%d widgets would change, %d would be created, %d would be deleted, %d skipped", len(preview.Changes), len(preview.Creations), len(preview.Deletions), len(preview.Skipped)),
+		"plan":    preview,
+	}, http.StatusOK)
+}
+
+// applyPlan applies plan and records its updates and copies as one entry in
+// the undo journal. Updates run first, then deletions, then copies. Its
+// events name clientID, the client the macro runs for.
+func (h *MacrosHandler) applyPlan(plan *MacroPlan, clientID string) *BatchReport {
+	report := &BatchReport{Skipped: plan.Skipped}
+	entry := JournalEntry{Macro: plan.Macro, CanvasID: plan.CanvasID}
+	total := len(plan.Updates) + len(plan.Deletes) + len(plan.Copies)
+	h.events.Publish(TopicMacro, "macro_started", map[string]interface{}{
+		"macro":     plan.Macro,
+		"client_id": clientID,
+		"canvas_id": plan.CanvasID,
+		"total":     total,
+	})
+	progress := func(phase string) {
+		h.events.Publish(TopicMacro, "macro_progress", map[string]interface{}{
+			"macro":     plan.Macro,
+			"client_id": clientID,
+			"canvas_id": plan.CanvasID,
+			"phase":     phase,
+			"done":      report.Total,
+			"total":     total,
+		})
+	}
+
+	if len(plan.Updates) > 0 {
+		updated := h.newOperations().BatchUpdateWidgets(plan.CanvasID, plan.Updates)
+		entry.Changes = journalChanges(plan.Widgets, plan.Updates, updated)
+		for _, result := range updated.Results {
+			report.add(result)
+		}
+		progress("updates")
+	}
+	if len(plan.Deletes) > 0 {
+		entry.Deletions = h.applyDeletes(plan, report)
+		progress("deletes")
+	}
+	if len(plan.Copies) > 0 {
+		entry.Creations = h.applyCopies(plan, report)
+		progress("copies")
+	}
+
+	h.journal.Record(entry)
+	h.events.Publish(TopicMacro, "macro_finished", map[string]interface{}{
+		"macro":     plan.Macro,
+		"client_id": clientID,
+		"canvas_id": plan.CanvasID,
+		"succeeded": report.Succeeded,
+		"failed":    report.Failed,
+		"skipped":   len(report.Skipped),
+	})
+	return report
+}
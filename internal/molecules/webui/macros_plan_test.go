@@ -0,0 +1,57 @@
+package webui
+
+import (
+	"testing"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestMacroPlan_Preview checks that the preview reports before and after
+// placements for updates and copies without touching the API.
+func TestMacroPlan_Preview(t *testing.T) {
+	note := webuiatoms.Widget{
+		ID:         "note-0000001",
+		WidgetType: "Note",
+		Location:   &webuiatoms.WidgetLocation{X: 10, Y: 20},
+		Size:       &webuiatoms.WidgetSize{Width: 100, Height: 50},
+	}
+	copied := cloneWidget(note)
+	copied.Location.X = 1010
+	copied.Scale = 2
+
+	plan := &MacroPlan{
+		Macro:    "auto-grid",
+		CanvasID: "canvas-1",
+		Widgets:  []webuiatoms.Widget{note},
+		Updates: []WidgetUpdate{{
+			WidgetID:   note.ID,
+			WidgetType: note.WidgetType,
+			Payload: map[string]interface{}{
+				"location": map[string]float64{"x": 300, "y": 400},
+				"pinned":   true,
+			},
+		}},
+		Copies: []PlannedCopy{{Source: note, Target: copied}},
+	}
+
+	preview := plan.Preview()
+	if len(preview.Changes) != 1 || len(preview.Creations) != 1 {
+		t.Fatalf("preview has %d changes and %d creations, want 1 and 1", len(preview.Changes), len(preview.Creations))
+	}
+
+	change := preview.Changes[0]
+	if change.Before.Location.X != 10 || change.After.Location.X != 300 || change.After.Location.Y != 400 {
+		t.Errorf("change locations = %+v -> %+v", change.Before.Location, change.After.Location)
+	}
+	if change.Before.Pinned || !change.After.Pinned || change.After.Scale != 1 {
+		t.Errorf("change after = %+v, want pinned with scale 1", change.After)
+	}
+
+	creation := preview.Creations[0]
+	if creation.WidgetID != note.ID || creation.After.Location.X != 1010 || creation.After.Scale != 2 {
+		t.Errorf("creation = %+v", creation)
+	}
+	if note.Location.X != 10 {
+		t.Errorf("source widget was modified: %+v", note.Location)
+	}
+}
@@ -0,0 +1,547 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// MaxJobRuns is how many job runs are kept in the run history.
+const MaxJobRuns = 100
+
+// MinJobInterval is the shortest interval a job may repeat at.
+const MinJobInterval = time.Minute
+
+// EventCanvasChanged fires when the canvas of a job's client changes.
+const EventCanvasChanged = "canvas_changed"
+
+// ErrJobNotFound is returned for operations on a job ID that does not exist.
+var ErrJobNotFound = errors.New("job not found")
+
+// zoneMacros are the built-in macros a job can run on a single zone
+// (params: zoneId); pairMacros run between two zones (params: sourceZoneId,
+// targetZoneId). User-defined macros are named custom:<id>.
+var (
+	zoneMacros = []string{"pin-all", "unpin-all", "auto-grid", "group-color", "group-title"}
+	pairMacros = []string{"move", "copy", "zone-sync"}
+)
+
+// MacroJob runs a macro on a schedule or when an event fires. Exactly one of
+// Every, At and On is set. Jobs run on the canvas of ClientID, or of the
+// primary client when it is empty.
+type MacroJob struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Macro     string            `json:"macro"`
+	ClientID  string            `json:"client_id,omitempty"`
+	Params    map[string]string `json:"params,omitempty"`
+	Every     string            `json:"every,omitempty"` // interval, e.g. "10m"
+	At        string            `json:"at,omitempty"`    // daily at HH:MM, server local time
+	On        string            `json:"on,omitempty"`    // event name, e.g. canvas_changed
+	Paused    bool              `json:"paused"`
+	CreatedAt time.Time         `json:"created_at"`
+	LastRun   *time.Time        `json:"last_run,omitempty"`
+	NextRun   *time.Time        `json:"next_run,omitempty"`
+}
+
+// JobRun is one run of a job in the run history.
+type JobRun struct {
+	JobID     string    `json:"job_id"`
+	JobName   string    `json:"job_name"`
+	Macro     string    `json:"macro"`
+	Trigger   string    `json:"trigger"` // schedule or the event name
+	ClientID  string    `json:"client_id,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	Skipped   int       `json:"skipped"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// schedulerFile is the on-disk layout of the scheduler.
+type schedulerFile struct {
+	Jobs    []*MacroJob `json:"jobs"`
+	History []JobRun    `json:"history"`
+}
+
+// MacroScheduler runs macro jobs in the background. Jobs and their run
+// history are persisted as JSON after every change when created with a path.
+// Runs missed while the server was stopped are skipped.
+type MacroScheduler struct {
+	mu      sync.Mutex
+	path    string
+	handler *MacrosHandler
+	jobs    []*MacroJob
+	history []JobRun
+	now     func() time.Time
+
+	lastCanvasIDs map[string]string // by client ID, "" for the primary client
+	stop          chan struct{}
+	done          chan struct{}
+}
+
+// NewMacroScheduler creates a scheduler running macros through handler,
+// persisted at path and loading any existing jobs. An empty path keeps jobs
+// in memory only. Call Start to begin running jobs.
+func NewMacroScheduler(handler *MacrosHandler, path string) *MacroScheduler {
+	s := &MacroScheduler{
+		path:          path,
+		handler:       handler,
+		now:           time.Now,
+		lastCanvasIDs: make(map[string]string),
+	}
+	if path == "" {
+		return s
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("[MacroScheduler] Failed to read %s: %v\n", path, err)
+		}
+		return s
+	}
+
+	var file schedulerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		fmt.Printf("[MacroScheduler] Failed to parse %s, starting without jobs: %v\n", path, err)
+		return s
+	}
+	s.jobs = file.Jobs
+	s.history = file.History
+	now := s.now()
+	for _, job := range s.jobs {
+		job.NextRun = nextRun(job, now)
+	}
+	fmt.Printf("[MacroScheduler] Loaded %d jobs from %s\n", len(s.jobs), path)
+	return s
+}
+
+// Start runs due jobs once a second until Stop is called.
+func (s *MacroScheduler) Start() {
+	s.mu.Lock()
+	if s.stop != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	clientIDs := s.jobClientIDs(false)
+	stop, done := s.stop, s.done
+	s.mu.Unlock()
+
+	canvasIDs := make(map[string]string)
+	for _, clientID := range clientIDs {
+		canvasIDs[clientID] = s.currentCanvasID(clientID)
+	}
+	s.mu.Lock()
+	s.lastCanvasIDs = canvasIDs
+	s.mu.Unlock()
+
+	fmt.Printf("[MacroScheduler] Started\n")
+	go func() {
+		defer close(done)
+		// Jobs of other clients need their canvases tracked
+		for _, clientID := range clientIDs {
+			s.trackClient(clientID)
+		}
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.Tick()
+			}
+		}
+	}()
+}
+
+// Stop stops running jobs and waits for a run in progress to finish.
+func (s *MacroScheduler) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+	fmt.Printf("[MacroScheduler] Stopped\n")
+}
+
+// currentCanvasID returns the canvas of clientID if its CanvasService is
+// running, without starting to track the client.
+func (s *MacroScheduler) currentCanvasID(clientID string) string {
+	canvasService := s.clientService(clientID)
+	if canvasService == nil {
+		return ""
+	}
+	return canvasService.GetCanvasID()
+}
+
+// clientService returns the tracked client clientID, the primary client for
+// "", or nil.
+func (s *MacroScheduler) clientService(clientID string) *CanvasService {
+	if s.handler.clients != nil {
+		canvasService, _ := s.handler.clients.lookup(clientID)
+		return canvasService
+	}
+	if clientID != "" {
+		return nil
+	}
+	return s.handler.canvasService
+}
+
+// trackClient returns the canvas of clientID, starting to track the client
+// if needed.
+func (s *MacroScheduler) trackClient(clientID string) string {
+	if clientID != "" && s.handler.clients != nil {
+		if _, err := s.handler.clients.Service(clientID); err != nil {
+			fmt.Printf("[MacroScheduler] Failed to track client %s: %v\n", clientID, err)
+		}
+	}
+	return s.currentCanvasID(clientID)
+}
+
+// jobClientIDs returns the clients the jobs run on, only those of jobs
+// triggered by events if eventsOnly is set. Callers must hold s.mu.
+func (s *MacroScheduler) jobClientIDs(eventsOnly bool) []string {
+	clientIDs := []string{""}
+	for _, job := range s.jobs {
+		if (!eventsOnly || job.On != "") && !containsString(clientIDs, job.ClientID) {
+			clientIDs = append(clientIDs, job.ClientID)
+		}
+	}
+	return clientIDs
+}
+
+// Tick runs the jobs that are due and, for each client whose canvas changed
+// since the last tick, its jobs triggered by EventCanvasChanged.
+func (s *MacroScheduler) Tick() {
+	now := s.now()
+
+	s.mu.Lock()
+	var due []MacroJob
+	for _, job := range s.jobs {
+		if !job.Paused && job.NextRun != nil && !now.Before(*job.NextRun) {
+			job.NextRun = nextRun(job, now)
+			due = append(due, *job)
+		}
+	}
+	clientIDs := s.jobClientIDs(true)
+	s.mu.Unlock()
+
+	// The first canvas seen of a client is not a change, and neither is
+	// losing the canvas for a while: only a move to another canvas is.
+	var changed []string
+	for _, clientID := range clientIDs {
+		canvasID := s.currentCanvasID(clientID)
+		if canvasID == "" {
+			continue
+		}
+		s.mu.Lock()
+		if last := s.lastCanvasIDs[clientID]; last != "" && last != canvasID {
+			changed = append(changed, clientID)
+		}
+		s.lastCanvasIDs[clientID] = canvasID
+		s.mu.Unlock()
+	}
+
+	for _, job := range due {
+		s.run(job, "schedule")
+	}
+	for _, clientID := range changed {
+		s.fire(EventCanvasChanged, func(job *MacroJob) bool { return job.ClientID == clientID })
+	}
+}
+
+// Fire runs the jobs triggered by event.
+func (s *MacroScheduler) Fire(event string) {
+	s.fire(event, func(*MacroJob) bool { return true })
+}
+
+// fire runs the jobs triggered by event that match.
+func (s *MacroScheduler) fire(event string, match func(*MacroJob) bool) {
+	s.mu.Lock()
+	var triggered []MacroJob
+	for _, job := range s.jobs {
+		if !job.Paused && job.On == event && match(job) {
+			triggered = append(triggered, *job)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, job := range triggered {
+		s.run(job, event)
+	}
+}
+
+// run runs job on the current canvas of its client and records the run.
+func (s *MacroScheduler) run(job MacroJob, trigger string) {
+	fmt.Printf("[MacroScheduler] Running job %s (%s) - trigger: %s\n", job.Name, job.Macro, trigger)
+	record := JobRun{JobID: job.ID, JobName: job.Name, Macro: job.Macro, Trigger: trigger, StartedAt: s.now()}
+
+	if canvasID := s.trackClient(job.ClientID); canvasID == "" {
+		record.Error = "canvas not available"
+	} else if plan, err := s.handler.planNamedMacro(canvasID, job.Macro, job.Params, s.handler.membership); err != nil {
+		record.Error = err.Error()
+	} else if !plan.Empty() {
+		if canvasService := s.clientService(job.ClientID); canvasService != nil {
+			record.ClientID = canvasService.GetClientID()
+		}
+		report := s.handler.applyPlan(plan, record.ClientID)
+		record.Succeeded, record.Failed, record.Skipped = report.Succeeded, report.Failed, len(report.Skipped)
+	} else {
+		record.Skipped = len(plan.Skipped)
+	}
+	if record.Error != "" {
+		fmt.Printf("[MacroScheduler] ERROR: Job %s failed: %s\n", job.Name, record.Error)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored := s.find(job.ID); stored != nil {
+		startedAt := record.StartedAt
+		stored.LastRun = &startedAt
+	}
+	s.handler.events.Publish(TopicMacro, "job_run", record)
+	s.history = append(s.history, record)
+	if len(s.history) > MaxJobRuns {
+		s.history = s.history[len(s.history)-MaxJobRuns:]
+	}
+	s.save()
+}
+
+// Create validates job, schedules it and returns the stored job.
+func (s *MacroScheduler) Create(job MacroJob) (MacroJob, error) {
+	job.Name = strings.TrimSpace(job.Name)
+	if job.Name == "" {
+		job.Name = job.Macro
+	}
+	if err := validateJobTrigger(job); err != nil {
+		return MacroJob{}, err
+	}
+	if err := s.handler.checkNamedMacro(job.Macro, job.Params); err != nil {
+		return MacroJob{}, err
+	}
+	if job.ClientID != "" && s.handler.clients != nil {
+		if _, err := s.handler.clients.Service(job.ClientID); err != nil {
+			return MacroJob{}, err
+		}
+	}
+
+	now := s.now()
+	job.CreatedAt = now
+	job.LastRun = nil
+	job.NextRun = nextRun(&job, now)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := now.UnixNano(); job.ID == ""; id++ {
+		if s.find(fmt.Sprintf("%d", id)) == nil {
+			job.ID = fmt.Sprintf("%d", id)
+		}
+	}
+	stored := job
+	s.jobs = append(s.jobs, &stored)
+	s.save()
+	fmt.Printf("[MacroScheduler] Created job %s (%s)\n", job.Name, job.Macro)
+	return job, nil
+}
+
+// List returns the jobs and the run history, most recent run first.
+func (s *MacroScheduler) List() ([]MacroJob, []JobRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]MacroJob, len(s.jobs))
+	for i, job := range s.jobs {
+		jobs[i] = *job
+	}
+	history := make([]JobRun, len(s.history))
+	for i, run := range s.history {
+		history[len(s.history)-1-i] = run
+	}
+	return jobs, history
+}
+
+// SetPaused pauses or resumes a job. A resumed job is rescheduled from now.
+func (s *MacroScheduler) SetPaused(id string, paused bool) (MacroJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.find(id)
+	if job == nil {
+		return MacroJob{}, ErrJobNotFound
+	}
+	job.Paused = paused
+	if !paused {
+		job.NextRun = nextRun(job, s.now())
+	}
+	s.save()
+	return *job, nil
+}
+
+// Delete removes a job. Its past runs stay in the history.
+func (s *MacroScheduler) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, job := range s.jobs {
+		if job.ID == id {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			s.save()
+			return nil
+		}
+	}
+	return ErrJobNotFound
+}
+
+// find returns the job with the given ID, or nil. Callers must hold s.mu.
+func (s *MacroScheduler) find(id string) *MacroJob {
+	for _, job := range s.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// save writes the jobs and history to disk. Callers must hold s.mu.
+func (s *MacroScheduler) save() {
+	if s.path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(schedulerFile{Jobs: s.jobs, History: s.history}, "", "  ")
+	if err != nil {
+		fmt.Printf("[MacroScheduler] Failed to encode jobs: %v\n", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		fmt.Printf("[MacroScheduler] Failed to create jobs directory: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		fmt.Printf("[MacroScheduler] Failed to write %s: %v\n", s.path, err)
+	}
+}
+
+// validateJobTrigger checks that job has exactly one valid trigger.
+func validateJobTrigger(job MacroJob) error {
+	set := 0
+	for _, value := range []string{job.Every, job.At, job.On} {
+		if value != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("a job needs exactly one of every, at or on")
+	}
+
+	switch {
+	case job.Every != "":
+		interval, err := time.ParseDuration(job.Every)
+		if err != nil {
+			return fmt.Errorf("invalid interval %q: %w", job.Every, err)
+		}
+		if interval < MinJobInterval {
+			return fmt.Errorf("interval must be at least %s", MinJobInterval)
+		}
+	case job.At != "":
+		if _, err := time.Parse("15:04", job.At); err != nil {
+			return fmt.Errorf("invalid time %q, expected HH:MM", job.At)
+		}
+	case job.On != EventCanvasChanged:
+		return fmt.Errorf("unknown event %q", job.On)
+	}
+	return nil
+}
+
+// nextRun returns when job is next due after now, or nil for jobs that run
+// on events.
+func nextRun(job *MacroJob, now time.Time) *time.Time {
+	var next time.Time
+	switch {
+	case job.Every != "":
+		interval, err := time.ParseDuration(job.Every)
+		if err != nil {
+			return nil
+		}
+		next = now.Add(interval)
+	case job.At != "":
+		at, err := time.Parse("15:04", job.At)
+		if err != nil {
+			return nil
+		}
+		next = time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+	default:
+		return nil
+	}
+	return &next
+}
+
+// checkNamedMacro checks that macro names a built-in or user-defined macro
+// and that params has the zones it needs.
+func (h *MacrosHandler) checkNamedMacro(macro string, params map[string]string) error {
+	switch {
+	case containsString(zoneMacros, macro):
+		if params["zoneId"] == "" {
+			return fmt.Errorf("%s needs params.zoneId", macro)
+		}
+	case containsString(pairMacros, macro):
+		if params["sourceZoneId"] == "" || params["targetZoneId"] == "" {
+			return fmt.Errorf("%s needs params.sourceZoneId and params.targetZoneId", macro)
+		}
+	case strings.HasPrefix(macro, "custom:"):
+		def, err := h.library.Get(strings.TrimPrefix(macro, "custom:"))
+		if err != nil {
+			return fmt.Errorf("%s: %w", macro, err)
+		}
+		return def.CheckParams(params)
+	default:
+		return fmt.Errorf("unknown macro %q", macro)
+	}
+	return nil
+}
+
+// planNamedMacro plans macro by name, as checked by checkNamedMacro.
+func (h *MacrosHandler) planNamedMacro(canvasID, macro string, params map[string]string, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+	if err := h.checkNamedMacro(macro, params); err != nil {
+		return nil, err
+	}
+	zoneID, sourceZoneID, targetZoneID := params["zoneId"], params["sourceZoneId"], params["targetZoneId"]
+	switch macro {
+	case "pin-all":
+		return h.planPin(canvasID, zoneID, true, membership)
+	case "unpin-all":
+		return h.planPin(canvasID, zoneID, false, membership)
+	case "auto-grid":
+		return h.planAutoGrid(canvasID, zoneID, membership)
+	case "group-color":
+		return h.planGroupByColor(canvasID, zoneID, membership)
+	case "group-title":
+		return h.planGroupByTitle(canvasID, zoneID, membership)
+	case "move":
+		return h.planMove(canvasID, sourceZoneID, targetZoneID, membership)
+	case "copy":
+		return h.planCopy(canvasID, sourceZoneID, targetZoneID, membership)
+	case "zone-sync":
+		return h.planZoneSync(canvasID, sourceZoneID, targetZoneID, membership)
+	}
+	def, err := h.library.Get(strings.TrimPrefix(macro, "custom:"))
+	if err != nil {
+		return nil, err
+	}
+	return h.planUserMacro(canvasID, def, params, membership)
+}
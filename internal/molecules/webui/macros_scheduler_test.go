@@ -0,0 +1,149 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+func TestMacroScheduler_RunsDueAndTriggeredJobs(t *testing.T) {
+	const canvas = "/api/v1/canvases/canvas-1"
+	var mu sync.Mutex
+	patched := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path := strings.TrimPrefix(r.URL.Path, canvas); {
+		case r.Method == http.MethodPatch && path == "/notes/pinned-note-1":
+			mu.Lock()
+			patched++
+			mu.Unlock()
+			w.Write([]byte(`{}`))
+		case path == "/anchors/inbox-zone":
+			w.Write([]byte(`{"id":"inbox-zone","location":{"x":0,"y":0},"size":{"width":1000,"height":1000},"scale":1}`))
+		case path == "/widgets":
+			w.Write([]byte(`[
+				{"id":"inbox-zone","widget_type":"Anchor","location":{"x":0,"y":0},"size":{"width":1000,"height":1000}},
+				{"id":"pinned-note-1","widget_type":"Note","pinned":true,"location":{"x":500,"y":500}}
+			]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "macro_jobs.json")
+	tracker := webuiatoms.NewCanvasTracker()
+	tracker.UpdateCanvas("canvas-1", "Board")
+	canvasService := &CanvasService{canvasTracker: tracker}
+	handler := NewMacrosHandler(webuiatoms.NewAPIClient(server.URL, "test-token"), canvasService)
+	scheduler := NewMacroScheduler(handler, path)
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local)
+	scheduler.now = func() time.Time { return now }
+	scheduler.lastCanvasIDs[""] = "canvas-1"
+
+	invalid := []MacroJob{
+		{Macro: "unpin-all", Params: map[string]string{"zoneId": "inbox-zone"}, Every: "5s"},
+		{Macro: "unpin-all", Params: map[string]string{"zoneId": "inbox-zone"}, Every: "10m", At: "18:00"},
+		{Macro: "unpin-all", Params: map[string]string{"zoneId": "inbox-zone"}, On: "widget_added"},
+		{Macro: "unpin-all", Every: "10m"},
+		{Macro: "explode", Every: "10m"},
+		{Macro: "custom:missing", Every: "10m"},
+	}
+	for _, job := range invalid {
+		if _, err := scheduler.Create(job); err == nil {
+			t.Errorf("Create(%+v) accepted an invalid job", job)
+		}
+	}
+
+	every, err := scheduler.Create(MacroJob{Name: "Unpin inbox", Macro: "unpin-all", Params: map[string]string{"zoneId": "inbox-zone"}, Every: "10m"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	daily, err := scheduler.Create(MacroJob{Macro: "unpin-all", Params: map[string]string{"zoneId": "inbox-zone"}, At: "08:30"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if want := time.Date(2024, 5, 2, 8, 30, 0, 0, time.Local); !daily.NextRun.Equal(want) {
+		t.Errorf("daily next run = %v, want %v", daily.NextRun, want)
+	}
+	if _, err := scheduler.SetPaused(daily.ID, true); err != nil {
+		t.Fatalf("SetPaused: %v", err)
+	}
+	if _, err := scheduler.Create(MacroJob{Macro: "unpin-all", Params: map[string]string{"zoneId": "inbox-zone"}, On: EventCanvasChanged}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	scheduler.Tick()
+	if patched != 0 {
+		t.Fatalf("ran %d updates before any job was due", patched)
+	}
+
+	now = now.Add(10 * time.Minute)
+	scheduler.Tick()
+	if patched != 1 {
+		t.Fatalf("patched = %d after the interval job was due, want 1", patched)
+	}
+
+	// canvas-2 does not exist on the fake server, so the triggered run fails.
+	tracker.UpdateCanvas("canvas-2", "Other board")
+	scheduler.Tick()
+	_, history := scheduler.List()
+	if len(history) != 2 || history[0].Trigger != EventCanvasChanged || history[0].Error == "" {
+		t.Fatalf("history = %+v, want a failed canvas_changed run first", history)
+	}
+	if history[1].JobID != every.ID || history[1].Trigger != "schedule" || history[1].Succeeded != 1 {
+		t.Errorf("scheduled run = %+v", history[1])
+	}
+
+	if _, err := scheduler.SetPaused(every.ID, true); err != nil {
+		t.Fatalf("SetPaused: %v", err)
+	}
+	if err := scheduler.Delete("missing"); err != ErrJobNotFound {
+		t.Errorf("Delete(missing) error = %v, want ErrJobNotFound", err)
+	}
+
+	// Jobs and history survive a restart; missed runs are rescheduled from now.
+	reloaded := NewMacroScheduler(handler, path)
+	jobs, history := reloaded.List()
+	if len(jobs) != 3 || len(history) != 2 {
+		t.Fatalf("reloaded %d jobs and %d runs, want 3 and 2", len(jobs), len(history))
+	}
+	if !jobs[0].Paused || jobs[0].LastRun == nil {
+		t.Errorf("reloaded job = %+v", jobs[0])
+	}
+}
+
+// TestMacroScheduler_FirstCanvasIsNotAChange checks that canvas_changed jobs
+// do not run when the canvas of a client is first seen, only when it moves.
+func TestMacroScheduler_FirstCanvasIsNotAChange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	tracker := webuiatoms.NewCanvasTracker()
+	canvasService := &CanvasService{canvasTracker: tracker}
+	handler := NewMacrosHandler(webuiatoms.NewAPIClient(server.URL, "test-token"), canvasService)
+	scheduler := NewMacroScheduler(handler, "")
+	if _, err := scheduler.Create(MacroJob{Macro: "unpin-all", Params: map[string]string{"zoneId": "inbox-zone"}, On: EventCanvasChanged}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	scheduler.Tick()
+	tracker.UpdateCanvas("canvas-1", "Board")
+	scheduler.Tick()
+	if _, history := scheduler.List(); len(history) != 0 {
+		t.Fatalf("history = %+v, want no run for the first canvas seen", history)
+	}
+
+	tracker.UpdateCanvas("canvas-2", "Other board")
+	scheduler.Tick()
+	if _, history := scheduler.List(); len(history) != 1 || history[0].Trigger != EventCanvasChanged {
+		t.Fatalf("history = %+v, want one canvas_changed run", history)
+	}
+}
@@ -0,0 +1,360 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// zoneContentFields are the fields compared and synced for each widget type
+// besides the placement. Types missing from the map are skipped.
+var zoneContentFields = map[string][]string{
+	"note":    {"title", "text", "background_color", "text_color"},
+	"browser": {"title", "url"},
+	"image":   {"title", "hash"},
+	"video":   {"title", "hash"},
+	"pdf":     {"title", "hash"},
+}
+
+// zoneLocationTolerance is how far apart, in canvas units, two widgets may be
+// after the zone transform and still count as in the same place.
+const zoneLocationTolerance = 1.0
+
+// zoneMatchRadius is the fraction of the target zone's size within which a
+// widget whose content differs is still treated as the same widget.
+const zoneMatchRadius = 0.05
+
+// ZoneDiff lists how the widgets of a target zone differ from a source zone.
+type ZoneDiff struct {
+	Added     []ZoneDiffEntry `json:"added"`   // only in the source zone
+	Removed   []ZoneDiffEntry `json:"removed"` // only in the target zone
+	Changed   []ZoneDiffEntry `json:"changed"`
+	Unchanged int             `json:"unchanged"`
+	Skipped   []SkippedWidget `json:"skipped,omitempty"`
+}
+
+// ZoneDiffEntry is one widget in a ZoneDiff. Fields lists what differs for a
+// changed widget.
+type ZoneDiffEntry struct {
+	SourceID   string   `json:"source_id,omitempty"`
+	TargetID   string   `json:"target_id,omitempty"`
+	WidgetType string   `json:"widget_type"`
+	Title      string   `json:"title,omitempty"`
+	Fields     []string `json:"fields,omitempty"`
+}
+
+// zoneWidget is a widget in a compared zone with its full data. placed is
+// where the widget sits in the target zone's coordinates.
+type zoneWidget struct {
+	widget webuiatoms.Widget
+	placed webuiatoms.Widget
+	data   map[string]interface{}
+}
+
+// zonePair is a source widget matched to a target widget.
+type zonePair struct {
+	source *zoneWidget
+	target *zoneWidget
+	fields []string
+}
+
+// zoneComparison is the result of comparing two zones.
+type zoneComparison struct {
+	sourceBB *webuiatoms.ZoneBoundingBox
+	targetBB *webuiatoms.ZoneBoundingBox
+	added    []*zoneWidget
+	removed  []*zoneWidget
+	pairs    []zonePair
+	skipped  []SkippedWidget
+}
+
+// Diff returns the comparison as a ZoneDiff.
+func (c *zoneComparison) Diff() ZoneDiff {
+	diff := ZoneDiff{Added: []ZoneDiffEntry{}, Removed: []ZoneDiffEntry{}, Changed: []ZoneDiffEntry{}, Skipped: c.skipped}
+	for _, w := range c.added {
+		diff.Added = append(diff.Added, ZoneDiffEntry{SourceID: w.widget.ID, WidgetType: w.widget.WidgetType, Title: w.title()})
+	}
+	for _, w := range c.removed {
+		diff.Removed = append(diff.Removed, ZoneDiffEntry{TargetID: w.widget.ID, WidgetType: w.widget.WidgetType, Title: w.title()})
+	}
+	for _, pair := range c.pairs {
+		if len(pair.fields) == 0 {
+			diff.Unchanged++
+			continue
+		}
+		diff.Changed = append(diff.Changed, ZoneDiffEntry{
+			SourceID:   pair.source.widget.ID,
+			TargetID:   pair.target.widget.ID,
+			WidgetType: pair.source.widget.WidgetType,
+			Title:      pair.source.title(),
+			Fields:     pair.fields,
+		})
+	}
+	return diff
+}
+
+func (w *zoneWidget) title() string {
+	title, _ := w.data["title"].(string)
+	return title
+}
+
+// contentKey identifies the content of the widget regardless of placement.
+func (w *zoneWidget) contentKey() string {
+	fields := zoneContentFields[strings.ToLower(w.widget.WidgetType)]
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i] = w.data[field]
+	}
+	key, _ := json.Marshal(values)
+	return strings.ToLower(w.widget.WidgetType) + string(key)
+}
+
+// compareZones matches the widgets of the source zone to those of the
+// target zone. Source widgets are moved into the target zone with the same
+// transform as move and copy, then matched first by content and nearest
+// position, then by position alone for widgets whose content was edited.
+// Anchors and connectors are not compared.
+func (h *MacrosHandler) compareZones(canvasID, sourceZoneID, targetZoneID string, membership webuiatoms.ZoneMembership) (*zoneComparison, error) {
+	fmt.Printf("[MacrosHandler] compareZones - canvasID: %s, sourceZoneID: %s, targetZoneID: %s\n", canvasID, sourceZoneID, targetZoneID)
+
+	sourceBB, err := zoneBoundingBox(h.apiClient, h.mirrors(), canvasID, sourceZoneID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source zone: %w", err)
+	}
+	targetBB, err := zoneBoundingBox(h.apiClient, h.mirrors(), canvasID, targetZoneID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target zone: %w", err)
+	}
+	allWidgets, err := listWidgets(h.apiClient, h.mirrors(), canvasID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widgets: %w", err)
+	}
+
+	comparison := &zoneComparison{sourceBB: sourceBB, targetBB: targetBB}
+	inSource := FilterWidgetsInZone(allWidgets, sourceBB, sourceZoneID, membership)
+	inTarget := FilterWidgetsInZone(allWidgets, targetBB, targetZoneID, membership)
+
+	inBoth := make(map[string]bool)
+	sourceIDs := make(map[string]bool, len(inSource))
+	for _, w := range inSource {
+		sourceIDs[w.ID] = true
+	}
+	for _, w := range inTarget {
+		if sourceIDs[w.ID] {
+			inBoth[w.ID] = true
+		}
+	}
+
+	sources, err := h.loadZoneWidgets(canvasID, inSource, inBoth, comparison)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := h.loadZoneWidgets(canvasID, inTarget, nil, comparison)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range sources {
+		webuiatoms.TransformWidgetLocationAndScale(&w.placed, sourceBB, targetBB)
+	}
+
+	matched := make(map[*zoneWidget]bool)
+	var unmatched []*zoneWidget
+	// First pass: same content, nearest position.
+	for _, source := range sources {
+		target := nearestZoneWidget(source, targets, matched, math.Inf(1), true)
+		if target == nil {
+			unmatched = append(unmatched, source)
+			continue
+		}
+		matched[target] = true
+		comparison.pairs = append(comparison.pairs, zonePair{source: source, target: target, fields: changedZoneFields(source, target)})
+	}
+	// Second pass: edited content at the same position.
+	radius := math.Max(targetBB.Width, targetBB.Height) * zoneMatchRadius
+	for _, source := range unmatched {
+		target := nearestZoneWidget(source, targets, matched, radius, false)
+		if target == nil {
+			comparison.added = append(comparison.added, source)
+			continue
+		}
+		matched[target] = true
+		comparison.pairs = append(comparison.pairs, zonePair{source: source, target: target, fields: changedZoneFields(source, target)})
+	}
+	for _, target := range targets {
+		if !matched[target] {
+			comparison.removed = append(comparison.removed, target)
+		}
+	}
+
+	fmt.Printf("[MacrosHandler] compareZones: %d added, %d removed, %d matched, %d skipped\n",
+		len(comparison.added), len(comparison.removed), len(comparison.pairs), len(comparison.skipped))
+	return comparison, nil
+}
+
+// loadZoneWidgets fetches the full data of widgets, skipping those in
+// exclude and those whose type cannot be compared.
+func (h *MacrosHandler) loadZoneWidgets(canvasID string, widgets []webuiatoms.Widget, exclude map[string]bool, comparison *zoneComparison) ([]*zoneWidget, error) {
+	var loaded []*zoneWidget
+	for _, widget := range widgets {
+		if exclude[widget.ID] {
+			comparison.skipped = append(comparison.skipped, SkippedWidget{WidgetID: widget.ID, WidgetType: widget.WidgetType, Reason: "widget is in both zones"})
+			continue
+		}
+		if _, ok := zoneContentFields[strings.ToLower(widget.WidgetType)]; !ok {
+			comparison.skipped = append(comparison.skipped, SkippedWidget{WidgetID: widget.ID, WidgetType: widget.WidgetType, Reason: fmt.Sprintf("widget type %s cannot be synced", widget.WidgetType)})
+			continue
+		}
+		data, err := fetchWidgetData(context.Background(), h.apiClient, canvasID, widget)
+		if webuiatoms.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s %s: %w", widget.WidgetType, widget.ID, err)
+		}
+		loaded = append(loaded, &zoneWidget{widget: widget, placed: cloneWidget(widget), data: data})
+	}
+	return loaded, nil
+}
+
+// nearestZoneWidget returns the unmatched target of the same type closest to
+// source within radius, or nil. With sameContent only targets with the same
+// content are considered.
+func nearestZoneWidget(source *zoneWidget, targets []*zoneWidget, matched map[*zoneWidget]bool, radius float64, sameContent bool) *zoneWidget {
+	var nearest *zoneWidget
+	best := radius
+	for _, target := range targets {
+		if matched[target] || !strings.EqualFold(target.widget.WidgetType, source.widget.WidgetType) {
+			continue
+		}
+		if sameContent && target.contentKey() != source.contentKey() {
+			continue
+		}
+		if d := zoneDistance(source.placed, target.widget); d <= best {
+			nearest, best = target, d
+		}
+	}
+	return nearest
+}
+
+// zoneDistance is the distance between the locations of a and b.
+func zoneDistance(a, b webuiatoms.Widget) float64 {
+	if a.Location == nil || b.Location == nil {
+		return math.Inf(1)
+	}
+	return math.Hypot(a.Location.X-b.Location.X, a.Location.Y-b.Location.Y)
+}
+
+// changedZoneFields returns the fields in which target differs from the
+// placed source widget.
+func changedZoneFields(source, target *zoneWidget) []string {
+	var fields []string
+	if zoneDistance(source.placed, target.widget) > zoneLocationTolerance {
+		fields = append(fields, "location")
+	}
+	if math.Abs(scaleOf(source.placed)-scaleOf(target.widget)) > 1e-3 {
+		fields = append(fields, "scale")
+	}
+	if s, t := source.placed.Size, target.widget.Size; s != nil && t != nil &&
+		(math.Abs(s.Width-t.Width) > zoneLocationTolerance || math.Abs(s.Height-t.Height) > zoneLocationTolerance) {
+		fields = append(fields, "size")
+	}
+	if source.widget.Pinned != target.widget.Pinned {
+		fields = append(fields, "pinned")
+	}
+	for _, field := range zoneContentFields[strings.ToLower(source.widget.WidgetType)] {
+		if !reflect.DeepEqual(source.data[field], target.data[field]) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// scaleOf returns the scale of widget, treating an unset scale as 1.
+func scaleOf(widget webuiatoms.Widget) float64 {
+	if widget.Scale == 0 {
+		return 1
+	}
+	return widget.Scale
+}
+
+// planZoneSync plans the creates, patches and deletes that make the target
+// zone match the source zone. Widgets whose file changed are replaced, as
+// the file of an existing widget cannot be patched.
+func (h *MacrosHandler) planZoneSync(canvasID, sourceZoneID, targetZoneID string, membership webuiatoms.ZoneMembership) (*MacroPlan, error) {
+	comparison, err := h.compareZones(canvasID, sourceZoneID, targetZoneID, membership)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &MacroPlan{
+		Macro:      "sync",
+		CanvasID:   canvasID,
+		SourceZone: comparison.sourceBB,
+		Zone:       comparison.targetBB,
+		Skipped:    comparison.skipped,
+	}
+	for _, target := range comparison.removed {
+		plan.Deletes = append(plan.Deletes, target.widget)
+	}
+	for _, source := range comparison.added {
+		plan.Copies = append(plan.Copies, PlannedCopy{Source: source.widget, Target: source.placed})
+	}
+	for _, pair := range comparison.pairs {
+		if len(pair.fields) == 0 {
+			continue
+		}
+		if containsString(pair.fields, "hash") {
+			plan.Deletes = append(plan.Deletes, pair.target.widget)
+			plan.Copies = append(plan.Copies, PlannedCopy{Source: pair.source.widget, Target: pair.source.placed})
+			continue
+		}
+
+		target := pair.target.widget
+		target.Data = pair.target.data
+		plan.Widgets = append(plan.Widgets, target)
+		plan.Updates = append(plan.Updates, WidgetUpdate{
+			WidgetID:   target.ID,
+			WidgetType: target.WidgetType,
+			Payload:    syncPayload(pair),
+		})
+	}
+
+	fmt.Printf("[MacrosHandler] planZoneSync: %d creates, %d patches, %d deletes\n", len(plan.Copies), len(plan.Updates), len(plan.Deletes))
+	return plan, nil
+}
+
+// syncPayload returns the PATCH payload copying the changed fields of the
+// source widget onto the target widget.
+func syncPayload(pair zonePair) map[string]interface{} {
+	payload := make(map[string]interface{}, len(pair.fields))
+	for _, field := range pair.fields {
+		switch field {
+		case "location":
+			payload["location"] = pair.source.placed.Location
+		case "scale":
+			payload["scale"] = scaleOf(pair.source.placed)
+		case "size":
+			payload["size"] = pair.source.placed.Size
+		case "pinned":
+			payload["pinned"] = pair.source.widget.Pinned
+		default:
+			payload[field] = pair.source.data[field]
+		}
+	}
+	return payload
+}
+
+// containsString reports whether values contains value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestZoneSync_DiffAndApply compares two zones holding a matching note, an
+// edited note, a source-only browser and a target-only note, then syncs the
+// target zone and checks the requests sent.
+func TestZoneSync_DiffAndApply(t *testing.T) {
+	const canvas = "/api/v1/canvases/canvas-1"
+	var mu sync.Mutex
+	patches := make(map[string]map[string]interface{})
+	var deleted []string
+	var created []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, canvas)
+		switch r.Method {
+		case http.MethodPatch:
+			var payload map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&payload)
+			patches[path] = payload
+			w.Write([]byte(`{}`))
+			return
+		case http.MethodDelete:
+			deleted = append(deleted, path)
+			w.Write([]byte(`{}`))
+			return
+		case http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			var payload map[string]interface{}
+			json.Unmarshal(body, &payload)
+			payload["collection"] = path
+			created = append(created, payload)
+			fmt.Fprintf(w, `{"id":"new-%d"}`, len(created))
+			return
+		}
+		switch path {
+		case "/anchors/source-zone":
+			w.Write([]byte(`{"id":"source-zone","location":{"x":0,"y":0},"size":{"width":1000,"height":1000},"scale":1}`))
+		case "/anchors/target-zone":
+			w.Write([]byte(`{"id":"target-zone","location":{"x":2000,"y":0},"size":{"width":1000,"height":1000},"scale":1}`))
+		case "/widgets":
+			w.Write([]byte(`[
+				{"id":"source-zone","widget_type":"Anchor","location":{"x":0,"y":0},"size":{"width":1000,"height":1000}},
+				{"id":"target-zone","widget_type":"Anchor","location":{"x":2000,"y":0},"size":{"width":1000,"height":1000}},
+				{"id":"note-same","widget_type":"Note","location":{"x":100,"y":100},"size":{"width":200,"height":200},"scale":1},
+				{"id":"note-edit","widget_type":"Note","location":{"x":400,"y":400},"size":{"width":200,"height":200},"scale":1},
+				{"id":"browser-1","widget_type":"Browser","location":{"x":700,"y":100},"size":{"width":300,"height":200},"scale":1},
+				{"id":"copy-same","widget_type":"Note","location":{"x":2100,"y":100},"size":{"width":200,"height":200},"scale":1},
+				{"id":"copy-edit","widget_type":"Note","location":{"x":2410,"y":400},"size":{"width":200,"height":200},"scale":1},
+				{"id":"extra-note","widget_type":"Note","location":{"x":2800,"y":800},"size":{"width":100,"height":100},"scale":1}
+			]`))
+		case "/notes/note-same", "/notes/copy-same":
+			w.Write([]byte(`{"title":"Same","text":"unchanged","background_color":"#ffffff"}`))
+		case "/notes/note-edit":
+			w.Write([]byte(`{"title":"Edit","text":"new text","background_color":"#ffffff"}`))
+		case "/notes/copy-edit":
+			w.Write([]byte(`{"title":"Edit","text":"old text","background_color":"#ffffff"}`))
+		case "/notes/extra-note":
+			w.Write([]byte(`{"id":"extra-note","title":"Extra","text":"","background_color":"#ffffff","location":{"x":2800,"y":800},"scale":1}`))
+		case "/browsers/browser-1":
+			w.Write([]byte(`{"title":"Docs","url":"https://example.com"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	handler := NewMacrosHandler(webuiatoms.NewAPIClient(server.URL, "test-token"), nil)
+	comparison, err := handler.compareZones("canvas-1", "source-zone", "target-zone", webuiatoms.DefaultZoneMembership())
+	if err != nil {
+		t.Fatalf("compareZones: %v", err)
+	}
+	diff := comparison.Diff()
+	if len(diff.Added) != 1 || diff.Added[0].SourceID != "browser-1" {
+		t.Errorf("added = %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].TargetID != "extra-note" {
+		t.Errorf("removed = %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].TargetID != "copy-edit" ||
+		strings.Join(diff.Changed[0].Fields, ",") != "location,text" {
+		t.Errorf("changed = %+v", diff.Changed)
+	}
+	if diff.Unchanged != 1 {
+		t.Errorf("unchanged = %d, want 1", diff.Unchanged)
+	}
+
+	plan, err := handler.planZoneSync("canvas-1", "source-zone", "target-zone", webuiatoms.DefaultZoneMembership())
+	if err != nil {
+		t.Fatalf("planZoneSync: %v", err)
+	}
+	report := handler.applyPlan(plan, "")
+	if report.Succeeded != 3 || report.Failed != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	patch := patches["/notes/copy-edit"]
+	location, _ := patch["location"].(map[string]interface{})
+	if patch["text"] != "new text" || location["x"] != 2400.0 || len(patch) != 2 {
+		t.Errorf("patch = %v", patch)
+	}
+	if len(deleted) != 1 || deleted[0] != "/notes/extra-note" {
+		t.Errorf("deleted = %v", deleted)
+	}
+	if len(created) != 1 || created[0]["collection"] != "/browsers" || created[0]["url"] != "https://example.com" {
+		t.Errorf("created = %v", created)
+	}
+
+	undo, _ := handler.journal.Len("")
+	if undo != 1 {
+		t.Errorf("journal has %d entries, want 1", undo)
+	}
+
+	// Undo recreates the deleted note and redo deletes the recreated one.
+	if _, err := handler.journal.Undo("canvas-1", func(entry JournalEntry) (JournalEntry, error) {
+		_, entry, err := handler.revertEntry(entry)
+		return entry, err
+	}); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if len(created) != 2 || created[1]["collection"] != "/notes" || created[1]["title"] != "Extra" {
+		t.Fatalf("created after undo = %v, want the deleted note recreated", created)
+	}
+	if location, _ := created[1]["location"].(map[string]interface{}); location["x"] != 2800.0 {
+		t.Errorf("recreated note location = %v", created[1]["location"])
+	}
+	if _, err := handler.journal.Redo("canvas-1", func(entry JournalEntry) (JournalEntry, error) {
+		_, entry, err := handler.replayEntry(entry)
+		return entry, err
+	}); err != nil {
+		t.Fatalf("redo: %v", err)
+	}
+	if deleted[len(deleted)-1] != "/notes/new-2" {
+		t.Errorf("deleted after redo = %v, want the recreated note", deleted)
+	}
+}
+
+// TestZoneSync_UndoRedoDeletedImage syncs a zone that removes an image from
+// the target, then undoes and redoes the sync: undo uploads the saved file
+// again and redo saves the file of the recreated image before deleting it.
+func TestZoneSync_UndoRedoDeletedImage(t *testing.T) {
+	const canvas = "/api/v1/canvases/canvas-1"
+	var mu sync.Mutex
+	var deleted, uploads []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, canvas)
+		switch {
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, path)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && path == "/images":
+			r.ParseMultipartForm(1 << 20)
+			file, _, err := r.FormFile("data")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			uploads = append(uploads, string(data))
+			fmt.Fprintf(w, `{"id":"new-%d"}`, len(uploads))
+		case path == "/anchors/source-zone":
+			w.Write([]byte(`{"id":"source-zone","location":{"x":0,"y":0},"size":{"width":1000,"height":1000},"scale":1}`))
+		case path == "/anchors/target-zone":
+			w.Write([]byte(`{"id":"target-zone","location":{"x":2000,"y":0},"size":{"width":1000,"height":1000},"scale":1}`))
+		case path == "/widgets":
+			w.Write([]byte(`[
+				{"id":"source-zone","widget_type":"Anchor","location":{"x":0,"y":0},"size":{"width":1000,"height":1000}},
+				{"id":"target-zone","widget_type":"Anchor","location":{"x":2000,"y":0},"size":{"width":1000,"height":1000}},
+				{"id":"extra-image","widget_type":"Image","location":{"x":2500,"y":500},"size":{"width":200,"height":200},"scale":1}
+			]`))
+		case strings.HasPrefix(path, "/images/") && strings.HasSuffix(path, "/download"):
+			w.Write([]byte("PNGDATA"))
+		case strings.HasPrefix(path, "/images/"):
+			fmt.Fprintf(w, `{"id":%q,"title":"Photo","original_filename":"photo.png","location":{"x":2500,"y":500},"size":{"width":200,"height":200},"scale":1}`, strings.TrimPrefix(path, "/images/"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	handler := NewMacrosHandler(webuiatoms.NewAPIClient(server.URL, "test-token"), nil)
+	plan, err := handler.planZoneSync("canvas-1", "source-zone", "target-zone", webuiatoms.DefaultZoneMembership())
+	if err != nil {
+		t.Fatalf("planZoneSync: %v", err)
+	}
+	if report := handler.applyPlan(plan, ""); report.Succeeded != 1 || report.Failed != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	// step runs with a deadline so a journal that locks itself fails the test
+	step := func(name string, run func() error) {
+		t.Helper()
+		done := make(chan error, 1)
+		go func() { done <- run() }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s did not return", name)
+		}
+	}
+	step("undo", func() error {
+		_, err := handler.journal.Undo("canvas-1", func(entry JournalEntry) (JournalEntry, error) {
+			_, entry, err := handler.revertEntry(entry)
+			return entry, err
+		})
+		return err
+	})
+	mu.Lock()
+	if len(uploads) != 1 || uploads[0] != "PNGDATA" {
+		t.Fatalf("uploads after undo = %v, want the deleted image uploaded again", uploads)
+	}
+	mu.Unlock()
+
+	step("redo", func() error {
+		_, err := handler.journal.Redo("canvas-1", func(entry JournalEntry) (JournalEntry, error) {
+			_, entry, err := handler.replayEntry(entry)
+			return entry, err
+		})
+		return err
+	})
+	mu.Lock()
+	if len(deleted) != 2 || deleted[1] != "/images/new-1" {
+		t.Errorf("deleted after redo = %v, want the recreated image", deleted)
+	}
+	mu.Unlock()
+	if undo, redo := handler.journal.Len("canvas-1"); undo != 1 || redo != 0 {
+		t.Errorf("journal has %d undo and %d redo entries, want 1 and 0", undo, redo)
+	}
+}
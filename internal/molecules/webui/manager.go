@@ -2,6 +2,7 @@ package webui
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io/fs"
 	"net"
@@ -27,6 +28,8 @@ type Manager struct {
 	fileService       *services.FileService
 	iniParser         *config.INIParser
 	server            *http.Server
+	redirectServer    *http.Server
+	serverScheme      string
 	serverURL         *widget.Entry
 	serverSelect      *widget.Select
 	authToken         *widget.Entry
@@ -40,6 +43,11 @@ type Manager struct {
 	apiRoutes         *APIRoutes
 	tokenInstructions *fyne.Container
 	tokenLinkButton    *widget.Button
+	rolePINs          map[Role]*widget.Entry
+	clearRolePINs     bool
+	useHTTPS          *widget.Check
+	httpRedirectPort  *widget.Entry
+	tlsFingerprint    *widget.Label
 }
 
 type webUIConfiguration struct {
@@ -47,6 +55,30 @@ type webUIConfiguration struct {
 	AuthToken    string          `json:"auth_token"`
 	ServerPort   string          `json:"server_port"`
 	EnabledPages map[string]bool `json:"enabled_pages"`
+
+	// Optional Canvus API tuning; not exposed in the UI.
+	APIRateLimit   float64 `json:"api_rate_limit,omitempty"`   // Requests per second (0 = unlimited)
+	APIRateBurst   int     `json:"api_rate_burst,omitempty"`   // Token bucket size
+	APIMaxAttempts int     `json:"api_max_attempts,omitempty"` // Attempts per request including retries
+
+	MacroConcurrency int    `json:"macro_concurrency,omitempty"` // Parallel widget updates per macro
+	ZoneMembership   string `json:"zone_membership,omitempty"`   // point, contained, center or overlap[:ratio]
+	MaxUploadMB      int    `json:"max_upload_mb,omitempty"`     // Largest file uploaded through the WebUI (default 2048)
+
+	// Watch folder: files dropped into WatchFolder are placed in the anchor
+	// zone WatchZone (ID or name) of the tracked canvas.
+	WatchFolder string `json:"watch_folder,omitempty"`
+	WatchZone   string `json:"watch_zone,omitempty"`
+
+	// WebUI login: role -> PIN or password hashed with webuiatoms.HashSecret.
+	// Roles without one need no login. Plain text typed in here is hashed on save.
+	RolePINs map[string]string `json:"role_pins,omitempty"`
+
+	// HTTPS: a self-signed certificate is created unless both files are given.
+	TLSEnabled       bool   `json:"tls_enabled,omitempty"`
+	TLSCertFile      string `json:"tls_cert_file,omitempty"`      // PEM certificate (config file only)
+	TLSKeyFile       string `json:"tls_key_file,omitempty"`       // PEM key (config file only)
+	HTTPRedirectPort string `json:"http_redirect_port,omitempty"` // Plain HTTP port redirecting to HTTPS
 }
 
 // NewManager creates a new WebUI Manager.
@@ -56,6 +88,7 @@ func NewManager(fileService *services.FileService) (*Manager, error) {
 		iniParser:         config.NewINIParser(),
 		enabledPages:      make(map[string]*widget.Check),
 		suppressSelectAll: false,
+		rolePINs:          make(map[Role]*widget.Entry),
 	}, nil
 }
 
@@ -76,6 +109,8 @@ Enable Canvus PowerToys to act as a web server for remote access and control.
 - User Auth Token: Access token from Canvus server profile
 - WebUI Server Port: Port number for the local WebUI server (default: 8080)
 - Enabled Pages: Select which WebUI pages to enable
+- Login PINs: PIN or password per role; roles without one need no login
+- HTTPS: Serve the WebUI over HTTPS, optionally redirecting a plain HTTP port
 `)
 
 	// Server URL - Load server names from mt-canvus.ini
@@ -128,6 +163,41 @@ Enable Canvus PowerToys to act as a web server for remote access and control.
 		m.serverPort.SetText(savedConfig.ServerPort)
 	}
 
+	// Login PINs, stored hashed; entries stay empty and only set a new PIN
+	rolePINsLabel := widget.NewLabel("WebUI Login PINs:")
+	rolePINRows := []fyne.CanvasObject{rolePINsLabel}
+	for i := len(Roles) - 1; i >= 0; i-- {
+		role := Roles[i]
+		entry := widget.NewPasswordEntry()
+		m.rolePINs[role] = entry
+		rolePINRows = append(rolePINRows, container.NewGridWithColumns(2,
+			widget.NewLabel(strings.ToUpper(string(role[:1]))+string(role[1:])+":"), entry,
+		))
+	}
+	m.updateRolePINPlaceholders(savedConfig)
+	clearPINsBtn := widget.NewButton("Clear Login PINs", func() {
+		dialog.ShowConfirm("Clear Login PINs", "Remove every login PIN? The WebUI will be open to everyone.", func(ok bool) {
+			if !ok {
+				return
+			}
+			m.clearRolePINs = true
+			m.saveConfiguration(window)
+		}, window)
+	})
+	rolePINRows = append(rolePINRows, clearPINsBtn)
+
+	// HTTPS with a self-signed or user-supplied certificate
+	m.useHTTPS = widget.NewCheck("Serve over HTTPS", nil)
+	m.httpRedirectPort = widget.NewEntry()
+	m.httpRedirectPort.SetPlaceHolder("HTTP redirect port (optional, e.g. 8081)")
+	m.tlsFingerprint = widget.NewLabel("")
+	m.tlsFingerprint.Wrapping = fyne.TextWrapBreak
+	m.tlsFingerprint.Importance = widget.LowImportance
+	if savedConfig != nil {
+		m.useHTTPS.SetChecked(savedConfig.TLSEnabled)
+		m.httpRedirectPort.SetText(savedConfig.HTTPRedirectPort)
+	}
+
 	// Token instructions (dynamic, updates when server URL changes)
 	m.tokenInstructions = m.createTokenInstructions()
 
@@ -230,6 +300,13 @@ Enable Canvus PowerToys to act as a web server for remote access and control.
 		),
 		m.tokenInstructions,
 		widget.NewSeparator(),
+		container.NewVBox(rolePINRows...),
+		widget.NewSeparator(),
+		container.NewGridWithColumns(2,
+			m.useHTTPS, m.httpRedirectPort,
+		),
+		m.tlsFingerprint,
+		widget.NewSeparator(),
 		m.serverStatus,
 	)
 
@@ -461,6 +538,44 @@ func (m *Manager) startServer(window fyne.Window) {
 		return
 	}
 
+	// Login PINs were hashed by persistConfiguration
+	var rolePINs map[string]string
+	if saved := m.loadSavedConfiguration(); saved != nil {
+		rolePINs = saved.RolePINs
+	}
+	auth, err := NewAuthenticator(rolePINs)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("invalid login PINs: %w", err), window)
+		return
+	}
+
+	// HTTPS certificate and the optional plain HTTP port redirecting to it
+	var tlsConfig *tls.Config
+	redirectPort := ""
+	if saved := m.loadSavedConfiguration(); saved != nil && saved.TLSEnabled {
+		cert, err := LoadServerCertificate(saved.TLSCertFile, saved.TLSKeyFile, m.getTLSDir())
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to load HTTPS certificate: %w", err), window)
+			return
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		m.tlsFingerprint.SetText("Certificate SHA-256: " + webuiatoms.CertificateFingerprint(cert))
+
+		redirectPort = saved.HTTPRedirectPort
+		if redirectPort == port {
+			dialog.ShowError(fmt.Errorf("HTTP redirect port must differ from the server port %s", port), window)
+			return
+		}
+		if redirectPort != "" {
+			redirectListener, err := net.Listen("tcp", ":"+redirectPort)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("HTTP redirect port %s is already in use. Please choose a different port.", redirectPort), window)
+				return
+			}
+			redirectListener.Close()
+		}
+	}
+
 	// Normalize server URL
 	apiBaseURL := strings.TrimSuffix(serverURL, "/")
 	apiBaseURL = strings.TrimSuffix(apiBaseURL, "/api/v1")
@@ -491,9 +606,53 @@ func (m *Manager) startServer(window fyne.Window) {
 
 	// Create API client
 	apiClient := webuiatoms.NewAPIClient(apiBaseURL, authToken)
+	m.applyAPITuning(apiClient)
 
 	// Create API routes (uploadDir can be empty for now)
 	apiRoutes := NewAPIRoutes(canvasService, apiClient, "")
+	apiRoutes.SetAuthenticator(auth)
+	audit := NewAuditLog(m.getAuditLogPath())
+	audit.SetAuthenticator(auth)
+	apiRoutes.SetAuditLog(audit)
+	maxUploadSize := DefaultMaxUploadSize
+	var watchFolder *WatchFolder
+	if saved := m.loadSavedConfiguration(); saved != nil {
+		if saved.MaxUploadMB > 0 {
+			maxUploadSize = int64(saved.MaxUploadMB) << 20
+		}
+		if saved.WatchFolder != "" {
+			if saved.WatchZone == "" {
+				fmt.Printf("[WebUI] Ignoring watch_folder setting: watch_zone is not set\n")
+			} else {
+				watchFolder = NewWatchFolder(apiClient, canvasService, saved.WatchFolder, saved.WatchZone)
+			}
+		}
+		if saved.MacroConcurrency > 0 {
+			apiRoutes.macrosHandler.SetBatchConcurrency(saved.MacroConcurrency)
+		}
+		if membership, err := webuiatoms.ParseZoneMembership(saved.ZoneMembership); err != nil {
+			fmt.Printf("[WebUI] Ignoring zone_membership setting: %v\n", err)
+		} else {
+			apiRoutes.macrosHandler.SetZoneMembership(membership)
+		}
+	}
+	if journalPath := m.getMacroJournalPath(); journalPath != "" {
+		apiRoutes.macrosHandler.SetJournal(NewMacroJournal(journalPath))
+	}
+	if historyPath := m.getUploadHistoryPath(); historyPath != "" {
+		apiRoutes.uploadHandler.SetHistory(NewUploadHistory(historyPath))
+	}
+	apiRoutes.rcuHandler.SetSession(NewRCUSessionStore(m.getRCUSessionPath()))
+	apiRoutes.SetUploadStaging(NewUploadStaging(m.getUploadStagingDir(), maxUploadSize))
+	apiRoutes.SetWatchFolder(watchFolder)
+	if snapshotDir := m.getSnapshotDir(); snapshotDir != "" {
+		apiRoutes.snapshotHandler.SetStore(NewSnapshotStore(snapshotDir))
+	}
+	if macrosDir := m.getMacrosDir(); macrosDir != "" {
+		apiRoutes.macrosHandler.SetLibrary(NewMacroLibrary(macrosDir))
+	}
+	scheduler := NewMacroScheduler(apiRoutes.macrosHandler, m.getMacroJobsPath())
+	apiRoutes.jobsHandler.SetScheduler(scheduler)
 
 	// Try to start canvas service, but don't fail if it doesn't work
 	// User can override client selection in WebUI
@@ -503,6 +662,12 @@ func (m *Manager) startServer(window fyne.Window) {
 		// The WebUI will load and user can manually override
 	}
 
+	// Start scheduled macros once the canvas service is tracking a canvas
+	scheduler.Start()
+	if watchFolder != nil {
+		watchFolder.Start()
+	}
+
 	// Store references
 	m.canvasService = canvasService
 	m.apiRoutes = apiRoutes
@@ -559,15 +724,27 @@ func (m *Manager) startServer(window fyne.Window) {
 
 	m.server = &http.Server{
 		Addr:         ":" + port,
-		Handler:      mux,
+		Handler:      audit.Audit(auth.Protect(mux)),
+		TLSConfig:    tlsConfig,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	m.serverScheme = "http"
+	if tlsConfig != nil {
+		m.serverScheme = "https"
+	}
 
 	// Start server in goroutine
+	server := m.server
 	go func() {
-		if err := m.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			fmt.Printf("Server error: %v\n", err)
 			// Don't update UI from goroutine - port check already happens before starting
 			// If we get here, it's an unexpected error - just log it
@@ -576,11 +753,28 @@ func (m *Manager) startServer(window fyne.Window) {
 		}
 	}()
 
+	// Plain HTTP port sending browsers to HTTPS
+	if tlsConfig != nil && redirectPort != "" {
+		m.redirectServer = &http.Server{
+			Addr:         ":" + redirectPort,
+			Handler:      RedirectToHTTPS(port),
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+		redirectServer := m.redirectServer
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Printf("HTTP redirect server error: %v\n", err)
+			}
+		}()
+	}
+
 	// Give server a moment to start
 	time.Sleep(100 * time.Millisecond)
 
 	// Update UI
-	serverURLStr := fmt.Sprintf("http://localhost:%s", port)
+	serverURLStr := fmt.Sprintf("%s://localhost:%s", m.serverScheme, port)
 	m.serverStatus.SetText(fmt.Sprintf("Server: Running on %s", serverURLStr))
 	m.serverStatus.Importance = widget.SuccessImportance
 	m.startStopBtn.SetText("Stop Server")
@@ -596,7 +790,19 @@ func (m *Manager) stopServer() {
 		return
 	}
 
-	// Stop canvas service first to stop workspace subscriptions
+	// Stop scheduled macros and the watch folder before the canvas service
+	// they run against
+	if m.apiRoutes != nil {
+		m.apiRoutes.jobsHandler.scheduler.Stop()
+		if m.apiRoutes.watchFolder != nil {
+			m.apiRoutes.watchFolder.Stop()
+		}
+	}
+
+	// Stop canvas services first to stop workspace subscriptions
+	if m.apiRoutes != nil {
+		m.apiRoutes.clients.Stop()
+	}
 	if m.canvasService != nil {
 		m.canvasService.Stop()
 		m.canvasService = nil
@@ -607,6 +813,11 @@ func (m *Manager) stopServer() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if m.redirectServer != nil {
+		m.redirectServer.Shutdown(ctx)
+		m.redirectServer = nil
+	}
+
 	if err := m.server.Shutdown(ctx); err != nil {
 		// Log error but don't fail - server will still stop
 		if err == context.DeadlineExceeded {
@@ -644,13 +855,19 @@ func (m *Manager) performConnectionTests(port, serverURL, authToken string) (str
 		localTestResult = fmt.Sprintf("❌ Local WebUI server is not running\n   Please start the server first.")
 		localTestSuccess = false
 	} else {
-		localTestURL := fmt.Sprintf("http://localhost:%s/health", port)
+		localTestURL := fmt.Sprintf("%s://localhost:%s/health", m.serverScheme, port)
 		req, err := http.NewRequest("GET", localTestURL, nil)
 		if err != nil {
 			localTestResult = fmt.Sprintf("❌ Failed to create request: %v\n   URL: %s", err, localTestURL)
 			localTestSuccess = false
 		} else {
-			resp, err := client.Do(req)
+			// The local server may use a self-signed certificate; this only
+			// checks that it answers
+			localClient := &http.Client{
+				Timeout:   client.Timeout,
+				Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+			}
+			resp, err := localClient.Do(req)
 			if err != nil {
 				localTestResult = fmt.Sprintf("❌ Cannot connect to local server on port %s\n   Error: %v\n   URL: %s\n   Make sure the server is running and the port is correct.", port, err, localTestURL)
 				localTestSuccess = false
@@ -762,6 +979,69 @@ func (m *Manager) getWebUIConfigPath() string {
 	return filepath.Join(m.fileService.GetUserConfigPath(), "CanvusPowerToys", "webui_config.json")
 }
 
+func (m *Manager) getMacroJournalPath() string {
+	if m.fileService == nil {
+		return ""
+	}
+	return filepath.Join(m.fileService.GetUserConfigPath(), "CanvusPowerToys", "macros_journal.json")
+}
+
+func (m *Manager) getUploadHistoryPath() string {
+	if m.fileService == nil {
+		return ""
+	}
+	return filepath.Join(m.fileService.GetUserConfigPath(), "CanvusPowerToys", "upload_history.json")
+}
+
+func (m *Manager) getRCUSessionPath() string {
+	if m.fileService == nil {
+		return ""
+	}
+	return filepath.Join(m.fileService.GetUserConfigPath(), "CanvusPowerToys", "rcu_session.json")
+}
+
+func (m *Manager) getUploadStagingDir() string {
+	if m.fileService == nil {
+		return ""
+	}
+	return filepath.Join(m.fileService.GetUserConfigPath(), "CanvusPowerToys", "upload_staging")
+}
+
+func (m *Manager) getAuditLogPath() string {
+	if m.fileService == nil {
+		return ""
+	}
+	return filepath.Join(m.fileService.GetUserConfigPath(), "CanvusPowerToys", "audit", "audit.jsonl")
+}
+
+func (m *Manager) getTLSDir() string {
+	if m.fileService == nil {
+		return ""
+	}
+	return filepath.Join(m.fileService.GetUserConfigPath(), "CanvusPowerToys", "webui_tls")
+}
+
+func (m *Manager) getSnapshotDir() string {
+	if m.fileService == nil {
+		return ""
+	}
+	return filepath.Join(m.fileService.GetUserConfigPath(), "CanvusPowerToys", "snapshots")
+}
+
+func (m *Manager) getMacroJobsPath() string {
+	if m.fileService == nil {
+		return ""
+	}
+	return filepath.Join(m.fileService.GetUserConfigPath(), "CanvusPowerToys", "macro_jobs.json")
+}
+
+func (m *Manager) getMacrosDir() string {
+	if m.fileService == nil {
+		return ""
+	}
+	return filepath.Join(m.fileService.GetUserConfigPath(), "CanvusPowerToys", "macros")
+}
+
 func (m *Manager) loadSavedConfiguration() *webUIConfiguration {
 	configPath := m.getWebUIConfigPath()
 	if configPath == "" {
@@ -827,7 +1107,118 @@ func (m *Manager) persistConfiguration() error {
 		}
 	}
 
-	return m.fileService.WriteJSONFile(configPath, cfg)
+	// Preserve settings that are only editable in the config file
+	saved := m.loadSavedConfiguration()
+	if saved != nil {
+		cfg.APIRateLimit = saved.APIRateLimit
+		cfg.APIRateBurst = saved.APIRateBurst
+		cfg.APIMaxAttempts = saved.APIMaxAttempts
+		cfg.MacroConcurrency = saved.MacroConcurrency
+		cfg.ZoneMembership = saved.ZoneMembership
+		cfg.MaxUploadMB = saved.MaxUploadMB
+		cfg.WatchFolder = saved.WatchFolder
+		cfg.WatchZone = saved.WatchZone
+		if !m.clearRolePINs {
+			cfg.RolePINs = saved.RolePINs
+		}
+		cfg.TLSCertFile = saved.TLSCertFile
+		cfg.TLSKeyFile = saved.TLSKeyFile
+	}
+	if m.useHTTPS != nil {
+		cfg.TLSEnabled = m.useHTTPS.Checked
+		cfg.HTTPRedirectPort = strings.TrimSpace(m.httpRedirectPort.Text)
+	} else if saved != nil {
+		cfg.TLSEnabled = saved.TLSEnabled
+		cfg.HTTPRedirectPort = saved.HTTPRedirectPort
+	}
+
+	rolePINs, err := m.hashRolePINs(cfg.RolePINs)
+	if err != nil {
+		return err
+	}
+	cfg.RolePINs = rolePINs
+
+	if err := m.fileService.WriteJSONFile(configPath, cfg); err != nil {
+		return err
+	}
+
+	// New PINs are stored; clear the entries and apply them to a running server
+	m.clearRolePINs = false
+	for _, entry := range m.rolePINs {
+		entry.SetText("")
+	}
+	m.updateRolePINPlaceholders(cfg)
+	if m.apiRoutes != nil && m.apiRoutes.auth != nil {
+		if err := m.apiRoutes.auth.SetSecrets(cfg.RolePINs); err != nil {
+			return fmt.Errorf("failed to apply login PINs: %w", err)
+		}
+	}
+	return nil
+}
+
+// hashRolePINs returns the saved role PINs with the PINs typed into the role
+// entries, and any plain text typed into the config file, hashed.
+func (m *Manager) hashRolePINs(saved map[string]string) (map[string]string, error) {
+	rolePINs := make(map[string]string)
+	for role, pin := range saved {
+		if pin == "" {
+			continue
+		}
+		if !webuiatoms.IsHashedSecret(pin) {
+			hash, err := webuiatoms.HashSecret(pin)
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash %s PIN: %w", role, err)
+			}
+			pin = hash
+		}
+		rolePINs[role] = pin
+	}
+	for role, entry := range m.rolePINs {
+		if entry.Text == "" {
+			continue
+		}
+		hash, err := webuiatoms.HashSecret(entry.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s PIN: %w", role, err)
+		}
+		rolePINs[string(role)] = hash
+	}
+	if len(rolePINs) == 0 {
+		return nil, nil
+	}
+	return rolePINs, nil
+}
+
+// updateRolePINPlaceholders shows which roles have a login PIN.
+func (m *Manager) updateRolePINPlaceholders(cfg *webUIConfiguration) {
+	for role, entry := range m.rolePINs {
+		if cfg != nil && cfg.RolePINs[string(role)] != "" {
+			entry.SetPlaceHolder("PIN set - type to change")
+		} else {
+			entry.SetPlaceHolder("No PIN - open to everyone")
+		}
+	}
+}
+
+// applyAPITuning applies the saved retry and rate limit settings to apiClient.
+func (m *Manager) applyAPITuning(apiClient *webuiatoms.APIClient) {
+	saved := m.loadSavedConfiguration()
+	if saved == nil {
+		return
+	}
+	if saved.APIMaxAttempts > 0 {
+		policy := webuiatoms.DefaultRetryPolicy()
+		policy.MaxAttempts = saved.APIMaxAttempts
+		apiClient.SetRetryPolicy(policy)
+	}
+	if saved.APIRateLimit > 0 {
+		burst := saved.APIRateBurst
+		if burst <= 0 {
+			burst = int(saved.APIRateLimit)
+		}
+		apiClient.SetRateLimit(saved.APIRateLimit, burst)
+		fmt.Printf("[WebUI] Canvus API rate limit: %.1f req/s (burst %d)\n", saved.APIRateLimit, burst)
+	}
 }
 
 func (m *Manager) syncSelectAllFromChecks() {
@@ -29,7 +29,7 @@ func (h *PagesHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
@@ -72,7 +72,7 @@ func (h *PagesHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
@@ -103,7 +103,7 @@ func (h *PagesHandler) HandleGetZones(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		// Return empty zones array with success=true when canvas not available (graceful degradation)
 		response := map[string]interface{}{
@@ -27,7 +27,7 @@ func (h *PagesHandler) HandleCreateZones(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
@@ -82,7 +82,7 @@ func (h *PagesHandler) HandleDeleteZones(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
@@ -1,13 +1,12 @@
 package webui
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
@@ -22,6 +21,10 @@ type RCUHandler struct {
 	fileService   *services.FileService
 	usersPath     string
 	usersMutex    sync.RWMutex
+	events        *EventBus
+	staging       *UploadStaging
+	pipeline      *UploadPipeline
+	session       *RCUSessionStore
 }
 
 // NewRCUHandler creates a new RCU handler.
@@ -38,12 +41,50 @@ func NewRCUHandler(apiClient *webuiatoms.APIClient, canvasService *CanvasService
 		canvasService: canvasService,
 		fileService:   fileService,
 		usersPath:     usersPath,
+		staging:       NewUploadStaging("", DefaultMaxUploadSize),
+		pipeline:      NewUploadPipeline(),
+		session:       NewRCUSessionStore(""),
 	}
 }
 
+// SetEventBus sets where RCU submissions are published.
+func (h *RCUHandler) SetEventBus(events *EventBus) {
+	h.events = events
+}
+
+// SetStaging sets where resumable uploads are received; its size limit
+// applies to every upload.
+func (h *RCUHandler) SetStaging(staging *UploadStaging) {
+	h.staging = staging
+}
+
+// SetPipeline sets how the type of uploaded items is sniffed and converted.
+func (h *RCUHandler) SetPipeline(pipeline *UploadPipeline) {
+	h.pipeline = pipeline
+}
+
+// SetSession sets the store of the teams of the RCU session.
+func (h *RCUHandler) SetSession(session *RCUSessionStore) {
+	h.session = session
+}
+
+// HandleTeams handles GET /rcu-teams - List the teams of the RCU session with
+// the IDs requests refer to them by.
+func (h *RCUHandler) HandleTeams(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"teams":   h.session.Get().Teams,
+	}, http.StatusOK)
+}
+
 // HandleConfig handles GET/POST /api/rcu/config - Get/Set RCU configuration.
 func (h *RCUHandler) HandleConfig(w http.ResponseWriter, r *http.Request) {
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 
 	switch r.Method {
 	case http.MethodGet:
@@ -117,7 +158,7 @@ func (h *RCUHandler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
@@ -152,7 +193,7 @@ func (h *RCUHandler) HandleTest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
@@ -195,8 +236,9 @@ func (h *RCUHandler) HandleIdentifyUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if req.Team < 1 || req.Team > 7 {
-		sendErrorResponse(w, "Team must be between 1 and 7", http.StatusBadRequest)
+	team, err := h.session.Team(req.Team)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -205,17 +247,6 @@ func (h *RCUHandler) HandleIdentifyUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Team base colors (ROYGBIV)
-	teamColors := map[int]string{
-		1: "#FF0000FF", // Red
-		2: "#FF7F00FF", // Orange
-		3: "#FFFF00FF", // Yellow
-		4: "#00FF00FF", // Green
-		5: "#0000FFFF", // Blue
-		6: "#4B0082FF", // Indigo
-		7: "#8B00FFFF", // Violet
-	}
-
 	// Load users from persistent storage
 	users := h.loadUsers()
 	teamKey := fmt.Sprintf("%d", req.Team)
@@ -230,23 +261,24 @@ func (h *RCUHandler) HandleIdentifyUser(w http.ResponseWriter, r *http.Request)
 	if existingColor, ok := teamUsers[req.Name].(string); ok {
 		// User exists - return stored color
 		sendJSONResponse(w, map[string]interface{}{
-			"success": true,
-			"color":   existingColor,
+			"success":   true,
+			"color":     existingColor,
+			"team_name": team.Name,
 		}, http.StatusOK)
 		return
 	}
 
 	// New user - generate color variation in team color range
-	baseColor := teamColors[req.Team]
-	userColor := generateColorVariationHSL(baseColor, req.Name)
+	userColor := generateColorVariationHSL(team.Color, req.Name)
 
 	// Store user
 	teamUsers[req.Name] = userColor
 	h.saveUsers(users)
 
 	sendJSONResponse(w, map[string]interface{}{
-		"success": true,
-		"color":   userColor,
+		"success":   true,
+		"color":     userColor,
+		"team_name": team.Name,
 	}, http.StatusOK)
 }
 
@@ -269,8 +301,8 @@ func (h *RCUHandler) HandleCreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Team < 1 || req.Team > 7 {
-		sendErrorResponse(w, "Team must be between 1 and 7", http.StatusBadRequest)
+	if _, err := h.session.Team(req.Team); err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -279,7 +311,7 @@ func (h *RCUHandler) HandleCreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
@@ -357,6 +389,13 @@ func (h *RCUHandler) HandleCreateNote(w http.ResponseWriter, r *http.Request) {
 		sendErrorResponse(w, fmt.Sprintf("Failed to create note: %v", err), http.StatusInternalServerError)
 		return
 	}
+	h.events.Publish(TopicRCU, "rcu_note_created", map[string]interface{}{
+		"client_id": requestClientID(r, h.canvasService),
+		"canvas_id": canvasID,
+		"team":      req.Team,
+		"name":      req.Name,
+		"title":     title,
+	})
 
 	sendJSONResponse(w, map[string]interface{}{
 		"success": true,
@@ -365,42 +404,62 @@ func (h *RCUHandler) HandleCreateNote(w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleUploadItem handles POST /upload-item - Upload file and create widget.
+// The file is either the file part of the form or, for large files, the id
+// of a complete upload from /api/uploads in staged_id.
 func (h *RCUHandler) HandleUploadItem(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Parse multipart form (32MB max)
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		sendErrorResponse(w, "Failed to parse form", http.StatusBadRequest)
+	extendDeadlines(w, uploadRequestTimeout)
+	form, ok := parseUploadForm(w, r, h.staging.MaxSize())
+	if !ok {
 		return
 	}
+	defer form.RemoveAll()
 
 	teamStr := r.FormValue("team")
 	name := r.FormValue("name")
-	file, fileHeader, err := r.FormFile("file")
-	if err != nil {
-		sendErrorResponse(w, "No file provided", http.StatusBadRequest)
-		return
+	var file io.ReadSeekCloser
+	var fileName string
+	stagedID := r.FormValue("staged_id")
+	if stagedID != "" {
+		staged, upload, err := h.staging.Open(stagedID)
+		if err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, fileName = staged, upload.Filename
+	} else {
+		formFile, fileHeader, err := r.FormFile("file")
+		if err != nil {
+			sendErrorResponse(w, "No file provided", http.StatusBadRequest)
+			return
+		}
+		file, fileName = formFile, fileHeader.Filename
 	}
 	defer file.Close()
 
-	// Read file data into memory (needed for multipart upload)
-	fileData, err := io.ReadAll(file)
-	if err != nil {
-		sendErrorResponse(w, "Failed to read file", http.StatusInternalServerError)
+	team, _ := parseInt(teamStr) // 0, an invalid team, when not a number
+	if _, err := h.session.Team(team); err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	fileReader := bytes.NewReader(fileData)
 
-	team, err := parseInt(teamStr)
-	if err != nil || team < 1 || team > 7 {
-		sendErrorResponse(w, "Team must be between 1 and 7", http.StatusBadRequest)
+	// Sniff the type before looking for the target, converting if needed
+	prepared, err := h.pipeline.Prepare(file, filepath.Base(fileName))
+	if err != nil {
+		var unsupported *UnsupportedUploadError
+		if errors.As(err, &unsupported) {
+			sendErrorResponse(w, err.Error(), http.StatusUnsupportedMediaType)
+		} else {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		}
 		return
 	}
 
-	canvasID := h.canvasService.GetCanvasID()
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
@@ -450,40 +509,38 @@ func (h *RCUHandler) HandleUploadItem(w http.ResponseWriter, r *http.Request) {
 		"y": getFloat(location, "y") + 100,
 	}
 
-	// Determine widget type and endpoint based on file extension
-	// Note: Use type-specific endpoints, not /widgets (widgets is read-only)
-	fileName := fileHeader.Filename
-	ext := getFileExtension(fileName)
-
 	// Format title as "Name @ date{yy/mm/dd} - time{HH:MM}"
 	now := time.Now()
 	dateStr := now.Format("06/01/02") // yy/mm/dd
 	timeStr := now.Format("15:04")   // HH:MM
 	title := fmt.Sprintf("%s @ %s - %s", name, dateStr, timeStr)
 
-	var endpoint string
 	jsonPayload := map[string]interface{}{
 		"title":    title,
 		"location": fileLocation,
 	}
 
-	if isImageFile(ext) {
-		endpoint = fmt.Sprintf("/api/v1/canvases/%s/images", canvasID)
-	} else if isVideoFile(ext) {
-		endpoint = fmt.Sprintf("/api/v1/canvases/%s/videos", canvasID)
-	} else {
-		// PDF or other file type
-		endpoint = fmt.Sprintf("/api/v1/canvases/%s/pdfs", canvasID)
-	}
-
-	// Upload file using multipart/form-data
-	// Canvus API expects: json (metadata) and data (file binary)
-	_, err = h.apiClient.PostMultipart(endpoint, jsonPayload, fileReader, fileName)
+	// Create the widget of the sniffed type; files are streamed from the
+	// form or staging
+	// Note: Use type-specific endpoints, not /widgets (widgets is read-only)
+	_, err = placeUpload(r.Context(), h.apiClient, canvasID, prepared, jsonPayload)
 	if err != nil {
 		fmt.Printf("[RCUHandler] ERROR: Failed to upload file: %v\n", err)
 		sendErrorResponse(w, fmt.Sprintf("Failed to upload file: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if stagedID != "" {
+		h.staging.Remove(stagedID)
+	}
+	h.events.Publish(TopicRCU, "rcu_item_uploaded", map[string]interface{}{
+		"client_id":   requestClientID(r, h.canvasService),
+		"canvas_id":   canvasID,
+		"team":        team,
+		"name":        name,
+		"file":        fileName,
+		"title":       title,
+		"widget_type": prepared.Kind,
+	})
 
 	sendJSONResponse(w, map[string]interface{}{
 		"success": true,
@@ -712,31 +769,3 @@ func parseInt(s string) (int, error) {
 	return result, nil
 }
 
-func getFileExtension(filename string) string {
-	parts := strings.Split(filename, ".")
-	if len(parts) < 2 {
-		return ""
-	}
-	return strings.ToLower(parts[len(parts)-1])
-}
-
-func isImageFile(ext string) bool {
-	imageExts := []string{"jpg", "jpeg", "png", "gif", "bmp", "tiff", "webp"}
-	for _, e := range imageExts {
-		if ext == e {
-			return true
-		}
-	}
-	return false
-}
-
-func isVideoFile(ext string) bool {
-	videoExts := []string{"mp4", "avi", "mov", "wmv", "mkv", "webm"}
-	for _, e := range videoExts {
-		if ext == e {
-			return true
-		}
-	}
-	return false
-}
-
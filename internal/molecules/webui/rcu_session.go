@@ -0,0 +1,214 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// MaxRCUTeams is the most teams an RCU session may have.
+const MaxRCUTeams = 32
+
+// RCUTeam is a team of an RCU session. Users, requests and target notes
+// (Team_<id>_Target) refer to teams by ID, which a team keeps while teams
+// are reordered, added or removed, and which is never given to another team.
+type RCUTeam struct {
+	ID          int    `json:"id"` // 0 for a team to be added
+	Name        string `json:"name"`
+	Color       string `json:"color"`        // #RRGGBBAA
+	AnchorIndex int    `json:"anchor_index"` // anchor_index of the zone the team's target is created in
+}
+
+// RCUSession is the team setup shared by every RCU endpoint.
+type RCUSession struct {
+	Teams  []RCUTeam `json:"teams"`
+	NextID int       `json:"next_id"` // ID the next team added gets
+}
+
+// hexColor matches #RRGGBB and #RRGGBBAA colors.
+var hexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}([0-9A-Fa-f]{2})?$`)
+
+// DefaultRCUSession returns the classic session: seven ROYGBIV teams, with
+// IDs 1-7, with their targets in the zones with anchor_index 1-7.
+func DefaultRCUSession() RCUSession {
+	colors := []string{
+		"#FF0000FF", // Red
+		"#FF7F00FF", // Orange
+		"#FFFF00FF", // Yellow
+		"#00FF00FF", // Green
+		"#0000FFFF", // Blue
+		"#4B0082FF", // Indigo
+		"#8B00FFFF", // Violet
+	}
+	session := RCUSession{}
+	for i, color := range colors {
+		session.Teams = append(session.Teams, RCUTeam{
+			ID:          i + 1,
+			Name:        fmt.Sprintf("Team %d", i+1),
+			Color:       color,
+			AnchorIndex: i + 1,
+		})
+	}
+	session.NextID = len(colors) + 1
+	return session
+}
+
+// assignIDs gives the teams without an ID the next unused ones. Sessions
+// saved before teams had IDs get 1, 2, ... in order, the numbers their users
+// and targets already refer to.
+func (s *RCUSession) assignIDs() {
+	for _, team := range s.Teams {
+		if team.ID >= s.NextID {
+			s.NextID = team.ID + 1
+		}
+	}
+	for i := range s.Teams {
+		if s.Teams[i].ID == 0 {
+			s.Teams[i].ID = s.NextID
+			s.NextID++
+		}
+	}
+}
+
+// Validate checks the session and normalizes its names and colors.
+func (s *RCUSession) Validate() error {
+	if len(s.Teams) == 0 {
+		return fmt.Errorf("at least one team is required")
+	}
+	if len(s.Teams) > MaxRCUTeams {
+		return fmt.Errorf("at most %d teams are supported", MaxRCUTeams)
+	}
+	anchors := make(map[int]int)
+	ids := make(map[int]bool)
+	for i := range s.Teams {
+		team := &s.Teams[i]
+		if team.ID < 1 {
+			return fmt.Errorf("team %d: id must be positive", i+1)
+		}
+		if ids[team.ID] {
+			return fmt.Errorf("team %d: id %d is used by another team", i+1, team.ID)
+		}
+		ids[team.ID] = true
+		team.Name = strings.TrimSpace(team.Name)
+		if team.Name == "" {
+			return fmt.Errorf("team %d: name is required", i+1)
+		}
+		if !hexColor.MatchString(team.Color) {
+			return fmt.Errorf("team %d: color %q is not #RRGGBB or #RRGGBBAA", i+1, team.Color)
+		}
+		team.Color = strings.ToUpper(team.Color)
+		if len(team.Color) == 7 {
+			team.Color += "FF"
+		}
+		if team.AnchorIndex < 1 {
+			return fmt.Errorf("team %d: anchor_index must be at least 1", i+1)
+		}
+		if other, ok := anchors[team.AnchorIndex]; ok {
+			return fmt.Errorf("teams %d and %d: both have their target in anchor_index %d", other, i+1, team.AnchorIndex)
+		}
+		anchors[team.AnchorIndex] = i + 1
+	}
+	return nil
+}
+
+// RCUSessionStore holds the RCU session. When created with a path it is
+// persisted as JSON after every change.
+type RCUSessionStore struct {
+	mu      sync.RWMutex
+	path    string
+	session RCUSession
+}
+
+// NewRCUSessionStore creates a store persisted at path, loading any saved
+// session. Without one (or with an empty path) it holds the default
+// session.
+func NewRCUSessionStore(path string) *RCUSessionStore {
+	s := &RCUSessionStore{path: path, session: DefaultRCUSession()}
+	if path == "" {
+		return s
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("[RCUSession] Failed to read %s: %v\n", path, err)
+		}
+		return s
+	}
+	var session RCUSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		fmt.Printf("[RCUSession] Failed to parse %s, using the default teams: %v\n", path, err)
+		return s
+	}
+	session.assignIDs()
+	if err := session.Validate(); err != nil {
+		fmt.Printf("[RCUSession] Invalid session in %s, using the default teams: %v\n", path, err)
+		return s
+	}
+	s.session = session
+	fmt.Printf("[RCUSession] Loaded %d teams from %s\n", len(session.Teams), path)
+	return s
+}
+
+// Get returns the session.
+func (s *RCUSessionStore) Get() RCUSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return RCUSession{Teams: append([]RCUTeam(nil), s.session.Teams...), NextID: s.session.NextID}
+}
+
+// Set validates and saves a session. Teams keep the ID they have in the
+// current session; teams without one are added with a new ID. When the
+// session cannot be written the current one is kept.
+func (s *RCUSessionStore) Set(session RCUSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	known := make(map[int]bool)
+	for _, team := range s.session.Teams {
+		known[team.ID] = true
+	}
+	session = RCUSession{Teams: append([]RCUTeam(nil), session.Teams...), NextID: s.session.NextID}
+	for i, team := range session.Teams {
+		if team.ID != 0 && !known[team.ID] {
+			return fmt.Errorf("team %d: id %d is not a team of the session", i+1, team.ID)
+		}
+	}
+	session.assignIDs()
+	if err := session.Validate(); err != nil {
+		return err
+	}
+	if s.path == "" {
+		s.session = session
+		return nil
+	}
+
+	// Only a session that was written replaces the current one
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	s.session = session
+	return nil
+}
+
+// Team returns the team with ID id, or an error when the session has none.
+func (s *RCUSessionStore) Team(id int) (RCUTeam, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, team := range s.session.Teams {
+		if team.ID == id {
+			return team, nil
+		}
+	}
+	return RCUTeam{}, fmt.Errorf("Team %d is not a team of the session", id)
+}
@@ -0,0 +1,200 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRCUSession_Validate(t *testing.T) {
+	session := RCUSession{Teams: []RCUTeam{
+		{ID: 4, Name: "  Blue Whales ", Color: "#0000ff", AnchorIndex: 3},
+		{ID: 2, Name: "Red Pandas", Color: "#FF000080", AnchorIndex: 1},
+	}}
+	if err := session.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if team := session.Teams[0]; team.Name != "Blue Whales" || team.Color != "#0000FFFF" {
+		t.Errorf("team 1 = %+v, want its name trimmed and color #0000FFFF", team)
+	}
+	if team := session.Teams[1]; team.Color != "#FF000080" {
+		t.Errorf("team 2 color = %q, want the alpha kept", team.Color)
+	}
+
+	tests := []struct {
+		name  string
+		teams []RCUTeam
+		want  string
+	}{
+		{"no teams", nil, "at least one team"},
+		{"blank name", []RCUTeam{{ID: 1, Name: " ", Color: "#000000", AnchorIndex: 1}}, "name is required"},
+		{"named color", []RCUTeam{{ID: 1, Name: "A", Color: "red", AnchorIndex: 1}}, "is not #RRGGBB"},
+		{"anchor 0", []RCUTeam{{ID: 1, Name: "A", Color: "#000000", AnchorIndex: 0}}, "must be at least 1"},
+		{"negative anchor", []RCUTeam{{ID: 1, Name: "A", Color: "#000000", AnchorIndex: -1}}, "must be at least 1"},
+		{"no id", []RCUTeam{{Name: "A", Color: "#000000", AnchorIndex: 1}}, "id must be positive"},
+		{"shared id", []RCUTeam{
+			{ID: 3, Name: "A", Color: "#000000", AnchorIndex: 1},
+			{ID: 3, Name: "B", Color: "#FFFFFF", AnchorIndex: 2},
+		}, "id 3 is used"},
+		{"shared anchor", []RCUTeam{
+			{ID: 1, Name: "A", Color: "#000000", AnchorIndex: 2},
+			{ID: 2, Name: "B", Color: "#FFFFFF", AnchorIndex: 2},
+		}, "teams 1 and 2"},
+		{"too many", make([]RCUTeam, MaxRCUTeams+1), "at most"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := RCUSession{Teams: tt.teams}
+			if err := session.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRCUSessionStore_PersistsSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CanvusPowerToys", "rcu_session.json")
+	store := NewRCUSessionStore(path)
+	if got := len(store.Get().Teams); got != 7 {
+		t.Fatalf("new store has %d teams, want the 7 default teams", got)
+	}
+
+	session := RCUSession{Teams: []RCUTeam{
+		{Name: "North", Color: "#112233", AnchorIndex: 4},
+		{Name: "South", Color: "#445566", AnchorIndex: 5},
+		{Name: "East", Color: "#778899", AnchorIndex: 6},
+	}}
+	if err := store.Set(session); err != nil {
+		t.Fatalf("Set() = %v", err)
+	}
+	if err := store.Set(RCUSession{}); err == nil {
+		t.Error("Set() accepted a session without teams")
+	}
+
+	// The teams replace the 7 defaults as teams 8-10
+	reloaded := NewRCUSessionStore(path)
+	team, err := reloaded.Team(10)
+	if err != nil || team.Name != "East" || team.Color != "#778899FF" || team.AnchorIndex != 6 {
+		t.Errorf("Team(10) = %+v, %v after reload", team, err)
+	}
+	for _, id := range []int{0, 3, 11} {
+		if _, err := reloaded.Team(id); err == nil || err.Error() != fmt.Sprintf("Team %d is not a team of the session", id) {
+			t.Errorf("Team(%d) error = %v", id, err)
+		}
+	}
+}
+
+// TestRCUSessionStore_KeepsSessionWhenWriteFails checks that a session that
+// cannot be saved does not replace the current one.
+func TestRCUSessionStore_KeepsSessionWhenWriteFails(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rcu_session.json")
+	store := NewRCUSessionStore(path)
+	// A directory in place of the file makes every write fail
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	teams := append(store.Get().Teams, RCUTeam{Name: "Newcomers", Color: "#123456", AnchorIndex: 8})
+	if err := store.Set(RCUSession{Teams: teams}); err == nil {
+		t.Fatal("Set() succeeded without writing the session")
+	}
+	if session := store.Get(); len(session.Teams) != 7 || session.NextID != 8 {
+		t.Errorf("session = %d teams, next ID %d after a failed write, want the 7 defaults and 8", len(session.Teams), session.NextID)
+	}
+	if _, err := store.Team(8); err == nil {
+		t.Error("team of the unsaved session found")
+	}
+}
+
+// TestRCUSessionStore_KeepsTeamIDs checks that reordering and removing teams
+// leaves the IDs users and targets refer to with their teams, and that the
+// ID of a removed team is not given to a new one.
+func TestRCUSessionStore_KeepsTeamIDs(t *testing.T) {
+	store := NewRCUSessionStore("")
+	teams := store.Get().Teams
+
+	// Team 1 is removed, team 7 moved first, and a team added
+	reordered := append([]RCUTeam{teams[6]}, teams[1:6]...)
+	reordered = append(reordered, RCUTeam{Name: "Newcomers", Color: "#123456", AnchorIndex: 8})
+	if err := store.Set(RCUSession{Teams: reordered}); err != nil {
+		t.Fatalf("Set() = %v", err)
+	}
+	if team, err := store.Team(7); err != nil || team.Name != "Team 7" || team.AnchorIndex != 7 {
+		t.Errorf("Team(7) = %+v, %v after it moved first", team, err)
+	}
+	if team, err := store.Team(8); err != nil || team.Name != "Newcomers" {
+		t.Errorf("Team(8) = %+v, %v, want the added team", team, err)
+	}
+	if _, err := store.Team(1); err == nil {
+		t.Error("removed team 1 still found")
+	}
+
+	// A team added later does not take the removed ID 1 or the ID of team 8
+	added := append(store.Get().Teams, RCUTeam{Name: "Latecomers", Color: "#654321", AnchorIndex: 9})
+	if err := store.Set(RCUSession{Teams: added}); err != nil {
+		t.Fatalf("Set() = %v", err)
+	}
+	if team, err := store.Team(9); err != nil || team.Name != "Latecomers" {
+		t.Errorf("Team(9) = %+v, %v, want the team added last", team, err)
+	}
+
+	// IDs are given by the store, not taken from requests
+	forged := append(store.Get().Teams, RCUTeam{ID: 1, Name: "Impostors", Color: "#000000", AnchorIndex: 10})
+	if err := store.Set(RCUSession{Teams: forged}); err == nil || !strings.Contains(err.Error(), "id 1 is not a team") {
+		t.Errorf("Set() with a removed team's ID = %v", err)
+	}
+}
+
+// TestRCUSessionStore_NumbersLegacySession checks that a session saved
+// before teams had IDs numbers them in order, as users and targets do.
+func TestRCUSessionStore_NumbersLegacySession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rcu_session.json")
+	legacy := `{"teams":[{"name":"North","color":"#112233FF","anchor_index":4},{"name":"South","color":"#445566FF","anchor_index":5}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewRCUSessionStore(path)
+	if team, err := store.Team(2); err != nil || team.Name != "South" {
+		t.Errorf("Team(2) = %+v, %v", team, err)
+	}
+	if session := store.Get(); session.NextID != 3 {
+		t.Errorf("next ID = %d, want 3", session.NextID)
+	}
+}
+
+func TestAdminHandler_HandleSession(t *testing.T) {
+	rcuHandler := NewRCUHandler(nil, nil)
+	adminHandler := NewAdminHandler(nil, nil, rcuHandler)
+
+	body := `{"teams":[{"name":"Alpha","color":"#00ff00","anchor_index":1},{"name":"Beta","color":"#0000ff","anchor_index":2}]}`
+	rec := httptest.NewRecorder()
+	adminHandler.HandleSession(rec, httptest.NewRequest(http.MethodPut, "/api/admin/rcu-session", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	adminHandler.HandleSession(rec, httptest.NewRequest(http.MethodPut, "/api/admin/rcu-session", strings.NewReader(`{"teams":[]}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT of an empty session status = %d, want 400", rec.Code)
+	}
+
+	// The RCU page sees the saved teams
+	rec = httptest.NewRecorder()
+	rcuHandler.HandleTeams(rec, httptest.NewRequest(http.MethodGet, "/rcu-teams", nil))
+	var resp struct {
+		Teams []RCUTeam `json:"teams"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Teams) != 2 || resp.Teams[1].Name != "Beta" || resp.Teams[1].Color != "#0000FFFF" {
+		t.Errorf("teams = %+v", resp.Teams)
+	}
+}
@@ -0,0 +1,439 @@
+package webui
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotManifestName is the archive entry holding the Snapshot JSON.
+const snapshotManifestName = "snapshot.json"
+
+// ErrSnapshotNotFound is returned when a snapshot ID does not name an archive.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// ErrInvalidSnapshotID is returned for snapshot IDs that are not safe file names.
+var ErrInvalidSnapshotID = errors.New("invalid snapshot id")
+
+// snapshotIDPattern restricts snapshot IDs to safe file names.
+var snapshotIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// snapshotSlugPattern matches the runs of characters replaced in ID slugs.
+var snapshotSlugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Snapshot is the captured state of a canvas. It is stored as the manifest
+// of a zip archive next to the downloaded asset files.
+type Snapshot struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	CanvasID   string           `json:"canvas_id"`
+	CanvasName string           `json:"canvas_name,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	Widgets    []SnapshotWidget `json:"widgets"`
+}
+
+// SnapshotWidget is one widget in a snapshot. Data is the widget as returned
+// by its type-specific endpoint; Asset is the archive path of its file for
+// images, videos and PDFs.
+type SnapshotWidget struct {
+	ID         string                 `json:"id"`
+	WidgetType string                 `json:"widget_type"`
+	Data       map[string]interface{} `json:"data"`
+	Asset      string                 `json:"asset,omitempty"`
+}
+
+// SnapshotSummary describes a stored snapshot without its widgets.
+type SnapshotSummary struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	CanvasID    string    `json:"canvas_id"`
+	CanvasName  string    `json:"canvas_name,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	WidgetCount int       `json:"widget_count"`
+	AssetCount  int       `json:"asset_count"`
+	Size        int64     `json:"size"`
+}
+
+// Summary returns the summary of s stored in an archive of size bytes.
+func (s *Snapshot) Summary(size int64) SnapshotSummary {
+	summary := SnapshotSummary{
+		ID:          s.ID,
+		Name:        s.Name,
+		CanvasID:    s.CanvasID,
+		CanvasName:  s.CanvasName,
+		CreatedAt:   s.CreatedAt,
+		WidgetCount: len(s.Widgets),
+		Size:        size,
+	}
+	for _, widget := range s.Widgets {
+		if widget.Asset != "" {
+			summary.AssetCount++
+		}
+	}
+	return summary
+}
+
+// SnapshotStore keeps snapshot archives in a directory, one zip per snapshot.
+type SnapshotStore struct {
+	dir string
+}
+
+// NewSnapshotStore creates a store for archives in dir. The directory is
+// created when the first snapshot is written.
+func NewSnapshotStore(dir string) *SnapshotStore {
+	return &SnapshotStore{dir: dir}
+}
+
+// NewSnapshotID returns an ID for a snapshot named name taken at t. A random
+// part keeps snapshots of the same name taken within a second apart.
+func NewSnapshotID(name string, t time.Time) string {
+	slug := strings.Trim(snapshotSlugPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.Trim(slug[:40], "-")
+	}
+	random := make([]byte, 4)
+	rand.Read(random)
+	id := t.Format("20060102-150405") + "-" + hex.EncodeToString(random)
+	if slug != "" {
+		id += "-" + slug
+	}
+	return id
+}
+
+// archivePath returns the archive file for id.
+func (s *SnapshotStore) archivePath(id string) (string, error) {
+	if s.dir == "" {
+		return "", fmt.Errorf("snapshot storage is not configured")
+	}
+	if !snapshotIDPattern.MatchString(id) {
+		return "", fmt.Errorf("%w %q", ErrInvalidSnapshotID, id)
+	}
+	return filepath.Join(s.dir, id+".zip"), nil
+}
+
+// List returns the stored snapshots, newest first. Unreadable archives are
+// skipped.
+func (s *SnapshotStore) List() ([]SnapshotSummary, error) {
+	summaries := []SnapshotSummary{}
+	if s.dir == "" {
+		return summaries, nil
+	}
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return summaries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".zip")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		snapshot, err := s.Load(id)
+		if err != nil {
+			fmt.Printf("[SnapshotStore] Skipping %s: %v\n", entry.Name(), err)
+			continue
+		}
+		var size int64
+		if info, err := entry.Info(); err == nil {
+			size = info.Size()
+		}
+		summaries = append(summaries, snapshot.Summary(size))
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].CreatedAt.After(summaries[j].CreatedAt)
+	})
+	return summaries, nil
+}
+
+// Load reads the manifest of snapshot id.
+func (s *SnapshotStore) Load(id string) (*Snapshot, error) {
+	archive, err := s.open(id)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	data, err := readArchiveEntry(archive.Reader, snapshotManifestName)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot manifest: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Delete removes snapshot id.
+func (s *SnapshotStore) Delete(id string) error {
+	archivePath, err := s.archivePath(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(archivePath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrSnapshotNotFound
+		}
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	return nil
+}
+
+// Create starts writing snapshot id. Assets are streamed into the archive as
+// they are added; the archive only becomes visible once Commit succeeds.
+func (s *SnapshotStore) Create(id string) (*SnapshotWriter, error) {
+	archivePath, err := s.archivePath(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	file, err := os.CreateTemp(s.dir, id+"-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot archive: %w", err)
+	}
+	return &SnapshotWriter{file: file, zip: zip.NewWriter(file), path: archivePath}, nil
+}
+
+// snapshotArchive is an open snapshot archive.
+type snapshotArchive struct {
+	*zip.Reader
+	file *os.File
+}
+
+// Close closes the archive file.
+func (a *snapshotArchive) Close() error {
+	return a.file.Close()
+}
+
+// openAsset returns the asset stored at assetPath. Assets are stored
+// uncompressed, so the returned reader reads straight from the archive file
+// and can be rewound, letting a failed upload of it be retried.
+func (a *snapshotArchive) openAsset(assetPath string) (io.ReadCloser, error) {
+	for _, f := range a.File {
+		if f.Name != assetPath {
+			continue
+		}
+		if f.Method == zip.Store {
+			offset, err := f.DataOffset()
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s: %w", assetPath, err)
+			}
+			return nopCloseReadSeeker{io.NewSectionReader(a.file, offset, int64(f.UncompressedSize64))}, nil
+		}
+		return f.Open()
+	}
+	return nil, fmt.Errorf("%s not found in snapshot", assetPath)
+}
+
+// nopCloseReadSeeker is an io.ReadSeeker with a Close that does nothing.
+type nopCloseReadSeeker struct {
+	io.ReadSeeker
+}
+
+func (nopCloseReadSeeker) Close() error { return nil }
+
+// open opens the archive of snapshot id for reading its manifest and assets.
+func (s *SnapshotStore) open(id string) (*snapshotArchive, error) {
+	archivePath, err := s.archivePath(id)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(archivePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	return &snapshotArchive{Reader: reader, file: file}, nil
+}
+
+// readArchiveEntry returns the contents of the entry called name.
+func readArchiveEntry(archive *zip.Reader, name string) ([]byte, error) {
+	for _, f := range archive.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%s not found in snapshot", name)
+}
+
+// SnapshotWriter writes a snapshot archive. Call Commit to finish it or Abort
+// to discard it.
+type SnapshotWriter struct {
+	file *os.File
+	zip  *zip.Writer
+	path string
+}
+
+// AddAsset streams the file of widgetID from data into the archive and
+// returns its archive path.
+func (w *SnapshotWriter) AddAsset(widgetID, fileName string, data io.Reader) (string, error) {
+	fileName = path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	if fileName == "." || fileName == "/" {
+		fileName = "asset"
+	}
+	assetPath := path.Join("assets", widgetID, fileName)
+	entry, err := w.zip.CreateHeader(&zip.FileHeader{Name: assetPath, Method: zip.Store})
+	if err != nil {
+		return "", fmt.Errorf("failed to add asset: %w", err)
+	}
+	if _, err := io.Copy(entry, data); err != nil {
+		return "", fmt.Errorf("failed to write asset: %w", err)
+	}
+	return assetPath, nil
+}
+
+// Commit writes the manifest, moves the archive into place and returns its
+// size in bytes.
+func (w *SnapshotWriter) Commit(snapshot *Snapshot) (int64, error) {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		w.Abort()
+		return 0, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	entry, err := w.zip.Create(snapshotManifestName)
+	if err == nil {
+		_, err = entry.Write(data)
+	}
+	if err == nil {
+		err = w.zip.Close()
+	}
+	var size int64
+	if err == nil {
+		size, err = w.file.Seek(0, io.SeekCurrent)
+	}
+	if err == nil {
+		err = w.file.Close()
+	}
+	if err != nil {
+		w.Abort()
+		return 0, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(w.file.Name(), w.path); err != nil {
+		os.Remove(w.file.Name())
+		return 0, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return size, nil
+}
+
+// Abort discards the partially written archive.
+func (w *SnapshotWriter) Abort() {
+	w.zip.Close()
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+// snapshotIgnoredFields are widget fields that change without the board
+// changing and are left out of diffs.
+var snapshotIgnoredFields = map[string]bool{
+	"id":        true,
+	"state":     true,
+	"parent_id": true,
+}
+
+// SnapshotDiff lists the widgets that differ between two canvas states.
+type SnapshotDiff struct {
+	Added     []WidgetDiff `json:"added"`
+	Removed   []WidgetDiff `json:"removed"`
+	Changed   []WidgetDiff `json:"changed"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// WidgetDiff is one widget in a SnapshotDiff. Fields lists the changed
+// fields of a changed widget.
+type WidgetDiff struct {
+	WidgetID   string   `json:"widget_id"`
+	WidgetType string   `json:"widget_type"`
+	Title      string   `json:"title,omitempty"`
+	Fields     []string `json:"fields,omitempty"`
+}
+
+// DiffSnapshots compares the widgets of from and to by widget ID. Widgets
+// only in to are added, widgets only in from are removed.
+func DiffSnapshots(from, to []SnapshotWidget) SnapshotDiff {
+	diff := SnapshotDiff{Added: []WidgetDiff{}, Removed: []WidgetDiff{}, Changed: []WidgetDiff{}}
+
+	before := make(map[string]SnapshotWidget, len(from))
+	for _, widget := range from {
+		before[widget.ID] = widget
+	}
+
+	for _, widget := range to {
+		old, ok := before[widget.ID]
+		if !ok {
+			diff.Added = append(diff.Added, widgetDiffOf(widget, nil))
+			continue
+		}
+		delete(before, widget.ID)
+		if fields := changedFields(old.Data, widget.Data); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, widgetDiffOf(widget, fields))
+		} else {
+			diff.Unchanged++
+		}
+	}
+
+	for _, widget := range from {
+		if _, ok := before[widget.ID]; ok {
+			diff.Removed = append(diff.Removed, widgetDiffOf(widget, nil))
+		}
+	}
+	return diff
+}
+
+func widgetDiffOf(widget SnapshotWidget, fields []string) WidgetDiff {
+	title, _ := widget.Data["title"].(string)
+	return WidgetDiff{WidgetID: widget.ID, WidgetType: widget.WidgetType, Title: title, Fields: fields}
+}
+
+// changedFields returns the sorted names of the fields that differ between
+// two widget states.
+func changedFields(before, after map[string]interface{}) []string {
+	var fields []string
+	for key, value := range after {
+		if !snapshotIgnoredFields[key] && !reflect.DeepEqual(before[key], value) {
+			fields = append(fields, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok && !snapshotIgnoredFields[key] {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,160 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestSnapshot_CaptureAndRestoreToOtherCanvas takes a snapshot of one canvas
+// and restores it onto another, checking assets and connector links.
+func TestSnapshot_CaptureAndRestoreToOtherCanvas(t *testing.T) {
+	const source = "/api/v1/canvases/source"
+	const target = "/api/v1/canvases/target"
+	var mu sync.Mutex
+	created := make(map[string]map[string]interface{})
+	uploads := make(map[string]string)
+	nextID := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasPrefix(r.URL.Path, target) && r.Method == http.MethodPost {
+			nextID++
+			id := fmt.Sprintf("restored-%d", nextID)
+			payload := map[string]interface{}{}
+			if file, _, err := r.FormFile("data"); err == nil {
+				content, _ := io.ReadAll(file)
+				uploads[id] = string(content)
+				json.Unmarshal([]byte(r.FormValue("json")), &payload)
+			} else {
+				json.NewDecoder(r.Body).Decode(&payload)
+			}
+			created[id] = payload
+			fmt.Fprintf(w, `{"id":%q}`, id)
+			return
+		}
+		switch strings.TrimPrefix(r.URL.Path, source) {
+		case "/widgets":
+			w.Write([]byte(`[
+				{"id":"canvas-root","widget_type":"SharedCanvas"},
+				{"id":"note-1","widget_type":"Note"},
+				{"id":"image-1","widget_type":"Image"},
+				{"id":"link-1","widget_type":"Connector"}
+			]`))
+		case "/notes/note-1":
+			w.Write([]byte(`{"id":"note-1","title":"Plan","text":"Ship it","location":{"x":10,"y":20},"state":"normal"}`))
+		case "/images/image-1":
+			w.Write([]byte(`{"id":"image-1","title":"Photo","original_filename":"photo.png","location":{"x":300,"y":20}}`))
+		case "/images/image-1/download":
+			w.Write([]byte("PNGDATA"))
+		case "/connectors/link-1":
+			w.Write([]byte(`{"id":"link-1","src":{"id":"note-1","tip":"none"},"dst":{"id":"image-1","tip":"solid-equilateral-triangle"},"line_width":3}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	handler := NewSnapshotHandler(webuiatoms.NewAPIClient(server.URL, "test-token"), nil)
+	handler.SetStore(NewSnapshotStore(t.TempDir()))
+
+	summary, skipped, err := handler.takeSnapshot(context.Background(), "source", "Workshop", "Workshop board")
+	if err != nil {
+		t.Fatalf("takeSnapshot: %v", err)
+	}
+	if summary.WidgetCount != 3 || summary.AssetCount != 1 || len(skipped) != 0 || summary.Size == 0 {
+		t.Fatalf("summary = %+v, skipped = %v", summary, skipped)
+	}
+
+	snapshots, err := handler.store.List()
+	if err != nil || len(snapshots) != 1 || snapshots[0].ID != summary.ID {
+		t.Fatalf("List() = %+v, %v", snapshots, err)
+	}
+	snapshot, err := handler.store.Load(summary.ID)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	report, err := handler.restoreSnapshot(context.Background(), snapshot, "target")
+	if err != nil {
+		t.Fatalf("restoreSnapshot: %v", err)
+	}
+	if report.Succeeded != 3 || report.Failed != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	var noteID, imageID string
+	var connector map[string]interface{}
+	for id, payload := range created {
+		switch {
+		case payload["text"] == "Ship it":
+			noteID = id
+		case uploads[id] == "PNGDATA":
+			imageID = id
+		case payload["src"] != nil:
+			connector = payload
+		}
+	}
+	if noteID == "" || imageID == "" || connector == nil {
+		t.Fatalf("created = %v, uploads = %v", created, uploads)
+	}
+	if _, ok := created[noteID]["state"]; ok {
+		t.Errorf("note payload contains read-only fields: %v", created[noteID])
+	}
+	src := connector["src"].(map[string]interface{})
+	dst := connector["dst"].(map[string]interface{})
+	if src["id"] != noteID || dst["id"] != imageID || dst["tip"] != "solid-equilateral-triangle" {
+		t.Errorf("connector ends = %v -> %v, want %s -> %s", src, dst, noteID, imageID)
+	}
+}
+
+// TestNewSnapshotID_Unique checks that snapshots with the same name taken in
+// the same second get different IDs.
+func TestNewSnapshotID_Unique(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := NewSnapshotID("Before demo", now)
+	second := NewSnapshotID("Before demo", now)
+	if first == second {
+		t.Fatalf("NewSnapshotID returned %s twice", first)
+	}
+	if !strings.HasPrefix(first, "20260301-120000-") || !strings.HasSuffix(first, "-before-demo") {
+		t.Errorf("NewSnapshotID = %s", first)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	from := []SnapshotWidget{
+		{ID: "a", WidgetType: "Note", Data: map[string]interface{}{"title": "A", "text": "one", "state": "normal"}},
+		{ID: "b", WidgetType: "Note", Data: map[string]interface{}{"title": "B"}},
+		{ID: "c", WidgetType: "Note", Data: map[string]interface{}{"title": "C"}},
+	}
+	to := []SnapshotWidget{
+		{ID: "a", WidgetType: "Note", Data: map[string]interface{}{"title": "A", "text": "two", "state": "moving"}},
+		{ID: "c", WidgetType: "Note", Data: map[string]interface{}{"title": "C"}},
+		{ID: "d", WidgetType: "Browser", Data: map[string]interface{}{"url": "https://example.com"}},
+	}
+
+	diff := DiffSnapshots(from, to)
+	if len(diff.Added) != 1 || diff.Added[0].WidgetID != "d" {
+		t.Errorf("added = %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].WidgetID != "b" {
+		t.Errorf("removed = %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].WidgetID != "a" || strings.Join(diff.Changed[0].Fields, ",") != "text" {
+		t.Errorf("changed = %+v", diff.Changed)
+	}
+	if diff.Unchanged != 1 {
+		t.Errorf("unchanged = %d, want 1", diff.Unchanged)
+	}
+}
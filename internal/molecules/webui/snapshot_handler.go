@@ -0,0 +1,232 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// SnapshotHandler handles canvas snapshot API endpoints. A snapshot is a
+// local archive of every widget on a canvas plus the files of its images,
+// videos and PDFs, so a board can be restored on the same or another canvas.
+type SnapshotHandler struct {
+	apiClient     *webuiatoms.APIClient
+	canvasService *CanvasService
+	store         *SnapshotStore
+}
+
+// NewSnapshotHandler creates a new snapshot handler. Snapshots cannot be
+// saved until SetStore is called.
+func NewSnapshotHandler(apiClient *webuiatoms.APIClient, canvasService *CanvasService) *SnapshotHandler {
+	return &SnapshotHandler{
+		apiClient:     apiClient,
+		canvasService: canvasService,
+		store:         NewSnapshotStore(""),
+	}
+}
+
+// SetStore sets where snapshot archives are kept.
+func (h *SnapshotHandler) SetStore(store *SnapshotStore) {
+	h.store = store
+}
+
+// HandleSnapshots handles /api/snapshots:
+// GET lists snapshots, POST captures the current canvas and DELETE ?id= removes one.
+func (h *SnapshotHandler) HandleSnapshots(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.handleList(w)
+	case http.MethodPost:
+		h.handleCapture(w, r)
+	case http.MethodDelete:
+		h.handleDelete(w, r)
+	default:
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *SnapshotHandler) handleList(w http.ResponseWriter) {
+	snapshots, err := h.store.List()
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, map[string]interface{}{
+		"success":   true,
+		"snapshots": snapshots,
+	}, http.StatusOK)
+}
+
+func (h *SnapshotHandler) handleCapture(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	canvasID := requestCanvasID(r, h.canvasService)
+	if canvasID == "" {
+		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
+		return
+	}
+	canvasName := requestCanvasName(r, h.canvasService)
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = canvasName
+	}
+
+	summary, skipped, err := h.takeSnapshot(r.Context(), canvasID, canvasName, name)
+	if err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Failed to take snapshot: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	sendJSONResponse(w, map[string]interface{}{
+		"success":  true,
+		"message":  fmt.Sprintf("Snapshot saved: %d widgets, %d files, %d skipped", summary.WidgetCount, summary.AssetCount, len(skipped)),
+		"snapshot": summary,
+		"skipped":  skipped,
+	}, http.StatusOK)
+}
+
+func (h *SnapshotHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Delete(r.URL.Query().Get("id")); err != nil {
+		sendSnapshotError(w, err)
+		return
+	}
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"message": "Snapshot deleted",
+	}, http.StatusOK)
+}
+
+// HandleDiff handles GET /api/snapshots/diff?id=<snapshot>[&against=<snapshot>] -
+// Compare a snapshot with the current canvas or with another snapshot.
+func (h *SnapshotHandler) HandleDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot, err := h.store.Load(r.URL.Query().Get("id"))
+	if err != nil {
+		sendSnapshotError(w, err)
+		return
+	}
+
+	against := "current canvas"
+	var current []SnapshotWidget
+	if otherID := r.URL.Query().Get("against"); otherID != "" {
+		other, err := h.store.Load(otherID)
+		if err != nil {
+			sendSnapshotError(w, err)
+			return
+		}
+		against = other.Name
+		current = other.Widgets
+	} else {
+		canvasID := requestCanvasID(r, h.canvasService)
+		if canvasID == "" {
+			sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
+			return
+		}
+		if current, _, err = h.captureWidgets(r.Context(), canvasID); err != nil {
+			sendErrorResponse(w, fmt.Sprintf("Failed to read canvas: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	diff := DiffSnapshots(snapshot.Widgets, current)
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Compared with %s: %d added, %d removed, %d changed, %d unchanged", against, len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged),
+		"diff":    diff,
+	}, http.StatusOK)
+}
+
+// HandleRestore handles POST /api/snapshots/restore - Recreate a snapshot on
+// the current canvas, or on canvas_id if given. With replace set, the widgets
+// already on the target canvas are deleted first.
+func (h *SnapshotHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID       string `json:"id"`
+		CanvasID string `json:"canvas_id"`
+		Replace  bool   `json:"replace"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	canvasID := req.CanvasID
+	if canvasID == "" {
+		canvasID = requestCanvasID(r, h.canvasService)
+	}
+	if canvasID == "" {
+		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	snapshot, err := h.store.Load(req.ID)
+	if err != nil {
+		sendSnapshotError(w, err)
+		return
+	}
+
+	if req.Replace {
+		if err := h.clearCanvas(r.Context(), canvasID); err != nil {
+			sendErrorResponse(w, fmt.Sprintf("Failed to clear canvas: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	report, err := h.restoreSnapshot(r.Context(), snapshot, canvasID)
+	if err != nil {
+		sendSnapshotError(w, err)
+		return
+	}
+	sendBatchResponse(w, report, "restored")
+}
+
+// HandleCanvases handles GET /api/snapshots/canvases - List the canvases a
+// snapshot can be restored to.
+func (h *SnapshotHandler) HandleCanvases(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	canvases, err := h.apiClient.Canvases().List(r.Context())
+	if err != nil {
+		sendErrorResponse(w, fmt.Sprintf("Failed to list canvases: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, map[string]interface{}{
+		"success":           true,
+		"current_canvas_id": requestCanvasID(r, h.canvasService),
+		"canvases":          canvases,
+	}, http.StatusOK)
+}
+
+// sendSnapshotError maps store errors to HTTP status codes.
+func sendSnapshotError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrSnapshotNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrInvalidSnapshotID):
+		status = http.StatusBadRequest
+	}
+	sendErrorResponse(w, err.Error(), status)
+}
@@ -0,0 +1,268 @@
+package webui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// snapshotCommonFields are restored for every widget type.
+var snapshotCommonFields = []string{"location", "size", "scale", "depth", "pinned", "title"}
+
+// snapshotTypeFields are the type-specific fields restored for each widget
+// type. Types missing from the map are not captured.
+var snapshotTypeFields = map[string][]string{
+	"note":      {"text", "background_color", "auto_text_color", "text_color"},
+	"browser":   {"url"},
+	"anchor":    {"anchor_name"},
+	"image":     {"original_filename"},
+	"video":     {"original_filename"},
+	"pdf":       {"original_filename"},
+	"connector": {"line_color", "line_width", "type"},
+}
+
+// snapshotHasAsset reports whether widgets of widgetType carry a file.
+func snapshotHasAsset(widgetType string) bool {
+	switch strings.ToLower(widgetType) {
+	case "image", "video", "pdf":
+		return true
+	}
+	return false
+}
+
+// captureWidgets fetches the full metadata of every widget on canvasID.
+// Widgets of types that cannot be restored are returned as skipped.
+func (h *SnapshotHandler) captureWidgets(ctx context.Context, canvasID string) ([]SnapshotWidget, []SkippedWidget, error) {
+	widgets, err := webuiatoms.GetAllWidgets(h.apiClient, canvasID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var captured []SnapshotWidget
+	var skipped []SkippedWidget
+	for _, widget := range widgets {
+		if widget.WidgetType == "SharedCanvas" {
+			continue
+		}
+		if _, ok := snapshotTypeFields[strings.ToLower(widget.WidgetType)]; !ok {
+			skipped = append(skipped, SkippedWidget{WidgetID: widget.ID, WidgetType: widget.WidgetType, Reason: "widget type cannot be restored"})
+			continue
+		}
+
+		metadata, err := fetchWidgetData(ctx, h.apiClient, canvasID, widget)
+		if webuiatoms.IsNotFound(err) {
+			continue // deleted while capturing
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch %s %s: %w", widget.WidgetType, widget.ID, err)
+		}
+		captured = append(captured, SnapshotWidget{ID: widget.ID, WidgetType: widget.WidgetType, Data: metadata})
+	}
+	return captured, skipped, nil
+}
+
+// takeSnapshot captures canvasID with its assets, stores it as name and
+// returns its summary.
+func (h *SnapshotHandler) takeSnapshot(ctx context.Context, canvasID, canvasName, name string) (SnapshotSummary, []SkippedWidget, error) {
+	fmt.Printf("[SnapshotHandler] Capturing canvas %s as %q\n", canvasID, name)
+	widgets, skipped, err := h.captureWidgets(ctx, canvasID)
+	if err != nil {
+		return SnapshotSummary{}, nil, err
+	}
+
+	now := time.Now()
+	snapshot := &Snapshot{
+		ID:         NewSnapshotID(name, now),
+		Name:       name,
+		CanvasID:   canvasID,
+		CanvasName: canvasName,
+		CreatedAt:  now,
+	}
+	writer, err := h.store.Create(snapshot.ID)
+	if err != nil {
+		return SnapshotSummary{}, nil, err
+	}
+
+	for _, widget := range widgets {
+		if snapshotHasAsset(widget.WidgetType) {
+			download, err := h.apiClient.OpenDownload(ctx, widgetEndpoint(canvasID, widget.WidgetType, widget.ID)+"/download")
+			if err != nil {
+				writer.Abort()
+				return SnapshotSummary{}, nil, fmt.Errorf("failed to download %s %s: %w", widget.WidgetType, widget.ID, err)
+			}
+			fileName, _ := widget.Data["original_filename"].(string)
+			if fileName == "" {
+				fileName = strings.ToLower(widget.WidgetType)
+			}
+			widget.Asset, err = writer.AddAsset(widget.ID, fileName, download)
+			download.Close()
+			if err != nil {
+				writer.Abort()
+				return SnapshotSummary{}, nil, fmt.Errorf("failed to download %s %s: %w", widget.WidgetType, widget.ID, err)
+			}
+		}
+		snapshot.Widgets = append(snapshot.Widgets, widget)
+	}
+
+	size, err := writer.Commit(snapshot)
+	if err != nil {
+		return SnapshotSummary{}, nil, err
+	}
+	fmt.Printf("[SnapshotHandler] Snapshot %s saved: %d widgets, %d skipped\n", snapshot.ID, len(snapshot.Widgets), len(skipped))
+	return snapshot.Summary(size), skipped, nil
+}
+
+// clearCanvas deletes every widget on canvasID so a restore reproduces the
+// snapshot exactly. Connectors go first as they vanish with their ends.
+func (h *SnapshotHandler) clearCanvas(ctx context.Context, canvasID string) error {
+	widgets, err := webuiatoms.GetAllWidgets(h.apiClient, canvasID)
+	if err != nil {
+		return err
+	}
+	var connectors, others []webuiatoms.Widget
+	for _, widget := range widgets {
+		switch {
+		case widget.WidgetType == "SharedCanvas":
+		case strings.EqualFold(widget.WidgetType, "connector"):
+			connectors = append(connectors, widget)
+		default:
+			if _, ok := snapshotTypeFields[strings.ToLower(widget.WidgetType)]; ok {
+				others = append(others, widget)
+			}
+		}
+	}
+	for _, widget := range append(connectors, others...) {
+		_, err := h.apiClient.Do(ctx, http.MethodDelete, widgetEndpoint(canvasID, widget.WidgetType, widget.ID), nil)
+		if err != nil && !webuiatoms.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s %s: %w", widget.WidgetType, widget.ID, err)
+		}
+	}
+	return nil
+}
+
+// restoreSnapshot recreates the widgets of snapshot on canvasID. Connectors
+// are created last and linked to the restored widgets.
+func (h *SnapshotHandler) restoreSnapshot(ctx context.Context, snapshot *Snapshot, canvasID string) (*BatchReport, error) {
+	archive, err := h.store.open(snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	fmt.Printf("[SnapshotHandler] Restoring snapshot %s to canvas %s\n", snapshot.ID, canvasID)
+	report := &BatchReport{}
+	restoredIDs := make(map[string]string)
+	var connectors []SnapshotWidget
+	for _, widget := range snapshot.Widgets {
+		if strings.EqualFold(widget.WidgetType, "connector") {
+			connectors = append(connectors, widget)
+			continue
+		}
+		result := WidgetUpdateResult{WidgetID: widget.ID, WidgetType: widget.WidgetType, Attempts: 1}
+		newID, err := createWidget(ctx, h.apiClient, canvasID, widget, archive.openAsset)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Succeeded = true
+			restoredIDs[widget.ID] = newID
+		}
+		report.add(result)
+	}
+
+	for _, connector := range connectors {
+		payload, ok := relinkConnector(connector, restoredIDs)
+		if !ok {
+			report.Skipped = append(report.Skipped, SkippedWidget{WidgetID: connector.ID, WidgetType: connector.WidgetType, Reason: "connector end was not restored"})
+			continue
+		}
+		result := WidgetUpdateResult{WidgetID: connector.ID, WidgetType: connector.WidgetType, Attempts: 1}
+		data, err := h.apiClient.Do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/canvases/%s/connectors", canvasID), payload)
+		if err == nil {
+			_, err = createdWidgetID(data)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Succeeded = true
+		}
+		report.add(result)
+	}
+
+	fmt.Printf("[SnapshotHandler] Restore completed: %d restored, %d failed, %d skipped\n", report.Succeeded, report.Failed, len(report.Skipped))
+	return report, nil
+}
+
+// createWidget creates widget on canvasID from its captured data and returns
+// the new widget ID. openAsset opens the captured file of image, video and
+// PDF widgets, which is streamed to the server. It is shared by snapshot
+// restores and macro undo.
+func createWidget(ctx context.Context, apiClient *webuiatoms.APIClient, canvasID string, widget SnapshotWidget, openAsset func(string) (io.ReadCloser, error)) (string, error) {
+	payload := snapshotPayload(widget)
+	endpoint := fmt.Sprintf("/api/v1/canvases/%s%s", canvasID, webuiatoms.GetWidgetPatchEndpoint(widget.WidgetType))
+
+	if !snapshotHasAsset(widget.WidgetType) {
+		data, err := apiClient.Do(ctx, http.MethodPost, endpoint, payload)
+		if err != nil {
+			return "", err
+		}
+		return createdWidgetID(data)
+	}
+
+	if widget.Asset == "" {
+		return "", fmt.Errorf("%s has no saved file", widget.WidgetType)
+	}
+	fileData, err := openAsset(widget.Asset)
+	if err != nil {
+		return "", err
+	}
+	defer fileData.Close()
+	fileName, _ := payload["original_filename"].(string)
+	if fileName == "" {
+		fileName = path.Base(widget.Asset)
+	}
+	data, err := apiClient.PostMultipartContext(ctx, endpoint, payload, fileData, fileName)
+	if err != nil {
+		return "", err
+	}
+	return createdWidgetID(data)
+}
+
+// snapshotPayload returns the create payload for widget.
+func snapshotPayload(widget SnapshotWidget) map[string]interface{} {
+	payload := make(map[string]interface{})
+	for _, fields := range [][]string{snapshotCommonFields, snapshotTypeFields[strings.ToLower(widget.WidgetType)]} {
+		for _, field := range fields {
+			if value, ok := widget.Data[field]; ok && value != nil {
+				payload[field] = value
+			}
+		}
+	}
+	return payload
+}
+
+// relinkConnector returns the create payload for connector with its ends
+// pointing at the restored widgets. ok is false if an end was not restored.
+func relinkConnector(connector SnapshotWidget, restoredIDs map[string]string) (payload map[string]interface{}, ok bool) {
+	payload = snapshotPayload(connector)
+	for _, end := range []string{"src", "dst"} {
+		original, _ := connector.Data[end].(map[string]interface{})
+		oldID, _ := original["id"].(string)
+		newID, restored := restoredIDs[oldID]
+		if !restored {
+			return nil, false
+		}
+		relinked := make(map[string]interface{}, len(original))
+		for key, value := range original {
+			relinked[key] = value
+		}
+		relinked["id"] = newID
+		payload[end] = relinked
+	}
+	return payload, true
+}
@@ -5,105 +5,143 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// SSEHandler handles Server-Sent Events for canvas_id updates.
+// sseKeepaliveInterval is how often a keepalive comment is sent on an idle
+// SSE connection.
+const sseKeepaliveInterval = 15 * time.Second
+
+// SSEHandler streams events from the EventBus as Server-Sent Events.
 type SSEHandler struct {
 	canvasService *CanvasService
+	events        *EventBus
 }
 
-// NewSSEHandler creates a new SSE handler.
-func NewSSEHandler(canvasService *CanvasService) *SSEHandler {
+// NewSSEHandler creates a new SSE handler streaming events from events.
+func NewSSEHandler(canvasService *CanvasService, events *EventBus) *SSEHandler {
 	return &SSEHandler{
 		canvasService: canvasService,
+		events:        events,
 	}
 }
 
 // HandleSubscribe handles the SSE subscription endpoint.
+// Query: topics=canvas,macro (default: all topics), lastEventId=<id> as an
+// alternative to the Last-Event-ID header sent by reconnecting EventSources.
+// Subscribers to the canvas topic first receive the current canvas state.
+// Events of clients other than the one selected by client_id (default: the
+// primary client), and canvas events of workspaces other than the one
+// selected by workspace, are left out.
 func (h *SSEHandler) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 	// Set headers for SSE
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Cache-Control")
+	w.Header().Set("Access-Control-Allow-Headers", "Cache-Control, Last-Event-ID")
 
 	// Get request context - this will be cancelled when server shuts down
-	// Explicitly type the context to ensure the import is recognized
 	var ctx context.Context = r.Context()
 
-	// Send initial canvas state
-	h.sendCanvasUpdate(w, h.canvasService.GetCanvasID(), h.canvasService.GetCanvasName())
+	topics := parseTopics(r.URL.Query().Get("topics"))
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	resumeFrom, _ := strconv.ParseUint(lastEventID, 10, 64)
 
-	// Use a shorter ticker interval (1 second) for more responsive shutdown detection
-	// This ensures we check for context cancellation more frequently
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	canvasService := requestCanvas(r, h.canvasService)
+	workspace := 0
+	if canvasService != nil {
+		workspace = requestWorkspace(r, canvasService)
+	}
+	sub, replay := h.events.Subscribe(topics, resumeFrom)
+	defer h.events.Unsubscribe(sub)
 
-	// Track last sent canvas_id and canvas_name to detect changes
-	lastCanvasID := h.canvasService.GetCanvasID()
-	lastCanvasName := h.canvasService.GetCanvasName()
+	// Send initial canvas state without an ID so it does not move Last-Event-ID
+	if sub.matches(TopicCanvas) {
+		h.sendEvent(w, Event{Type: "canvas_update", Data: canvasService.canvasUpdate(workspace)})
+	}
+	for _, event := range replay {
+		if concernsClient(event, canvasService, workspace) {
+			h.sendEvent(w, event)
+		}
+	}
+
+	ticker := time.NewTicker(sseKeepaliveInterval)
+	defer ticker.Stop()
 
 	for {
-		// Use a context-aware select that prioritizes context cancellation
-		// Context cancellation has highest priority - will exit immediately when server shuts down
 		select {
 		case <-ctx.Done():
 			// Client disconnected or server shutting down
 			fmt.Printf("[SSEHandler] Connection closed (client disconnected or server shutdown)\n")
 			return
-		case <-ticker.C:
-			// Check context before processing (server might have shut down during tick)
-			if ctx.Err() != nil {
-				fmt.Printf("[SSEHandler] Context cancelled during tick, closing connection\n")
+		case event, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client resumes from its Last-Event-ID
+				fmt.Printf("[SSEHandler] Subscription ended, closing connection\n")
 				return
 			}
-
-			// Periodic update check - send update if canvas_id OR canvas_name changed
-			currentCanvasID := h.canvasService.GetCanvasID()
-			currentCanvasName := h.canvasService.GetCanvasName()
-
-			if currentCanvasID != lastCanvasID || currentCanvasName != lastCanvasName {
-				// Check context again before sending (write might block)
-				if ctx.Err() != nil {
-					fmt.Printf("[SSEHandler] Context cancelled before sending update, closing connection\n")
-					return
-				}
-				h.sendCanvasUpdate(w, currentCanvasID, currentCanvasName)
-				lastCanvasID = currentCanvasID
-				lastCanvasName = currentCanvasName
-			} else {
-				// Check context before sending keepalive
-				if ctx.Err() != nil {
-					fmt.Printf("[SSEHandler] Context cancelled before sending keepalive, closing connection\n")
-					return
-				}
-				// Send keepalive
-				h.sendKeepalive(w)
+			if concernsClient(event, canvasService, workspace) {
+				h.sendEvent(w, event)
 			}
+		case <-ticker.C:
+			h.sendKeepalive(w)
 		}
 	}
 }
 
-// sendCanvasUpdate sends a canvas update event.
-func (h *SSEHandler) sendCanvasUpdate(w http.ResponseWriter, canvasID, canvasName string) {
-	event := map[string]interface{}{
-		"canvas_id":   canvasID,
-		"canvas_name": canvasName,
-		"client_name": h.canvasService.GetClientName(),
-		"client_id":   h.canvasService.GetClientID(),
-		"timestamp":   time.Now().Unix(),
+// concernsClient reports whether event concerns the client of canvasService
+// and, for canvas updates, its workspace. Events that do not name a client
+// concern every client.
+func concernsClient(event Event, canvasService *CanvasService, workspace int) bool {
+	var clientID string
+	switch data := event.Data.(type) {
+	case map[string]interface{}:
+		clientID, _ = data["client_id"].(string)
+		if eventWorkspace, ok := data["workspace"].(int); ok && event.Type == "canvas_update" && eventWorkspace != workspace {
+			return false
+		}
+	case ConnectionStatus:
+		clientID = data.ClientID
+	case JobRun:
+		clientID = data.ClientID
 	}
+	return clientID == "" || (canvasService != nil && clientID == canvasService.GetClientID())
+}
 
-	eventJSON, err := json.Marshal(event)
+// parseTopics splits a comma-separated topic list. An empty list means all
+// topics.
+func parseTopics(value string) []string {
+	var topics []string
+	for _, topic := range strings.Split(value, ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// sendEvent sends an event in SSE format, with its ID when it has one.
+func (h *SSEHandler) sendEvent(w http.ResponseWriter, event Event) {
+	eventJSON, err := json.Marshal(event.Data)
 	if err != nil {
-		fmt.Printf("Error marshaling canvas event: %v\n", err)
+		fmt.Printf("Error marshaling %s event: %v\n", event.Type, err)
 		return
 	}
 
 	// Send SSE formatted event - check for write errors
-	if _, err := fmt.Fprintf(w, "event: canvas_update\n"); err != nil {
+	if event.ID > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.ID); err != nil {
+			fmt.Printf("Error writing SSE event id: %v\n", err)
+			return
+		}
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\n", event.Type); err != nil {
 		fmt.Printf("Error writing SSE event header: %v\n", err)
 		return
 	}
@@ -121,11 +159,10 @@ func (h *SSEHandler) sendCanvasUpdate(w http.ResponseWriter, canvasID, canvasNam
 // sendKeepalive sends a keepalive comment to maintain connection.
 func (h *SSEHandler) sendKeepalive(w http.ResponseWriter) {
 	if _, err := fmt.Fprintf(w, ": keepalive\n\n"); err != nil {
-		// Connection likely closed, will be detected by clientGone channel
+		// Connection likely closed, will be detected through the request context
 		return
 	}
 	if flusher, ok := w.(http.Flusher); ok {
 		flusher.Flush()
 	}
 }
-
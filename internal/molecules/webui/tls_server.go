@@ -0,0 +1,76 @@
+package webui
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// LoadServerCertificate returns the certificate of the WebUI server: the
+// user-supplied certFile and keyFile when set, else a self-signed certificate
+// for this machine's names and addresses, created in dir on first use.
+func LoadServerCertificate(certFile, keyFile, dir string) (tls.Certificate, error) {
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return tls.Certificate{}, fmt.Errorf("both a certificate and a key file are needed")
+		}
+		return webuiatoms.LoadCertificate(certFile, keyFile)
+	}
+	if dir == "" {
+		return tls.Certificate{}, fmt.Errorf("no directory for the self-signed certificate")
+	}
+	return webuiatoms.LoadOrCreateSelfSignedCertificate(
+		filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), serverHosts())
+}
+
+// serverHosts returns the names and addresses browsers may reach this
+// machine by.
+func serverHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		hosts = append(hosts, hostname)
+	}
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return hosts
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				hosts = append(hosts, ipNet.IP.String())
+			}
+		}
+	}
+	return hosts
+}
+
+// RedirectToHTTPS returns a handler sending every request to the same host
+// and path on httpsPort over HTTPS. The redirect is temporary so browsers
+// do not remember it once HTTPS is turned off again.
+func RedirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if net.ParseIP(host) != nil && net.ParseIP(host).To4() == nil {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	})
+}
@@ -0,0 +1,24 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRedirectToHTTPS keeps host, path and query and switches scheme and port.
+func TestRedirectToHTTPS(t *testing.T) {
+	for _, tc := range []struct {
+		port, target, want string
+	}{
+		{"8443", "http://192.168.1.20:8081/rcu.html?client_id=c1", "https://192.168.1.20:8443/rcu.html?client_id=c1"},
+		{"8443", "http://wall.local/", "https://wall.local:8443/"},
+		{"443", "http://wall.local:8081/macros.html", "https://wall.local/macros.html"},
+	} {
+		recorder := httptest.NewRecorder()
+		RedirectToHTTPS(tc.port).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, tc.target, nil))
+		if recorder.Code != http.StatusTemporaryRedirect || recorder.Header().Get("Location") != tc.want {
+			t.Errorf("%s to port %s = %d %q, want %q", tc.target, tc.port, recorder.Code, recorder.Header().Get("Location"), tc.want)
+		}
+	}
+}
@@ -1,129 +1,384 @@
 package webui
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"math"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
 )
 
+// Upload placements.
+const (
+	PlacementViewport = "viewport" // the visible area of the selected workspace
+	PlacementZone     = "zone"     // a zone (anchor) of the canvas
+)
+
+// uploadCellMargin is the share of a grid cell left free around an upload.
+const uploadCellMargin = 0.05
+
 // UploadHandler handles remote content upload API endpoints.
 type UploadHandler struct {
 	apiClient     *webuiatoms.APIClient
 	canvasService *CanvasService
 	uploadDir     string
+	events        *EventBus
+	history       *UploadHistory
+	staging       *UploadStaging
+	pipeline      *UploadPipeline
+}
+
+// uploadSource is a file to place: a part of the upload form or a complete
+// staged upload.
+type uploadSource struct {
+	name     string
+	size     int64
+	stagedID string
+	open     func() (io.ReadSeekCloser, error)
 }
 
-// NewUploadHandler creates a new upload handler.
+// NewUploadHandler creates a new upload handler. When uploadDir is set, a
+// copy of every uploaded file is kept there.
 func NewUploadHandler(apiClient *webuiatoms.APIClient, canvasService *CanvasService, uploadDir string) *UploadHandler {
 	// Ensure upload directory exists
-	os.MkdirAll(uploadDir, 0755)
+	if uploadDir != "" {
+		os.MkdirAll(uploadDir, 0755)
+	}
 
 	return &UploadHandler{
 		apiClient:     apiClient,
 		canvasService: canvasService,
 		uploadDir:     uploadDir,
+		history:       NewUploadHistory(""),
+		staging:       NewUploadStaging("", DefaultMaxUploadSize),
+		pipeline:      NewUploadPipeline(),
 	}
 }
 
-// HandleUpload handles POST /api/remote-upload - Upload files.
+// SetEventBus sets where upload progress is published.
+func (h *UploadHandler) SetEventBus(events *EventBus) {
+	h.events = events
+}
+
+// SetHistory sets the store uploads are recorded in.
+func (h *UploadHandler) SetHistory(history *UploadHistory) {
+	h.history = history
+}
+
+// SetStaging sets where resumable uploads are received; its size limit
+// applies to every upload.
+func (h *UploadHandler) SetStaging(staging *UploadStaging) {
+	h.staging = staging
+}
+
+// SetPipeline sets how the type of uploads is sniffed and converted.
+func (h *UploadHandler) SetPipeline(pipeline *UploadPipeline) {
+	h.pipeline = pipeline
+}
+
+// HandleUpload handles POST /api/remote-upload - Upload files to the canvas.
+// Form: files (one or more) and/or staged (ids of complete uploads from
+// /api/uploads), placement (viewport or zone, default viewport), zone_id
+// (for zone), uploader and upload_id (optional, echoed in the upload
+// events). Files are laid out in a grid over the placement area.
 func (h *UploadHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Parse multipart form (32MB max)
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		sendErrorResponse(w, "Failed to parse form", http.StatusBadRequest)
+	extendDeadlines(w, uploadRequestTimeout)
+	form, ok := parseUploadForm(w, r, h.staging.MaxSize())
+	if !ok {
 		return
 	}
+	defer form.RemoveAll()
 
-	files := r.MultipartForm.File["files"]
+	var files []uploadSource
+	for _, fileHeader := range form.File["files"] {
+		files = append(files, uploadSource{
+			name: filepath.Base(fileHeader.Filename),
+			size: fileHeader.Size,
+			open: func() (io.ReadSeekCloser, error) { return fileHeader.Open() },
+		})
+	}
+	for _, id := range form.Value["staged"] {
+		files = append(files, h.stagedSource(id))
+	}
 	if len(files) == 0 {
 		sendErrorResponse(w, "No files provided", http.StatusBadRequest)
 		return
 	}
 
-	uploadPath := r.FormValue("path")
-	if uploadPath == "" {
-		uploadPath = "/"
-	}
-
-	canvasID := h.canvasService.GetCanvasID()
+	cs := requestCanvas(r, h.canvasService)
+	canvasID := requestCanvasID(r, h.canvasService)
 	if canvasID == "" {
 		sendErrorResponse(w, "Canvas not available", http.StatusServiceUnavailable)
 		return
 	}
 
-	var uploadedFiles []map[string]interface{}
+	placement := r.FormValue("placement")
+	if placement == "" {
+		placement = PlacementViewport
+	}
+	zoneID := r.FormValue("zone_id")
+	area, err := h.placementArea(r.Context(), cs, requestWorkspace(r, cs), canvasID, placement, zoneID)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	uploadID := r.FormValue("upload_id")
+	if uploadID == "" {
+		uploadID = strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	uploader := r.FormValue("uploader")
+	if uploader == "" {
+		uploader = remoteIP(r)
+	}
+
+	records := make([]UploadRecord, 0, len(files))
+	failed := 0
+	for i, file := range files {
+		record := UploadRecord{
+			UploadID:   uploadID,
+			Filename:   file.name,
+			Size:       file.size,
+			Uploader:   uploader,
+			RemoteIP:   remoteIP(r),
+			ClientID:   cs.GetClientID(),
+			CanvasID:   canvasID,
+			Placement:  placement,
+			UploadedAt: time.Now(),
+		}
+		if placement == PlacementZone {
+			record.ZoneID = zoneID
+		}
+		progress := func(phase string) map[string]interface{} {
+			return map[string]interface{}{
+				"upload_id": uploadID,
+				"client_id": record.ClientID,
+				"canvas_id": canvasID,
+				"file":      record.Filename,
+				"size":      record.Size,
+				"index":     i + 1,
+				"total":     len(files),
+				"phase":     phase,
+			}
+		}
+		h.events.Publish(TopicUpload, "upload_progress", progress("uploading"))
 
-	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
+		location := gridLocation(area, i, len(files))
+		if err := h.uploadFile(r.Context(), file, canvasID, location, &record); err != nil {
+			fmt.Printf("[UploadHandler] ERROR: Failed to upload %s: %v\n", record.Filename, err)
+			record.Outcome = "failure"
+			record.Error = err.Error()
+			failed++
+			data := progress("failed")
+			data["error"] = record.Error
+			h.events.Publish(TopicUpload, "upload_progress", data)
+		} else {
+			record.Outcome = "success"
+			if file.stagedID != "" {
+				h.staging.Remove(file.stagedID)
+			}
+			data := progress("uploaded")
+			data["widget_id"] = record.WidgetID
+			h.events.Publish(TopicUpload, "upload_progress", data)
+		}
+		records = append(records, record)
+	}
+	h.history.Record(records...)
+
+	h.events.Publish(TopicUpload, "upload_finished", map[string]interface{}{
+		"upload_id": uploadID,
+		"client_id": cs.GetClientID(),
+		"canvas_id": canvasID,
+		"files":     records,
+		"total":     len(files),
+		"failed":    failed,
+	})
+
+	response := map[string]interface{}{
+		"success":   failed == 0,
+		"upload_id": uploadID,
+		"files":     records,
+	}
+	status := http.StatusOK
+	if failed == len(files) {
+		response["error"] = records[0].Error
+		status = http.StatusBadGateway
+	} else if failed > 0 {
+		response["error"] = fmt.Sprintf("%d of %d files failed", failed, len(files))
+	}
+	sendJSONResponse(w, response, status)
+}
+
+// placementArea returns the canvas area uploads are laid out over.
+func (h *UploadHandler) placementArea(ctx context.Context, cs *CanvasService, workspace int, canvasID, placement, zoneID string) (*webuiatoms.ZoneBoundingBox, error) {
+	switch placement {
+	case PlacementZone:
+		if zoneID == "" {
+			return nil, fmt.Errorf("zone_id is required for zone placement")
+		}
+		return webuiatoms.GetZoneBoundingBox(h.apiClient, canvasID, zoneID)
+	case PlacementViewport:
+		clientID := cs.GetClientID()
+		if clientID == "" {
+			return nil, fmt.Errorf("viewport not available: no client tracked")
+		}
+		view, err := h.apiClient.Clients().Workspace(ctx, clientID, workspace)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("failed to get viewport: %w", err)
 		}
+		if view.Location == nil || view.Size == nil {
+			return nil, fmt.Errorf("viewport of workspace %d not available", workspace)
+		}
+		return &webuiatoms.ZoneBoundingBox{
+			X: view.Location.X, Y: view.Location.Y,
+			Width: view.Size.Width, Height: view.Size.Height,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown placement %q: use %s or %s", placement, PlacementViewport, PlacementZone)
+	}
+}
+
+// gridLocation returns where the i-th of n uploads goes: the top left of its
+// cell in a near-square grid over area, inside a small margin.
+func gridLocation(area *webuiatoms.ZoneBoundingBox, i, n int) map[string]interface{} {
+	cols := int(math.Ceil(math.Sqrt(float64(n))))
+	rows := (n + cols - 1) / cols
+	cellWidth, cellHeight := area.Width/float64(cols), area.Height/float64(rows)
+	return map[string]interface{}{
+		"x": area.X + (float64(i%cols)+uploadCellMargin)*cellWidth,
+		"y": area.Y + (float64(i/cols)+uploadCellMargin)*cellHeight,
+	}
+}
 
-		// Save file locally first
-		filename := filepath.Join(h.uploadDir, fileHeader.Filename)
-		dst, err := os.Create(filename)
+// stagedSource returns the complete staged upload id as a file to place.
+func (h *UploadHandler) stagedSource(id string) uploadSource {
+	source := uploadSource{name: id, stagedID: id}
+	upload, err := h.staging.Get(id)
+	if err == nil {
+		source.name, source.size = upload.Filename, upload.Size
+	}
+	source.open = func() (io.ReadSeekCloser, error) {
+		file, _, err := h.staging.Open(id)
 		if err != nil {
-			file.Close()
-			continue
+			return nil, err
 		}
+		return file, nil
+	}
+	return source
+}
 
-		io.Copy(dst, file)
-		file.Close()
-		dst.Close()
-
-		// Upload to Canvus API
-		// TODO: Actually upload file to Canvus API
-		// This would require multipart form upload to the API
-		uploadedFiles = append(uploadedFiles, map[string]interface{}{
-			"name": fileHeader.Filename,
-			"size": fileHeader.Size,
-			"path": uploadPath,
-		})
+// parseUploadForm parses the multipart form of an upload of up to maxSize
+// bytes, spooling files to temporary files rather than memory.
+func parseUploadForm(w http.ResponseWriter, r *http.Request, maxSize int64) (*multipart.Form, bool) {
+	// Allow for the form fields and part headers around the files
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendErrorResponse(w, fmt.Sprintf("upload is larger than the %d MB limit: send large files through /api/uploads", maxSize>>20), http.StatusRequestEntityTooLarge)
+		} else {
+			sendErrorResponse(w, "Failed to parse form", http.StatusBadRequest)
+		}
+		return nil, false
 	}
+	return r.MultipartForm, true
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"files":   uploadedFiles,
-	})
+// uploadFile places a file as the widget of its sniffed type, converting it
+// if needed, and fills the hash, type and widget of record. Files placed as
+// they are are streamed, never read into memory.
+func (h *UploadHandler) uploadFile(ctx context.Context, source uploadSource, canvasID string, location map[string]interface{}, record *UploadRecord) error {
+	file, err := source.open()
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	var copyFile io.Writer = io.Discard
+	if h.uploadDir != "" {
+		if kept, err := os.Create(filepath.Join(h.uploadDir, record.Filename)); err != nil {
+			fmt.Printf("[UploadHandler] Failed to keep a copy of %s: %v\n", record.Filename, err)
+		} else {
+			defer kept.Close()
+			copyFile = kept
+		}
+	}
+	if _, err := io.Copy(io.MultiWriter(hash, copyFile), file); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	record.Hash = hex.EncodeToString(hash.Sum(nil))
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind file: %w", err)
+	}
+
+	prepared, err := h.pipeline.Prepare(file, record.Filename)
+	if err != nil {
+		var unsupported *UnsupportedUploadError
+		if errors.As(err, &unsupported) {
+			record.ContentType = unsupported.MIMEType
+		}
+		return err
+	}
+	record.ContentType = prepared.MIMEType
+	record.Converter = prepared.Converter
+	record.WidgetType = prepared.Kind
+
+	metadata := map[string]interface{}{
+		"title":    record.Filename,
+		"location": location,
+	}
+	widgetID, err := placeUpload(ctx, h.apiClient, canvasID, prepared, metadata)
+	if err != nil {
+		return err
+	}
+	record.WidgetID = widgetID
+	return nil
 }
 
-// HandleHistory handles GET /api/remote-upload/history - Get upload history.
+// HandleHistory handles GET /api/remote-upload/history - Page through the
+// upload history, newest first. Query: offset (default 0) and limit
+// (default 50, at most 200).
 func (h *UploadHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Read upload history from local storage or API
-	// For now, return empty list
-	history := []map[string]interface{}{}
-
-	// TODO: Implement actual history tracking
-	// Could read from a local database or fetch from Canvus API
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(history)
-}
+	offset, limit := 0, 50
+	for param, value := range map[string]*int{"offset": &offset, "limit": &limit} {
+		if raw := r.URL.Query().Get(param); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 0 {
+				sendErrorResponse(w, fmt.Sprintf("invalid %s %q", param, raw), http.StatusBadRequest)
+				return
+			}
+			*value = parsed
+		}
+	}
+	limit = min(max(limit, 1), 200)
 
-// UploadRecord represents an upload history record.
-type UploadRecord struct {
-	Filename   string    `json:"filename"`
-	Size       int64     `json:"size"`
-	Path       string    `json:"path"`
-	UploadedAt time.Time `json:"uploaded_at"`
+	records, total := h.history.Page(offset, limit)
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"records": records,
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+	}, http.StatusOK)
 }
-
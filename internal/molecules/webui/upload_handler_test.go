@@ -0,0 +1,167 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestUploadHandler_DeliversFilesToCanvas uploads into a zone and the
+// viewport, and pages the recorded uploads back.
+func TestUploadHandler_DeliversFilesToCanvas(t *testing.T) {
+	var mu sync.Mutex
+	posted := map[string][]map[string]interface{}{} // endpoint -> metadata
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/canvases/canvas-1/anchors/zone-1":
+			w.Write([]byte(`{"id":"zone-1","location":{"x":1000,"y":2000},"size":{"width":400,"height":400},"scale":1}`))
+		case r.URL.Path == "/api/v1/clients/client-1/workspaces/0":
+			w.Write([]byte(`{"index":0,"location":{"x":-500,"y":-500},"size":{"width":1000,"height":1000}}`))
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/v1/canvases/canvas-1/"):
+			r.ParseMultipartForm(1 << 20)
+			var metadata map[string]interface{}
+			json.Unmarshal([]byte(r.FormValue("json")), &metadata)
+			endpoint := strings.TrimPrefix(r.URL.Path, "/api/v1/canvases/canvas-1/")
+			mu.Lock()
+			posted[endpoint] = append(posted[endpoint], metadata)
+			id := fmt.Sprintf("%s-%d", endpoint, len(posted[endpoint]))
+			mu.Unlock()
+			fmt.Fprintf(w, `{"id":%q}`, id)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tracker := webuiatoms.NewCanvasTracker()
+	tracker.UpdateCanvas("canvas-1", "Canvas")
+	cs := &CanvasService{canvasTracker: tracker, clientID: "client-1"}
+	handler := NewUploadHandler(webuiatoms.NewAPIClient(server.URL, "test-token"), cs, "")
+	handler.SetHistory(NewUploadHistory(filepath.Join(t.TempDir(), "upload_history.json")))
+	events := NewEventBus()
+	handler.SetEventBus(events)
+	sub, _ := events.Subscribe([]string{TopicUpload}, 0)
+
+	// Uploads are placed by the type sniffed from their first bytes
+	signatures := map[string]string{
+		".jpg":  "\xff\xd8\xff\xe0\x00\x10JFIF\x00",
+		".mp4":  "\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2",
+		".pdf":  "%PDF-1.7\n",
+		".docx": "PK\x03\x04\x14\x00\x06\x00",
+	}
+	upload := func(fields map[string]string, files ...string) (int, map[string]interface{}) {
+		t.Helper()
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		for name, value := range fields {
+			form.WriteField(name, value)
+		}
+		for _, name := range files {
+			file, _ := form.CreateFormFile("files", name)
+			file.Write([]byte(signatures[filepath.Ext(name)] + "content of " + name))
+		}
+		form.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/remote-upload", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		recorder := httptest.NewRecorder()
+		handler.HandleUpload(recorder, req)
+		var response map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response
+	}
+
+	code, response := upload(map[string]string{"placement": "zone", "zone_id": "zone-1", "uploader": "Alice"}, "photo.jpg", "clip.mp4", "report.docx")
+	if code != http.StatusOK || response["success"] != false {
+		t.Fatalf("zone upload = %d %v, want 200 with a failed file", code, response)
+	}
+	mu.Lock()
+	images, videos := posted["images"], posted["videos"]
+	mu.Unlock()
+	if len(images) != 1 || len(videos) != 1 {
+		t.Fatalf("posted = %v, want one image and one video", posted)
+	}
+	location := images[0]["location"].(map[string]interface{})
+	if x, y := location["x"].(float64), location["y"].(float64); x < 1000 || x > 1400 || y < 2000 || y > 2400 || images[0]["title"] != "photo.jpg" {
+		t.Errorf("image metadata = %v, want inside zone-1", images[0])
+	}
+
+	if code, response := upload(nil, "slides.pdf"); code != http.StatusOK || response["success"] != true {
+		t.Fatalf("viewport upload = %d %v", code, response)
+	}
+	mu.Lock()
+	pdfLocation := posted["pdfs"][0]["location"].(map[string]interface{})
+	mu.Unlock()
+	if x := pdfLocation["x"].(float64); x < -500 || x > 500 {
+		t.Errorf("pdf location = %v, want inside the viewport", pdfLocation)
+	}
+	if code, _ := upload(map[string]string{"placement": "zone"}, "photo.jpg"); code != http.StatusBadRequest {
+		t.Errorf("zone upload without zone_id = %d, want 400", code)
+	}
+
+	phases := map[string]int{}
+	for len(sub.C) > 0 {
+		event := <-sub.C
+		if data, ok := event.Data.(map[string]interface{}); ok && event.Type == "upload_progress" {
+			phases[data["phase"].(string)]++
+		} else if event.Type == "upload_finished" {
+			phases["finished"]++
+		}
+	}
+	if phases["uploading"] != 4 || phases["uploaded"] != 3 || phases["failed"] != 1 || phases["finished"] != 2 {
+		t.Errorf("upload events = %v", phases)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.HandleHistory(recorder, httptest.NewRequest(http.MethodGet, "/api/remote-upload/history?offset=1&limit=2", nil))
+	var page struct {
+		Records []UploadRecord `json:"records"`
+		Total   int            `json:"total"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &page)
+	if page.Total != 4 || len(page.Records) != 2 {
+		t.Fatalf("history page = %+v, want 2 of 4", page)
+	}
+	failed, clip := page.Records[0], page.Records[1]
+	if failed.Filename != "report.docx" || failed.ContentType != "application/zip" || failed.Outcome != "failure" || !strings.Contains(failed.Error, "not supported") {
+		t.Errorf("failed record = %+v", failed)
+	}
+	if clip.WidgetID != "videos-1" || clip.Uploader != "Alice" || clip.ZoneID != "zone-1" || clip.CanvasID != "canvas-1" || clip.Hash == "" || clip.ContentType != "video/mp4" {
+		t.Errorf("clip record = %+v", clip)
+	}
+	if reloaded, total := NewUploadHistory(handler.history.path).Page(0, 1); total != 4 || reloaded[0].Filename != "slides.pdf" {
+		t.Errorf("reloaded history = %d records, newest %+v", total, reloaded)
+	}
+
+	// A file sent through /api/uploads is placed by its id, then dropped
+	staging := NewUploadStaging(t.TempDir(), 1<<10)
+	handler.SetStaging(staging)
+	staged, _ := staging.Create("poster.png", 8)
+	staging.Append(staged.ID, 0, strings.NewReader("\x89PNG\r\n\x1a\n"))
+	if code, response := upload(map[string]string{"staged": staged.ID}); code != http.StatusOK || response["success"] != true {
+		t.Fatalf("staged upload = %d %v", code, response)
+	}
+	if _, err := staging.Get(staged.ID); err == nil {
+		t.Error("placed staged upload kept")
+	}
+	var tooLarge bytes.Buffer
+	form := multipart.NewWriter(&tooLarge)
+	file, _ := form.CreateFormFile("files", "large.png")
+	file.Write(bytes.Repeat([]byte("x"), 2<<20))
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/remote-upload", &tooLarge)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	recorder = httptest.NewRecorder()
+	handler.HandleUpload(recorder, req)
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("upload over the limit = %d, want 413", recorder.Code)
+	}
+}
@@ -0,0 +1,215 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxUploadHistory is how many uploads the history keeps.
+const MaxUploadHistory = 1000
+
+// UploadRecord represents an upload history record.
+type UploadRecord struct {
+	UploadID    string    `json:"upload_id"`
+	Filename    string    `json:"filename"`
+	Size        int64     `json:"size"`
+	Hash        string    `json:"hash,omitempty"`         // SHA-256 of the file, hex
+	ContentType string    `json:"content_type,omitempty"` // sniffed MIME type
+	Converter   string    `json:"converter,omitempty"`    // conversion applied, if any
+	Uploader    string    `json:"uploader,omitempty"`
+	RemoteIP    string    `json:"remote_ip,omitempty"`
+	ClientID    string    `json:"client_id,omitempty"`
+	CanvasID    string    `json:"canvas_id"`
+	WidgetID    string    `json:"widget_id,omitempty"`
+	WidgetType  string    `json:"widget_type,omitempty"`
+	Placement   string    `json:"placement"` // viewport or zone
+	ZoneID      string    `json:"zone_id,omitempty"`
+	Outcome     string    `json:"outcome"` // success, failure or duplicate
+	Error       string    `json:"error,omitempty"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
+
+// UploadHistory is the history of remote uploads, oldest first. When
+// created with a path it is persisted as JSON after every upload.
+//
+// Besides the records, which are capped at MaxUploadHistory, it keeps an
+// index of the hashes of every file placed on each canvas, persisted next to
+// the history, so duplicates are recognised however long ago they were
+// placed.
+type UploadHistory struct {
+	mu      sync.Mutex
+	path    string
+	records []UploadRecord
+	placed  map[string]bool // canvas ID + "/" + hash of successful uploads
+}
+
+// NewUploadHistory creates a history persisted at path, loading any existing
+// records. An empty path keeps the history in memory only.
+func NewUploadHistory(path string) *UploadHistory {
+	h := &UploadHistory{path: path, placed: make(map[string]bool)}
+	if path == "" {
+		return h
+	}
+	h.loadPlaced()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("[UploadHistory] Failed to read %s: %v\n", path, err)
+		}
+		return h
+	}
+	if err := json.Unmarshal(data, &h.records); err != nil {
+		fmt.Printf("[UploadHistory] Failed to parse %s, starting with empty history: %v\n", path, err)
+		h.records = nil
+		return h
+	}
+	fmt.Printf("[UploadHistory] Loaded %d uploads from %s\n", len(h.records), path)
+	for _, record := range h.records {
+		h.index(record)
+	}
+	return h
+}
+
+// loadPlaced reads the index of placed files.
+func (h *UploadHistory) loadPlaced() {
+	data, err := os.ReadFile(h.placedPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("[UploadHistory] Failed to read %s: %v\n", h.placedPath(), err)
+		}
+		return
+	}
+	var keys []string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		fmt.Printf("[UploadHistory] Failed to parse %s: %v\n", h.placedPath(), err)
+		return
+	}
+	for _, key := range keys {
+		h.placed[key] = true
+	}
+}
+
+// placedPath is where the index of placed files is persisted.
+func (h *UploadHistory) placedPath() string {
+	return strings.TrimSuffix(h.path, filepath.Ext(h.path)) + "_hashes.json"
+}
+
+// index adds record to the index of placed files and reports whether it was
+// not in it yet. Must be called with h.mu held.
+func (h *UploadHistory) index(record UploadRecord) bool {
+	if record.Outcome != "success" || record.Hash == "" {
+		return false
+	}
+	key := record.CanvasID + "/" + record.Hash
+	if h.placed[key] {
+		return false
+	}
+	h.placed[key] = true
+	return true
+}
+
+// Placed reports whether a file with hash was successfully placed on
+// canvasID, including uploads dropped from the records since.
+func (h *UploadHistory) Placed(canvasID, hash string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.placed[canvasID+"/"+hash]
+}
+
+// Record adds uploads to the history, dropping the oldest beyond
+// MaxUploadHistory.
+func (h *UploadHistory) Record(records ...UploadRecord) {
+	if len(records) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, records...)
+	if len(h.records) > MaxUploadHistory {
+		h.records = h.records[len(h.records)-MaxUploadHistory:]
+	}
+	indexed := false
+	for _, record := range records {
+		if h.index(record) {
+			indexed = true
+		}
+	}
+	h.save()
+	if indexed {
+		h.savePlaced()
+	}
+}
+
+// Page returns up to limit records, newest first, skipping the newest
+// offset, and the number of records in the history.
+func (h *UploadHistory) Page(offset, limit int) ([]UploadRecord, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	page := []UploadRecord{}
+	for i := len(h.records) - 1 - offset; i >= 0 && len(page) < limit; i-- {
+		page = append(page, h.records[i])
+	}
+	return page, len(h.records)
+}
+
+// Find returns the records match accepts, oldest first.
+func (h *UploadHistory) Find(match func(UploadRecord) bool) []UploadRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var found []UploadRecord
+	for _, record := range h.records {
+		if match(record) {
+			found = append(found, record)
+		}
+	}
+	return found
+}
+
+// save writes the history to disk. Must be called with h.mu held.
+func (h *UploadHistory) save() {
+	if h.path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(h.records, "", "  ")
+	if err != nil {
+		fmt.Printf("[UploadHistory] Failed to encode history: %v\n", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		fmt.Printf("[UploadHistory] Failed to create history directory: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(h.path, data, 0644); err != nil {
+		fmt.Printf("[UploadHistory] Failed to write %s: %v\n", h.path, err)
+	}
+}
+
+// savePlaced writes the index of placed files to disk. Must be called with
+// h.mu held.
+func (h *UploadHistory) savePlaced() {
+	if h.path == "" {
+		return
+	}
+
+	keys := make([]string, 0, len(h.placed))
+	for key := range h.placed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	data, err := json.Marshal(keys)
+	if err != nil {
+		fmt.Printf("[UploadHistory] Failed to encode placed files: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(h.placedPath(), data, 0644); err != nil {
+		fmt.Printf("[UploadHistory] Failed to write %s: %v\n", h.placedPath(), err)
+	}
+}
@@ -0,0 +1,29 @@
+package webui
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestUploadHistory_PlacedOutlivesRecords checks that placed files are still
+// recognised after their records are dropped and the history is reloaded.
+func TestUploadHistory_PlacedOutlivesRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload_history.json")
+	history := NewUploadHistory(path)
+	history.Record(UploadRecord{Filename: "a.png", CanvasID: "canvas-1", Hash: "aaaa", Outcome: "success"})
+	history.Record(UploadRecord{Filename: "b.png", CanvasID: "canvas-1", Hash: "bbbb", Outcome: "failure"})
+	for i := 0; i < MaxUploadHistory; i++ {
+		history.Record(UploadRecord{Filename: "other.png", CanvasID: "canvas-1", Outcome: "failure"})
+	}
+	if found := history.Find(func(record UploadRecord) bool { return record.Hash == "aaaa" }); len(found) != 0 {
+		t.Fatalf("record of a.png not dropped: %+v", found)
+	}
+
+	reloaded := NewUploadHistory(path)
+	if !reloaded.Placed("canvas-1", "aaaa") {
+		t.Error("a.png not recognised as placed after its record was dropped")
+	}
+	if reloaded.Placed("canvas-1", "bbbb") || reloaded.Placed("canvas-2", "aaaa") {
+		t.Error("failed upload or other canvas recognised as placed")
+	}
+}
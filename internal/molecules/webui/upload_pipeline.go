@@ -0,0 +1,432 @@
+package webui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+	"golang.org/x/image/webp"
+)
+
+// Upload kinds: the widget type an upload is placed as.
+const (
+	KindImage   = "Image"
+	KindVideo   = "Video"
+	KindPDF     = "Pdf"
+	KindNote    = "Note"
+	KindBrowser = "Browser"
+)
+
+// maxConvertSize is the largest file converters are given; they work in
+// memory.
+const maxConvertSize = 64 << 20
+
+// maxConvertPixels is the largest image converters decode, in pixels. It
+// keeps a small file that decodes to gigabytes from exhausting memory.
+const maxConvertPixels = 50_000_000
+
+// maxNoteText is the longest text placed as a note.
+const maxNoteText = 32 << 10
+
+// nativeUploadTypes maps the MIME types the Canvus server takes as they are
+// to the kind they are placed as.
+var nativeUploadTypes = map[string]string{
+	"image/jpeg":       KindImage,
+	"image/png":        KindImage,
+	"image/gif":        KindImage,
+	"image/bmp":        KindImage,
+	"image/tiff":       KindImage,
+	"video/mp4":        KindVideo,
+	"video/quicktime":  KindVideo,
+	"video/avi":        KindVideo,
+	"video/x-ms-wmv":   KindVideo,
+	"video/webm":       KindVideo,
+	"video/x-matroska": KindVideo,
+	"application/pdf":  KindPDF,
+}
+
+// unsupportedHints tells how to get a type the pipeline cannot place into
+// one it can.
+var unsupportedHints = map[string]string{
+	"image/heic":      "export it as JPEG; iPhones take JPEG photos with Settings > Camera > Formats > Most Compatible",
+	"image/avif":      "export it as JPEG or PNG",
+	"application/zip": "export documents as PDF",
+}
+
+// PreparedUpload is a file ready to be placed on a canvas: converted if
+// needed, with the kind of widget it becomes.
+type PreparedUpload struct {
+	Kind      string        // widget type to create
+	MIMEType  string        // sniffed type of the original file
+	Filename  string        // name to upload as; conversions change the extension
+	Converter string        // name of the converter applied, if any
+	Data      io.ReadSeeker // content of images, videos and PDFs
+	Text      string        // text of notes
+	URL       string        // address of browsers
+}
+
+// UploadConverter turns files of some MIME types into something a canvas
+// takes. Convert returns nil to keep the file as it is.
+type UploadConverter struct {
+	Name    string
+	From    []string               // MIME types converted
+	To      string                 // kind produced
+	Applies func(head []byte) bool // optional: whether a file needs converting
+	Convert func(data []byte, filename string) (*PreparedUpload, error)
+}
+
+// UnsupportedUploadError reports a file of a type that can neither be
+// placed nor converted.
+type UnsupportedUploadError struct {
+	Filename string
+	MIMEType string
+	Reason   string
+}
+
+func (e *UnsupportedUploadError) Error() string {
+	msg := fmt.Sprintf("%s is not supported (%s): upload images, videos, PDFs, text or links", e.Filename, e.MIMEType)
+	if e.Reason != "" {
+		msg += "; " + e.Reason
+	}
+	return msg
+}
+
+// UploadPipeline sniffs the type of uploads and converts the ones the
+// Canvus server does not take.
+type UploadPipeline struct {
+	mu         sync.RWMutex
+	converters []UploadConverter
+}
+
+// NewUploadPipeline creates a pipeline with the built-in converters: WebP
+// to PNG, JPEG orientation, text to notes and link files to browsers.
+func NewUploadPipeline() *UploadPipeline {
+	p := &UploadPipeline{}
+	p.Register(webpConverter())
+	p.Register(orientationConverter())
+	p.Register(noteConverter())
+	p.Register(linkConverter())
+	return p
+}
+
+// Register adds a converter. A converter registered later takes precedence
+// for the types it shares with earlier ones.
+func (p *UploadPipeline) Register(converter UploadConverter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.converters = append(p.converters, converter)
+}
+
+// converter returns the converter for a file of mimeType, or nil.
+func (p *UploadPipeline) converter(mimeType string, head []byte) *UploadConverter {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for i := len(p.converters) - 1; i >= 0; i-- {
+		converter := p.converters[i]
+		for _, from := range converter.From {
+			if from == mimeType && (converter.Applies == nil || converter.Applies(head)) {
+				return &converter
+			}
+		}
+	}
+	return nil
+}
+
+// Prepare sniffs the type of file and converts it if needed. It returns an
+// *UnsupportedUploadError for files that cannot be placed.
+func (p *UploadPipeline) Prepare(file io.ReadSeeker, filename string) (*PreparedUpload, error) {
+	head := make([]byte, webuiatoms.SniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	head = head[:n]
+	size, err := file.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind file: %w", err)
+	}
+
+	mimeType := webuiatoms.SniffContentType(head, filename)
+	upload := &PreparedUpload{
+		Kind:     nativeUploadTypes[mimeType],
+		MIMEType: mimeType,
+		Filename: filename,
+		Data:     file,
+	}
+
+	if converter := p.converter(mimeType, head); converter != nil {
+		if size <= maxConvertSize {
+			data, err := io.ReadAll(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file: %w", err)
+			}
+			converted, err := converter.Convert(data, filename)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert %s (%s): %w", filename, converter.Name, err)
+			}
+			if converted != nil {
+				converted.MIMEType = mimeType
+				converted.Converter = converter.Name
+				if converted.Filename == "" {
+					converted.Filename = filename
+				}
+				return converted, nil
+			}
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("failed to rewind file: %w", err)
+			}
+		} else if upload.Kind == "" {
+			return nil, &UnsupportedUploadError{
+				Filename: filename,
+				MIMEType: mimeType,
+				Reason:   fmt.Sprintf("files over %d MB are not converted", maxConvertSize>>20),
+			}
+		}
+	}
+
+	if upload.Kind == "" {
+		return nil, &UnsupportedUploadError{Filename: filename, MIMEType: mimeType, Reason: unsupportedHints[mimeType]}
+	}
+	return upload, nil
+}
+
+// placeUpload creates the widget of a prepared upload on a canvas. metadata
+// holds the title and location; it returns the widget id.
+func placeUpload(ctx context.Context, apiClient *webuiatoms.APIClient, canvasID string, upload *PreparedUpload, metadata map[string]interface{}) (string, error) {
+	switch upload.Kind {
+	case KindImage:
+		image, err := apiClient.Images(canvasID).Upload(ctx, metadata, upload.Data, upload.Filename)
+		if err != nil {
+			return "", err
+		}
+		return image.ID, nil
+	case KindVideo:
+		video, err := apiClient.Videos(canvasID).Upload(ctx, metadata, upload.Data, upload.Filename)
+		if err != nil {
+			return "", err
+		}
+		return video.ID, nil
+	case KindPDF:
+		pdf, err := apiClient.PDFs(canvasID).Upload(ctx, metadata, upload.Data, upload.Filename)
+		if err != nil {
+			return "", err
+		}
+		return pdf.ID, nil
+	case KindNote:
+		payload := map[string]interface{}{"text": upload.Text, "auto_text_color": true}
+		for key, value := range metadata {
+			payload[key] = value
+		}
+		note, err := apiClient.Notes(canvasID).Create(ctx, payload)
+		if err != nil {
+			return "", err
+		}
+		return note.ID, nil
+	case KindBrowser:
+		payload := map[string]interface{}{"url": upload.URL}
+		for key, value := range metadata {
+			payload[key] = value
+		}
+		browser, err := apiClient.Browsers(canvasID).Create(ctx, payload)
+		if err != nil {
+			return "", err
+		}
+		return browser.ID, nil
+	default:
+		return "", fmt.Errorf("cannot place uploads of kind %q", upload.Kind)
+	}
+}
+
+// HandleTypes handles GET /api/uploads/types - List the file types placed
+// as they are and the conversions applied to others.
+func (p *UploadPipeline) HandleTypes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	type nativeType struct {
+		MIMEType string `json:"mime_type"`
+		Kind     string `json:"kind"`
+	}
+	types := make([]nativeType, 0, len(nativeUploadTypes))
+	for mimeType, kind := range nativeUploadTypes {
+		types = append(types, nativeType{MIMEType: mimeType, Kind: kind})
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].MIMEType < types[j].MIMEType })
+
+	type conversion struct {
+		Name string   `json:"name"`
+		From []string `json:"from"`
+		To   string   `json:"to"`
+	}
+	p.mu.RLock()
+	conversions := make([]conversion, 0, len(p.converters))
+	for _, converter := range p.converters {
+		conversions = append(conversions, conversion{Name: converter.Name, From: converter.From, To: converter.To})
+	}
+	p.mu.RUnlock()
+
+	sendJSONResponse(w, map[string]interface{}{
+		"success":            true,
+		"types":              types,
+		"conversions":        conversions,
+		"max_convert_size":   maxConvertSize,
+		"max_convert_pixels": maxConvertPixels,
+	}, http.StatusOK)
+}
+
+// withExtension returns filename with its extension replaced by ext.
+func withExtension(filename, ext string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + ext
+}
+
+// checkImageSize rejects images larger than maxConvertPixels, read from the
+// header by decodeConfig before anything is decoded.
+func checkImageSize(data []byte, decodeConfig func(io.Reader) (image.Config, error)) error {
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return fmt.Errorf("image has no pixels")
+	}
+	if int64(config.Width)*int64(config.Height) > maxConvertPixels {
+		return fmt.Errorf("image is %dx%d pixels, larger than the %d megapixels that can be converted", config.Width, config.Height, maxConvertPixels/1_000_000)
+	}
+	return nil
+}
+
+// encodePNG returns img as a PNG upload named after filename.
+func encodePNG(img image.Image, filename string) (*PreparedUpload, error) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		return nil, err
+	}
+	return &PreparedUpload{
+		Kind:     KindImage,
+		Filename: withExtension(filename, ".png"),
+		Data:     bytes.NewReader(encoded.Bytes()),
+	}, nil
+}
+
+// webpConverter converts WebP images, which the Canvus server does not
+// show, to PNG.
+func webpConverter() UploadConverter {
+	return UploadConverter{
+		Name: "webp-to-png",
+		From: []string{"image/webp"},
+		To:   KindImage,
+		Convert: func(data []byte, filename string) (*PreparedUpload, error) {
+			if err := checkImageSize(data, webp.DecodeConfig); err != nil {
+				return nil, err
+			}
+			img, err := webp.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			return encodePNG(img, filename)
+		},
+	}
+}
+
+// orientationConverter turns JPEG photos taken sideways upright, since the
+// Canvus server ignores their EXIF orientation.
+func orientationConverter() UploadConverter {
+	return UploadConverter{
+		Name:    "jpeg-orientation",
+		From:    []string{"image/jpeg"},
+		To:      KindImage,
+		Applies: func(head []byte) bool { return webuiatoms.JPEGOrientation(head) > 1 },
+		Convert: func(data []byte, filename string) (*PreparedUpload, error) {
+			if err := checkImageSize(data, jpeg.DecodeConfig); err != nil {
+				return nil, err
+			}
+			img, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			// Re-encoding drops the EXIF block, orientation included
+			var encoded bytes.Buffer
+			upright := webuiatoms.ApplyOrientation(img, webuiatoms.JPEGOrientation(data))
+			if err := jpeg.Encode(&encoded, upright, &jpeg.Options{Quality: 92}); err != nil {
+				return nil, err
+			}
+			return &PreparedUpload{Kind: KindImage, Data: bytes.NewReader(encoded.Bytes())}, nil
+		},
+	}
+}
+
+// noteConverter places plain text and Markdown files as notes.
+func noteConverter() UploadConverter {
+	return UploadConverter{
+		Name: "text-to-note",
+		From: []string{"text/plain", "text/markdown"},
+		To:   KindNote,
+		Convert: func(data []byte, filename string) (*PreparedUpload, error) {
+			if !utf8.Valid(data) {
+				return nil, fmt.Errorf("text is not UTF-8")
+			}
+			if len(data) > maxNoteText {
+				return nil, fmt.Errorf("text is longer than %d KB: upload it as a PDF", maxNoteText>>10)
+			}
+			text := strings.TrimPrefix(string(data), "\ufeff")
+			return &PreparedUpload{Kind: KindNote, Text: strings.TrimSpace(text)}, nil
+		},
+	}
+}
+
+// linkConverter places Windows .url and macOS .webloc shortcuts as
+// browsers showing their address.
+func linkConverter() UploadConverter {
+	return UploadConverter{
+		Name: "link-to-browser",
+		From: []string{"application/x-url", "application/x-webloc"},
+		To:   KindBrowser,
+		Convert: func(data []byte, filename string) (*PreparedUpload, error) {
+			address := shortcutURL(data)
+			parsed, err := url.Parse(address)
+			if address == "" || err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return nil, fmt.Errorf("no web address found in the shortcut")
+			}
+			return &PreparedUpload{Kind: KindBrowser, URL: parsed.String()}, nil
+		},
+	}
+}
+
+// shortcutURL returns the address of a .url ("URL=" line) or XML .webloc
+// (string after the URL key) shortcut.
+func shortcutURL(data []byte) string {
+	text := string(data)
+	if _, plist, ok := strings.Cut(text, "<key>URL</key>"); ok {
+		if _, rest, ok := strings.Cut(plist, "<string>"); ok {
+			address, _, _ := strings.Cut(rest, "</string>")
+			return strings.ReplaceAll(strings.TrimSpace(address), "&amp;", "&")
+		}
+		return ""
+	}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "URL") {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestUploadPipeline_SniffsAndConverts prepares files by their content,
+// whatever their name says.
+func TestUploadPipeline_SniffsAndConverts(t *testing.T) {
+	pipeline := NewUploadPipeline()
+	prepare := func(name, content string) (*PreparedUpload, error) {
+		t.Helper()
+		return pipeline.Prepare(strings.NewReader(content), name)
+	}
+
+	// 1x1 lossless WebP
+	webpData, _ := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+	converted, err := prepare("sticker.webp", string(webpData))
+	if err != nil || converted.Kind != KindImage || converted.Filename != "sticker.png" || converted.Converter != "webp-to-png" {
+		t.Fatalf("webp = %+v, %v", converted, err)
+	}
+	data, _ := io.ReadAll(converted.Data)
+	if img, err := png.Decode(bytes.NewReader(data)); err != nil || img.Bounds() != image.Rect(0, 0, 1, 1) {
+		t.Errorf("converted webp does not decode as a 1x1 PNG: %v", err)
+	}
+
+	pdf, err := prepare("slides.png", "%PDF-1.7\nslides")
+	if err != nil || pdf.Kind != KindPDF || pdf.Converter != "" || pdf.Filename != "slides.png" {
+		t.Errorf("mislabelled pdf = %+v, %v", pdf, err)
+	}
+	if data, _ := io.ReadAll(pdf.Data); string(data) != "%PDF-1.7\nslides" {
+		t.Errorf("pdf read back as %q, want the whole file", data)
+	}
+
+	note, err := prepare("agenda.md", "\ufeff# Agenda\n\n- Intro\n")
+	if err != nil || note.Kind != KindNote || note.Text != "# Agenda\n\n- Intro" || note.MIMEType != "text/markdown" {
+		t.Errorf("markdown = %+v, %v", note, err)
+	}
+
+	link, err := prepare("Docs.url", "[InternetShortcut]\r\nURL=https://example.com/docs?a=1\r\n")
+	if err != nil || link.Kind != KindBrowser || link.URL != "https://example.com/docs?a=1" {
+		t.Errorf("url shortcut = %+v, %v", link, err)
+	}
+	if _, err := prepare("Local.url", "[InternetShortcut]\r\nURL=file:///C:/secret.txt\r\n"); err == nil {
+		t.Error("shortcut to a local file accepted")
+	}
+
+	var unsupported *UnsupportedUploadError
+	if _, err := prepare("IMG_0001.HEIC", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"); !errors.As(err, &unsupported) || unsupported.MIMEType != "image/heic" || unsupported.Reason == "" {
+		t.Errorf("heic = %v, want unsupported with a hint", err)
+	}
+
+	// Later converters take precedence
+	pipeline.Register(UploadConverter{
+		Name: "heic-stub",
+		From: []string{"image/heic"},
+		To:   KindImage,
+		Convert: func(data []byte, filename string) (*PreparedUpload, error) {
+			return &PreparedUpload{Kind: KindImage, Filename: withExtension(filename, ".png"), Data: bytes.NewReader(data)}, nil
+		},
+	})
+	if heic, err := prepare("IMG_0001.HEIC", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"); err != nil || heic.Converter != "heic-stub" {
+		t.Errorf("heic with a converter = %+v, %v", heic, err)
+	}
+
+	recorder := httptest.NewRecorder()
+	pipeline.HandleTypes(recorder, httptest.NewRequest(http.MethodGet, "/api/uploads/types", nil))
+	var types struct {
+		Types []struct {
+			MIMEType string `json:"mime_type"`
+		} `json:"types"`
+		Conversions []struct {
+			Name string `json:"name"`
+		} `json:"conversions"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &types)
+	if len(types.Types) != len(nativeUploadTypes) || len(types.Conversions) != 5 || types.Conversions[4].Name != "heic-stub" {
+		t.Errorf("types = %+v", types)
+	}
+}
+
+// TestCheckImageSize_RejectsDecompressionBombs reads the size of a JPEG from
+// its header and refuses to convert one claiming more than
+// maxConvertPixels.
+func TestCheckImageSize_RejectsDecompressionBombs(t *testing.T) {
+	var small bytes.Buffer
+	jpeg.Encode(&small, image.NewGray(image.Rect(0, 0, 8, 8)), nil)
+	if err := checkImageSize(small.Bytes(), jpeg.DecodeConfig); err != nil {
+		t.Errorf("8x8 JPEG rejected: %v", err)
+	}
+
+	// Same file with its frame header claiming 30000x30000 pixels
+	bomb := bytes.Clone(small.Bytes())
+	sof := bytes.Index(bomb, []byte{0xFF, 0xC0})
+	if sof < 0 {
+		t.Fatal("no SOF0 marker in encoded JPEG")
+	}
+	binary.BigEndian.PutUint16(bomb[sof+5:], 30000)
+	binary.BigEndian.PutUint16(bomb[sof+7:], 30000)
+	if err := checkImageSize(bomb, jpeg.DecodeConfig); err == nil || !strings.Contains(err.Error(), "30000x30000") {
+		t.Errorf("30000x30000 JPEG = %v, want rejected", err)
+	}
+}
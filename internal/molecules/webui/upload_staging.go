@@ -0,0 +1,374 @@
+package webui
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxUploadSize is the largest file the WebUI accepts unless
+// max_upload_mb is configured.
+const DefaultMaxUploadSize int64 = 2 << 30
+
+// UploadChunkSize is the chunk size suggested to browsers for resumable
+// uploads; maxUploadChunk is the largest chunk accepted.
+const (
+	UploadChunkSize = 8 << 20
+	maxUploadChunk  = 64 << 20
+)
+
+// StagedUploadTTL is how long a staged upload is kept after its last chunk.
+const StagedUploadTTL = 24 * time.Hour
+
+// uploadRequestTimeout bounds reading and answering an upload request; the
+// server's own timeouts are sized for small API calls.
+const uploadRequestTimeout = 30 * time.Minute
+
+// Errors of staged uploads.
+var (
+	errStagedUploadNotFound = errors.New("upload not found or expired")
+	errStagedUploadBusy     = errors.New("upload is busy with another request")
+	errStagedUploadOffset   = errors.New("chunk does not start at the upload offset")
+	errStagedUploadOverflow = errors.New("chunk goes past the end of the upload")
+)
+
+// StagedUpload is a file sent in chunks, placed on the canvas once complete.
+type StagedUpload struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"` // bytes received so far
+	Complete  bool      `json:"complete"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UploadStaging receives files in chunks so that interrupted uploads resume
+// where they stopped. Each upload is a data file and a metadata file in dir;
+// the size of the data file is the offset, so uploads survive a restart.
+// With an empty dir, a temporary directory is used.
+type UploadStaging struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+	uploads map[string]*StagedUpload
+	busy    map[string]bool
+	now     func() time.Time
+}
+
+// NewUploadStaging creates a staging area in dir accepting files of up to
+// maxSize bytes, loading the unexpired uploads already there.
+func NewUploadStaging(dir string, maxSize int64) *UploadStaging {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxUploadSize
+	}
+	s := &UploadStaging{
+		dir:     dir,
+		maxSize: maxSize,
+		uploads: make(map[string]*StagedUpload),
+		busy:    make(map[string]bool),
+		now:     time.Now,
+	}
+	if dir == "" {
+		return s
+	}
+
+	metaFiles, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, metaFile := range metaFiles {
+		data, err := os.ReadFile(metaFile)
+		var upload StagedUpload
+		if err == nil {
+			err = json.Unmarshal(data, &upload)
+		}
+		info, statErr := os.Stat(s.dataPath(upload.ID))
+		if err != nil || statErr != nil || upload.ID == "" {
+			fmt.Printf("[UploadStaging] Dropping unreadable upload %s\n", metaFile)
+			os.Remove(metaFile)
+			continue
+		}
+		upload.Offset = min(info.Size(), upload.Size)
+		upload.Complete = upload.Offset == upload.Size
+		upload.UpdatedAt = info.ModTime()
+		s.uploads[upload.ID] = &upload
+	}
+	s.expire()
+	if len(s.uploads) > 0 {
+		fmt.Printf("[UploadStaging] Resuming %d uploads from %s\n", len(s.uploads), dir)
+	}
+	return s
+}
+
+// MaxSize returns the largest file accepted, in bytes.
+func (s *UploadStaging) MaxSize() int64 {
+	return s.maxSize
+}
+
+func (s *UploadStaging) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".part")
+}
+
+func (s *UploadStaging) metaPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Create starts an upload of a file of size bytes.
+func (s *UploadStaging) Create(filename string, size int64) (StagedUpload, error) {
+	filename = filepath.Base(filename)
+	if filename == "." || filename == string(filepath.Separator) {
+		return StagedUpload{}, fmt.Errorf("filename is required")
+	}
+	if size <= 0 {
+		return StagedUpload{}, fmt.Errorf("size must be positive")
+	}
+	if size > s.maxSize {
+		return StagedUpload{}, fmt.Errorf("%s is larger than the %d MB upload limit", filename, s.maxSize>>20)
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return StagedUpload{}, fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "powertoys-uploads-")
+		if err != nil {
+			return StagedUpload{}, fmt.Errorf("failed to create upload directory: %w", err)
+		}
+		s.dir = dir
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return StagedUpload{}, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	upload := &StagedUpload{
+		ID:        hex.EncodeToString(raw),
+		Filename:  filename,
+		Size:      size,
+		UpdatedAt: s.now(),
+	}
+	data, _ := json.Marshal(upload)
+	if err := os.WriteFile(s.dataPath(upload.ID), nil, 0600); err != nil {
+		return StagedUpload{}, fmt.Errorf("failed to create upload: %w", err)
+	}
+	if err := os.WriteFile(s.metaPath(upload.ID), data, 0600); err != nil {
+		os.Remove(s.dataPath(upload.ID))
+		return StagedUpload{}, fmt.Errorf("failed to create upload: %w", err)
+	}
+	s.uploads[upload.ID] = upload
+	fmt.Printf("[UploadStaging] Started upload %s of %s (%d bytes)\n", upload.ID, filename, size)
+	return *upload, nil
+}
+
+// Get returns the upload id.
+func (s *UploadStaging) Get(id string) (StagedUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	upload, ok := s.uploads[id]
+	if !ok {
+		return StagedUpload{}, errStagedUploadNotFound
+	}
+	return *upload, nil
+}
+
+// Append adds chunk to upload id, which must stand at offset. Whatever part
+// of the chunk arrives is kept, so a client cut off mid-chunk resumes from
+// the offset of the upload.
+func (s *UploadStaging) Append(id string, offset int64, chunk io.Reader) (StagedUpload, error) {
+	s.mu.Lock()
+	upload, ok := s.uploads[id]
+	switch {
+	case !ok:
+		s.mu.Unlock()
+		return StagedUpload{}, errStagedUploadNotFound
+	case s.busy[id]:
+		s.mu.Unlock()
+		return *upload, errStagedUploadBusy
+	case offset != upload.Offset:
+		s.mu.Unlock()
+		return *upload, errStagedUploadOffset
+	}
+	s.busy[id] = true
+	remaining := upload.Size - upload.Offset
+	s.mu.Unlock()
+
+	written, err := s.write(id, chunk, remaining)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.busy, id)
+	upload.Offset += written
+	upload.Complete = upload.Offset == upload.Size
+	upload.UpdatedAt = s.now()
+	return *upload, err
+}
+
+// write appends up to remaining bytes of chunk to the data of upload id.
+func (s *UploadStaging) write(id string, chunk io.Reader, remaining int64) (int64, error) {
+	file, err := os.OpenFile(s.dataPath(id), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open upload: %w", err)
+	}
+	written, err := io.Copy(file, io.LimitReader(chunk, remaining))
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, fmt.Errorf("chunk interrupted after %d bytes: %w", written, err)
+	}
+	if n, _ := chunk.Read(make([]byte, 1)); n > 0 {
+		return written, errStagedUploadOverflow
+	}
+	return written, nil
+}
+
+// Open returns the data of the complete upload id.
+func (s *UploadStaging) Open(id string) (*os.File, StagedUpload, error) {
+	upload, err := s.Get(id)
+	if err != nil {
+		return nil, upload, err
+	}
+	if !upload.Complete {
+		return nil, upload, fmt.Errorf("upload of %s is not complete: %d of %d bytes", upload.Filename, upload.Offset, upload.Size)
+	}
+	file, err := os.Open(s.dataPath(id))
+	if err != nil {
+		return nil, upload, fmt.Errorf("failed to open upload: %w", err)
+	}
+	return file, upload, nil
+}
+
+// Remove deletes upload id.
+func (s *UploadStaging) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(id)
+}
+
+// remove deletes upload id. Must be called with s.mu held.
+func (s *UploadStaging) remove(id string) {
+	if _, ok := s.uploads[id]; !ok {
+		return
+	}
+	delete(s.uploads, id)
+	os.Remove(s.dataPath(id))
+	os.Remove(s.metaPath(id))
+}
+
+// expire removes uploads untouched for StagedUploadTTL. Must be called with
+// s.mu held.
+func (s *UploadStaging) expire() {
+	cutoff := s.now().Add(-StagedUploadTTL)
+	for id, upload := range s.uploads {
+		if !s.busy[id] && upload.UpdatedAt.Before(cutoff) {
+			fmt.Printf("[UploadStaging] Expiring upload %s of %s\n", id, upload.Filename)
+			s.remove(id)
+		}
+	}
+}
+
+// HandleUploads handles the resumable upload protocol:
+//
+//	POST   /api/uploads               {"filename": "...", "size": N} - Start an upload
+//	GET    /api/uploads/{id}          - Report the offset to resume from
+//	PUT    /api/uploads/{id}?offset=N - Append the request body at offset N
+//	DELETE /api/uploads/{id}          - Cancel an upload
+//
+// A complete upload is placed by passing its id as staged (Remote Upload) or
+// staged_id (RCU upload-item).
+func (s *UploadStaging) HandleUploads(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/uploads"), "/")
+	if id == "" {
+		if r.Method != http.MethodPost {
+			sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Filename string `json:"filename"`
+			Size     int64  `json:"size"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			sendErrorResponse(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		upload, err := s.Create(req.Filename, req.Size)
+		if err != nil {
+			status := http.StatusBadRequest
+			if req.Size > s.maxSize {
+				status = http.StatusRequestEntityTooLarge
+			}
+			sendErrorResponse(w, err.Error(), status)
+			return
+		}
+		sendJSONResponse(w, map[string]interface{}{
+			"success":    true,
+			"upload":     upload,
+			"chunk_size": UploadChunkSize,
+		}, http.StatusCreated)
+		return
+	}
+
+	var upload StagedUpload
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		upload, err = s.Get(id)
+	case http.MethodPut:
+		offset, parseErr := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+		if parseErr != nil {
+			sendErrorResponse(w, "offset is required", http.StatusBadRequest)
+			return
+		}
+		extendDeadlines(w, uploadRequestTimeout)
+		upload, err = s.Append(id, offset, http.MaxBytesReader(w, r.Body, maxUploadChunk))
+	case http.MethodDelete:
+		s.Remove(id)
+		sendJSONResponse(w, map[string]interface{}{"success": true}, http.StatusOK)
+		return
+	default:
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, errStagedUploadNotFound):
+			sendErrorResponse(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, errStagedUploadBusy), errors.Is(err, errStagedUploadOffset):
+			status = http.StatusConflict
+		}
+		// The offset lets the client resume
+		sendJSONResponse(w, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+			"upload":  upload,
+		}, status)
+		return
+	}
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"upload":  upload,
+	}, http.StatusOK)
+}
+
+// extendDeadlines lets the request of w take up to timeout to read and
+// answer, beyond the server's timeouts.
+func extendDeadlines(w http.ResponseWriter, timeout time.Duration) {
+	controller := http.NewResponseController(w)
+	deadline := time.Now().Add(timeout)
+	controller.SetReadDeadline(deadline)
+	controller.SetWriteDeadline(deadline)
+}
@@ -0,0 +1,84 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestUploadStaging_ResumesInterruptedUploads sends a file in chunks through
+// /api/uploads, cut off mid-chunk and across a restart.
+func TestUploadStaging_ResumesInterruptedUploads(t *testing.T) {
+	dir := t.TempDir()
+	staging := NewUploadStaging(dir, 1<<20)
+	content := bytes.Repeat([]byte("chunked upload "), 4000) // 60000 bytes
+
+	call := func(staging *UploadStaging, method, target string, body io.Reader) (int, StagedUpload) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		staging.HandleUploads(recorder, httptest.NewRequest(method, target, body))
+		var response struct {
+			Upload StagedUpload `json:"upload"`
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response.Upload
+	}
+
+	code, upload := call(staging, http.MethodPost, "/api/uploads", strings.NewReader(`{"filename":"../clip.mp4","size":60000}`))
+	if code != http.StatusCreated || upload.ID == "" || upload.Filename != "clip.mp4" {
+		t.Fatalf("create = %d %+v", code, upload)
+	}
+	chunk := func(staging *UploadStaging, offset int, body io.Reader) (int, StagedUpload) {
+		return call(staging, http.MethodPut, fmt.Sprintf("/api/uploads/%s?offset=%d", upload.ID, offset), body)
+	}
+
+	if code, got := chunk(staging, 0, bytes.NewReader(content[:20000])); code != http.StatusOK || got.Offset != 20000 {
+		t.Fatalf("first chunk = %d %+v", code, got)
+	}
+	// The connection drops 5000 bytes into the second chunk
+	cutOff := io.MultiReader(bytes.NewReader(content[20000:25000]), errorReader{errors.New("connection reset")})
+	if code, got := chunk(staging, 20000, cutOff); code != http.StatusBadRequest || got.Offset != 25000 {
+		t.Fatalf("cut off chunk = %d %+v, want the received bytes kept", code, got)
+	}
+	if code, got := chunk(staging, 20000, bytes.NewReader(content[20000:40000])); code != http.StatusConflict || got.Offset != 25000 {
+		t.Errorf("chunk at a stale offset = %d %+v, want 409 with the offset", code, got)
+	}
+
+	// The server restarts; the client asks where to resume
+	staging = NewUploadStaging(dir, 1<<20)
+	if code, got := call(staging, http.MethodGet, "/api/uploads/"+upload.ID, nil); code != http.StatusOK || got.Offset != 25000 || got.Complete {
+		t.Fatalf("resume = %d %+v", code, got)
+	}
+	if code, got := chunk(staging, 25000, bytes.NewReader(content[25000:])); code != http.StatusOK || !got.Complete {
+		t.Fatalf("last chunk = %d %+v", code, got)
+	}
+
+	file, _, err := staging.Open(upload.ID)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	received, _ := io.ReadAll(file)
+	file.Close()
+	if !bytes.Equal(received, content) {
+		t.Errorf("received %d bytes, want the %d sent", len(received), len(content))
+	}
+
+	if code, _ := call(staging, http.MethodPost, "/api/uploads", strings.NewReader(`{"filename":"huge.mp4","size":2097152}`)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("create over the limit = %d, want 413", code)
+	}
+	call(staging, http.MethodDelete, "/api/uploads/"+upload.ID, nil)
+	if code, _ := call(staging, http.MethodGet, "/api/uploads/"+upload.ID, nil); code != http.StatusNotFound {
+		t.Errorf("cancelled upload = %d, want 404", code)
+	}
+}
+
+// errorReader fails every read with err.
+type errorReader struct{ err error }
+
+func (r errorReader) Read(p []byte) (int, error) { return 0, r.err }
@@ -0,0 +1,557 @@
+package webui
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// DefaultWatchInterval is how often the watch folder is scanned.
+const DefaultWatchInterval = 5 * time.Second
+
+// WatchFolderUploader is the uploader of files placed from the watch folder
+// in the upload history.
+const WatchFolderUploader = "watch folder"
+
+// Subfolders of the watch folder processed files are moved to.
+const (
+	watchArchiveDir = "archive"
+	watchFailedDir  = "failed" // inside the archive
+)
+
+// maxWatchAttempts is how many scans a file failing to upload is retried in
+// before it is moved to the failed archive.
+const maxWatchAttempts = 3
+
+// watchedFile is what a scan saw of a file; it is placed once two scans in
+// a row see the same size and modification time, so files still being
+// copied are left alone.
+type watchedFile struct {
+	size     int64
+	modTime  time.Time
+	attempts int
+}
+
+// watchWidget is a widget the watch folder placed in its zone.
+type watchWidget struct {
+	ID   string
+	Type string
+	X, Y float64 // NaN when not known
+}
+
+// WatchFolderStatus is the state of the watch folder served by
+// /api/watch-folder.
+type WatchFolderStatus struct {
+	Enabled   bool       `json:"enabled"`
+	Dir       string     `json:"dir,omitempty"`
+	Zone      string     `json:"zone,omitempty"`
+	Pending   int        `json:"pending"`
+	Placed    int        `json:"placed"` // widgets in the zone grid
+	LastScan  *time.Time `json:"last_scan,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// WatchFolder places files dropped into a local or network directory on the
+// tracked canvas, gridded over an anchor zone. Files are typed and converted
+// by the upload pipeline; files already placed on the canvas (same SHA-256)
+// are skipped. Processed files are moved to the archive subfolder, files
+// that cannot be placed to archive/failed.
+type WatchFolder struct {
+	mu            sync.Mutex
+	dir           string
+	zone          string // anchor ID or name
+	interval      time.Duration
+	apiClient     *webuiatoms.APIClient
+	canvasService *CanvasService
+	ops           *MacrosOperations
+	pipeline      *UploadPipeline
+	history       *UploadHistory
+	events        *EventBus
+
+	files     map[string]*watchedFile // by name
+	canvasID  string                  // canvas and zone the grid is on
+	zoneID    string
+	widgets   []watchWidget
+	lastScan  time.Time
+	lastError string
+
+	cancel context.CancelFunc // cancels the scan in progress on Stop
+	done   chan struct{}
+}
+
+// NewWatchFolder creates a watch folder placing files from dir into zone
+// (anchor ID or name) of the canvas tracked by canvasService. Call Start to
+// begin scanning.
+func NewWatchFolder(apiClient *webuiatoms.APIClient, canvasService *CanvasService, dir, zone string) *WatchFolder {
+	return &WatchFolder{
+		dir:           dir,
+		zone:          zone,
+		interval:      DefaultWatchInterval,
+		apiClient:     apiClient,
+		canvasService: canvasService,
+		ops:           NewMacrosOperations(apiClient, canvasService),
+		pipeline:      NewUploadPipeline(),
+		history:       NewUploadHistory(""),
+		files:         make(map[string]*watchedFile),
+	}
+}
+
+// SetPipeline sets how the type of files is sniffed and converted.
+func (wf *WatchFolder) SetPipeline(pipeline *UploadPipeline) {
+	wf.pipeline = pipeline
+}
+
+// SetHistory sets the store placed files are recorded in and duplicates
+// are looked up in.
+func (wf *WatchFolder) SetHistory(history *UploadHistory) {
+	wf.history = history
+}
+
+// SetEventBus sets where upload progress is published.
+func (wf *WatchFolder) SetEventBus(events *EventBus) {
+	wf.events = events
+}
+
+// Start scans the folder every interval until Stop is called.
+func (wf *WatchFolder) Start() {
+	wf.mu.Lock()
+	if wf.cancel != nil {
+		wf.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	wf.cancel = cancel
+	wf.done = make(chan struct{})
+	done := wf.done
+	wf.mu.Unlock()
+
+	fmt.Printf("[WatchFolder] Watching %s for zone %s\n", wf.dir, wf.zone)
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(wf.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				wf.scan(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops scanning, cancelling the uploads and moves of a scan in
+// progress, and waits for it to finish.
+func (wf *WatchFolder) Stop() {
+	wf.mu.Lock()
+	cancel, done := wf.cancel, wf.done
+	wf.cancel, wf.done = nil, nil
+	wf.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Status returns the state of the watch folder.
+func (wf *WatchFolder) Status() WatchFolderStatus {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+	status := WatchFolderStatus{
+		Enabled:   true,
+		Dir:       wf.dir,
+		Zone:      wf.zone,
+		Pending:   len(wf.files),
+		Placed:    len(wf.widgets),
+		LastError: wf.lastError,
+	}
+	if !wf.lastScan.IsZero() {
+		lastScan := wf.lastScan
+		status.LastScan = &lastScan
+	}
+	return status
+}
+
+// HandleStatus handles GET /api/watch-folder - Get the state of the watch
+// folder.
+func (wf *WatchFolder) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := WatchFolderStatus{}
+	if wf != nil {
+		status = wf.Status()
+	}
+	sendJSONResponse(w, map[string]interface{}{
+		"success":      true,
+		"watch_folder": status,
+	}, http.StatusOK)
+}
+
+// scan places the files that stopped changing since the previous scan.
+func (wf *WatchFolder) scan(ctx context.Context) {
+	ready, err := wf.readyFiles()
+	if err == nil && len(ready) > 0 {
+		err = wf.place(ctx, ready)
+	}
+
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+	wf.lastScan = time.Now()
+	if err != nil {
+		if err.Error() != wf.lastError {
+			fmt.Printf("[WatchFolder] ERROR: %v\n", err)
+		}
+		wf.lastError = err.Error()
+	} else {
+		wf.lastError = ""
+	}
+}
+
+// readyFiles lists the files of the folder, oldest first, that have the
+// same size and modification time as in the previous scan.
+func (wf *WatchFolder) readyFiles() ([]string, error) {
+	entries, err := os.ReadDir(wf.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch folder: %w", err)
+	}
+
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+	present := make(map[string]bool)
+	type readyFile struct {
+		name    string
+		modTime time.Time
+	}
+	var ready []readyFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || ignoredWatchFile(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		present[name] = true
+		seen := wf.files[name]
+		if seen != nil && seen.size == info.Size() && seen.modTime.Equal(info.ModTime()) {
+			ready = append(ready, readyFile{name, info.ModTime()})
+			continue
+		}
+		wf.files[name] = &watchedFile{size: info.Size(), modTime: info.ModTime()}
+	}
+	for name := range wf.files {
+		if !present[name] {
+			delete(wf.files, name)
+		}
+	}
+
+	sort.Slice(ready, func(i, j int) bool { return ready[i].modTime.Before(ready[j].modTime) })
+	names := make([]string, len(ready))
+	for i, file := range ready {
+		names[i] = file.name
+	}
+	return names, nil
+}
+
+// ignoredWatchFile reports whether a file is hidden or a partial download
+// or copy.
+func ignoredWatchFile(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~") {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".tmp", ".part", ".partial", ".crdownload", ".download":
+		return true
+	}
+	return false
+}
+
+// place places files into the zone grid and moves them to the archive. It
+// returns an error when the canvas or zone is not available; the files are
+// then tried again at the next scan. Each file is recorded in the history as
+// soon as it is processed, so a copy later in the same scan is a duplicate.
+func (wf *WatchFolder) place(ctx context.Context, names []string) error {
+	canvasID := wf.canvasService.GetCanvasID()
+	if canvasID == "" {
+		return fmt.Errorf("canvas not available: %d files waiting", len(names))
+	}
+	anchor, err := wf.findZone(ctx, canvasID)
+	if err != nil {
+		return err
+	}
+	zoneBB := anchor.BoundingBox()
+	if zoneBB == nil {
+		return fmt.Errorf("zone %s has no location or size", wf.zone)
+	}
+	wf.loadGrid(canvasID, anchor.ID)
+
+	uploadID := "watch-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	locations := GridLocations(len(wf.widgets)+len(names), zoneBB)
+	var records []UploadRecord
+	failed := 0
+	for i, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		record := UploadRecord{
+			UploadID:   uploadID,
+			Filename:   name,
+			Uploader:   WatchFolderUploader,
+			ClientID:   wf.canvasService.GetClientID(),
+			CanvasID:   canvasID,
+			Placement:  PlacementZone,
+			ZoneID:     anchor.ID,
+			UploadedAt: time.Now(),
+		}
+		progress := func(phase string) map[string]interface{} {
+			return map[string]interface{}{
+				"upload_id": uploadID,
+				"client_id": record.ClientID,
+				"canvas_id": canvasID,
+				"file":      name,
+				"index":     i + 1,
+				"total":     len(names),
+				"phase":     phase,
+			}
+		}
+		wf.events.Publish(TopicUpload, "upload_progress", progress("uploading"))
+
+		location := locations[len(wf.widgets)]
+		err := wf.placeFile(ctx, name, location, &record)
+		switch {
+		case err == nil:
+			record.Outcome = "success"
+			wf.mu.Lock()
+			wf.widgets = append(wf.widgets, watchWidget{ID: record.WidgetID, Type: record.WidgetType, X: location["x"], Y: location["y"]})
+			wf.mu.Unlock()
+			wf.archive(name, watchArchiveDir)
+			data := progress("uploaded")
+			data["widget_id"] = record.WidgetID
+			wf.events.Publish(TopicUpload, "upload_progress", data)
+		case errors.Is(err, errDuplicateUpload):
+			record.Outcome = "duplicate"
+			wf.archive(name, watchArchiveDir)
+			fmt.Printf("[WatchFolder] Skipped %s: already on the canvas\n", name)
+			wf.events.Publish(TopicUpload, "upload_progress", progress("duplicate"))
+		case ctx.Err() != nil:
+			// Stopped: the file is tried again when the folder is next watched.
+			continue
+		default:
+			fmt.Printf("[WatchFolder] ERROR: Failed to upload %s: %v\n", name, err)
+			record.Outcome = "failure"
+			record.Error = err.Error()
+			failed++
+			if !wf.retry(name, err) {
+				wf.archive(name, filepath.Join(watchArchiveDir, watchFailedDir))
+			}
+			data := progress("failed")
+			data["error"] = record.Error
+			wf.events.Publish(TopicUpload, "upload_progress", data)
+		}
+		wf.history.Record(record)
+		records = append(records, record)
+	}
+	wf.regrid(ctx, canvasID, zoneBB)
+
+	wf.events.Publish(TopicUpload, "upload_finished", map[string]interface{}{
+		"upload_id": uploadID,
+		"client_id": wf.canvasService.GetClientID(),
+		"canvas_id": canvasID,
+		"files":     records,
+		"total":     len(names),
+		"failed":    failed,
+	})
+	return nil
+}
+
+// errDuplicateUpload is returned for files already placed on the canvas.
+var errDuplicateUpload = errors.New("already on the canvas")
+
+// placeFile places a file at location unless a file with the same content
+// was placed on the canvas before, and fills record.
+func (wf *WatchFolder) placeFile(ctx context.Context, name string, location map[string]float64, record *UploadRecord) error {
+	file, err := os.Open(filepath.Join(wf.dir, name))
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	record.Size = size
+	record.Hash = hex.EncodeToString(hash.Sum(nil))
+	if wf.history.Placed(record.CanvasID, record.Hash) {
+		return errDuplicateUpload
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind file: %w", err)
+	}
+
+	prepared, err := wf.pipeline.Prepare(file, name)
+	if err != nil {
+		var unsupported *UnsupportedUploadError
+		if errors.As(err, &unsupported) {
+			record.ContentType = unsupported.MIMEType
+		}
+		return err
+	}
+	record.ContentType = prepared.MIMEType
+	record.Converter = prepared.Converter
+	record.WidgetType = prepared.Kind
+
+	metadata := map[string]interface{}{
+		"title":    name,
+		"location": location,
+	}
+	widgetID, err := placeUpload(ctx, wf.apiClient, record.CanvasID, prepared, metadata)
+	if err != nil {
+		return err
+	}
+	record.WidgetID = widgetID
+	return nil
+}
+
+// retry reports whether a file that failed with err is tried again at the
+// next scan. Files of unsupported types and files failing in every attempt
+// are not.
+func (wf *WatchFolder) retry(name string, err error) bool {
+	var unsupported *UnsupportedUploadError
+	if errors.As(err, &unsupported) {
+		return false
+	}
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+	file := wf.files[name]
+	if file == nil {
+		return false
+	}
+	file.attempts++
+	return file.attempts < maxWatchAttempts
+}
+
+// archive moves a processed file into a subfolder of the watch folder,
+// renaming it if the subfolder has a file of that name.
+func (wf *WatchFolder) archive(name, subdir string) {
+	dir := filepath.Join(wf.dir, subdir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("[WatchFolder] ERROR: Failed to create %s: %v\n", dir, err)
+		return
+	}
+	target := filepath.Join(dir, name)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(name)
+		target = filepath.Join(dir, fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), time.Now().Format("20060102-150405.000"), ext))
+	}
+	if err := os.Rename(filepath.Join(wf.dir, name), target); err != nil {
+		fmt.Printf("[WatchFolder] ERROR: Failed to archive %s: %v\n", name, err)
+		return
+	}
+	wf.mu.Lock()
+	delete(wf.files, name)
+	wf.mu.Unlock()
+}
+
+// findZone returns the anchor the watch folder places files in, by ID or
+// name.
+func (wf *WatchFolder) findZone(ctx context.Context, canvasID string) (*webuiatoms.Anchor, error) {
+	anchors, err := wf.apiClient.Anchors(canvasID).List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list zones: %w", err)
+	}
+	for i := range anchors {
+		if anchors[i].ID == wf.zone || anchors[i].AnchorName == wf.zone {
+			return &anchors[i], nil
+		}
+	}
+	return nil, fmt.Errorf("zone %q not found on canvas %s", wf.zone, canvasID)
+}
+
+// loadGrid sets the widgets in the grid when the canvas or zone changed:
+// the ones placed there from the watch folder before, as in the history.
+// Only scans change the grid, so they read it without holding wf.mu.
+func (wf *WatchFolder) loadGrid(canvasID, zoneID string) {
+	if canvasID == wf.canvasID && zoneID == wf.zoneID {
+		return
+	}
+	placed := wf.history.Find(func(record UploadRecord) bool {
+		return record.Uploader == WatchFolderUploader && record.Outcome == "success" &&
+			record.CanvasID == canvasID && record.ZoneID == zoneID && record.WidgetID != ""
+	})
+	widgets := make([]watchWidget, 0, len(placed))
+	for _, record := range placed {
+		widgets = append(widgets, watchWidget{ID: record.WidgetID, Type: record.WidgetType, X: math.NaN(), Y: math.NaN()})
+	}
+
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+	wf.canvasID, wf.zoneID = canvasID, zoneID
+	wf.widgets = widgets
+}
+
+// regrid moves the widgets in the grid whose cell changed: the grid grows
+// with every file placed. Widgets that cannot be moved, removed from the
+// canvas for one, leave the grid.
+func (wf *WatchFolder) regrid(ctx context.Context, canvasID string, zoneBB *webuiatoms.ZoneBoundingBox) {
+	var moved []int
+	var updates []WidgetUpdate
+	for i, location := range GridLocations(len(wf.widgets), zoneBB) {
+		widget := wf.widgets[i]
+		if widget.X == location["x"] && widget.Y == location["y"] {
+			continue
+		}
+		moved = append(moved, i)
+		updates = append(updates, WidgetUpdate{
+			WidgetID:   widget.ID,
+			WidgetType: widget.Type,
+			Payload:    map[string]interface{}{"location": location},
+		})
+	}
+	if len(updates) == 0 || ctx.Err() != nil {
+		return
+	}
+
+	report := wf.ops.BatchUpdateWidgetsContext(ctx, canvasID, updates)
+	stopped := ctx.Err() != nil // failed updates then say nothing of their widgets
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+	gone := make(map[int]bool)
+	for j, result := range report.Results {
+		i := moved[j]
+		if result.Succeeded {
+			location := updates[j].Payload["location"].(map[string]float64)
+			wf.widgets[i].X, wf.widgets[i].Y = location["x"], location["y"]
+		} else if !stopped {
+			gone[i] = true
+		}
+	}
+	kept := make([]watchWidget, 0, len(wf.widgets))
+	for i, widget := range wf.widgets {
+		if !gone[i] {
+			kept = append(kept, widget)
+		}
+	}
+	wf.widgets = kept
+}
@@ -0,0 +1,176 @@
+package webui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestWatchFolder_PlacesDroppedFilesInZone drops files into the folder in
+// two batches and checks they are gridded, deduplicated and archived.
+func TestWatchFolder_PlacesDroppedFilesInZone(t *testing.T) {
+	var mu sync.Mutex
+	var created []string
+	moved := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/api/v1/canvases/canvas-1/anchors":
+			w.Write([]byte(`[{"id":"zone-0001","anchor_name":"Photos","location":{"x":0,"y":0},"size":{"width":2000,"height":2000},"scale":1}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/canvases/canvas-1/images":
+			r.ParseMultipartForm(1 << 20)
+			_, header, _ := r.FormFile("data")
+			created = append(created, header.Filename)
+			fmt.Fprintf(w, `{"id":"image-%04d"}`, len(created))
+		case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/api/v1/canvases/canvas-1/images/"):
+			moved[strings.TrimPrefix(r.URL.Path, "/api/v1/canvases/canvas-1/images/")]++
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tracker := webuiatoms.NewCanvasTracker()
+	tracker.UpdateCanvas("canvas-1", "Canvas")
+	cs := &CanvasService{canvasTracker: tracker, clientID: "client-1"}
+	dir := t.TempDir()
+	watchFolder := NewWatchFolder(webuiatoms.NewAPIClient(server.URL, "test-token"), cs, dir, "Photos")
+
+	modTime := time.Now().Add(-time.Hour)
+	drop := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime = modTime.Add(time.Second)
+		os.Chtimes(path, modTime, modTime)
+	}
+	exists := func(path ...string) bool {
+		_, err := os.Stat(filepath.Join(append([]string{dir}, path...)...))
+		return err == nil
+	}
+	png := "\x89PNG\r\n\x1a\n"
+	ctx := context.Background()
+
+	drop("a.png", png+"first")
+	drop("b.png", png+"second")
+	drop("a again.png", png+"first")
+	drop("report.docx", "PK\x03\x04\x14\x00\x06\x00")
+	drop("c.png.crdownload", png+"still downloading")
+	watchFolder.scan(ctx)
+	if len(created) != 0 {
+		t.Fatalf("placed %v on first sight, want files left until they stop changing", created)
+	}
+	watchFolder.scan(ctx)
+	if strings.Join(created, ",") != "a.png,b.png" {
+		t.Fatalf("placed %v, want a.png and b.png in drop order", created)
+	}
+	if !exists("archive", "a.png") || !exists("archive", "b.png") || !exists("archive", "a again.png") || exists("a.png") {
+		t.Error("placed files not moved to the archive")
+	}
+	if !exists("archive", "failed", "report.docx") || !exists("c.png.crdownload") {
+		t.Error("unsupported file not in archive/failed, or partial download touched")
+	}
+
+	// A copy of a.png is skipped; a third file regrids the zone
+	drop("a copy.png", png+"first")
+	drop("d.png", png+"third")
+	watchFolder.scan(ctx)
+	watchFolder.scan(ctx)
+	mu.Lock()
+	if strings.Join(created, ",") != "a.png,b.png,d.png" {
+		t.Errorf("placed %v, want the copy of a.png skipped", created)
+	}
+	// Two rows of one become two rows of two: b.png moves up beside a.png
+	if moved["image-0001"] != 0 || moved["image-0002"] == 0 || moved["image-0003"] != 0 {
+		t.Errorf("moved %v, want only b.png moved into the larger grid", moved)
+	}
+	mu.Unlock()
+	if !exists("archive", "a copy.png") {
+		t.Error("duplicate not moved to the archive")
+	}
+
+	outcomes := map[string]string{}
+	for _, record := range watchFolder.history.Find(func(UploadRecord) bool { return true }) {
+		outcomes[record.Filename] = record.Outcome
+		if record.Uploader != WatchFolderUploader || record.ZoneID != "zone-0001" || record.Hash == "" {
+			t.Errorf("record = %+v", record)
+		}
+	}
+	if outcomes["a copy.png"] != "duplicate" || outcomes["a again.png"] != "duplicate" || outcomes["report.docx"] != "failure" || outcomes["d.png"] != "success" {
+		t.Errorf("outcomes = %v", outcomes)
+	}
+	if status := watchFolder.Status(); status.Placed != 3 || status.Pending != 0 || status.LastError != "" {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+// TestWatchFolder_StopCancelsUpload checks that Stop does not wait for an
+// upload to a server that does not answer, and leaves the file in place.
+func TestWatchFolder_StopCancelsUpload(t *testing.T) {
+	uploading := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/canvases/canvas-1/anchors":
+			w.Write([]byte(`[{"id":"zone-0001","anchor_name":"Photos","location":{"x":0,"y":0},"size":{"width":2000,"height":2000},"scale":1}]`))
+		case r.Method == http.MethodPost:
+			select {
+			case uploading <- struct{}{}:
+			default:
+			}
+			io.Copy(io.Discard, r.Body)
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tracker := webuiatoms.NewCanvasTracker()
+	tracker.UpdateCanvas("canvas-1", "Canvas")
+	cs := &CanvasService{canvasTracker: tracker, clientID: "client-1"}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.png")
+	if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1a\nfirst"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	watchFolder := NewWatchFolder(webuiatoms.NewAPIClient(server.URL, "test-token"), cs, dir, "Photos")
+	watchFolder.interval = 10 * time.Millisecond
+	watchFolder.Start()
+
+	select {
+	case <-uploading:
+	case <-time.After(5 * time.Second):
+		watchFolder.Stop()
+		t.Fatal("file not uploaded")
+	}
+	stopped := make(chan struct{})
+	go func() {
+		watchFolder.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop waited for the upload")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("file moved after a cancelled upload: %v", err)
+	}
+	if records := watchFolder.history.Find(func(UploadRecord) bool { return true }); len(records) != 0 {
+		t.Errorf("history = %+v, want nothing recorded for the cancelled upload", records)
+	}
+}
@@ -0,0 +1,276 @@
+package webui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// WidgetMirror is an in-memory copy of the widgets of the tracked canvas,
+// kept up to date from the widget stream. It is only in sync between an
+// initial event and the next stream error; readers fall back to the REST API
+// otherwise.
+type WidgetMirror struct {
+	mu       sync.RWMutex
+	canvasID string
+	widgets  map[string]webuiatoms.Widget
+	order    []string // widget IDs in stream order, matching the REST API
+	inSync   bool
+	updated  time.Time
+	events   *EventBus
+}
+
+// WidgetChanges lists the widget IDs changed by one widget stream event.
+type WidgetChanges struct {
+	CanvasID string   `json:"canvas_id"`
+	Created  []string `json:"created,omitempty"`
+	Updated  []string `json:"updated,omitempty"`
+	Deleted  []string `json:"deleted,omitempty"`
+}
+
+// WidgetMirrorStatus describes the state of a WidgetMirror.
+type WidgetMirrorStatus struct {
+	CanvasID    string    `json:"canvas_id"`
+	InSync      bool      `json:"in_sync"`
+	WidgetCount int       `json:"widget_count"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NewWidgetMirror creates an empty mirror that tracks no canvas.
+func NewWidgetMirror() *WidgetMirror {
+	return &WidgetMirror{widgets: make(map[string]webuiatoms.Widget)}
+}
+
+// SetEventBus sets where widget changes are published.
+func (m *WidgetMirror) SetEventBus(events *EventBus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = events
+}
+
+// Track empties the mirror and makes it follow canvasID.
+func (m *WidgetMirror) Track(canvasID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.canvasID = canvasID
+	m.widgets = make(map[string]webuiatoms.Widget)
+	m.order = nil
+	m.inSync = false
+}
+
+// Apply applies a widget stream event of canvasID and returns what changed.
+// Events of other canvases are ignored. An initial event replaces the
+// mirrored widgets and brings the mirror in sync.
+func (m *WidgetMirror) Apply(canvasID string, event webuiatoms.WidgetEvent) WidgetChanges {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changes := WidgetChanges{CanvasID: canvasID}
+	if canvasID != m.canvasID {
+		return changes
+	}
+
+	if event.Initial {
+		m.widgets = make(map[string]webuiatoms.Widget, len(event.Widgets))
+		m.order = nil
+		m.inSync = true
+	}
+	for _, widget := range event.Widgets {
+		if _, exists := m.widgets[widget.ID]; exists {
+			changes.Updated = append(changes.Updated, widget.ID)
+		} else {
+			changes.Created = append(changes.Created, widget.ID)
+			m.order = append(m.order, widget.ID)
+		}
+		m.widgets[widget.ID] = widget
+	}
+	if len(event.Deleted) > 0 {
+		for _, id := range event.Deleted {
+			if _, exists := m.widgets[id]; exists {
+				changes.Deleted = append(changes.Deleted, id)
+				delete(m.widgets, id)
+			}
+		}
+		order := m.order[:0]
+		for _, id := range m.order {
+			if _, exists := m.widgets[id]; exists {
+				order = append(order, id)
+			}
+		}
+		m.order = order
+	}
+	m.updated = event.Timestamp
+	return changes
+}
+
+// Invalidate marks the mirror of canvasID out of sync until the next
+// initial event, e.g. after the stream dropped.
+func (m *WidgetMirror) Invalidate(canvasID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if canvasID == m.canvasID {
+		m.inSync = false
+	}
+}
+
+// Widgets returns the mirrored widgets of canvasID in stream order. ok is false
+// when the mirror does not follow canvasID or is out of sync.
+func (m *WidgetMirror) Widgets(canvasID string) (widgets []webuiatoms.Widget, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if canvasID == "" || canvasID != m.canvasID || !m.inSync {
+		return nil, false
+	}
+
+	widgets = make([]webuiatoms.Widget, 0, len(m.order))
+	for _, id := range m.order {
+		widgets = append(widgets, m.widgets[id])
+	}
+	return widgets, true
+}
+
+// Widget returns the mirrored widget id of canvasID. ok is false when the
+// mirror is not in sync for canvasID or has no such widget.
+func (m *WidgetMirror) Widget(canvasID, id string) (widget webuiatoms.Widget, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if canvasID == "" || canvasID != m.canvasID || !m.inSync {
+		return webuiatoms.Widget{}, false
+	}
+	widget, ok = m.widgets[id]
+	return widget, ok
+}
+
+// Status returns the current state of the mirror.
+func (m *WidgetMirror) Status() WidgetMirrorStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return WidgetMirrorStatus{
+		CanvasID:    m.canvasID,
+		InSync:      m.inSync,
+		WidgetCount: len(m.widgets),
+		UpdatedAt:   m.updated,
+	}
+}
+
+// Follow keeps the mirror on the canvas returned by canvasID, checking
+// every second like the SSE handler and resubscribing to the widget stream
+// of subscriber whenever the canvas changes, until ctx is done.
+func (m *WidgetMirror) Follow(ctx context.Context, subscriber *webuiatoms.WidgetSubscriber, canvasID func() string) {
+	var cancelStream context.CancelFunc
+	defer func() {
+		if cancelStream != nil {
+			cancelStream()
+		}
+	}()
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	tracked := ""
+	for {
+		if current := canvasID(); current != tracked {
+			if cancelStream != nil {
+				cancelStream()
+				cancelStream = nil
+			}
+			tracked = current
+			m.Track(current)
+			if current != "" {
+				fmt.Printf("[WidgetMirror] Following widgets of canvas %s\n", current)
+				cancelStream = m.stream(ctx, current, subscriber)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stream starts consuming the widget stream of canvasID and returns the
+// function that stops it.
+func (m *WidgetMirror) stream(ctx context.Context, canvasID string, subscriber *webuiatoms.WidgetSubscriber) context.CancelFunc {
+	streamCtx, cancel := context.WithCancel(ctx)
+	go m.consume(streamCtx, canvasID, subscriber)
+	return cancel
+}
+
+// consume applies the widget stream of canvasID until ctx is done.
+func (m *WidgetMirror) consume(ctx context.Context, canvasID string, subscriber *webuiatoms.WidgetSubscriber) {
+	eventChan, errChan := subscriber.Subscribe(ctx, canvasID)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-eventChan:
+			if !ok {
+				return
+			}
+			changes := m.Apply(canvasID, event)
+			if event.Initial {
+				fmt.Printf("[WidgetMirror] In sync with canvas %s: %d widgets\n", canvasID, len(event.Widgets))
+				m.publish("widgets_synced", map[string]interface{}{"canvas_id": canvasID, "widget_count": len(event.Widgets)})
+			} else if len(changes.Created)+len(changes.Updated)+len(changes.Deleted) > 0 {
+				m.publish("widgets_changed", changes)
+			}
+		case err, ok := <-errChan:
+			if !ok {
+				return
+			}
+			m.Invalidate(canvasID)
+			fmt.Printf("[WidgetMirror] Widget stream error, falling back to REST: %v\n", err)
+		}
+	}
+}
+
+func (m *WidgetMirror) publish(eventType string, data interface{}) {
+	m.mu.RLock()
+	events := m.events
+	m.mu.RUnlock()
+	events.Publish(TopicWidgets, eventType, data)
+}
+
+// widgetMirrors looks up widgets in widget mirrors. It is implemented by
+// CanvasService and by ClientRegistry, which searches every tracked client.
+type widgetMirrors interface {
+	MirroredWidgets(canvasID string) ([]webuiatoms.Widget, bool)
+	MirroredWidget(canvasID, id string) (webuiatoms.Widget, bool)
+}
+
+// listWidgets returns the widgets of canvasID from the widget mirrors, or
+// from the REST API while no mirror is in sync with it.
+func listWidgets(apiClient *webuiatoms.APIClient, mirrors widgetMirrors, canvasID string) ([]webuiatoms.Widget, error) {
+	if mirrors != nil {
+		if widgets, ok := mirrors.MirroredWidgets(canvasID); ok {
+			return widgets, nil
+		}
+	}
+	return webuiatoms.GetAllWidgets(apiClient, canvasID)
+}
+
+// zoneBoundingBox returns the bounding box of the zone anchor zoneID from
+// the widget mirrors, or from the REST API while no mirror is in sync with
+// canvasID or the anchor is missing.
+func zoneBoundingBox(apiClient *webuiatoms.APIClient, mirrors widgetMirrors, canvasID, zoneID string) (*webuiatoms.ZoneBoundingBox, error) {
+	var widget webuiatoms.Widget
+	ok := false
+	if mirrors != nil {
+		widget, ok = mirrors.MirroredWidget(canvasID, zoneID)
+	}
+	if ok && strings.EqualFold(widget.WidgetType, "Anchor") && widget.Location != nil && widget.Size != nil {
+		return &webuiatoms.ZoneBoundingBox{
+			X:      widget.Location.X,
+			Y:      widget.Location.Y,
+			Width:  widget.Size.Width,
+			Height: widget.Size.Height,
+			Scale:  widget.Scale,
+		}, nil
+	}
+	return webuiatoms.GetZoneBoundingBox(apiClient, canvasID, zoneID)
+}
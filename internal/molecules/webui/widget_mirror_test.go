@@ -0,0 +1,84 @@
+package webui
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	webuiatoms "github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+func TestWidgetMirror_Apply(t *testing.T) {
+	mirror := NewWidgetMirror()
+	mirror.Track("canvas-1")
+	if _, ok := mirror.Widgets("canvas-1"); ok {
+		t.Fatal("mirror in sync before the initial event")
+	}
+
+	note := func(id, title string) webuiatoms.Widget {
+		return webuiatoms.Widget{ID: id, WidgetType: "Note", Title: title}
+	}
+	mirror.Apply("canvas-1", webuiatoms.WidgetEvent{Initial: true, Widgets: []webuiatoms.Widget{note("b", "B"), note("a", "A"), note("c", "C")}})
+	mirror.Apply("canvas-1", webuiatoms.WidgetEvent{Widgets: []webuiatoms.Widget{note("a", "A2"), note("d", "D")}, Deleted: []string{"b"}})
+	mirror.Apply("canvas-2", webuiatoms.WidgetEvent{Deleted: []string{"c"}})
+
+	widgets, ok := mirror.Widgets("canvas-1")
+	if !ok {
+		t.Fatal("mirror not in sync after the initial event")
+	}
+	var got []string
+	for _, widget := range widgets {
+		got = append(got, widget.ID+"="+widget.Title)
+	}
+	if want := "[a=A2 c=C d=D]"; fmt.Sprint(got) != want {
+		t.Errorf("widgets = %v, want %s", got, want)
+	}
+	if _, ok := mirror.Widgets("canvas-2"); ok {
+		t.Error("mirror in sync for a canvas it does not follow")
+	}
+
+	mirror.Invalidate("canvas-1")
+	if _, ok := mirror.Widget("canvas-1", "a"); ok {
+		t.Error("mirror still in sync after Invalidate")
+	}
+	mirror.Apply("canvas-1", webuiatoms.WidgetEvent{Initial: true, Widgets: []webuiatoms.Widget{note("e", "E")}})
+	if status := mirror.Status(); !status.InSync || status.WidgetCount != 1 {
+		t.Errorf("status = %+v after resync", status)
+	}
+}
+
+// TestListWidgets_PrefersMirror checks that macros read from the mirror while
+// it is in sync and fall back to the REST API otherwise.
+func TestListWidgets_PrefersMirror(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[{"id":"rest-widget","widget_type":"Note"}]`))
+	}))
+	defer server.Close()
+	apiClient := webuiatoms.NewAPIClient(server.URL, "test-token")
+
+	canvasService := &CanvasService{widgetMirror: NewWidgetMirror()}
+	canvasService.widgetMirror.Track("canvas-1")
+	canvasService.widgetMirror.Apply("canvas-1", webuiatoms.WidgetEvent{Initial: true, Widgets: []webuiatoms.Widget{
+		{ID: "zone-anchor", WidgetType: "Anchor", Location: &webuiatoms.WidgetLocation{X: 10, Y: 20}, Size: &webuiatoms.WidgetSize{Width: 300, Height: 200}, Scale: 1},
+	}})
+
+	widgets, err := listWidgets(apiClient, canvasService, "canvas-1")
+	if err != nil || len(widgets) != 1 || widgets[0].ID != "zone-anchor" {
+		t.Fatalf("listWidgets = %+v, %v", widgets, err)
+	}
+	zoneBB, err := zoneBoundingBox(apiClient, canvasService, "canvas-1", "zone-anchor")
+	if err != nil || zoneBB.X != 10 || zoneBB.Width != 300 {
+		t.Fatalf("zoneBoundingBox = %+v, %v", zoneBB, err)
+	}
+	if requests != 0 {
+		t.Errorf("made %d REST requests while the mirror was in sync", requests)
+	}
+
+	widgets, err = listWidgets(apiClient, canvasService, "canvas-2")
+	if err != nil || len(widgets) != 1 || widgets[0].ID != "rest-widget" {
+		t.Errorf("listWidgets fallback = %+v, %v", widgets, err)
+	}
+}
@@ -2,6 +2,7 @@ package webui
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"time"
@@ -14,6 +15,9 @@ import (
 // Server represents the main WebUI HTTP server.
 type Server struct {
 	httpServer    *http.Server
+	redirect      *http.Server
+	tlsConfig     *tls.Config
+	redirectPort  string
 	canvasService *webuimolecules.CanvasService
 	apiRoutes     *webuimolecules.APIRoutes
 	port          string
@@ -48,6 +52,13 @@ func NewServer(fileService *services.FileService, apiBaseURL, authToken, port, u
 	}, nil
 }
 
+// EnableTLS serves the WebUI over HTTPS with cert. When redirectPort is set,
+// plain HTTP requests to it are redirected to HTTPS. Call before Start.
+func (s *Server) EnableTLS(cert tls.Certificate, redirectPort string) {
+	s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	s.redirectPort = redirectPort
+}
+
 // Start starts the HTTP server and canvas tracking.
 func (s *Server) Start() error {
 	// Start canvas service (resolves client_id and starts subscription)
@@ -69,6 +80,7 @@ func (s *Server) Start() error {
 	s.httpServer = &http.Server{
 		Addr:         ":" + s.port,
 		Handler:      mux,
+		TLSConfig:    s.tlsConfig,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -76,11 +88,33 @@ func (s *Server) Start() error {
 
 	// Start server in goroutine
 	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.tlsConfig != nil {
+			err = s.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			fmt.Printf("WebUI server error: %v\n", err)
 		}
 	}()
 
+	// Plain HTTP port sending browsers to HTTPS
+	if s.tlsConfig != nil && s.redirectPort != "" {
+		s.redirect = &http.Server{
+			Addr:         ":" + s.redirectPort,
+			Handler:      webuimolecules.RedirectToHTTPS(s.port),
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+		go func() {
+			if err := s.redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Printf("WebUI redirect server error: %v\n", err)
+			}
+		}()
+	}
+
 	return nil
 }
 
@@ -89,6 +123,12 @@ func (s *Server) Stop() error {
 	// Stop canvas service
 	s.canvasService.Stop()
 
+	// Shutdown HTTP redirect server
+	if s.redirect != nil {
+		s.redirect.Close()
+		s.redirect = nil
+	}
+
 	// Shutdown HTTP server
 	if s.httpServer != nil {
 		// Reduced timeout to 5 seconds since SSE handler now checks context every 1 second
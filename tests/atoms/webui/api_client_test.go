@@ -0,0 +1,185 @@
+package webui_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+func TestResource_ListAndGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Private-Token") != "token" {
+			t.Errorf("missing Private-Token header")
+		}
+		switch r.URL.Path {
+		case "/api/v1/canvases/c1/notes":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"id": "n1", "widget_type": "Note", "title": "Hello", "background_color": "#FF0000FF"},
+			})
+		case "/api/v1/canvases/c1/anchors/a1":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":           "a1",
+				"anchor_name":  "Zone 1",
+				"anchor_index": 3,
+				"location":     map[string]float64{"x": 10, "y": 20},
+				"size":         map[string]float64{"width": 300, "height": 200},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := webui.NewAPIClient(server.URL, "token")
+	ctx := context.Background()
+
+	notes, err := client.Notes("c1").List(ctx)
+	if err != nil {
+		t.Fatalf("Notes().List() error = %v", err)
+	}
+	if len(notes) != 1 || notes[0].ID != "n1" || notes[0].BackgroundColor != "#FF0000FF" {
+		t.Errorf("Notes().List() = %+v", notes)
+	}
+
+	anchor, err := client.Anchors("c1").Get(ctx, "a1")
+	if err != nil {
+		t.Fatalf("Anchors().Get() error = %v", err)
+	}
+	bb := anchor.BoundingBox()
+	if bb == nil || bb.X != 10 || bb.Width != 300 || anchor.AnchorIndex != 3 {
+		t.Errorf("Anchors().Get() = %+v, bounding box %+v", anchor, bb)
+	}
+}
+
+func TestAPIError_KeepsStatusAndMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"msg":"Canvas not found"}`))
+	}))
+	defer server.Close()
+
+	client := webui.NewAPIClient(server.URL, "token")
+	_, err := client.Canvases().Get(context.Background(), "missing")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	apiErr, ok := webui.AsAPIError(err)
+	if !ok {
+		t.Fatalf("expected *APIError, got %T", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Canvas not found" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+	if !webui.IsNotFound(err) {
+		t.Error("IsNotFound() = false, want true")
+	}
+}
+
+func TestPostMultipart_StreamsAndRetriesSeekableFiles(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1<<20) // 16MB
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("MultipartReader() error = %v", err)
+			return
+		}
+		jsonPart, _ := reader.NextPart()
+		metadata, _ := io.ReadAll(jsonPart)
+		dataPart, _ := reader.NextPart()
+		data, _ := io.ReadAll(dataPart)
+		if jsonPart.FormName() != "json" || !strings.Contains(string(metadata), `"title":"clip"`) ||
+			dataPart.FormName() != "data" || dataPart.FileName() != "clip.mp4" || !bytes.Equal(data, content) {
+			t.Errorf("parts = %s %s, %s %s with %d bytes", jsonPart.FormName(), metadata, dataPart.FormName(), dataPart.FileName(), len(data))
+		}
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":"v1"}`))
+	}))
+	defer server.Close()
+
+	client := webui.NewAPIClient(server.URL, "token")
+	client.SetRetryPolicy(webui.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+	metadata := map[string]interface{}{"title": "clip"}
+
+	video, err := client.Videos("c1").Upload(context.Background(), metadata, bytes.NewReader(content), "clip.mp4")
+	if err != nil || video.ID != "v1" || attempts != 2 {
+		t.Fatalf("Upload() = %+v, %v after %d attempts; want v1 after 2", video, err, attempts)
+	}
+
+	// A stream that cannot be rewound is sent once
+	atomic.StoreInt32(&attempts, 0)
+	_, err = client.Videos("c1").Upload(context.Background(), metadata, io.MultiReader(bytes.NewReader(content)), "clip.mp4")
+	if err == nil || attempts != 1 {
+		t.Errorf("Upload() of a stream = %v after %d attempts, want the 503 after 1", err, attempts)
+	}
+}
+
+// overlapReader is a slow io.ReadSeeker that records a Seek, or a Read after
+// the upload returned, while a Read is in progress.
+type overlapReader struct {
+	data     *bytes.Reader
+	reading  int32
+	overlaps int32
+}
+
+func (r *overlapReader) Read(p []byte) (int, error) {
+	if atomic.AddInt32(&r.reading, 1) > 1 {
+		atomic.AddInt32(&r.overlaps, 1)
+	}
+	defer atomic.AddInt32(&r.reading, -1)
+	time.Sleep(2 * time.Millisecond)
+	if len(p) > 4096 {
+		p = p[:4096]
+	}
+	return r.data.Read(p)
+}
+
+func (r *overlapReader) Seek(offset int64, whence int) (int64, error) {
+	if atomic.LoadInt32(&r.reading) > 0 {
+		atomic.AddInt32(&r.overlaps, 1)
+	}
+	return r.data.Seek(offset, whence)
+}
+
+func TestPostMultipart_WaitsForPreviousAttemptBeforeRewinding(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempts without reading the body, so the client
+		// gives up on them while their writers are still copying the file.
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(`{"id":"v1"}`))
+	}))
+	defer server.Close()
+
+	client := webui.NewAPIClient(server.URL, "token")
+	client.SetRetryPolicy(webui.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+	file := &overlapReader{data: bytes.NewReader(bytes.Repeat([]byte("x"), 256<<10))}
+
+	video, err := client.Videos("c1").Upload(context.Background(), map[string]interface{}{}, file, "clip.mp4")
+	if err != nil || video.ID != "v1" {
+		t.Fatalf("Upload() = %+v, %v", video, err)
+	}
+	if reading := atomic.LoadInt32(&file.reading); reading != 0 {
+		t.Errorf("%d reads still in progress after Upload returned", reading)
+	}
+	if overlaps := atomic.LoadInt32(&file.overlaps); overlaps != 0 {
+		t.Errorf("file read or rewound %d times while a previous attempt was reading it", overlaps)
+	}
+}
@@ -0,0 +1,38 @@
+package webui_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+func TestCanvasTracker_Workspaces(t *testing.T) {
+	tracker := webui.NewCanvasTracker()
+	tracker.UpdateCanvas("canvas-0", "Lobby")
+	tracker.SetWorkspaces([]int{2, 0, 1})
+	tracker.UpdateWorkspaceCanvas(2, "canvas-2", "Board")
+
+	if got := tracker.Workspaces(); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Errorf("workspaces = %v, want [0 1 2]", got)
+	}
+	if id, name := tracker.GetCanvas(); id != "canvas-0" || name != "Lobby" {
+		t.Errorf("selected canvas = %s %q, want the canvas of workspace 0 kept", id, name)
+	}
+
+	tracker.SelectWorkspace(2)
+	if id := tracker.GetCanvasID(); id != "canvas-2" {
+		t.Errorf("canvas of selected workspace 2 = %q", id)
+	}
+	if id, _, ok := tracker.GetWorkspaceCanvas(1); !ok || id != "" {
+		t.Errorf("workspace 1 = %q, %v; want tracked without a canvas", id, ok)
+	}
+
+	tracker.SetWorkspaces([]int{2})
+	if _, _, ok := tracker.GetWorkspaceCanvas(0); ok {
+		t.Error("workspace 0 still tracked after it closed")
+	}
+	if id := tracker.GetCanvasID(); id != "canvas-2" {
+		t.Errorf("canvas of workspace 2 lost: %q", id)
+	}
+}
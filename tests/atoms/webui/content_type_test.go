@@ -0,0 +1,137 @@
+package webui_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+func TestSniffContentType(t *testing.T) {
+	var pngData bytes.Buffer
+	png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+
+	tests := []struct {
+		name     string
+		head     []byte
+		filename string
+		want     string
+	}{
+		{"png named jpg", pngData.Bytes(), "photo.jpg", "image/png"},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"), "IMG_0001.HEIC", "image/heic"},
+		{"quicktime", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00"), "clip.mov", "video/quicktime"},
+		{"mp4", []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2"), "clip.mp4", "video/mp4"},
+		{"tiff", []byte("II*\x00\x08\x00\x00\x00"), "scan.tif", "image/tiff"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "image.webp", "image/webp"},
+		{"pdf", []byte("%PDF-1.7\n"), "slides", "application/pdf"},
+		{"docx", []byte("PK\x03\x04\x14\x00\x06\x00"), "report.docx", "application/zip"},
+		{"markdown", []byte("# Agenda\n\n- Intro\n"), "agenda.md", "text/markdown"},
+		{"text", []byte("Remember the milk\n"), "todo.txt", "text/plain"},
+		{"url shortcut", []byte("[InternetShortcut]\r\nURL=https://example.com/\r\n"), "Example", "application/x-url"},
+		{"webloc", []byte(`<?xml version="1.0" encoding="UTF-8"?><plist><dict><key>URL</key><string>https://example.com</string></dict></plist>`), "Example.webloc", "application/x-webloc"},
+	}
+	for _, tt := range tests {
+		if got := webui.SniffContentType(tt.head, tt.filename); got != tt.want {
+			t.Errorf("%s: SniffContentType() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// orientedJPEG returns a JPEG of img carrying an EXIF orientation, in the
+// byte order of a big-endian camera.
+func orientedJPEG(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00*")
+	binary.Write(&tiff, binary.BigEndian, uint32(8))      // IFD0 offset
+	binary.Write(&tiff, binary.BigEndian, uint16(1))      // one entry
+	binary.Write(&tiff, binary.BigEndian, uint16(0x0112)) // orientation
+	binary.Write(&tiff, binary.BigEndian, uint16(3))      // SHORT
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, orientation)
+	binary.Write(&tiff, binary.BigEndian, uint16(0))
+	binary.Write(&tiff, binary.BigEndian, uint32(0)) // no next IFD
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var out bytes.Buffer
+	out.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(segment)+2))
+	out.Write(segment)
+	out.Write(encoded.Bytes()[2:])
+	return out.Bytes()
+}
+
+func TestJPEGOrientation_TurnsImageUpright(t *testing.T) {
+	// 2x1: red on the left, blue on the right
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	img.Set(1, 0, color.RGBA{B: 255, A: 255})
+
+	data := orientedJPEG(t, img, 6)
+	orientation := webui.JPEGOrientation(data)
+	if orientation != 6 {
+		t.Fatalf("JPEGOrientation() = %d, want 6", orientation)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("oriented JPEG does not decode: %v", err)
+	}
+
+	upright := webui.ApplyOrientation(img, orientation)
+	if bounds := upright.Bounds(); bounds.Dx() != 1 || bounds.Dy() != 2 {
+		t.Fatalf("upright bounds = %v, want 1x2", bounds)
+	}
+	// Turned clockwise, the left edge ends up on top
+	if r, _, _, _ := upright.At(0, 0).RGBA(); r == 0 {
+		t.Errorf("top pixel = %v, want red", upright.At(0, 0))
+	}
+
+	plain := bytes.Buffer{}
+	jpeg.Encode(&plain, img, nil)
+	if got := webui.JPEGOrientation(plain.Bytes()); got != 1 {
+		t.Errorf("JPEGOrientation() without EXIF = %d, want 1", got)
+	}
+}
+
+func TestApplyOrientation_SameResultForEveryPixelFormat(t *testing.T) {
+	// 3x2 with a distinct gray level per pixel
+	gray := image.NewGray(image.Rect(10, 20, 13, 22))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(40 * (i + 1))
+	}
+	rgba := image.NewRGBA(gray.Bounds())
+	nrgba := image.NewNRGBA(gray.Bounds())
+	ycbcr := image.NewYCbCr(gray.Bounds(), image.YCbCrSubsampleRatio444)
+	for y := gray.Bounds().Min.Y; y < gray.Bounds().Max.Y; y++ {
+		for x := gray.Bounds().Min.X; x < gray.Bounds().Max.X; x++ {
+			v := gray.GrayAt(x, y).Y
+			rgba.Set(x, y, color.Gray{Y: v})
+			nrgba.Set(x, y, color.Gray{Y: v})
+			ycbcr.Y[ycbcr.YOffset(x, y)] = v
+			ycbcr.Cb[ycbcr.COffset(x, y)] = 128
+			ycbcr.Cr[ycbcr.COffset(x, y)] = 128
+		}
+	}
+
+	for orientation := 2; orientation <= 8; orientation++ {
+		want := webui.ApplyOrientation(gray, orientation).(*image.RGBA)
+		if orientation == 6 && want.RGBAAt(0, 0).R != gray.GrayAt(10, 21).Y {
+			t.Errorf("orientation 6: top left = %v, want the bottom left pixel %v", want.RGBAAt(0, 0), gray.GrayAt(10, 21))
+		}
+		for name, img := range map[string]image.Image{"RGBA": rgba, "NRGBA": nrgba, "YCbCr": ycbcr} {
+			got := webui.ApplyOrientation(img, orientation).(*image.RGBA)
+			if got.Bounds() != want.Bounds() || !bytes.Equal(got.Pix, want.Pix) {
+				t.Errorf("orientation %d of %s = %v, want %v", orientation, name, got.Pix, want.Pix)
+			}
+		}
+	}
+}
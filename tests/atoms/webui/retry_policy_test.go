@@ -0,0 +1,71 @@
+package webui_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+func fastRetryPolicy() webui.RetryPolicy {
+	return webui.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+func TestAPIClient_RetriesRetryableStatus(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":"n1"}`))
+	}))
+	defer server.Close()
+
+	client := webui.NewAPIClient(server.URL, "token")
+	client.SetRetryPolicy(fastRetryPolicy())
+
+	if _, err := client.Do(context.Background(), http.MethodPost, "/api/v1/canvases/c1/notes", map[string]string{"title": "x"}); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("server calls = %d, want 3", got)
+	}
+}
+
+func TestAPIClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := webui.NewAPIClient(server.URL, "token")
+	client.SetRetryPolicy(fastRetryPolicy())
+
+	if _, err := client.Get("/api/v1/canvases"); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("server calls = %d, want 1", got)
+	}
+}
+
+func TestRateLimiter_WaitHonoursContext(t *testing.T) {
+	limiter := webui.NewRateLimiter(1, 1)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("second Wait() should block until the context expires")
+	}
+}
@@ -0,0 +1,38 @@
+package webui_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+func TestHashSecret_VerifiesOnlyTheSecret(t *testing.T) {
+	hash, err := webui.HashSecret("4821")
+	if err != nil {
+		t.Fatalf("HashSecret: %v", err)
+	}
+	if strings.Contains(hash, "4821") || !webui.IsHashedSecret(hash) {
+		t.Fatalf("hash = %q", hash)
+	}
+	if !webui.VerifySecret(hash, "4821") {
+		t.Error("secret not verified")
+	}
+	for _, wrong := range []string{"", "4822", "48210"} {
+		if webui.VerifySecret(hash, wrong) {
+			t.Errorf("%q verified", wrong)
+		}
+	}
+
+	again, _ := webui.HashSecret("4821")
+	if again == hash {
+		t.Error("equal hashes for the same secret; salt not random")
+	}
+	// A secret typed into the config file is not a hash
+	if webui.IsHashedSecret("4821") || webui.VerifySecret("4821", "4821") {
+		t.Error("plain text accepted as a hash")
+	}
+	if _, err := webui.HashSecret(""); err == nil {
+		t.Error("empty secret hashed")
+	}
+}
@@ -0,0 +1,51 @@
+package webui_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+func TestLoadOrCreateSelfSignedCertificate_PersistsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls", "cert.pem")
+	keyFile := filepath.Join(dir, "tls", "key.pem")
+	hosts := []string{"localhost", "127.0.0.1", "192.168.1.20"}
+
+	cert, err := webui.LoadOrCreateSelfSignedCertificate(certFile, keyFile, hosts)
+	if err != nil {
+		t.Fatalf("LoadOrCreateSelfSignedCertificate: %v", err)
+	}
+	for _, host := range hosts {
+		if err := cert.Leaf.VerifyHostname(host); err != nil {
+			t.Errorf("certificate does not cover %s: %v", host, err)
+		}
+	}
+	fingerprint := webui.CertificateFingerprint(cert)
+	if len(fingerprint) != 32*3-1 {
+		t.Errorf("fingerprint = %q", fingerprint)
+	}
+
+	// The stored certificate is reused, so its fingerprint stays the same
+	again, err := webui.LoadOrCreateSelfSignedCertificate(certFile, keyFile, hosts)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if webui.CertificateFingerprint(again) != fingerprint {
+		t.Error("certificate recreated although it covers the hosts")
+	}
+
+	// A new address needs a new certificate
+	moved, err := webui.LoadOrCreateSelfSignedCertificate(certFile, keyFile, []string{"localhost", "10.0.0.5"})
+	if err != nil {
+		t.Fatalf("recreate: %v", err)
+	}
+	if webui.CertificateFingerprint(moved) == fingerprint || moved.Leaf.VerifyHostname("10.0.0.5") != nil {
+		t.Error("certificate not recreated for a new address")
+	}
+
+	if _, err := webui.LoadCertificate(filepath.Join(dir, "missing.pem"), keyFile); err == nil {
+		t.Error("missing certificate loaded")
+	}
+}
@@ -0,0 +1,72 @@
+package webui_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+func TestWidgetSubscriber_Subscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/canvases/c1/widgets" || !r.URL.Query().Has("subscribe") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Private-Token") != "token" {
+			t.Errorf("missing Private-Token header")
+		}
+		fmt.Fprintln(w, `[{"id":"n1","widget_type":"Note","state":"normal","location":{"x":1,"y":2}},{"id":"n2","widget_type":"Note","state":"normal"}]`)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, `[{"id":"n1","widget_type":"Note","state":"normal","pinned":true},{"id":"n2","widget_type":"Note","state":"deleted"}]`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, errs := webui.NewWidgetSubscriber(server.URL, "token").Subscribe(ctx, "c1")
+
+	var received []webui.WidgetEvent
+	for len(received) < 2 {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case err := <-errs:
+			t.Fatalf("stream error: %v", err)
+		case <-ctx.Done():
+			t.Fatalf("received %d events before timeout", len(received))
+		}
+	}
+
+	initial := received[0]
+	if !initial.Initial || len(initial.Widgets) != 2 || initial.Widgets[0].Location == nil || initial.Widgets[0].Location.Y != 2 {
+		t.Errorf("initial event = %+v", initial)
+	}
+	change := received[1]
+	if change.Initial || len(change.Widgets) != 1 || !change.Widgets[0].Pinned {
+		t.Errorf("change event = %+v", change)
+	}
+	if len(change.Deleted) != 1 || change.Deleted[0] != "n2" {
+		t.Errorf("deleted = %v, want [n2]", change.Deleted)
+	}
+}
+
+func TestParseWidgetEvent_SingleObject(t *testing.T) {
+	event, err := webui.ParseWidgetEvent([]byte(`{"id":"a1","widget_type":"Anchor","state":"deleted"}`))
+	if err != nil {
+		t.Fatalf("ParseWidgetEvent: %v", err)
+	}
+	if len(event.Widgets) != 0 || len(event.Deleted) != 1 || event.Deleted[0] != "a1" {
+		t.Errorf("event = %+v", event)
+	}
+
+	if _, err := webui.ParseWidgetEvent([]byte(`not json`)); err == nil {
+		t.Error("ParseWidgetEvent accepted invalid JSON")
+	}
+}
@@ -0,0 +1,126 @@
+package webui_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+// TestWorkspaceSubscriber_BacksOffWithoutBlocking checks that failed
+// connections back off up to MaxDelay and keep retrying while nobody
+// receives the errors.
+func TestWorkspaceSubscriber_BacksOffWithoutBlocking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	subscriber := webui.NewWorkspaceSubscriber("client-1", server.URL, "token")
+	subscriber.SetReconnectPolicy(webui.ReconnectPolicy{BaseDelay: 2 * time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	subscriber.Subscribe(ctx) // errors are never received
+
+	deadline := time.Now().Add(5 * time.Second)
+	for subscriber.Stats().Reconnects < 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("stalled after %d reconnects", subscriber.Stats().Reconnects)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	stats := subscriber.Stats()
+	if stats.Connected || stats.Connects != 0 || stats.ConsecutiveFailures < 5 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if !strings.Contains(stats.LastFailure, "503") {
+		t.Errorf("last failure = %q, want the 503 status", stats.LastFailure)
+	}
+	if delay := stats.NextAttemptAt.Sub(stats.LastFailureAt); delay != 10*time.Millisecond {
+		t.Errorf("delay after %d failures = %v, want the 10ms cap", stats.ConsecutiveFailures, delay)
+	}
+}
+
+// TestWorkspaceSubscriber_ClosesIdleStream checks that a connection that stops
+// sending keepalives is torn down and reconnected.
+func TestWorkspaceSubscriber_ClosesIdleStream(t *testing.T) {
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&connections, 1)
+		fmt.Fprintf(w, "{\"canvas_id\":\"canvas-%d\"}\n\n", n)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done() // then silence
+	}))
+	defer server.Close()
+
+	subscriber := webui.NewWorkspaceSubscriber("client-1", server.URL, "token")
+	subscriber.SetReconnectPolicy(webui.ReconnectPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, IdleTimeout: 50 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, errs := subscriber.Subscribe(ctx)
+
+	var canvasIDs []string
+	var idleErr error
+	for len(canvasIDs) < 2 {
+		select {
+		case event := <-events:
+			canvasIDs = append(canvasIDs, event.CanvasID)
+		case err := <-errs:
+			idleErr = err
+		case <-ctx.Done():
+			t.Fatalf("received %v before timeout", canvasIDs)
+		}
+	}
+	if idleErr == nil {
+		// The error is sent before reconnecting, so it is already buffered
+		idleErr = <-errs
+	}
+	if canvasIDs[0] != "canvas-1" || canvasIDs[1] != "canvas-2" {
+		t.Errorf("canvas IDs = %v", canvasIDs)
+	}
+	if idleErr == nil || !strings.Contains(idleErr.Error(), "idle") {
+		t.Errorf("error = %v, want idle stream error", idleErr)
+	}
+	if stats := subscriber.Stats(); stats.Connects != 2 || stats.Reconnects != 1 || stats.LastDataAt.IsZero() {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+// TestWorkspaceSubscriber_StreamsSelectedWorkspace checks that the subscriber
+// connects to the workspace it was given and tags its events with it.
+func TestWorkspaceSubscriber_StreamsSelectedWorkspace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/clients/client-1/workspaces/2/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `{"canvas_id":"canvas-2"}`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	subscriber := webui.NewWorkspaceSubscriber("client-1", server.URL, "token")
+	subscriber.SetWorkspace(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, errs := subscriber.Subscribe(ctx)
+
+	select {
+	case event := <-events:
+		if event.Workspace != 2 || event.CanvasID != "canvas-2" {
+			t.Errorf("event = %+v, want canvas-2 in workspace 2", event)
+		}
+	case err := <-errs:
+		t.Fatalf("stream failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+}
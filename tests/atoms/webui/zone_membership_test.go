@@ -0,0 +1,78 @@
+package webui_test
+
+import (
+	"testing"
+
+	"github.com/jaypaulb/CanvusPowerToys/internal/atoms/webui"
+)
+
+func TestParseZoneMembership(t *testing.T) {
+	cases := map[string]webui.ZoneMembership{
+		"":            {Mode: webui.MembershipPoint},
+		"point":       {Mode: webui.MembershipPoint},
+		"Center":      {Mode: webui.MembershipCenter},
+		"contained":   {Mode: webui.MembershipContained},
+		"overlap":     {Mode: webui.MembershipOverlap, Threshold: webui.DefaultOverlapThreshold},
+		"overlap:0.8": {Mode: webui.MembershipOverlap, Threshold: 0.8},
+	}
+	for input, want := range cases {
+		got, err := webui.ParseZoneMembership(input)
+		if err != nil || got != want {
+			t.Errorf("ParseZoneMembership(%q) = %+v, %v; want %+v", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"corner", "overlap:0", "overlap:1.5", "overlap:abc", "center:0.5"} {
+		if _, err := webui.ParseZoneMembership(input); err == nil {
+			t.Errorf("ParseZoneMembership(%q) succeeded, want error", input)
+		}
+	}
+}
+
+// TestZoneMembership_LargeWidgetAcrossZones covers a large PDF whose top-left
+// corner is in zone B while most of it lies in zone A.
+func TestZoneMembership_LargeWidgetAcrossZones(t *testing.T) {
+	zoneA := &webui.ZoneBoundingBox{X: 0, Y: 0, Width: 1000, Height: 1000}
+	zoneB := &webui.ZoneBoundingBox{X: -1000, Y: 0, Width: 1000, Height: 1000}
+	pdf := &webui.Widget{
+		ID:         "pdf-00000001",
+		WidgetType: "Pdf",
+		Location:   &webui.WidgetLocation{X: -100, Y: 100},
+		Size:       &webui.WidgetSize{Width: 300, Height: 400},
+		Scale:      2, // covers x -100..500
+	}
+
+	cases := []struct {
+		membership string
+		inA, inB   bool
+	}{
+		{"point", false, true},
+		{"center", true, false},
+		{"contained", false, false},
+		{"overlap:0.5", true, false},
+		{"overlap:0.1", true, true},
+	}
+	for _, c := range cases {
+		membership, err := webui.ParseZoneMembership(c.membership)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := membership.Contains(pdf, zoneA); got != c.inA {
+			t.Errorf("%s: in zone A = %v, want %v", c.membership, got, c.inA)
+		}
+		if got := membership.Contains(pdf, zoneB); got != c.inB {
+			t.Errorf("%s: in zone B = %v, want %v", c.membership, got, c.inB)
+		}
+	}
+}
+
+func TestZoneMembership_WidgetWithoutSizeUsesPoint(t *testing.T) {
+	zone := &webui.ZoneBoundingBox{X: 0, Y: 0, Width: 100, Height: 100}
+	widget := &webui.Widget{ID: "note-00000001", Location: &webui.WidgetLocation{X: 50, Y: 50}}
+	for _, mode := range []string{"point", "center", "contained", "overlap"} {
+		membership, _ := webui.ParseZoneMembership(mode)
+		if !membership.Contains(widget, zone) {
+			t.Errorf("%s: widget without size not in zone", mode)
+		}
+	}
+}
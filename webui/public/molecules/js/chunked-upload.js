@@ -0,0 +1 @@
+class ChunkedUpload{constructor(e,t={}){this.file=e,this.onProgress=t.onProgress||(()=>{}),this.maxRetries=t.maxRetries||10,this.chunkSize=8*1024*1024,this.storageKey=`chunkedUpload:${e.name}:${e.size}:${e.lastModified}`}async start(){let e=await this.resume()||await this.create(),t=0;for(this.onProgress(e.offset,e.size);!e.complete;){try{const n=await this.sendChunk(e);t=n.offset>e.offset?0:t+1,e=n}catch(n){if(n.fatal)throw localStorage.removeItem(this.storageKey),n;if(t++,t>this.maxRetries)throw new Error(`Upload of ${this.file.name} keeps failing (${n.message}); try again to resume it`);await ChunkedUpload.waitToRetry(t),e=await this.status(e.id)||e}this.onProgress(e.offset,e.size)}return localStorage.removeItem(this.storageKey),e}async cancel(){const e=localStorage.getItem(this.storageKey);localStorage.removeItem(this.storageKey),e&&await fetch(`/api/uploads/${e}`,{method:"DELETE"}).catch(()=>{})}async resume(){const e=localStorage.getItem(this.storageKey);if(!e)return null;const t=await this.status(e);return t||localStorage.removeItem(this.storageKey),t}async status(e){try{const t=await fetch(`/api/uploads/${e}`),n=await t.json();return t.ok&&n.success?n.upload:null}catch{return null}}async create(){const t=await fetch("/api/uploads",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({filename:this.file.name,size:this.file.size})}),e=await t.json();if(!t.ok||!e.success)throw new Error(e.error||`Failed to start the upload of ${this.file.name}`);return this.chunkSize=e.chunk_size||this.chunkSize,localStorage.setItem(this.storageKey,e.upload.id),e.upload}async sendChunk(e){const o=Math.min(e.offset+this.chunkSize,e.size),t=await fetch(`/api/uploads/${e.id}?offset=${e.offset}`,{method:"PUT",headers:{"Content-Type":"application/octet-stream"},body:this.file.slice(e.offset,o)}),n=await t.json();if(n.upload&&n.upload.id)return n.upload;const s=new Error(n.error||`Upload failed with status ${t.status}`);throw s.fatal=t.status===404||t.status===413,s}static waitToRetry(e){return new Promise(t=>{if(!navigator.onLine){window.addEventListener("online",t,{once:!0});return}setTimeout(t,Math.min(1e3*2**(e-1),3e4))})}}typeof module!="undefined"&&module.exports&&(module.exports=ChunkedUpload)
\ No newline at end of file
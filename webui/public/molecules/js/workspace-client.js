@@ -1 +1 @@
-class WorkspaceClient{constructor(){this.eventSource=null,this.listeners=new Map,this.reconnectAttempts=0,this.maxReconnectAttempts=5,this.reconnectDelay=3e3,this.isConnected=!1}connect(e){this.eventSource&&this.disconnect();const t=`${e}/api/subscribe-workspace`;this.eventSource=new EventSource(t),this.eventSource.onopen=()=>{this.isConnected=!0,this.reconnectAttempts=0,this.emit("connected")},this.eventSource.addEventListener("canvas_update",e=>{try{const t=JSON.parse(e.data);this.emit("canvas_update",t)}catch(e){(window.location.hostname==="localhost"||window.location.hostname==="127.0.0.1")&&console.error("Error parsing canvas update:",e)}}),this.eventSource.onerror=t=>{this.isConnected=!1,this.emit("error",t),this.eventSource.readyState===EventSource.CLOSED&&this.handleReconnect(e)}}disconnect(){this.eventSource&&(this.eventSource.close(),this.eventSource=null,this.isConnected=!1,this.emit("disconnected"))}handleReconnect(e){if(this.reconnectAttempts>=this.maxReconnectAttempts){this.emit("max_reconnect_attempts");return}this.reconnectAttempts++,this.emit("reconnecting",this.reconnectAttempts),setTimeout(()=>{this.connect(e)},this.reconnectDelay)}on(e,t){this.listeners.has(e)||this.listeners.set(e,[]),this.listeners.get(e).push(t)}off(e,t){if(this.listeners.has(e)){const n=this.listeners.get(e),s=n.indexOf(t);s>-1&&n.splice(s,1)}}emit(e,t){this.listeners.has(e)&&this.listeners.get(e).forEach(n=>{try{n(t)}catch(t){(window.location.hostname==="localhost"||window.location.hostname==="127.0.0.1")&&console.error(`Error in event listener for ${e}:`,t)}})}}typeof module!="undefined"&&module.exports&&(module.exports=WorkspaceClient)
\ No newline at end of file
+class WorkspaceClient{constructor(){this.eventSource=null,this.listeners=new Map,this.reconnectAttempts=0,this.maxReconnectAttempts=5,this.reconnectDelay=3e3,this.isConnected=!1,this.lastEventId="",this.topics=[],this.clientId="",this.workspace=""}connect(e,t){this.eventSource&&this.disconnect(),t&&(this.topics=t);const n=new URLSearchParams;this.topics.length>0&&n.set("topics",this.topics.join(",")),this.clientId&&n.set("client_id",this.clientId),this.workspace!==""&&n.set("workspace",this.workspace),this.lastEventId&&n.set("lastEventId",this.lastEventId);const s=n.toString(),o=`${e}/api/subscribe-workspace${s?`?${s}`:""}`;this.eventSource=new EventSource(o),this.boundEvents=new Set,this.eventSource.onopen=()=>{this.isConnected=!0,this.reconnectAttempts=0,this.emit("connected")},this.bindEvent("canvas_update"),this.listeners.forEach((e,t)=>this.bindEvent(t)),this.eventSource.onerror=t=>{this.isConnected=!1,this.emit("error",t),this.eventSource.readyState===EventSource.CLOSED&&this.handleReconnect(e)}}bindEvent(e){if(!this.eventSource||this.boundEvents.has(e)||WorkspaceClient.LOCAL_EVENTS.includes(e))return;this.boundEvents.add(e),this.eventSource.addEventListener(e,t=>{t.lastEventId&&(this.lastEventId=t.lastEventId);try{const n=JSON.parse(t.data);this.emit(e,n)}catch(t){(window.location.hostname==="localhost"||window.location.hostname==="127.0.0.1")&&console.error(`Error parsing ${e} event:`,t)}})}disconnect(){this.eventSource&&(this.eventSource.close(),this.eventSource=null,this.isConnected=!1,this.emit("disconnected"))}handleReconnect(e){if(this.reconnectAttempts>=this.maxReconnectAttempts){this.emit("max_reconnect_attempts");return}this.reconnectAttempts++,this.emit("reconnecting",this.reconnectAttempts),setTimeout(()=>{this.connect(e)},this.reconnectDelay)}on(e,t){this.listeners.has(e)||this.listeners.set(e,[]),this.listeners.get(e).push(t),this.bindEvent(e)}off(e,t){if(this.listeners.has(e)){const n=this.listeners.get(e),s=n.indexOf(t);s>-1&&n.splice(s,1)}}emit(e,t){this.listeners.has(e)&&this.listeners.get(e).forEach(n=>{try{n(t)}catch(t){(window.location.hostname==="localhost"||window.location.hostname==="127.0.0.1")&&console.error(`Error in event listener for ${e}:`,t)}})}}WorkspaceClient.LOCAL_EVENTS=["connected","disconnected","error","reconnecting","max_reconnect_attempts"],typeof module!="undefined"&&module.exports&&(module.exports=WorkspaceClient)
\ No newline at end of file
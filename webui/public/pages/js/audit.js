@@ -0,0 +1 @@
+document.addEventListener("DOMContentLoaded",()=>{const s=document.getElementById("auditFilter"),i=document.getElementById("auditReset"),o=document.querySelector("#auditTable tbody"),t=document.getElementById("auditMessage"),a={action:document.getElementById("auditAction"),user:document.getElementById("auditUser"),role:document.getElementById("auditRole"),outcome:document.getElementById("auditOutcome"),remote_ip:document.getElementById("auditRemoteIP"),canvas_id:document.getElementById("auditCanvasID"),since:document.getElementById("auditSince"),until:document.getElementById("auditUntil")};function e(e,t){const n=document.createElement("td");return n.textContent=e,t&&(n.className=t),n}function r(n){o.innerHTML="",n.forEach(t=>{const n=document.createElement("tr");n.appendChild(e(new Date(t.time).toLocaleString())),n.appendChild(e(`${t.method} ${t.action}`)),n.appendChild(e([t.role,t.user].filter(Boolean).join(" / ")||"-")),n.appendChild(e(t.remote_ip)),n.appendChild(e(t.canvas_id||"-"));const s=t.error?`${t.outcome} (${t.status}): ${t.error}`:`${t.outcome} (${t.status})`;n.appendChild(e(s,`audit-outcome-${t.outcome}`)),n.appendChild(e(t.params?JSON.stringify(t.params):"","audit-params")),o.appendChild(n)}),t.textContent=n.length===0?"No entries match the filter.":`${n.length} entries`}async function n(){const e=new URLSearchParams;Object.entries(a).forEach(([t,n])=>{let s=n.value.trim();if(!s)return;n.type==="datetime-local"&&(s=new Date(s).toISOString()),e.set(t,s)});try{const s=await fetch(`/api/admin/audit?${e}`),n=await s.json();if(!s.ok||!n.success){t.textContent=n.error||"Failed to load the audit log";return}if(!n.enabled){t.textContent="The audit log is not enabled on this server.";return}r(n.entries)}catch(e){t.textContent=`Failed to load the audit log: ${e.message}`}}s.addEventListener("submit",e=>{e.preventDefault(),n()}),i.addEventListener("click",()=>{s.reset(),n()}),n()})
\ No newline at end of file
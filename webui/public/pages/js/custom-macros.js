@@ -0,0 +1 @@
+document.addEventListener("DOMContentLoaded",()=>{console.log("[custom-macros.js] Initializing custom macros tab"),fetchCustomMacros()});async function fetchCustomMacros(){const e=document.getElementById("customMacroList");if(!e)return;try{const[t,n]=await Promise.all([fetch("/api/macros/definitions"),fetch("/get-zones",{headers:{"Cache-Control":"no-cache"}})]),e=await t.json();if(!t.ok||!e.success)throw new Error(e.error||`HTTP ${t.status}`);const s=await n.json();renderCustomMacros(e.macros||[],e.errors||[],s.zones||[])}catch(e){console.error("[custom-macros.js] Failed to list macros:",e),displayCustomMessage(e.message||"Failed to list custom macros","error")}}function renderCustomMacros(e,t,n){const s=document.getElementById("customMacroList");if(s.innerHTML="",e.length===0&&t.length===0){s.textContent="No custom macros yet.";return}const o=[...n].sort((e,t)=>(e.anchor_name||"").toLowerCase().localeCompare((t.anchor_name||"").toLowerCase(),0[0],{numeric:!0}));e.forEach(e=>{const t=document.createElement("div");t.className="custom-macro";const a=document.createElement("strong");if(a.textContent=e.name,t.appendChild(a),e.description){const n=document.createElement("p");n.textContent=e.description,t.appendChild(n)}const r={};(e.params||[]).forEach(e=>{const s=document.createElement("div");s.className="form-group";const i=document.createElement("label");i.className="input-label",i.textContent=`${e.label||e.name}:`;const n=document.createElement("select");n.className="input select",n.innerHTML='<option value="">Select a zone...</option>',o.forEach(e=>{const t=document.createElement("option");t.value=e.id,t.textContent=e.anchor_name||`Zone ${e.id}`,n.appendChild(t)}),s.appendChild(i),s.appendChild(n),t.appendChild(s),r[e.name]=n});const i=document.createElement("div");i.className="form-actions";const n=document.createElement("button");n.className="btn btn-primary",n.textContent="Run",n.addEventListener("click",()=>runCustomMacro(e,r)),i.appendChild(n),t.appendChild(i),s.appendChild(t)}),t.forEach(e=>{const t=document.createElement("div");t.className="custom-macro custom-macro-error",t.textContent=`${e.file}: ${e.error}`,s.appendChild(t)})}async function runCustomMacro(e,t){const n={};for(const[s,e]of Object.entries(t)){if(!e.value){displayCustomMessage("Please select a zone for every parameter.","error");return}n[s]=e.value}try{const t=await postMacro("/api/macros/run",{macro:e.id,params:n});if(!t)return;console.log("[custom-macros.js] Run response:",t);const s=t.report?.failed||0;displayCustomMessage(t.message||`${e.name} finished`,s>0?"error":"success")}catch(t){console.error("[custom-macros.js] Run failed:",t),displayCustomMessage(t.message||`Failed to run ${e.name}`,"error")}}function displayCustomMessage(e,t){const n=document.getElementById("customMessage");if(!n){console.log(`[${t}] ${e}`);return}n.textContent=e,n.className=`message ${t} mt-md`,n.style.display="block"}
\ No newline at end of file
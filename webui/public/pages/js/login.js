@@ -0,0 +1 @@
+document.addEventListener("DOMContentLoaded",()=>{const t=document.getElementById("login-section"),o=document.getElementById("session-section"),c=document.getElementById("loginForm"),n=document.getElementById("secret"),l=document.getElementById("sessionRole"),i=document.getElementById("loginMessage"),s=new URLSearchParams(window.location.search).get("next")||"/",a=s.startsWith("/")&&!s.startsWith("//")?s:"/";function e(e,t){i.textContent=e,i.className=t?"badge badge-error mt-md":"text-muted mt-md"}function r(e){e.authenticated?(l.textContent=e.role,t.style.display="none",o.style.display="block"):(t.style.display="block",o.style.display="none",n.focus())}fetch("/api/auth/session").then(e=>e.json()).then(n=>{if(!n.enabled){e("Login is not enabled on this server.",!1),t.style.display="none";return}r(n)}).catch(t=>e(`Failed to check session: ${t.message}`,!0)),c.addEventListener("submit",async t=>{t.preventDefault(),e("",!1);try{const t=await fetch("/api/auth/login",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({secret:n.value})}),s=await t.json();if(n.value="",!t.ok||!s.success){e(s.error||"Login failed",!0);return}window.location.href=a}catch(t){e(`Login failed: ${t.message}`,!0)}}),document.getElementById("continueButton").addEventListener("click",()=>{window.location.href=a}),document.getElementById("logoutButton").addEventListener("click",async()=>{try{await fetch("/api/auth/logout",{method:"POST"}),r({authenticated:!1}),e("Logged out.",!1)}catch(t){e(`Logout failed: ${t.message}`,!0)}})})
\ No newline at end of file
@@ -1 +1 @@
-document.addEventListener("DOMContentLoaded",()=>{console.log("[macros.js] DOMContentLoaded - Initializing macros page"),console.log("[macros.js] Setting up tabs"),setupTabs(),console.log("[macros.js] Fetching zones"),fetchZones();const e=document.getElementById("moveButton"),t=document.getElementById("copyButton");console.log("[macros.js] Binding Manage buttons:",{moveButton:!!e,copyButton:!!t}),e?e.addEventListener("click",()=>{console.log("[macros.js] Move button clicked"),manageMove()}):console.error("[macros.js] ERROR: moveButton not found!"),t?t.addEventListener("click",()=>{console.log("[macros.js] Copy button clicked"),manageCopy()}):console.error("[macros.js] ERROR: copyButton not found!");const n=document.getElementById("autoGridButton"),s=document.getElementById("groupColorButton"),o=document.getElementById("groupTitleButton");console.log("[macros.js] Binding Grouping buttons:",{autoGridButton:!!n,groupColorButton:!!s,groupTitleButton:!!o}),n?n.addEventListener("click",()=>{console.log("[macros.js] Auto Grid button clicked"),autoGrid()}):console.error("[macros.js] ERROR: autoGridButton not found!"),s?s.addEventListener("click",()=>{console.log("[macros.js] Group by Color button clicked"),groupByColor()}):console.error("[macros.js] ERROR: groupColorButton not found!"),o?o.addEventListener("click",()=>{console.log("[macros.js] Group by Title button clicked"),groupByTitle()}):console.error("[macros.js] ERROR: groupTitleButton not found!");const i=document.getElementById("pinAllButton"),a=document.getElementById("unpinAllButton");console.log("[macros.js] Binding Pinning buttons:",{pinAllButton:!!i,unpinAllButton:!!a}),i?i.addEventListener("click",()=>{console.log("[macros.js] Pin All button clicked"),pinAll()}):console.error("[macros.js] ERROR: pinAllButton not found!"),a?a.addEventListener("click",()=>{console.log("[macros.js] Unpin All button clicked"),unpinAll()}):console.error("[macros.js] ERROR: unpinAllButton not found!"),console.log("[macros.js] Setting up color tolerance slider"),setupColorToleranceSlider(),console.log("[macros.js] Initialization complete")});function setupTabs(){const e=document.querySelectorAll(".tab-button"),t=document.querySelectorAll(".tab-content");e.forEach(n=>{n.addEventListener("click",()=>{e.forEach(e=>e.classList.remove("active")),t.forEach(e=>e.classList.remove("active")),n.classList.add("active");const o=n.getAttribute("data-tab"),s=document.getElementById(`${o}-content`);s&&s.classList.add("active")})})}async function fetchZones(){try{console.log("[fetchZones] Fetching zones and canvas details...");const t=await fetch("/get-zones",{headers:{"Cache-Control":"no-cache"}}),e=await t.json();if(!e.success||!e.zones)throw new Error("Failed to retrieve zones from the server.");console.log(`[fetchZones] Retrieved ${e.zones.length} zones.`),populateZoneDropdowns(e.zones)}catch(e){console.error("[fetchZones] Error:",e.message),displayMessage(e.message,"error")}}function populateZoneDropdowns(e){try{const t={manageSourceZone:document.getElementById("manageSourceZone"),manageTargetZone:document.getElementById("manageTargetZone"),arrangeSourceZone:document.getElementById("arrangeSourceZone"),pinSourceZone:document.getElementById("pinSourceZone")};Object.values(t).forEach(e=>{e&&(e.innerHTML='<option value="">Select a zone...</option>')});const n=[...e].sort((e,t)=>{const n=(e.anchor_name||"").toLowerCase(),s=(t.anchor_name||"").toLowerCase();return n.localeCompare(s,0[0],{numeric:!0})});n.forEach(e=>{const s=e.anchor_name||`Zone ${e.id}`,n=document.createElement("option");n.value=e.id,n.textContent=s,Object.values(t).forEach(e=>{e&&e.appendChild(n.cloneNode(!0))})})}catch(e){console.error("[populateZoneDropdowns] Error:",e.message),displayMessage("Error populating zone dropdowns: "+e.message,"error")}}async function manageMove(){console.log("[macros.js] manageMove() called");const e=document.getElementById("manageSourceZone")?.value,t=document.getElementById("manageTargetZone")?.value;if(console.log("[macros.js] Zone IDs:",{sourceZoneId:e,targetZoneId:t}),!e||!t){const e="Please select both Source and Target zones.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const n={sourceZoneId:e,targetZoneId:t};console.log("[macros.js] Sending POST /api/macros/move with payload:",n);const s=await postJson("/api/macros/move",n);console.log("[macros.js] Move response:",s),displayMessage(s.message||"Widgets moved successfully","success")}catch(e){console.error("[macros.js] Move failed:",e),displayMessage(e.message||"Failed to move widgets","error")}}async function manageCopy(){console.log("[macros.js] manageCopy() called");const e=document.getElementById("manageSourceZone")?.value,t=document.getElementById("manageTargetZone")?.value;if(console.log("[macros.js] Zone IDs:",{sourceZoneId:e,targetZoneId:t}),!e||!t){const e="Please select both Source and Target zones.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const n={sourceZoneId:e,targetZoneId:t};console.log("[macros.js] Sending POST /api/macros/copy with payload:",n);const s=await postJson("/api/macros/copy",n);console.log("[macros.js] Copy response:",s),displayMessage(s.message||"Widgets copied successfully","success")}catch(e){console.error("[macros.js] Copy failed:",e),displayMessage(e.message||"Failed to copy widgets","error")}}async function autoGrid(){console.log("[macros.js] autoGrid() called");const e=document.getElementById("arrangeSourceZone")?.value;if(console.log("[macros.js] Zone ID:",e),!e){const e="Please select a Source zone.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const t={zoneId:e};console.log("[macros.js] Sending POST /api/macros/auto-grid with payload:",t);const n=await postJson("/api/macros/auto-grid",t);console.log("[macros.js] Auto grid response:",n),displayMessage(n.message||"Auto grid applied successfully","success")}catch(e){console.error("[macros.js] Auto grid failed:",e),displayMessage(e.message||"Failed to apply auto grid","error")}}async function groupByColor(){console.log("[macros.js] groupByColor() called");const e=document.getElementById("arrangeSourceZone")?.value,t=document.getElementById("colorToleranceSlider")?.value;if(console.log("[macros.js] Zone ID:",e,"Color tolerance:",t),!e){const e="Please select a Source zone.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const n={zoneId:e,colorTolerance:parseInt(t)};console.log("[macros.js] Sending POST /api/macros/group-color with payload:",n);const s=await postJson("/api/macros/group-color",n);console.log("[macros.js] Group by color response:",s),displayMessage(s.message||"Grouped by color successfully","success")}catch(e){console.error("[macros.js] Group by color failed:",e),displayMessage(e.message||"Failed to group by color","error")}}async function groupByTitle(){console.log("[macros.js] groupByTitle() called");const e=document.getElementById("arrangeSourceZone")?.value;if(console.log("[macros.js] Zone ID:",e),!e){const e="Please select a Source zone.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const t={zoneId:e};console.log("[macros.js] Sending POST /api/macros/group-title with payload:",t);const n=await postJson("/api/macros/group-title",t);console.log("[macros.js] Group by title response:",n),displayMessage(n.message||"Grouped by title successfully","success")}catch(e){console.error("[macros.js] Group by title failed:",e),displayMessage(e.message||"Failed to group by title","error")}}async function pinAll(){console.log("[macros.js] pinAll() called");const e=document.getElementById("pinSourceZone")?.value;if(console.log("[macros.js] Zone ID:",e),!e){const e="Please select a Source zone.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const t={zoneId:e};console.log("[macros.js] Sending POST /api/macros/pin-all with payload:",t);const n=await postJson("/api/macros/pin-all",t);console.log("[macros.js] Pin all response:",n),displayMessage(n.message||"All widgets pinned successfully","success")}catch(e){console.error("[macros.js] Pin all failed:",e),displayMessage(e.message||"Failed to pin widgets","error")}}async function unpinAll(){console.log("[macros.js] unpinAll() called");const e=document.getElementById("pinSourceZone")?.value;if(console.log("[macros.js] Zone ID:",e),!e){const e="Please select a Source zone.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const t={zoneId:e};console.log("[macros.js] Sending POST /api/macros/unpin-all with payload:",t);const n=await postJson("/api/macros/unpin-all",t);console.log("[macros.js] Unpin all response:",n),displayMessage(n.message||"All widgets unpinned successfully","success")}catch(e){console.error("[macros.js] Unpin all failed:",e),displayMessage(e.message||"Failed to unpin widgets","error")}}function setupColorToleranceSlider(){const e=document.getElementById("colorToleranceSlider"),t=document.getElementById("colorToleranceValue");e&&t&&e.addEventListener("input",e=>{t.textContent=e.target.value+"%"})}async function postJson(e,t){console.log("[macros.js] postJson() - URL:",e,"Payload:",t);const n=await fetch(e,{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify(t)});if(console.log("[macros.js] postJson() - Response status:",n.status,n.statusText),!n.ok){const e=await n.text();console.error("[macros.js] postJson() - Error response:",e);let t;try{t=JSON.parse(e)}catch{t={error:e||"Request failed"}}throw new Error(t.error||`HTTP ${n.status}`)}const s=await n.json();return console.log("[macros.js] postJson() - Success response:",s),s}function displayMessage(e,t){const n=document.getElementById("manageMessage")||document.getElementById("arrangeMessage")||document.getElementById("pinMessage");n?(n.textContent=e,n.className=`message ${t} mt-md`,n.style.display="block",setTimeout(()=>{n.style.display="none"},5e3)):console.log(`[${t}] ${e}`)}
\ No newline at end of file
+document.addEventListener("DOMContentLoaded",()=>{console.log("[macros.js] DOMContentLoaded - Initializing macros page"),console.log("[macros.js] Setting up tabs"),setupTabs(),console.log("[macros.js] Fetching zones"),fetchZones();const e=document.getElementById("moveButton"),t=document.getElementById("copyButton");console.log("[macros.js] Binding Manage buttons:",{moveButton:!!e,copyButton:!!t}),e?e.addEventListener("click",()=>{console.log("[macros.js] Move button clicked"),manageMove()}):console.error("[macros.js] ERROR: moveButton not found!"),t?t.addEventListener("click",()=>{console.log("[macros.js] Copy button clicked"),manageCopy()}):console.error("[macros.js] ERROR: copyButton not found!");const r=document.getElementById("compareButton");r&&r.addEventListener("click",()=>{console.log("[macros.js] Compare button clicked"),manageCompare()});const c=document.getElementById("syncButton");c&&c.addEventListener("click",()=>{console.log("[macros.js] Sync button clicked"),manageSync()});const n=document.getElementById("autoGridButton"),s=document.getElementById("groupColorButton"),o=document.getElementById("groupTitleButton");console.log("[macros.js] Binding Grouping buttons:",{autoGridButton:!!n,groupColorButton:!!s,groupTitleButton:!!o}),n?n.addEventListener("click",()=>{console.log("[macros.js] Auto Grid button clicked"),autoGrid()}):console.error("[macros.js] ERROR: autoGridButton not found!"),s?s.addEventListener("click",()=>{console.log("[macros.js] Group by Color button clicked"),groupByColor()}):console.error("[macros.js] ERROR: groupColorButton not found!"),o?o.addEventListener("click",()=>{console.log("[macros.js] Group by Title button clicked"),groupByTitle()}):console.error("[macros.js] ERROR: groupTitleButton not found!");const i=document.getElementById("pinAllButton"),a=document.getElementById("unpinAllButton");console.log("[macros.js] Binding Pinning buttons:",{pinAllButton:!!i,unpinAllButton:!!a}),i?i.addEventListener("click",()=>{console.log("[macros.js] Pin All button clicked"),pinAll()}):console.error("[macros.js] ERROR: pinAllButton not found!"),a?a.addEventListener("click",()=>{console.log("[macros.js] Unpin All button clicked"),unpinAll()}):console.error("[macros.js] ERROR: unpinAllButton not found!");const l=document.getElementById("undoButton"),d=document.getElementById("redoButton");l&&l.addEventListener("click",()=>{console.log("[macros.js] Undo button clicked"),undoMacro()}),d&&d.addEventListener("click",()=>{console.log("[macros.js] Redo button clicked"),redoMacro()}),console.log("[macros.js] Setting up color tolerance slider"),setupColorToleranceSlider(),console.log("[macros.js] Initialization complete")});function setupTabs(){const e=document.querySelectorAll(".tab-button"),t=document.querySelectorAll(".tab-content");e.forEach(n=>{n.addEventListener("click",()=>{e.forEach(e=>e.classList.remove("active")),t.forEach(e=>e.classList.remove("active")),n.classList.add("active");const o=n.getAttribute("data-tab"),s=document.getElementById(`${o}-content`);s&&s.classList.add("active")})})}async function fetchZones(){try{console.log("[fetchZones] Fetching zones and canvas details...");const t=await fetch("/get-zones",{headers:{"Cache-Control":"no-cache"}}),e=await t.json();if(!e.success||!e.zones)throw new Error("Failed to retrieve zones from the server.");console.log(`[fetchZones] Retrieved ${e.zones.length} zones.`),populateZoneDropdowns(e.zones)}catch(e){console.error("[fetchZones] Error:",e.message),displayMessage(e.message,"error")}}function populateZoneDropdowns(e){try{const t={manageSourceZone:document.getElementById("manageSourceZone"),manageTargetZone:document.getElementById("manageTargetZone"),arrangeSourceZone:document.getElementById("arrangeSourceZone"),pinSourceZone:document.getElementById("pinSourceZone")};Object.values(t).forEach(e=>{e&&(e.innerHTML='<option value="">Select a zone...</option>')});const n=[...e].sort((e,t)=>{const n=(e.anchor_name||"").toLowerCase(),s=(t.anchor_name||"").toLowerCase();return n.localeCompare(s,0[0],{numeric:!0})});n.forEach(e=>{const s=e.anchor_name||`Zone ${e.id}`,n=document.createElement("option");n.value=e.id,n.textContent=s,Object.values(t).forEach(e=>{e&&e.appendChild(n.cloneNode(!0))})})}catch(e){console.error("[populateZoneDropdowns] Error:",e.message),displayMessage("Error populating zone dropdowns: "+e.message,"error")}}async function manageMove(){console.log("[macros.js] manageMove() called");const e=document.getElementById("manageSourceZone")?.value,t=document.getElementById("manageTargetZone")?.value;if(console.log("[macros.js] Zone IDs:",{sourceZoneId:e,targetZoneId:t}),!e||!t){const e="Please select both Source and Target zones.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const s={sourceZoneId:e,targetZoneId:t};console.log("[macros.js] Sending POST /api/macros/move with payload:",s);const n=await postMacro("/api/macros/move",s);if(!n)return;console.log("[macros.js] Move response:",n),displayBatchResult(n,"Widgets moved successfully")}catch(e){console.error("[macros.js] Move failed:",e),displayMessage(e.message||"Failed to move widgets","error")}}async function manageCopy(){console.log("[macros.js] manageCopy() called");const e=document.getElementById("manageSourceZone")?.value,t=document.getElementById("manageTargetZone")?.value;if(console.log("[macros.js] Zone IDs:",{sourceZoneId:e,targetZoneId:t}),!e||!t){const e="Please select both Source and Target zones.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const s={sourceZoneId:e,targetZoneId:t};console.log("[macros.js] Sending POST /api/macros/copy with payload:",s);const n=await postMacro("/api/macros/copy",s);if(!n)return;console.log("[macros.js] Copy response:",n),displayBatchResult(n,"Widgets copied successfully")}catch(e){console.error("[macros.js] Copy failed:",e),displayMessage(e.message||"Failed to copy widgets","error")}}async function manageCompare(){const e=document.getElementById("manageSourceZone")?.value,t=document.getElementById("manageTargetZone")?.value;if(!e||!t){displayMessage("Please select both Source and Target zones.","error");return}try{const n=document.getElementById("membershipSelect"),o=n&&n.value?`?membership=${encodeURIComponent(n.value)}`:"",s=await postJson(`/api/macros/zone-diff${o}`,{sourceZoneId:e,targetZoneId:t});console.log("[macros.js] Compare response:",s),displayZoneDiff(s)}catch(e){console.error("[macros.js] Compare failed:",e),displayMessage(e.message||"Failed to compare zones","error")}}function displayZoneDiff(e){const t=document.getElementById("manageMessage");if(!t){console.log("[macros.js] Zone diff:",e.diff);return}const s=e.diff||{},o=[["Missing from target",s.added||[]],["Only in target",s.removed||[]],["Changed",s.changed||[]]];t.textContent=e.message;const n=document.createElement("ul");n.className="batch-failures",o.forEach(([e,t])=>{t.forEach(t=>{const s=document.createElement("li"),o=t.source_id||t.target_id,i=(t.fields||[]).length>0?`: ${t.fields.join(", ")}`:"";s.textContent=`${e}: ${t.widget_type} ${t.title||o.substring(0,8)}${i}`,n.appendChild(s)})}),(s.skipped||[]).forEach(e=>{const t=document.createElement("li");t.textContent=`Skipped ${e.widget_type} ${e.widget_id.substring(0,8)}: ${e.reason}`,n.appendChild(t)}),n.children.length>0&&t.appendChild(n),t.className="message info mt-md",t.style.display="block"}async function manageSync(){const e=document.getElementById("manageSourceZone")?.value,t=document.getElementById("manageTargetZone")?.value;if(!e||!t){displayMessage("Please select both Source and Target zones.","error");return}try{const n=await postMacro("/api/macros/zone-sync",{sourceZoneId:e,targetZoneId:t});if(!n)return;console.log("[macros.js] Sync response:",n),displayBatchResult(n,"Zones synced successfully")}catch(e){console.error("[macros.js] Sync failed:",e),displayMessage(e.message||"Failed to sync zones","error")}}async function autoGrid(){console.log("[macros.js] autoGrid() called");const e=document.getElementById("arrangeSourceZone")?.value;if(console.log("[macros.js] Zone ID:",e),!e){const e="Please select a Source zone.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const n={zoneId:e};console.log("[macros.js] Sending POST /api/macros/auto-grid with payload:",n);const t=await postMacro("/api/macros/auto-grid",n);if(!t)return;console.log("[macros.js] Auto grid response:",t),displayBatchResult(t,"Auto grid applied successfully")}catch(e){console.error("[macros.js] Auto grid failed:",e),displayMessage(e.message||"Failed to apply auto grid","error")}}async function groupByColor(){console.log("[macros.js] groupByColor() called");const e=document.getElementById("arrangeSourceZone")?.value,t=document.getElementById("colorToleranceSlider")?.value;if(console.log("[macros.js] Zone ID:",e,"Color tolerance:",t),!e){const e="Please select a Source zone.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const s={zoneId:e,colorTolerance:parseInt(t)};console.log("[macros.js] Sending POST /api/macros/group-color with payload:",s);const n=await postMacro("/api/macros/group-color",s);if(!n)return;console.log("[macros.js] Group by color response:",n),displayBatchResult(n,"Grouped by color successfully")}catch(e){console.error("[macros.js] Group by color failed:",e),displayMessage(e.message||"Failed to group by color","error")}}async function groupByTitle(){console.log("[macros.js] groupByTitle() called");const e=document.getElementById("arrangeSourceZone")?.value;if(console.log("[macros.js] Zone ID:",e),!e){const e="Please select a Source zone.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const n={zoneId:e};console.log("[macros.js] Sending POST /api/macros/group-title with payload:",n);const t=await postMacro("/api/macros/group-title",n);if(!t)return;console.log("[macros.js] Group by title response:",t),displayBatchResult(t,"Grouped by title successfully")}catch(e){console.error("[macros.js] Group by title failed:",e),displayMessage(e.message||"Failed to group by title","error")}}async function pinAll(){console.log("[macros.js] pinAll() called");const e=document.getElementById("pinSourceZone")?.value;if(console.log("[macros.js] Zone ID:",e),!e){const e="Please select a Source zone.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const n={zoneId:e};console.log("[macros.js] Sending POST /api/macros/pin-all with payload:",n);const t=await postMacro("/api/macros/pin-all",n);if(!t)return;console.log("[macros.js] Pin all response:",t),displayBatchResult(t,"All widgets pinned successfully")}catch(e){console.error("[macros.js] Pin all failed:",e),displayMessage(e.message||"Failed to pin widgets","error")}}async function unpinAll(){console.log("[macros.js] unpinAll() called");const e=document.getElementById("pinSourceZone")?.value;if(console.log("[macros.js] Zone ID:",e),!e){const e="Please select a Source zone.";console.warn("[macros.js] Validation failed:",e),displayMessage(e,"error");return}try{const n={zoneId:e};console.log("[macros.js] Sending POST /api/macros/unpin-all with payload:",n);const t=await postMacro("/api/macros/unpin-all",n);if(!t)return;console.log("[macros.js] Unpin all response:",t),displayBatchResult(t,"All widgets unpinned successfully")}catch(e){console.error("[macros.js] Unpin all failed:",e),displayMessage(e.message||"Failed to unpin widgets","error")}}async function postMacro(e,t){const s=document.getElementById("membershipSelect"),n=new URLSearchParams;s&&s.value&&n.set("membership",s.value);const o=n.toString(),i=o?`${e}?${o}`:e,a=document.getElementById("previewToggle");if(!a||!a.checked)return postJson(i,t);n.set("dry_run","1");const r=await postJson(`${e}?${n.toString()}`,t);console.log("[macros.js] Dry run response:",r);const c=await showPlanPreview(r.plan);return c?postJson(i,t):(displayMessage("Macro cancelled","info"),null)}function showPlanPreview(e){return new Promise(t=>{const c=(e.changes||[]).concat(e.creations||[]),i=e.deletions||[],s=document.createElement("div");s.className="plan-preview-overlay";const n=document.createElement("div");n.className="plan-preview card",s.appendChild(n);const l=document.createElement("h2");l.className="card-title",l.textContent=`Preview: ${e.macro}`,n.appendChild(l);const u=document.createElement("p");if(u.textContent=`${(e.changes||[]).length} widgets will change, ${(e.creations||[]).length} will be created, ${i.length} will be deleted (zone membership: ${e.membership}).`,n.appendChild(u),(e.skipped||[]).length>0){const t=document.createElement("ul");t.className="batch-failures",e.skipped.forEach(e=>{const n=document.createElement("li");n.textContent=`Skipped ${e.widget_type} ${e.widget_id.substring(0,8)}: ${e.reason}`,t.appendChild(n)}),n.appendChild(t)}e.zone&&c.length+i.length>0&&n.appendChild(renderPlanZone(e,c,i));const a=document.createElement("div");a.className="form-actions";const o=document.createElement("button");o.className="btn btn-primary",o.textContent="Apply",o.disabled=c.length+i.length===0;const r=document.createElement("button");r.className="btn btn-secondary",r.textContent="Cancel",a.appendChild(o),a.appendChild(r),n.appendChild(a);const d=e=>{s.remove(),t(e)};o.addEventListener("click",()=>d(!0)),r.addEventListener("click",()=>d(!1)),s.addEventListener("click",e=>{e.target===s&&d(!1)}),document.body.appendChild(s)})}function renderPlanZone(e,t,n=[]){const o=[e.zone];e.source_zone&&o.push(e.source_zone);const c=Math.min(...o.map(e=>e.x)),l=Math.min(...o.map(e=>e.y)),u=Math.max(...o.map(e=>e.x+e.width)),h=Math.max(...o.map(e=>e.y+e.height)),d=560,i=d/(u-c||1),s=document.createElement("div");s.className="plan-preview-zone",s.style.width=`${d}px`,s.style.height=`${(h-l)*i}px`;const a=(e,t,n,s,o)=>{e.style.left=`${(t-c)*i}px`,e.style.top=`${(n-l)*i}px`,e.style.width=`${Math.max(s*i,4)}px`,e.style.height=`${Math.max(o*i,4)}px`};o.forEach((e,t)=>{const n=document.createElement("div");n.className=t===0?"plan-zone plan-zone-target":"plan-zone plan-zone-source",a(n,e.x,e.y,e.width,e.height),s.appendChild(n)});const r=e=>{const t=e.scale||1;return e.size?[e.size.width*t,e.size.height*t]:[100,100]};return t.forEach(e=>{const{before:n,after:t}=e;if(n.location&&(!t.location||n.location.x!==t.location.x||n.location.y!==t.location.y)){const e=document.createElement("div");e.className="plan-widget plan-widget-before";const[t,o]=r(n);a(e,n.location.x,n.location.y,t,o),s.appendChild(e)}if(t.location){const n=document.createElement("div");n.className=t.pinned?"plan-widget plan-widget-pinned":"plan-widget",n.title=`${e.widget_type} ${e.title||e.widget_id.substring(0,8)}`;const[o,i]=r(t);a(n,t.location.x,t.location.y,o,i),s.appendChild(n)}}),n.forEach(e=>{const{before:t}=e;if(!t.location)return;const n=document.createElement("div");n.className="plan-widget plan-widget-deleted",n.title=`Delete ${e.widget_type} ${e.title||e.widget_id.substring(0,8)}`;const[o,i]=r(t);a(n,t.location.x,t.location.y,o,i),s.appendChild(n)}),s}async function undoMacro(){try{const e=await postJson("/api/macros/undo",{});console.log("[macros.js] Undo response:",e),displayBatchResult(e,"Last macro undone")}catch(e){console.error("[macros.js] Undo failed:",e),displayMessage(e.message||"Failed to undo","error")}}async function redoMacro(){try{const e=await postJson("/api/macros/redo",{});console.log("[macros.js] Redo response:",e),displayBatchResult(e,"Macro redone")}catch(e){console.error("[macros.js] Redo failed:",e),displayMessage(e.message||"Failed to redo","error")}}function setupColorToleranceSlider(){const e=document.getElementById("colorToleranceSlider"),t=document.getElementById("colorToleranceValue");e&&t&&e.addEventListener("input",e=>{t.textContent=e.target.value+"%"})}async function postJson(e,t){console.log("[macros.js] postJson() - URL:",e,"Payload:",t);const n=await fetch(e,{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify(t)});if(console.log("[macros.js] postJson() - Response status:",n.status,n.statusText),!n.ok){const e=await n.text();console.error("[macros.js] postJson() - Error response:",e);let t;try{t=JSON.parse(e)}catch{t={error:e||"Request failed"}}throw new Error(t.error||`HTTP ${n.status}`)}const s=await n.json();return console.log("[macros.js] postJson() - Success response:",s),s}function displayMessage(e,t){const n=document.getElementById("manageMessage")||document.getElementById("arrangeMessage")||document.getElementById("pinMessage");n?(n.textContent=e,n.className=`message ${t} mt-md`,n.style.display="block",setTimeout(()=>{n.style.display="none"},5e3)):console.log(`[${t}] ${e}`)}function displayBatchResult(e,t){const n=(e.report?.results||[]).filter(e=>!e.succeeded),i=e.report?.skipped||[];if(n.length===0&&i.length===0){displayMessage(e.message||t,"success");return}const s=document.getElementById("manageMessage")||document.getElementById("arrangeMessage")||document.getElementById("pinMessage");if(!s){console.warn("[macros.js] Failed widgets:",n,"Skipped widgets:",i);return}s.textContent=e.message||`${n.length} widgets failed`;const o=document.createElement("ul");o.className="batch-failures",n.forEach(e=>{const t=document.createElement("li"),n=e.attempts>1?` after ${e.attempts} attempts`:"";t.textContent=`${e.widget_type} ${e.widget_id.substring(0,8)}${n}: ${e.error}`,o.appendChild(t)}),i.forEach(e=>{const t=document.createElement("li");t.textContent=`Skipped ${e.widget_type} ${e.widget_id.substring(0,8)}: ${e.reason}`,o.appendChild(t)}),s.appendChild(o),s.className=n.length>0?"message error mt-md":"message info mt-md",s.style.display="block"}
\ No newline at end of file
@@ -1,6 +1,12 @@
-document.addEventListener("DOMContentLoaded",()=>{initTeamButtonStyles(),initCreateTargets(),initTestTeamButtons(),initUserManagement()});function initTeamButtonStyles(){const t=document.querySelectorAll("[data-test-team]"),e={1:"rgb(255, 0, 0)",2:"rgb(255, 127, 0)",3:"rgb(255, 255, 0)",4:"rgb(0, 255, 0)",5:"rgb(0, 0, 255)",6:"rgb(75, 0, 130)",7:"rgb(139, 0, 255)"},n={1:"rgb(255, 255, 255)",2:"rgb(0, 0, 0)",3:"rgb(0, 0, 0)",4:"rgb(0, 0, 0)",5:"rgb(255, 255, 255)",6:"rgb(255, 255, 255)",7:"rgb(255, 255, 255)"};t.forEach(t=>{const s=parseInt(t.getAttribute("data-test-team"));s&&e[s]&&(t.style.width="120px",t.style.height="40px",t.style.flexShrink="0",t.style.minWidth="120px",t.style.maxWidth="120px",t.style.backgroundColor=e[s],t.style.color=n[s])})}function initCreateTargets(){const t=document.getElementById("createTargetsBtn"),n=document.getElementById("deleteTargetsBtn"),e=document.getElementById("targetsMessage");t&&t.addEventListener("click",async()=>{try{const n=await fetch("/api/admin/create-targets",{method:"POST",headers:{"Content-Type":"application/json"}}),t=await n.json();n.ok&&t.success?displayMessage(e,t.message||"Target notes created successfully","success"):displayMessage(e,t.error||"Failed to create target notes","error")}catch(t){console.error("Error creating targets:",t),displayMessage(e,"An error occurred while creating targets","error")}}),n&&n.addEventListener("click",async()=>{if(!confirm("Are you sure you want to delete all target notes?"))return;try{const n=await fetch("/api/admin/delete-targets",{method:"POST",headers:{"Content-Type":"application/json"}}),t=await n.json();n.ok&&t.success?displayMessage(e,t.message||"Target notes deleted successfully","success"):displayMessage(e,t.error||"Failed to delete target notes","error")}catch(t){console.error("Error deleting targets:",t),displayMessage(e,"An error occurred while deleting targets","error")}})}function initTestTeamButtons(){const t=document.querySelectorAll("[data-test-team]"),e=document.getElementById("testTeamMessage");t.forEach(t=>{t.addEventListener("click",async()=>{const n=parseInt(t.getAttribute("data-test-team"));try{const t=await fetch("/api/admin/test-team",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({team:n,text:`Test note from Admin to Team ${n}`})}),s=await t.json();t.ok&&s.success?displayMessage(e,`Test note sent to Team ${n} successfully`,"success"):displayMessage(e,s.error||`Failed to send test note to Team ${n}`,"error")}catch(t){console.error(`Error sending test note to Team ${n}:`,t),displayMessage(e,`An error occurred while sending test note to Team ${n}`,"error")}})})}function initUserManagement(){const n=document.getElementById("listUsersBtn"),s=document.getElementById("deleteUsersBtn"),o=document.getElementById("userListTable"),e=document.getElementById("userListMessage"),t=o?o.querySelector("tbody"):null;n&&n.addEventListener("click",async()=>{try{const s=await fetch("/api/admin/list-users",{method:"GET",headers:{"Content-Type":"application/json"}}),n=await s.json();s.ok&&n.success&&n.users?displayUserList(n.users,t,e):displayMessage(e,n.error||"Failed to list users","error")}catch(t){console.error("Error listing users:",t),displayMessage(e,"An error occurred while listing users","error")}}),s&&s.addEventListener("click",async()=>{if(!confirm("Are you sure you want to delete all users?"))return;try{const s=await fetch("/api/admin/delete-users",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({all:!0})}),n=await s.json();s.ok&&n.success?(displayMessage(e,n.message||"Users deleted successfully","success"),t&&(t.innerHTML="")):displayMessage(e,n.error||"Failed to delete users","error")}catch(t){console.error("Error deleting users:",t),displayMessage(e,"An error occurred while deleting users","error")}})}function displayUserList(e,t,n){if(!t)return;if(!e||e.length===0){t.innerHTML="",n&&(n.textContent="No users to display.",n.style.display="block");return}n&&(n.style.display="none"),t.innerHTML=e.map(e=>`
+document.addEventListener("DOMContentLoaded",()=>{initUpload(),initUploadHistory(),initRCUSession(),initCreateTargets(),initUserManagement()});function initUpload(){const i=document.getElementById("uploadForm"),p=document.getElementById("uploadFiles"),c=document.getElementById("uploadPlacement"),u=document.getElementById("uploadZoneGroup"),o=document.getElementById("uploadZone"),d=document.getElementById("uploadBtn"),f=document.getElementById("uploadProgress"),m=document.getElementById("uploadProgressFill"),h=document.getElementById("uploadProgressText"),l=document.getElementById("uploadFileList"),t=document.getElementById("uploadMessage");if(!i)return;let a="";const r=new Map;function s(e,t){m.style.width=`${e}%`,h.textContent=t}function e(e,t,n){const s=r.get(e);if(!s)return;s.textContent=`${e}: ${t}`,s.className=n||""}const n=new WorkspaceClient;n.clientId=selectedClientId(),n.workspace=selectedWorkspace(),n.on("upload_progress",t=>{if(t.upload_id!==a)return;const n=t.phase==="uploading"?t.index-1:t.index;s(Math.round(n/t.total*100),`Placing ${t.index} of ${t.total} on the canvas...`),t.phase==="uploaded"?e(t.file,"placed","upload-file-success"):t.phase==="failed"?e(t.file,`failed: ${t.error}`,"upload-file-failure"):e(t.file,"placing...")}),n.on("upload_finished",e=>{if(e.upload_id!==a)return;s(100,`${e.total-e.failed} of ${e.total} files placed`),loadUploadHistory(0)}),n.connect(window.location.origin,["upload"]);async function g(){try{const n=await fetch("/get-zones"),e=await n.json();o.innerHTML="",(e.zones||[]).forEach(e=>{if(!e.id)return;const t=document.createElement("option");t.value=e.id,t.textContent=e.anchor_name||e.id,o.appendChild(t)}),o.options.length===0&&displayMessage(t,e.error||"No zones on this canvas","error")}catch(e){console.error("Error loading zones:",e),displayMessage(t,"An error occurred while loading zones","error")}}async function v(){const e=document.getElementById("uploadTypes");try{const n=await fetch("/api/uploads/types"),t=await n.json();if(!n.ok||!t.success)return;const s=t.conversions.map(e=>`${e.name} (${e.from.join(", ")} to ${e.to})`);e.textContent=`Placed as they are: ${t.types.map(e=>e.mime_type).join(", ")}. `+`Conversions: ${s.join("; ")}.`}catch(e){console.error("Error loading upload types:",e)}}v(),c.addEventListener("change",()=>{const e=c.value==="zone";u.style.display=e?"block":"none",e&&g()}),i.addEventListener("submit",async n=>{n.preventDefault();const h=Array.from(p.files);if(h.length===0)return;if(c.value==="zone"&&!o.value){displayMessage(t,"Select a zone to upload into","error");return}a=`${Date.now()}-${Math.random().toString(36).slice(2,8)}`;const m=new FormData(i);m.delete("files"),m.set("upload_id",a),r.clear(),l.innerHTML="",h.forEach(t=>{const n=document.createElement("li");l.appendChild(n),r.set(t.name,n),e(t.name,"waiting")}),f.style.display="block",s(0,"Sending files..."),d.disabled=!0;try{const a=h.reduce((e,t)=>e+t.size,0);let o=0;for(const t of h){e(t.name,"sending...");const n=await new ChunkedUpload(t,{onProgress:e=>{const t=Math.round((o+e)/a*100);s(t,`Sending files... ${Math.round((o+e)/1048576)} of ${Math.round(a/1048576)} MB`)}}).start();o+=t.size,m.append("staged",n.id),e(t.name,"sent")}s(0,"Placing files on the canvas...");const r=await fetch("/api/remote-upload",{method:"POST",body:m}),n=await r.json();(n.files||[]).forEach(t=>{t.outcome==="success"?e(t.filename,"placed","upload-file-success"):e(t.filename,`failed: ${t.error}`,"upload-file-failure")}),n.success?(displayMessage(t,`${n.files.length} files uploaded to the canvas`,"success"),i.reset(),u.style.display="none"):displayMessage(t,n.error||"Upload failed","error"),loadUploadHistory(0)}catch(e){console.error("Error uploading files:",e),displayMessage(t,e.message||"An error occurred while uploading","error")}finally{d.disabled=!1}})}const uploadHistoryPageSize=20;let uploadHistoryOffset=0;function initUploadHistory(){const e=document.getElementById("uploadHistoryPrev"),t=document.getElementById("uploadHistoryNext");if(!e||!t)return;e.addEventListener("click",()=>loadUploadHistory(Math.max(uploadHistoryOffset-uploadHistoryPageSize,0))),t.addEventListener("click",()=>loadUploadHistory(uploadHistoryOffset+uploadHistoryPageSize)),loadUploadHistory(0)}async function loadUploadHistory(e){const t=document.querySelector("#uploadHistoryTable tbody"),n=document.getElementById("uploadHistoryInfo");if(!t)return;try{const o=await fetch(`/api/remote-upload/history?offset=${e}&limit=${uploadHistoryPageSize}`),s=await o.json();if(!o.ok||!s.success){n.textContent=s.error||"Failed to load the upload history";return}uploadHistoryOffset=e,t.innerHTML="",s.records.forEach(e=>{const n=document.createElement("tr"),s=e.error?`${e.outcome}: ${e.error}`:e.outcome;[new Date(e.uploaded_at).toLocaleString(),`${e.filename} (${Math.round(e.size/1024)} KB${e.converter?`, ${e.converter}`:""})`,e.uploader||e.remote_ip||"-",e.canvas_id,e.widget_id?`${e.widget_type} ${e.widget_id}`:"-",s].forEach((t,s)=>{const o=document.createElement("td");o.textContent=t,s===5&&(o.className=`upload-file-${e.outcome}`),n.appendChild(o)}),t.appendChild(n)}),n.textContent=s.total===0?"No uploads yet.":`${e+1}-${e+s.records.length} of ${s.total}`,document.getElementById("uploadHistoryPrev").disabled=e===0,document.getElementById("uploadHistoryNext").disabled=e+s.records.length>=s.total}catch(e){console.error("Error loading upload history:",e),n.textContent="An error occurred while loading the upload history"}}function initRCUSession(){const t=document.querySelector("#teamsTable tbody"),n=document.getElementById("addTeamBtn"),s=document.getElementById("saveTeamsBtn"),e=document.getElementById("teamsMessage");if(!t)return;const o=()=>Array.from(t.querySelectorAll("tr")).map(e=>({id:parseInt(e.dataset.id)||0,name:e.querySelector('[data-field="name"]').value,color:e.querySelector('[data-field="color"]').value,anchor_index:parseInt(e.querySelector('[data-field="anchor_index"]').value)||0})),i=e=>{const n=document.createElement("tr");n.innerHTML=`
+      <td class="team-number"></td>
+      <td><input type="text" class="input" data-field="name"></td>
+      <td><input type="color" data-field="color"></td>
+      <td><input type="number" class="input" min="1" data-field="anchor_index" style="width: 6em;"></td>
+      <td><button type="button" class="btn btn-danger btn-sm">Remove</button></td>
+    `,n.dataset.id=e.id||"",n.querySelector(".team-number").textContent=e.id||"New",n.querySelector('[data-field="name"]').value=e.name,n.querySelector('[data-field="color"]').value=e.color.slice(0,7).toLowerCase(),n.querySelector('[data-field="anchor_index"]').value=e.anchor_index,n.querySelector("button").addEventListener("click",()=>n.remove()),t.appendChild(n)},a=e=>{t.innerHTML="",e.forEach(i),renderTestTeamButtons(e)},r=async()=>{try{const n=await fetch("/api/admin/rcu-session"),t=await n.json();n.ok&&t.success?a(t.session.teams):displayMessage(e,t.error||"Failed to load the teams","error")}catch(t){console.error("Error loading teams:",t),displayMessage(e,"An error occurred while loading the teams","error")}};n&&n.addEventListener("click",()=>{const e=o(),t=e.reduce((e,t)=>Math.max(e,t.anchor_index),0)+1;i({name:`Team ${e.length+1}`,color:"#808080",anchor_index:t})}),s&&s.addEventListener("click",async()=>{try{const n=await fetch("/api/admin/rcu-session",{method:"PUT",headers:{"Content-Type":"application/json"},body:JSON.stringify({teams:o()})}),t=await n.json();n.ok&&t.success?(a(t.session.teams),displayMessage(e,"Teams saved","success")):displayMessage(e,t.error||"Failed to save the teams","error")}catch(t){console.error("Error saving teams:",t),displayMessage(e,"An error occurred while saving the teams","error")}}),r()}function renderTestTeamButtons(e){const t=document.getElementById("testTeamButtons"),n=document.getElementById("testTeamMessage");if(!t)return;t.innerHTML="",e.forEach(e=>{const o=e.color.slice(0,7),s=document.createElement("button");s.type="button",s.className="btn btn-secondary",s.textContent=`Test ${e.name}`,s.style.width="120px",s.style.height="40px",s.style.flexShrink="0",s.style.minWidth="120px",s.style.maxWidth="120px",s.style.backgroundColor=o,s.style.color=isColorDark(o)?"#FFFFFF":"#000000",s.addEventListener("click",async()=>{try{const t=await fetch("/api/admin/test-team",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({team:e.id,text:`Test note from Admin to ${e.name}`})}),s=await t.json();t.ok&&s.success?displayMessage(n,`Test note sent to ${e.name} successfully`,"success"):displayMessage(n,s.error||`Failed to send test note to ${e.name}`,"error")}catch(t){console.error(`Error sending test note to ${e.name}:`,t),displayMessage(n,`An error occurred while sending test note to ${e.name}`,"error")}}),t.appendChild(s)})}function isColorDark(e){const t=parseInt(e.slice(1,3),16),n=parseInt(e.slice(3,5),16),s=parseInt(e.slice(5,7),16);return(t*.299+n*.587+s*.114)/255<.5}function initCreateTargets(){const t=document.getElementById("createTargetsBtn"),n=document.getElementById("deleteTargetsBtn"),e=document.getElementById("targetsMessage");t&&t.addEventListener("click",async()=>{try{const n=await fetch("/api/admin/create-targets",{method:"POST",headers:{"Content-Type":"application/json"}}),t=await n.json();n.ok&&t.success?displayMessage(e,t.message||"Target notes created successfully","success"):displayMessage(e,t.error||"Failed to create target notes","error")}catch(t){console.error("Error creating targets:",t),displayMessage(e,"An error occurred while creating targets","error")}}),n&&n.addEventListener("click",async()=>{if(!confirm("Are you sure you want to delete all target notes?"))return;try{const n=await fetch("/api/admin/delete-targets",{method:"POST",headers:{"Content-Type":"application/json"}}),t=await n.json();n.ok&&t.success?displayMessage(e,t.message||"Target notes deleted successfully","success"):displayMessage(e,t.error||"Failed to delete target notes","error")}catch(t){console.error("Error deleting targets:",t),displayMessage(e,"An error occurred while deleting targets","error")}})}function initUserManagement(){const n=document.getElementById("listUsersBtn"),s=document.getElementById("deleteUsersBtn"),o=document.getElementById("userListTable"),e=document.getElementById("userListMessage"),t=o?o.querySelector("tbody"):null;n&&n.addEventListener("click",async()=>{try{const s=await fetch("/api/admin/list-users",{method:"GET",headers:{"Content-Type":"application/json"}}),n=await s.json();s.ok&&n.success&&n.users?displayUserList(n.users,t,e):displayMessage(e,n.error||"Failed to list users","error")}catch(t){console.error("Error listing users:",t),displayMessage(e,"An error occurred while listing users","error")}}),s&&s.addEventListener("click",async()=>{if(!confirm("Are you sure you want to delete all users?"))return;try{const s=await fetch("/api/admin/delete-users",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({all:!0})}),n=await s.json();s.ok&&n.success?(displayMessage(e,n.message||"Users deleted successfully","success"),t&&(t.innerHTML="")):displayMessage(e,n.error||"Failed to delete users","error")}catch(t){console.error("Error deleting users:",t),displayMessage(e,"An error occurred while deleting users","error")}})}function displayUserList(e,t,n){if(!t)return;if(!e||e.length===0){t.innerHTML="",n&&(n.textContent="No users to display.",n.style.display="block");return}n&&(n.style.display="none"),t.innerHTML=e.map(e=>`
     <tr>
-      <td>Team ${e.team}</td>
+      <td>${e.team_name||`Team ${e.team}`}</td>
       <td>${e.name}</td>
       <td>${e.color?e.color.toUpperCase():"N/A"}</td>
     </tr>
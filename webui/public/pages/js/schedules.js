@@ -0,0 +1 @@
+const SCHEDULE_ZONE_PARAMS={"pin-all":[{name:"zoneId",label:"Zone"}],"unpin-all":[{name:"zoneId",label:"Zone"}],"auto-grid":[{name:"zoneId",label:"Zone"}],"group-color":[{name:"zoneId",label:"Zone"}],"group-title":[{name:"zoneId",label:"Zone"}],move:[{name:"sourceZoneId",label:"Source Zone"},{name:"targetZoneId",label:"Target Zone"}],copy:[{name:"sourceZoneId",label:"Source Zone"},{name:"targetZoneId",label:"Target Zone"}],"zone-sync":[{name:"sourceZoneId",label:"Source Zone"},{name:"targetZoneId",label:"Target Zone"}]};let scheduleZones=[],scheduleParamSelects={};document.addEventListener("DOMContentLoaded",()=>{console.log("[schedules.js] Initializing schedule tab");const e=document.getElementById("jobMacro");if(!e)return;e.addEventListener("change",renderJobParams),document.getElementById("jobTrigger").addEventListener("change",updateJobTriggerInput),document.getElementById("createJobButton").addEventListener("click",()=>{console.log("[schedules.js] Schedule button clicked"),createJob()}),updateJobTriggerInput(),loadScheduleOptions(),fetchJobs()});async function loadScheduleOptions(){try{const[e,n]=await Promise.all([fetch("/api/macros/definitions"),fetch("/get-zones",{headers:{"Cache-Control":"no-cache"}})]),s=await n.json();scheduleZones=[...s.zones||[]].sort((e,t)=>(e.anchor_name||"").toLowerCase().localeCompare((t.anchor_name||"").toLowerCase(),0[0],{numeric:!0}));const t=await e.json();if(e.ok&&t.success){const e=document.getElementById("jobCustomMacros");(t.macros||[]).forEach(t=>{SCHEDULE_ZONE_PARAMS[`custom:${t.id}`]=(t.params||[]).map(e=>({name:e.name,label:e.label||e.name}));const n=document.createElement("option");n.value=`custom:${t.id}`,n.textContent=t.name,e.appendChild(n)})}}catch(e){console.error("[schedules.js] Failed to load macros and zones:",e),displayScheduleMessage(e.message||"Failed to load macros and zones","error")}renderJobParams()}function renderJobParams(){const e=document.getElementById("jobParams"),t=document.getElementById("jobMacro").value;e.innerHTML="",scheduleParamSelects={},(SCHEDULE_ZONE_PARAMS[t]||[]).forEach(t=>{const s=document.createElement("div");s.className="form-group";const o=document.createElement("label");o.className="input-label",o.textContent=`${t.label}:`;const n=document.createElement("select");n.className="input select",n.innerHTML='<option value="">Select a zone...</option>',scheduleZones.forEach(e=>{const t=document.createElement("option");t.value=e.id,t.textContent=e.anchor_name||`Zone ${e.id}`,n.appendChild(t)}),s.appendChild(o),s.appendChild(n),e.appendChild(s),scheduleParamSelects[t.name]=n})}function updateJobTriggerInput(){const e=document.getElementById("jobTrigger").value,t=document.getElementById("jobTriggerValue");document.getElementById("jobTriggerValueGroup").style.display=e==="on"?"none":"block",t.placeholder=e==="at"?"18:00":"10m"}async function createJob(){const o=document.getElementById("jobMacro"),e=document.getElementById("jobTrigger").value,n=document.getElementById("jobTriggerValue").value.trim(),s={};for(const[t,e]of Object.entries(scheduleParamSelects)){if(!e.value){displayScheduleMessage("Please select a zone for every parameter.","error");return}s[t]=e.value}const t={name:document.getElementById("jobName").value,macro:o.value,params:s};if(e==="on")t.on="canvas_changed";else if(n)t[e]=n;else{displayScheduleMessage(e==="at"?"Please enter a time (HH:MM).":"Please enter an interval (e.g. 10m).","error");return}try{const e=await postJson("/api/macros/jobs",t);displayScheduleMessage(e.message||"Job scheduled","success"),document.getElementById("jobName").value="",fetchJobs()}catch(e){console.error("[schedules.js] Create failed:",e),displayScheduleMessage(e.message||"Failed to schedule job","error")}}async function fetchJobs(){try{const t=await fetch("/api/macros/jobs"),e=await t.json();if(!t.ok||!e.success)throw new Error(e.error||`HTTP ${t.status}`);renderJobList(e.jobs||[]),renderJobHistory(e.history||[])}catch(e){console.error("[schedules.js] Failed to list jobs:",e),displayScheduleMessage(e.message||"Failed to list jobs","error")}}function renderJobList(e){const t=document.getElementById("jobList");if(t.innerHTML="",e.length===0){t.textContent="No scheduled macros yet.";return}e.forEach(e=>{const n=document.createElement("div");n.className=e.paused?"job-row job-paused":"job-row";const s=document.createElement("div");s.className="job-info";const a=document.createElement("strong");a.textContent=e.paused?`${e.name} (paused)`:e.name;const r=document.createElement("span");let i=`${e.macro} - ${describeJobTrigger(e)}`;e.last_run&&(i+=` - last run ${new Date(e.last_run).toLocaleString()}`),e.next_run&&!e.paused&&(i+=` - next run ${new Date(e.next_run).toLocaleString()}`),r.textContent=i,s.appendChild(a),s.appendChild(r),n.appendChild(s);const o=document.createElement("div");o.className="form-actions",o.appendChild(jobButton(e.paused?"Resume":"Pause","btn-primary",()=>pauseJob(e,!e.paused))),o.appendChild(jobButton("Delete","btn-secondary",()=>deleteJob(e))),n.appendChild(o),t.appendChild(n)})}function renderJobHistory(e){const t=document.getElementById("jobHistory");if(t.innerHTML="",e.length===0){t.textContent="No runs yet.";return}e.forEach(e=>{const n=document.createElement("div");n.className=e.error||e.failed>0?"job-info job-run-failed":"job-info";const s=new Date(e.started_at).toLocaleString(),o=e.error?e.error:`${e.succeeded} changed, ${e.failed} failed, ${e.skipped} skipped`;n.textContent=`${s} - ${e.job_name} (${e.trigger}) - ${o}`,t.appendChild(n)})}function describeJobTrigger(e){return e.every?`every ${e.every}`:e.at?`daily at ${e.at}`:e.on==="canvas_changed"?"when the canvas changes":e.on||""}function jobButton(e,t,n){const s=document.createElement("button");return s.className=`btn ${t}`,s.textContent=e,s.addEventListener("click",n),s}async function pauseJob(e,t){try{const n=await postJson("/api/macros/jobs/pause",{id:e.id,paused:t});displayScheduleMessage(n.message||(t?"Job paused":"Job resumed"),"success"),fetchJobs()}catch(e){console.error("[schedules.js] Pause failed:",e),displayScheduleMessage(e.message||"Failed to update job","error")}}async function deleteJob(e){if(!confirm(`Delete scheduled macro "${e.name}"?`))return;try{const t=await fetch(`/api/macros/jobs?id=${encodeURIComponent(e.id)}`,{method:"DELETE"}),n=await t.json();if(!t.ok||!n.success)throw new Error(n.error||`HTTP ${t.status}`);displayScheduleMessage(n.message||"Job deleted","success"),fetchJobs()}catch(e){console.error("[schedules.js] Delete failed:",e),displayScheduleMessage(e.message||"Failed to delete job","error")}}function displayScheduleMessage(e,t){const n=document.getElementById("scheduleMessage");if(!n){console.log(`[${t}] ${e}`);return}n.textContent=e,n.className=`message ${t} mt-md`,n.style.display="block"}
\ No newline at end of file
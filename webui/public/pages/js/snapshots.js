@@ -0,0 +1,3 @@
+document.addEventListener("DOMContentLoaded",()=>{console.log("[snapshots.js] Initializing snapshots tab");const e=document.getElementById("takeSnapshotButton");e&&e.addEventListener("click",()=>{console.log("[snapshots.js] Take Snapshot button clicked"),takeSnapshot()}),fetchSnapshots(),fetchRestoreCanvases()});async function fetchSnapshots(){const e=document.getElementById("snapshotList");if(!e)return;try{const e=await fetch("/api/snapshots"),t=await e.json();if(!e.ok||!t.success)throw new Error(t.error||`HTTP ${e.status}`);renderSnapshotList(t.snapshots||[])}catch(e){console.error("[snapshots.js] Failed to list snapshots:",e),displaySnapshotMessage(e.message||"Failed to list snapshots","error")}}function renderSnapshotList(e){const t=document.getElementById("snapshotList");if(t.innerHTML="",e.length===0){t.textContent="No snapshots yet.";return}e.forEach(e=>{const s=document.createElement("div");s.className="snapshot-row";const o=document.createElement("div");o.className="snapshot-info";const i=document.createElement("strong");i.textContent=e.name||e.id;const a=document.createElement("span"),r=new Date(e.created_at).toLocaleString();a.textContent=`${r} - ${e.canvas_name||e.canvas_id} - `+`${e.widget_count} widgets, ${e.asset_count} files, ${formatSnapshotSize(e.size)}`,o.appendChild(i),o.appendChild(a),s.appendChild(o);const n=document.createElement("div");n.className="form-actions",n.appendChild(snapshotButton("Diff","btn-secondary",()=>diffSnapshot(e))),n.appendChild(snapshotButton("Restore","btn-primary",()=>restoreSnapshot(e))),n.appendChild(snapshotButton("Delete","btn-secondary",()=>deleteSnapshot(e))),s.appendChild(n),t.appendChild(s)})}function snapshotButton(e,t,n){const s=document.createElement("button");return s.className=`btn ${t}`,s.textContent=e,s.addEventListener("click",n),s}function formatSnapshotSize(e){if(!e)return"0 B";const s=["B","KB","MB","GB"];let n=e,t=0;for(;n>=1024&&t<s.length-1;)n/=1024,t++;return`${n.toFixed(t===0?0:1)} ${s[t]}`}async function fetchRestoreCanvases(){const e=document.getElementById("restoreCanvas");if(!e)return;try{const n=await fetch("/api/snapshots/canvases"),t=await n.json();if(!n.ok||!t.success)throw new Error(t.error||`HTTP ${n.status}`);(t.canvases||[]).filter(e=>e.id!==t.current_canvas_id).forEach(t=>{const n=document.createElement("option");n.value=t.id,n.textContent=t.name||t.id,e.appendChild(n)})}catch(e){console.warn("[snapshots.js] Failed to list canvases:",e)}}async function takeSnapshot(){const e=document.getElementById("snapshotName"),t=document.getElementById("takeSnapshotButton");t&&(t.disabled=!0),displaySnapshotMessage("Taking snapshot, downloading files...","info");try{const t=await postJson("/api/snapshots",{name:e?e.value:""});displaySnapshotMessage(t.message||"Snapshot saved","success"),e&&(e.value=""),fetchSnapshots()}catch(e){console.error("[snapshots.js] Snapshot failed:",e),displaySnapshotMessage(e.message||"Failed to take snapshot","error")}finally{t&&(t.disabled=!1)}}async function diffSnapshot(e){const t=document.getElementById("snapshotDiff");displaySnapshotMessage("Comparing with the current canvas...","info");try{const s=await fetch(`/api/snapshots/diff?id=${encodeURIComponent(e.id)}`),n=await s.json();if(!s.ok||!n.success)throw new Error(n.error||`HTTP ${s.status}`);displaySnapshotMessage(n.message,"info"),renderSnapshotDiff(t,e,n.diff)}catch(e){console.error("[snapshots.js] Diff failed:",e),displaySnapshotMessage(e.message||"Failed to compare snapshot","error")}}function renderSnapshotDiff(e,t,n){if(!e)return;e.innerHTML="";const s=document.createElement("h3");s.textContent=`Changes since "${t.name||t.id}"`,e.appendChild(s);const o=[["Added",n.added||[]],["Removed",n.removed||[]],["Changed",n.changed||[]]];o.forEach(([t,n])=>{if(n.length===0)return;const o=document.createElement("h4");o.textContent=`${t} (${n.length})`,e.appendChild(o);const s=document.createElement("ul");s.className="batch-failures",n.forEach(e=>{const t=document.createElement("li"),n=(e.fields||[]).length>0?`: ${e.fields.join(", ")}`:"";t.textContent=`${e.widget_type} ${e.title||e.widget_id.substring(0,8)}${n}`,s.appendChild(t)}),e.appendChild(s)})}async function restoreSnapshot(e){const t=document.getElementById("restoreCanvas"),n=document.getElementById("restoreReplace"),s=t?t.value:"",o=!!n&&n.checked,a=s?t.options[t.selectedIndex].textContent:"the current canvas";let i=`Restore ${e.widget_count} widgets from "${e.name||e.id}" to ${a}?`;if(o&&(i+=`
+
+All widgets already on that canvas will be deleted first.`),!confirm(i))return;displaySnapshotMessage("Restoring snapshot, uploading files...","info");try{const t=await postJson("/api/snapshots/restore",{id:e.id,canvas_id:s,replace:o}),n=t.report?.failed||0;displaySnapshotMessage(t.message||"Snapshot restored",n>0?"error":"success")}catch(e){console.error("[snapshots.js] Restore failed:",e),displaySnapshotMessage(e.message||"Failed to restore snapshot","error")}}async function deleteSnapshot(e){if(!confirm(`Delete snapshot "${e.name||e.id}"?`))return;try{const t=await fetch(`/api/snapshots?id=${encodeURIComponent(e.id)}`,{method:"DELETE"}),n=await t.json();if(!t.ok||!n.success)throw new Error(n.error||`HTTP ${t.status}`);displaySnapshotMessage(n.message||"Snapshot deleted","success"),fetchSnapshots()}catch(e){console.error("[snapshots.js] Delete failed:",e),displaySnapshotMessage(e.message||"Failed to delete snapshot","error")}}function displaySnapshotMessage(e,t){const n=document.getElementById("snapshotsMessage");if(!n){console.log(`[${t}] ${e}`);return}n.textContent=e,n.className=`message ${t} mt-md`,n.style.display="block"}
\ No newline at end of file
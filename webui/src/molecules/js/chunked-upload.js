@@ -0,0 +1,144 @@
+/**
+ * Chunked Upload Molecule - Resumable file uploads
+ * Sends a file to /api/uploads in chunks. An interrupted upload (dropped
+ * Wi-Fi, reloaded page) resumes from the offset the server has instead of
+ * restarting; the upload id is remembered per file in localStorage.
+ */
+
+class ChunkedUpload {
+  /**
+   * @param {File} file - File to send
+   * @param {Object} [options]
+   * @param {Function} [options.onProgress] - Called with (sentBytes, totalBytes)
+   * @param {number} [options.maxRetries] - Failed chunks in a row before giving up
+   */
+  constructor(file, options = {}) {
+    this.file = file;
+    this.onProgress = options.onProgress || (() => {});
+    this.maxRetries = options.maxRetries || 10;
+    this.chunkSize = 8 * 1024 * 1024;
+    this.storageKey = `chunkedUpload:${file.name}:${file.size}:${file.lastModified}`;
+  }
+
+  /**
+   * Send the file, resuming an earlier attempt the server still has
+   * @returns {Promise<Object>} The complete upload; place it by its id
+   */
+  async start() {
+    let upload = await this.resume() || await this.create();
+    let failures = 0;
+    this.onProgress(upload.offset, upload.size);
+
+    while (!upload.complete) {
+      try {
+        const next = await this.sendChunk(upload);
+        failures = next.offset > upload.offset ? 0 : failures + 1;
+        upload = next;
+      } catch (error) {
+        if (error.fatal) {
+          localStorage.removeItem(this.storageKey);
+          throw error;
+        }
+        failures++;
+        if (failures > this.maxRetries) {
+          throw new Error(`Upload of ${this.file.name} keeps failing (${error.message}); try again to resume it`);
+        }
+        await ChunkedUpload.waitToRetry(failures);
+        // The server may have kept part of the chunk
+        upload = await this.status(upload.id) || upload;
+      }
+      this.onProgress(upload.offset, upload.size);
+    }
+
+    localStorage.removeItem(this.storageKey);
+    return upload;
+  }
+
+  /**
+   * Cancel the upload and drop what the server received
+   */
+  async cancel() {
+    const id = localStorage.getItem(this.storageKey);
+    localStorage.removeItem(this.storageKey);
+    if (id) {
+      await fetch(`/api/uploads/${id}`, { method: 'DELETE' }).catch(() => {});
+    }
+  }
+
+  /**
+   * The earlier upload of this file, if the server still has it
+   */
+  async resume() {
+    const id = localStorage.getItem(this.storageKey);
+    if (!id) return null;
+    const upload = await this.status(id);
+    if (!upload) {
+      localStorage.removeItem(this.storageKey);
+    }
+    return upload;
+  }
+
+  /**
+   * The upload id as the server has it, or null
+   */
+  async status(id) {
+    try {
+      const response = await fetch(`/api/uploads/${id}`);
+      const data = await response.json();
+      return response.ok && data.success ? data.upload : null;
+    } catch (error) {
+      return null;
+    }
+  }
+
+  async create() {
+    const response = await fetch('/api/uploads', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ filename: this.file.name, size: this.file.size })
+    });
+    const data = await response.json();
+    if (!response.ok || !data.success) {
+      throw new Error(data.error || `Failed to start the upload of ${this.file.name}`);
+    }
+    this.chunkSize = data.chunk_size || this.chunkSize;
+    localStorage.setItem(this.storageKey, data.upload.id);
+    return data.upload;
+  }
+
+  async sendChunk(upload) {
+    const end = Math.min(upload.offset + this.chunkSize, upload.size);
+    const response = await fetch(`/api/uploads/${upload.id}?offset=${upload.offset}`, {
+      method: 'PUT',
+      headers: { 'Content-Type': 'application/octet-stream' },
+      body: this.file.slice(upload.offset, end)
+    });
+    const data = await response.json();
+    // A stale offset or a cut-off chunk: carry on from what the server has
+    if (data.upload && data.upload.id) {
+      return data.upload;
+    }
+    const error = new Error(data.error || `Upload failed with status ${response.status}`);
+    error.fatal = response.status === 404 || response.status === 413;
+    throw error;
+  }
+
+  /**
+   * Wait before retrying a chunk: until the browser is back online, else
+   * with exponential backoff up to 30 seconds
+   */
+  static waitToRetry(failures) {
+    return new Promise(resolve => {
+      if (!navigator.onLine) {
+        window.addEventListener('online', resolve, { once: true });
+        return;
+      }
+      setTimeout(resolve, Math.min(1000 * 2 ** (failures - 1), 30000));
+    });
+  }
+}
+
+// Export for use in other modules
+if (typeof module !== 'undefined' && module.exports) {
+  module.exports = ChunkedUpload;
+}
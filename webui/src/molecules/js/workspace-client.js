@@ -1,6 +1,8 @@
 /**
  * Workspace Client Molecule - SSE Client Utility
- * Handles connection to /api/subscribe-workspace endpoint
+ * Handles connection to /api/subscribe-workspace endpoint.
+ * Any server event type (canvas_update, macro_progress, widgets_changed...)
+ * can be listened to with on(); reconnects resume from the last event ID.
  */
 
 class WorkspaceClient {
@@ -11,19 +13,44 @@ class WorkspaceClient {
     this.maxReconnectAttempts = 5;
     this.reconnectDelay = 3000;
     this.isConnected = false;
+    this.lastEventId = '';
+    this.topics = [];
+    this.clientId = '';
+    this.workspace = '';
   }
 
   /**
    * Connect to workspace subscription endpoint
    * @param {string} baseURL - Base URL for the API
+   * @param {string[]} [topics] - Event topics (canvas, macro, widgets, ...); all when omitted
    */
-  connect(baseURL) {
+  connect(baseURL, topics) {
     if (this.eventSource) {
       this.disconnect();
     }
+    if (topics) {
+      this.topics = topics;
+    }
 
-    const url = `${baseURL}/api/subscribe-workspace`;
+    const params = new URLSearchParams();
+    if (this.topics.length > 0) {
+      params.set('topics', this.topics.join(','));
+    }
+    // Canvas and connection events of the selected wall and workspace only
+    if (this.clientId) {
+      params.set('client_id', this.clientId);
+    }
+    if (this.workspace !== '') {
+      params.set('workspace', this.workspace);
+    }
+    // EventSource only sends Last-Event-ID on its own reconnects
+    if (this.lastEventId) {
+      params.set('lastEventId', this.lastEventId);
+    }
+    const query = params.toString();
+    const url = `${baseURL}/api/subscribe-workspace${query ? `?${query}` : ''}`;
     this.eventSource = new EventSource(url);
+    this.boundEvents = new Set();
 
     this.eventSource.onopen = () => {
       this.isConnected = true;
@@ -31,17 +58,8 @@ class WorkspaceClient {
       this.emit('connected');
     };
 
-    this.eventSource.addEventListener('canvas_update', (event) => {
-      try {
-        const data = JSON.parse(event.data);
-        this.emit('canvas_update', data);
-      } catch (error) {
-        // Only log in development
-        if (window.location.hostname === 'localhost' || window.location.hostname === '127.0.0.1') {
-          console.error('Error parsing canvas update:', error);
-        }
-      }
-    });
+    this.bindEvent('canvas_update');
+    this.listeners.forEach((_, event) => this.bindEvent(event));
 
     this.eventSource.onerror = (error) => {
       this.isConnected = false;
@@ -53,6 +71,31 @@ class WorkspaceClient {
     };
   }
 
+  /**
+   * Forward a server event type to the listeners registered with on()
+   * @param {string} event - Server event type
+   */
+  bindEvent(event) {
+    if (!this.eventSource || this.boundEvents.has(event) || WorkspaceClient.LOCAL_EVENTS.includes(event)) {
+      return;
+    }
+    this.boundEvents.add(event);
+    this.eventSource.addEventListener(event, (message) => {
+      if (message.lastEventId) {
+        this.lastEventId = message.lastEventId;
+      }
+      try {
+        const data = JSON.parse(message.data);
+        this.emit(event, data);
+      } catch (error) {
+        // Only log in development
+        if (window.location.hostname === 'localhost' || window.location.hostname === '127.0.0.1') {
+          console.error(`Error parsing ${event} event:`, error);
+        }
+      }
+    });
+  }
+
   /**
    * Disconnect from workspace subscription
    */
@@ -93,6 +136,7 @@ class WorkspaceClient {
       this.listeners.set(event, []);
     }
     this.listeners.get(event).push(callback);
+    this.bindEvent(event);
   }
 
   /**
@@ -131,6 +175,9 @@ class WorkspaceClient {
   }
 }
 
+// Events emitted by the client itself rather than sent by the server
+WorkspaceClient.LOCAL_EVENTS = ['connected', 'disconnected', 'error', 'reconnecting', 'max_reconnect_attempts'];
+
 // Export for use in other modules
 if (typeof module !== 'undefined' && module.exports) {
   module.exports = WorkspaceClient;
@@ -0,0 +1,87 @@
+/**
+ * Audit Log Page JavaScript
+ * Lists the recorded mutating WebUI requests with filters
+ */
+
+document.addEventListener('DOMContentLoaded', () => {
+    const filterForm = document.getElementById('auditFilter');
+    const resetButton = document.getElementById('auditReset');
+    const tbody = document.querySelector('#auditTable tbody');
+    const message = document.getElementById('auditMessage');
+
+    // Filter inputs by query parameter
+    const filters = {
+        action: document.getElementById('auditAction'),
+        user: document.getElementById('auditUser'),
+        role: document.getElementById('auditRole'),
+        outcome: document.getElementById('auditOutcome'),
+        remote_ip: document.getElementById('auditRemoteIP'),
+        canvas_id: document.getElementById('auditCanvasID'),
+        since: document.getElementById('auditSince'),
+        until: document.getElementById('auditUntil')
+    };
+
+    function cell(text, className) {
+        const td = document.createElement('td');
+        td.textContent = text;
+        if (className) td.className = className;
+        return td;
+    }
+
+    function showEntries(entries) {
+        tbody.innerHTML = '';
+        entries.forEach(entry => {
+            const row = document.createElement('tr');
+            row.appendChild(cell(new Date(entry.time).toLocaleString()));
+            row.appendChild(cell(`${entry.method} ${entry.action}`));
+            row.appendChild(cell([entry.role, entry.user].filter(Boolean).join(' / ') || '-'));
+            row.appendChild(cell(entry.remote_ip));
+            row.appendChild(cell(entry.canvas_id || '-'));
+            const outcome = entry.error ? `${entry.outcome} (${entry.status}): ${entry.error}` : `${entry.outcome} (${entry.status})`;
+            row.appendChild(cell(outcome, `audit-outcome-${entry.outcome}`));
+            row.appendChild(cell(entry.params ? JSON.stringify(entry.params) : '', 'audit-params'));
+            tbody.appendChild(row);
+        });
+        message.textContent = entries.length === 0 ? 'No entries match the filter.' : `${entries.length} entries`;
+    }
+
+    async function loadEntries() {
+        const query = new URLSearchParams();
+        Object.entries(filters).forEach(([param, input]) => {
+            let value = input.value.trim();
+            if (!value) return;
+            if (input.type === 'datetime-local') {
+                value = new Date(value).toISOString();
+            }
+            query.set(param, value);
+        });
+
+        try {
+            const response = await fetch(`/api/admin/audit?${query}`);
+            const data = await response.json();
+            if (!response.ok || !data.success) {
+                message.textContent = data.error || 'Failed to load the audit log';
+                return;
+            }
+            if (!data.enabled) {
+                message.textContent = 'The audit log is not enabled on this server.';
+                return;
+            }
+            showEntries(data.entries);
+        } catch (error) {
+            message.textContent = `Failed to load the audit log: ${error.message}`;
+        }
+    }
+
+    filterForm.addEventListener('submit', (event) => {
+        event.preventDefault();
+        loadEntries();
+    });
+
+    resetButton.addEventListener('click', () => {
+        filterForm.reset();
+        loadEntries();
+    });
+
+    loadEntries();
+});
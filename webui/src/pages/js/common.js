@@ -19,6 +19,65 @@ if (typeof ErrorHandler !== 'undefined') {
   };
 }
 
+/**
+ * Canvus client (wall) the pages act on: the client_id page parameter, else
+ * the wall picked from the navbar; empty for the server's own client.
+ */
+function selectedClientId() {
+  const fromURL = new URLSearchParams(window.location.search).get('client_id');
+  if (fromURL !== null) {
+    sessionStorage.setItem('clientId', fromURL);
+    return fromURL;
+  }
+  return sessionStorage.getItem('clientId') || '';
+}
+
+/**
+ * Workspace of the wall the pages act on: the workspace page parameter, else
+ * the one picked in the navbar; empty for the wall's first workspace.
+ */
+function selectedWorkspace() {
+  const fromURL = new URLSearchParams(window.location.search).get('workspace');
+  if (fromURL !== null) {
+    sessionStorage.setItem('workspace', fromURL);
+    return fromURL;
+  }
+  return sessionStorage.getItem('workspace') || '';
+}
+
+// Scope every request to this server to the selected wall and workspace, and
+// send the browser to the login page once the session has ended
+const nativeFetch = window.fetch.bind(window);
+window.fetch = (resource, options) => {
+  const selection = { client_id: selectedClientId(), workspace: selectedWorkspace() };
+  if (typeof resource === 'string' && (selection.client_id || selection.workspace)) {
+    const url = new URL(resource, window.location.origin);
+    if (url.origin === window.location.origin) {
+      Object.entries(selection).forEach(([param, value]) => {
+        if (value && !url.searchParams.has(param)) {
+          url.searchParams.set(param, value);
+        }
+      });
+      resource = url.toString();
+    }
+  }
+  return nativeFetch(resource, options).then(response => {
+    const path = new URL(response.url || window.location.href).pathname;
+    if (response.status === 401 && !path.startsWith('/api/auth/')) {
+      redirectToLogin();
+    }
+    return response;
+  });
+};
+
+/**
+ * Open the login page, coming back to this page after logging in
+ */
+function redirectToLogin() {
+  const next = window.location.pathname + window.location.search;
+  window.location.href = `/login.html?next=${encodeURIComponent(next)}`;
+}
+
 document.addEventListener('DOMContentLoaded', () => {
   initMobileMenu();
   initCanvasHeader();
@@ -65,7 +124,7 @@ function initCanvasHeader() {
     navbarClientWarning.style.display = storedClientWarning ? 'inline' : 'none';
   }
 
-  // Make navbar client name clickable for override (double-click shows dropdown)
+  // Make navbar client name clickable to switch walls (double-click shows dropdown)
   if (navbarClientName) {
     navbarClientName.addEventListener('dblclick', async () => {
       // Fetch available clients
@@ -82,7 +141,7 @@ function initCanvasHeader() {
         }
 
         // Create dropdown menu
-        const currentName = navbarClientName.textContent.replace('✓ ', '');
+        const currentId = selectedClientId();
         const dropdown = document.createElement('select');
         dropdown.className = 'client-select-dropdown';
         dropdown.style.cssText = `
@@ -105,15 +164,15 @@ function initCanvasHeader() {
         // Add default option
         const defaultOption = document.createElement('option');
         defaultOption.value = '';
-        defaultOption.textContent = '-- Select Client --';
+        defaultOption.textContent = '-- Select Wall --';
         dropdown.appendChild(defaultOption);
 
         // Add client options
         data.clients.forEach(client => {
           const option = document.createElement('option');
-          option.value = client.name;
-          option.textContent = client.name;
-          if (client.name === currentName) {
+          option.value = client.id;
+          option.textContent = client.name || client.id;
+          if (client.id === currentId) {
             option.selected = true;
           }
           dropdown.appendChild(option);
@@ -140,53 +199,28 @@ function initCanvasHeader() {
         dropdown.focus();
 
         // Handle selection
-        const handleSelection = async () => {
-          const selectedName = dropdown.value;
-          if (!selectedName) {
-            document.body.removeChild(dropdown);
+        const handleSelection = () => {
+          const selectedId = dropdown.value;
+          document.body.removeChild(dropdown);
+          if (!selectedId) {
             return;
           }
 
-          // Remove dropdown
-          document.body.removeChild(dropdown);
-
-          // Send override request
-          try {
-            const overrideResponse = await fetch(`${baseURL}/api/client/override`, {
-              method: 'POST',
-              headers: {
-                'Content-Type': 'application/json',
-              },
-              body: JSON.stringify({ client_name: selectedName })
-            });
-
-            if (!overrideResponse.ok) {
-              const errorText = await overrideResponse.text();
-              throw new Error(`HTTP ${overrideResponse.status}: ${errorText}`);
-            }
-
-            const overrideData = await overrideResponse.json();
-            if (overrideData.success) {
-              // Update navbar client name
-              navbarClientName.textContent = overrideData.client_name || selectedName;
-              sessionStorage.setItem('clientName', overrideData.client_name || selectedName);
-              // Show success message briefly before reload
-              navbarClientName.textContent = '✓ ' + navbarClientName.textContent;
-              setTimeout(() => {
-                // Refresh page to update connection
-                window.location.reload();
-              }, 500);
-            } else {
-              alert('Failed to override client: ' + (overrideData.error || 'Unknown error'));
-            }
-          } catch (error) {
-            errorHandler.logError('Error overriding client', error, 'ClientOverride');
-            let errorMsg = 'Error overriding client';
-            if (error.message) {
-              errorMsg = error.message;
-            }
-            alert(errorMsg);
-          }
+          // Remember the wall for this tab; the server tracks each wall separately
+          const selectedName = dropdown.options[dropdown.selectedIndex].textContent;
+          sessionStorage.setItem('clientId', selectedId);
+          sessionStorage.setItem('clientName', selectedName);
+          sessionStorage.removeItem('canvasName');
+          sessionStorage.removeItem('workspace');
+          navbarClientName.textContent = '✓ ' + selectedName;
+
+          // Reload without a client_id parameter that would pin the old wall
+          const url = new URL(window.location.href);
+          url.searchParams.delete('client_id');
+          url.searchParams.delete('workspace');
+          setTimeout(() => {
+            window.location.href = url.toString();
+          }, 300);
         };
 
         // Handle selection on change
@@ -231,6 +265,7 @@ function initCanvasHeader() {
     .then(response => response.json())
     .then(data => {
       console.log('[common.js] Installation info received:', data);
+      initWorkspaceSelector(data.workspaces || [], data.workspace);
       // Update navbar elements (primary) and legacy canvas-header elements (fallback)
       const navbarClientNameEl = document.getElementById('navbarClientName');
       const navbarCanvasNameEl = document.getElementById('navbarCanvasName');
@@ -320,6 +355,37 @@ function initCanvasHeader() {
     });
 }
 
+/**
+ * Show the workspace selector when the wall has more than one workspace
+ * @param {number[]} workspaces - Workspace indexes of the wall
+ * @param {number} current - Workspace the page acts on
+ */
+function initWorkspaceSelector(workspaces, current) {
+  const selector = document.getElementById('navbarWorkspace');
+  if (!selector || workspaces.length < 2) {
+    return;
+  }
+
+  selector.innerHTML = '';
+  workspaces.forEach(index => {
+    const option = document.createElement('option');
+    option.value = String(index);
+    option.textContent = `Workspace ${index + 1}`;
+    option.selected = index === current;
+    selector.appendChild(option);
+  });
+  selector.style.display = '';
+
+  selector.onchange = () => {
+    // Remember the workspace for this tab and reload without a pinned one
+    sessionStorage.setItem('workspace', selector.value);
+    sessionStorage.removeItem('canvasName');
+    const url = new URL(window.location.href);
+    url.searchParams.delete('workspace');
+    window.location.href = url.toString();
+  };
+}
+
 /**
  * Initialize workspace client
  */
@@ -329,6 +395,8 @@ function initWorkspaceClient() {
   }
 
   const workspaceClient = new WorkspaceClient();
+  workspaceClient.clientId = selectedClientId();
+  workspaceClient.workspace = selectedWorkspace();
   const baseURL = window.location.origin;
 
   // Check if this is a page refresh on the home page
@@ -0,0 +1,132 @@
+/**
+ * Custom Macros Tab JavaScript
+ * Lists the user-defined macros from the macros folder and runs them.
+ * Uses postMacro() from macros.js, so previews and the zone membership
+ * policy work as for the built-in macros.
+ */
+
+document.addEventListener("DOMContentLoaded", () => {
+  console.log("[custom-macros.js] Initializing custom macros tab");
+  fetchCustomMacros();
+});
+
+async function fetchCustomMacros() {
+  const listEl = document.getElementById("customMacroList");
+  if (!listEl) return;
+
+  try {
+    const [macrosRes, zonesRes] = await Promise.all([
+      fetch("/api/macros/definitions"),
+      fetch("/get-zones", { headers: { 'Cache-Control': 'no-cache' } })
+    ]);
+    const data = await macrosRes.json();
+    if (!macrosRes.ok || !data.success) {
+      throw new Error(data.error || `HTTP ${macrosRes.status}`);
+    }
+    const zonesData = await zonesRes.json();
+    renderCustomMacros(data.macros || [], data.errors || [], zonesData.zones || []);
+  } catch (err) {
+    console.error("[custom-macros.js] Failed to list macros:", err);
+    displayCustomMessage(err.message || "Failed to list custom macros", "error");
+  }
+}
+
+function renderCustomMacros(macros, loadErrors, zones) {
+  const listEl = document.getElementById("customMacroList");
+  listEl.innerHTML = "";
+
+  if (macros.length === 0 && loadErrors.length === 0) {
+    listEl.textContent = "No custom macros yet.";
+    return;
+  }
+
+  const sortedZones = [...zones].sort((a, b) =>
+    (a.anchor_name || "").toLowerCase().localeCompare((b.anchor_name || "").toLowerCase(), undefined, { numeric: true }));
+
+  macros.forEach(macro => {
+    const row = document.createElement("div");
+    row.className = "custom-macro";
+
+    const name = document.createElement("strong");
+    name.textContent = macro.name;
+    row.appendChild(name);
+    if (macro.description) {
+      const description = document.createElement("p");
+      description.textContent = macro.description;
+      row.appendChild(description);
+    }
+
+    const selects = {};
+    (macro.params || []).forEach(param => {
+      const group = document.createElement("div");
+      group.className = "form-group";
+      const label = document.createElement("label");
+      label.className = "input-label";
+      label.textContent = `${param.label || param.name}:`;
+      const select = document.createElement("select");
+      select.className = "input select";
+      select.innerHTML = '<option value="">Select a zone...</option>';
+      sortedZones.forEach(zone => {
+        const option = document.createElement("option");
+        option.value = zone.id;
+        option.textContent = zone.anchor_name || `Zone ${zone.id}`;
+        select.appendChild(option);
+      });
+      group.appendChild(label);
+      group.appendChild(select);
+      row.appendChild(group);
+      selects[param.name] = select;
+    });
+
+    const actions = document.createElement("div");
+    actions.className = "form-actions";
+    const runButton = document.createElement("button");
+    runButton.className = "btn btn-primary";
+    runButton.textContent = "Run";
+    runButton.addEventListener("click", () => runCustomMacro(macro, selects));
+    actions.appendChild(runButton);
+    row.appendChild(actions);
+
+    listEl.appendChild(row);
+  });
+
+  loadErrors.forEach(loadError => {
+    const row = document.createElement("div");
+    row.className = "custom-macro custom-macro-error";
+    row.textContent = `${loadError.file}: ${loadError.error}`;
+    listEl.appendChild(row);
+  });
+}
+
+async function runCustomMacro(macro, selects) {
+  const params = {};
+  for (const [name, select] of Object.entries(selects)) {
+    if (!select.value) {
+      displayCustomMessage("Please select a zone for every parameter.", "error");
+      return;
+    }
+    params[name] = select.value;
+  }
+
+  try {
+    const resp = await postMacro("/api/macros/run", { macro: macro.id, params });
+    if (!resp) return;
+    console.log("[custom-macros.js] Run response:", resp);
+    const failed = resp.report?.failed || 0;
+    displayCustomMessage(resp.message || `${macro.name} finished`, failed > 0 ? "error" : "success");
+  } catch (err) {
+    console.error("[custom-macros.js] Run failed:", err);
+    displayCustomMessage(err.message || `Failed to run ${macro.name}`, "error");
+  }
+}
+
+function displayCustomMessage(text, type) {
+  const messageEl = document.getElementById("customMessage");
+  if (!messageEl) {
+    console.log(`[${type}] ${text}`);
+    return;
+  }
+  messageEl.textContent = text;
+  messageEl.className = `message ${type} mt-md`;
+  messageEl.style.display = "block";
+}
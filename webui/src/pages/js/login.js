@@ -0,0 +1,82 @@
+/**
+ * Login Page JavaScript
+ * Logs in with a role PIN or password and returns to the page that asked for it
+ */
+
+document.addEventListener('DOMContentLoaded', () => {
+    const loginSection = document.getElementById('login-section');
+    const sessionSection = document.getElementById('session-section');
+    const loginForm = document.getElementById('loginForm');
+    const secretInput = document.getElementById('secret');
+    const sessionRole = document.getElementById('sessionRole');
+    const message = document.getElementById('loginMessage');
+
+    // Page to return to; only paths on this server
+    const requested = new URLSearchParams(window.location.search).get('next') || '/';
+    const next = requested.startsWith('/') && !requested.startsWith('//') ? requested : '/';
+
+    function showMessage(text, isError) {
+        message.textContent = text;
+        message.className = isError ? 'badge badge-error mt-md' : 'text-muted mt-md';
+    }
+
+    function showSession(session) {
+        if (session.authenticated) {
+            sessionRole.textContent = session.role;
+            loginSection.style.display = 'none';
+            sessionSection.style.display = 'block';
+        } else {
+            loginSection.style.display = 'block';
+            sessionSection.style.display = 'none';
+            secretInput.focus();
+        }
+    }
+
+    // Show the current session, if any
+    fetch('/api/auth/session')
+        .then(response => response.json())
+        .then(session => {
+            if (!session.enabled) {
+                showMessage('Login is not enabled on this server.', false);
+                loginSection.style.display = 'none';
+                return;
+            }
+            showSession(session);
+        })
+        .catch(error => showMessage(`Failed to check session: ${error.message}`, true));
+
+    loginForm.addEventListener('submit', async (event) => {
+        event.preventDefault();
+        showMessage('', false);
+        try {
+            const response = await fetch('/api/auth/login', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ secret: secretInput.value })
+            });
+            const data = await response.json();
+            secretInput.value = '';
+            if (!response.ok || !data.success) {
+                showMessage(data.error || 'Login failed', true);
+                return;
+            }
+            window.location.href = next;
+        } catch (error) {
+            showMessage(`Login failed: ${error.message}`, true);
+        }
+    });
+
+    document.getElementById('continueButton').addEventListener('click', () => {
+        window.location.href = next;
+    });
+
+    document.getElementById('logoutButton').addEventListener('click', async () => {
+        try {
+            await fetch('/api/auth/logout', { method: 'POST' });
+            showSession({ authenticated: false });
+            showMessage('Logged out.', false);
+        } catch (error) {
+            showMessage(`Logout failed: ${error.message}`, true);
+        }
+    });
+});
@@ -1,6 +1,6 @@
 /**
  * Macros Page JavaScript
- * Handles widget management: move, copy, grouping, and pinning
+ * Handles widget management: move, copy, zone sync, grouping, and pinning
  */
 
 document.addEventListener("DOMContentLoaded", () => {
@@ -14,7 +14,7 @@ document.addEventListener("DOMContentLoaded", () => {
   console.log("[macros.js] Fetching zones");
   fetchZones();
 
-  // 3) Bind button clicks for Manage (Move/Copy)
+  // 3) Bind button clicks for Manage (Move/Copy/Compare/Sync)
   const moveButton = document.getElementById("moveButton");
   const copyButton = document.getElementById("copyButton");
 
@@ -37,6 +37,22 @@ document.addEventListener("DOMContentLoaded", () => {
     console.error("[macros.js] ERROR: copyButton not found!");
   }
 
+  const compareButton = document.getElementById("compareButton");
+  if (compareButton) {
+    compareButton.addEventListener("click", () => {
+      console.log("[macros.js] Compare button clicked");
+      manageCompare();
+    });
+  }
+
+  const syncButton = document.getElementById("syncButton");
+  if (syncButton) {
+    syncButton.addEventListener("click", () => {
+      console.log("[macros.js] Sync button clicked");
+      manageSync();
+    });
+  }
+
   // 4) Bind Grouping logic
   const autoGridButton = document.getElementById("autoGridButton");
   const groupColorButton = document.getElementById("groupColorButton");
@@ -97,7 +113,23 @@ document.addEventListener("DOMContentLoaded", () => {
     console.error("[macros.js] ERROR: unpinAllButton not found!");
   }
 
-  // 6) Color tolerance slider
+  // 6) Bind Undo/Redo
+  const undoButton = document.getElementById("undoButton");
+  const redoButton = document.getElementById("redoButton");
+  if (undoButton) {
+    undoButton.addEventListener("click", () => {
+      console.log("[macros.js] Undo button clicked");
+      undoMacro();
+    });
+  }
+  if (redoButton) {
+    redoButton.addEventListener("click", () => {
+      console.log("[macros.js] Redo button clicked");
+      redoMacro();
+    });
+  }
+
+  // 7) Color tolerance slider
   console.log("[macros.js] Setting up color tolerance slider");
   setupColorToleranceSlider();
 
@@ -210,9 +242,10 @@ async function manageMove() {
   try {
     const payload = { sourceZoneId, targetZoneId };
     console.log("[macros.js] Sending POST /api/macros/move with payload:", payload);
-    const resp = await postJson("/api/macros/move", payload);
+    const resp = await postMacro("/api/macros/move", payload);
+    if (!resp) return;
     console.log("[macros.js] Move response:", resp);
-    displayMessage(resp.message || "Widgets moved successfully", "success");
+    displayBatchResult(resp, "Widgets moved successfully");
   } catch (err) {
     console.error("[macros.js] Move failed:", err);
     displayMessage(err.message || "Failed to move widgets", "error");
@@ -234,15 +267,94 @@ async function manageCopy() {
   try {
     const payload = { sourceZoneId, targetZoneId };
     console.log("[macros.js] Sending POST /api/macros/copy with payload:", payload);
-    const resp = await postJson("/api/macros/copy", payload);
+    const resp = await postMacro("/api/macros/copy", payload);
+    if (!resp) return;
     console.log("[macros.js] Copy response:", resp);
-    displayMessage(resp.message || "Widgets copied successfully", "success");
+    displayBatchResult(resp, "Widgets copied successfully");
   } catch (err) {
     console.error("[macros.js] Copy failed:", err);
     displayMessage(err.message || "Failed to copy widgets", "error");
   }
 }
 
+// Compares the target zone with the source zone and lists the differences.
+async function manageCompare() {
+  const sourceZoneId = document.getElementById("manageSourceZone")?.value;
+  const targetZoneId = document.getElementById("manageTargetZone")?.value;
+  if (!sourceZoneId || !targetZoneId) {
+    displayMessage("Please select both Source and Target zones.", "error");
+    return;
+  }
+  try {
+    const membershipSelect = document.getElementById("membershipSelect");
+    const query = membershipSelect && membershipSelect.value
+      ? `?membership=${encodeURIComponent(membershipSelect.value)}` : "";
+    const resp = await postJson(`/api/macros/zone-diff${query}`, { sourceZoneId, targetZoneId });
+    console.log("[macros.js] Compare response:", resp);
+    displayZoneDiff(resp);
+  } catch (err) {
+    console.error("[macros.js] Compare failed:", err);
+    displayMessage(err.message || "Failed to compare zones", "error");
+  }
+}
+
+// Lists the differences below the message, which stays visible.
+function displayZoneDiff(resp) {
+  const messageEl = document.getElementById("manageMessage");
+  if (!messageEl) {
+    console.log("[macros.js] Zone diff:", resp.diff);
+    return;
+  }
+
+  const diff = resp.diff || {};
+  const sections = [
+    ["Missing from target", diff.added || []],
+    ["Only in target", diff.removed || []],
+    ["Changed", diff.changed || []]
+  ];
+  messageEl.textContent = resp.message;
+  const list = document.createElement("ul");
+  list.className = "batch-failures";
+  sections.forEach(([label, widgets]) => {
+    widgets.forEach(w => {
+      const item = document.createElement("li");
+      const id = w.source_id || w.target_id;
+      const fields = (w.fields || []).length > 0 ? `: ${w.fields.join(", ")}` : "";
+      item.textContent = `${label}: ${w.widget_type} ${w.title || id.substring(0, 8)}${fields}`;
+      list.appendChild(item);
+    });
+  });
+  (diff.skipped || []).forEach(s => {
+    const item = document.createElement("li");
+    item.textContent = `Skipped ${s.widget_type} ${s.widget_id.substring(0, 8)}: ${s.reason}`;
+    list.appendChild(item);
+  });
+  if (list.children.length > 0) {
+    messageEl.appendChild(list);
+  }
+  messageEl.className = "message info mt-md";
+  messageEl.style.display = "block";
+}
+
+// Makes the target zone match the source zone.
+async function manageSync() {
+  const sourceZoneId = document.getElementById("manageSourceZone")?.value;
+  const targetZoneId = document.getElementById("manageTargetZone")?.value;
+  if (!sourceZoneId || !targetZoneId) {
+    displayMessage("Please select both Source and Target zones.", "error");
+    return;
+  }
+  try {
+    const resp = await postMacro("/api/macros/zone-sync", { sourceZoneId, targetZoneId });
+    if (!resp) return;
+    console.log("[macros.js] Sync response:", resp);
+    displayBatchResult(resp, "Zones synced successfully");
+  } catch (err) {
+    console.error("[macros.js] Sync failed:", err);
+    displayMessage(err.message || "Failed to sync zones", "error");
+  }
+}
+
 /* ------------------------------ ARRANGE ACTIONS ------------------------------ */
 async function autoGrid() {
   console.log("[macros.js] autoGrid() called");
@@ -258,9 +370,10 @@ async function autoGrid() {
   try {
     const payload = { zoneId: sourceZoneId };
     console.log("[macros.js] Sending POST /api/macros/auto-grid with payload:", payload);
-    const resp = await postJson("/api/macros/auto-grid", payload);
+    const resp = await postMacro("/api/macros/auto-grid", payload);
+    if (!resp) return;
     console.log("[macros.js] Auto grid response:", resp);
-    displayMessage(resp.message || "Auto grid applied successfully", "success");
+    displayBatchResult(resp, "Auto grid applied successfully");
   } catch (err) {
     console.error("[macros.js] Auto grid failed:", err);
     displayMessage(err.message || "Failed to apply auto grid", "error");
@@ -282,9 +395,10 @@ async function groupByColor() {
   try {
     const payload = { zoneId: sourceZoneId, colorTolerance: parseInt(colorTolerance) };
     console.log("[macros.js] Sending POST /api/macros/group-color with payload:", payload);
-    const resp = await postJson("/api/macros/group-color", payload);
+    const resp = await postMacro("/api/macros/group-color", payload);
+    if (!resp) return;
     console.log("[macros.js] Group by color response:", resp);
-    displayMessage(resp.message || "Grouped by color successfully", "success");
+    displayBatchResult(resp, "Grouped by color successfully");
   } catch (err) {
     console.error("[macros.js] Group by color failed:", err);
     displayMessage(err.message || "Failed to group by color", "error");
@@ -305,9 +419,10 @@ async function groupByTitle() {
   try {
     const payload = { zoneId: sourceZoneId };
     console.log("[macros.js] Sending POST /api/macros/group-title with payload:", payload);
-    const resp = await postJson("/api/macros/group-title", payload);
+    const resp = await postMacro("/api/macros/group-title", payload);
+    if (!resp) return;
     console.log("[macros.js] Group by title response:", resp);
-    displayMessage(resp.message || "Grouped by title successfully", "success");
+    displayBatchResult(resp, "Grouped by title successfully");
   } catch (err) {
     console.error("[macros.js] Group by title failed:", err);
     displayMessage(err.message || "Failed to group by title", "error");
@@ -329,9 +444,10 @@ async function pinAll() {
   try {
     const payload = { zoneId: sourceZoneId };
     console.log("[macros.js] Sending POST /api/macros/pin-all with payload:", payload);
-    const resp = await postJson("/api/macros/pin-all", payload);
+    const resp = await postMacro("/api/macros/pin-all", payload);
+    if (!resp) return;
     console.log("[macros.js] Pin all response:", resp);
-    displayMessage(resp.message || "All widgets pinned successfully", "success");
+    displayBatchResult(resp, "All widgets pinned successfully");
   } catch (err) {
     console.error("[macros.js] Pin all failed:", err);
     displayMessage(err.message || "Failed to pin widgets", "error");
@@ -352,15 +468,205 @@ async function unpinAll() {
   try {
     const payload = { zoneId: sourceZoneId };
     console.log("[macros.js] Sending POST /api/macros/unpin-all with payload:", payload);
-    const resp = await postJson("/api/macros/unpin-all", payload);
+    const resp = await postMacro("/api/macros/unpin-all", payload);
+    if (!resp) return;
     console.log("[macros.js] Unpin all response:", resp);
-    displayMessage(resp.message || "All widgets unpinned successfully", "success");
+    displayBatchResult(resp, "All widgets unpinned successfully");
   } catch (err) {
     console.error("[macros.js] Unpin all failed:", err);
     displayMessage(err.message || "Failed to unpin widgets", "error");
   }
 }
 
+/* ------------------------------ PREVIEW (DRY RUN) ------------------------------ */
+// Posts a macro. When "Preview before applying" is checked the macro is first
+// run with ?dry_run=1 and only applied once the operator confirms the preview.
+// The selected zone membership policy is sent as ?membership=.
+// Returns null if the operator cancels.
+async function postMacro(url, payload) {
+  const membershipSelect = document.getElementById("membershipSelect");
+  const params = new URLSearchParams();
+  if (membershipSelect && membershipSelect.value) {
+    params.set("membership", membershipSelect.value);
+  }
+  const query = params.toString();
+  const applyUrl = query ? `${url}?${query}` : url;
+
+  const previewToggle = document.getElementById("previewToggle");
+  if (!previewToggle || !previewToggle.checked) {
+    return postJson(applyUrl, payload);
+  }
+
+  params.set("dry_run", "1");
+  const preview = await postJson(`${url}?${params.toString()}`, payload);
+  console.log("[macros.js] Dry run response:", preview);
+  const confirmed = await showPlanPreview(preview.plan);
+  if (!confirmed) {
+    displayMessage("Macro cancelled", "info");
+    return null;
+  }
+  return postJson(applyUrl, payload);
+}
+
+// Renders the plan over a scaled drawing of the zone and resolves to true
+// when the operator confirms.
+function showPlanPreview(plan) {
+  return new Promise(resolve => {
+    const changes = (plan.changes || []).concat(plan.creations || []);
+    const deletions = plan.deletions || [];
+    const overlay = document.createElement("div");
+    overlay.className = "plan-preview-overlay";
+
+    const dialog = document.createElement("div");
+    dialog.className = "plan-preview card";
+    overlay.appendChild(dialog);
+
+    const title = document.createElement("h2");
+    title.className = "card-title";
+    title.textContent = `Preview: ${plan.macro}`;
+    dialog.appendChild(title);
+
+    const summary = document.createElement("p");
+    summary.textContent = `${(plan.changes || []).length} widgets will change, ${(plan.creations || []).length} will be created, ${deletions.length} will be deleted (zone membership: ${plan.membership}).`;
+    dialog.appendChild(summary);
+
+    if ((plan.skipped || []).length > 0) {
+      const skippedList = document.createElement("ul");
+      skippedList.className = "batch-failures";
+      plan.skipped.forEach(s => {
+        const item = document.createElement("li");
+        item.textContent = `Skipped ${s.widget_type} ${s.widget_id.substring(0, 8)}: ${s.reason}`;
+        skippedList.appendChild(item);
+      });
+      dialog.appendChild(skippedList);
+    }
+
+    if (plan.zone && changes.length + deletions.length > 0) {
+      dialog.appendChild(renderPlanZone(plan, changes, deletions));
+    }
+
+    const actions = document.createElement("div");
+    actions.className = "form-actions";
+    const confirmButton = document.createElement("button");
+    confirmButton.className = "btn btn-primary";
+    confirmButton.textContent = "Apply";
+    confirmButton.disabled = changes.length + deletions.length === 0;
+    const cancelButton = document.createElement("button");
+    cancelButton.className = "btn btn-secondary";
+    cancelButton.textContent = "Cancel";
+    actions.appendChild(confirmButton);
+    actions.appendChild(cancelButton);
+    dialog.appendChild(actions);
+
+    const close = result => {
+      overlay.remove();
+      resolve(result);
+    };
+    confirmButton.addEventListener("click", () => close(true));
+    cancelButton.addEventListener("click", () => close(false));
+    overlay.addEventListener("click", e => {
+      if (e.target === overlay) close(false);
+    });
+
+    document.body.appendChild(overlay);
+  });
+}
+
+// Draws the target zone (and the source zone for move/copy/sync) with a box
+// per widget at its planned position. Widgets that start elsewhere get a
+// faded box at their current position, and deleted widgets a red one.
+function renderPlanZone(plan, changes, deletions = []) {
+  const zones = [plan.zone];
+  if (plan.source_zone) zones.push(plan.source_zone);
+
+  const minX = Math.min(...zones.map(z => z.x));
+  const minY = Math.min(...zones.map(z => z.y));
+  const maxX = Math.max(...zones.map(z => z.x + z.width));
+  const maxY = Math.max(...zones.map(z => z.y + z.height));
+  const width = 560;
+  const scale = width / (maxX - minX || 1);
+
+  const view = document.createElement("div");
+  view.className = "plan-preview-zone";
+  view.style.width = `${width}px`;
+  view.style.height = `${(maxY - minY) * scale}px`;
+
+  const place = (el, x, y, w, h) => {
+    el.style.left = `${(x - minX) * scale}px`;
+    el.style.top = `${(y - minY) * scale}px`;
+    el.style.width = `${Math.max(w * scale, 4)}px`;
+    el.style.height = `${Math.max(h * scale, 4)}px`;
+  };
+
+  zones.forEach((zone, i) => {
+    const el = document.createElement("div");
+    el.className = i === 0 ? "plan-zone plan-zone-target" : "plan-zone plan-zone-source";
+    place(el, zone.x, zone.y, zone.width, zone.height);
+    view.appendChild(el);
+  });
+
+  const boxSize = p => {
+    const s = p.scale || 1;
+    return p.size ? [p.size.width * s, p.size.height * s] : [100, 100];
+  };
+
+  changes.forEach(change => {
+    const { before, after } = change;
+    if (before.location && (!after.location ||
+        before.location.x !== after.location.x || before.location.y !== after.location.y)) {
+      const ghost = document.createElement("div");
+      ghost.className = "plan-widget plan-widget-before";
+      const [w, h] = boxSize(before);
+      place(ghost, before.location.x, before.location.y, w, h);
+      view.appendChild(ghost);
+    }
+    if (after.location) {
+      const box = document.createElement("div");
+      box.className = after.pinned ? "plan-widget plan-widget-pinned" : "plan-widget";
+      box.title = `${change.widget_type} ${change.title || change.widget_id.substring(0, 8)}`;
+      const [w, h] = boxSize(after);
+      place(box, after.location.x, after.location.y, w, h);
+      view.appendChild(box);
+    }
+  });
+
+  deletions.forEach(deletion => {
+    const { before } = deletion;
+    if (!before.location) return;
+    const box = document.createElement("div");
+    box.className = "plan-widget plan-widget-deleted";
+    box.title = `Delete ${deletion.widget_type} ${deletion.title || deletion.widget_id.substring(0, 8)}`;
+    const [w, h] = boxSize(before);
+    place(box, before.location.x, before.location.y, w, h);
+    view.appendChild(box);
+  });
+
+  return view;
+}
+
+/* ------------------------------ UNDO / REDO ------------------------------ */
+async function undoMacro() {
+  try {
+    const resp = await postJson("/api/macros/undo", {});
+    console.log("[macros.js] Undo response:", resp);
+    displayBatchResult(resp, "Last macro undone");
+  } catch (err) {
+    console.error("[macros.js] Undo failed:", err);
+    displayMessage(err.message || "Failed to undo", "error");
+  }
+}
+
+async function redoMacro() {
+  try {
+    const resp = await postJson("/api/macros/redo", {});
+    console.log("[macros.js] Redo response:", resp);
+    displayBatchResult(resp, "Macro redone");
+  } catch (err) {
+    console.error("[macros.js] Redo failed:", err);
+    displayMessage(err.message || "Failed to redo", "error");
+  }
+}
+
 /* ------------------------------ COLOR TOLERANCE SLIDER ------------------------------ */
 function setupColorToleranceSlider() {
   const slider = document.getElementById("colorToleranceSlider");
@@ -421,3 +727,41 @@ function displayMessage(text, type) {
     console.log(`[${type}] ${text}`);
   }
 }
+
+// Shows the result of a batch macro. When the server reports failed or
+// skipped widgets they are listed below the message and the message stays
+// visible.
+function displayBatchResult(resp, fallbackText) {
+  const failures = (resp.report?.results || []).filter(r => !r.succeeded);
+  const skipped = resp.report?.skipped || [];
+  if (failures.length === 0 && skipped.length === 0) {
+    displayMessage(resp.message || fallbackText, "success");
+    return;
+  }
+
+  const messageEl = document.getElementById("manageMessage") ||
+                    document.getElementById("arrangeMessage") ||
+                    document.getElementById("pinMessage");
+  if (!messageEl) {
+    console.warn("[macros.js] Failed widgets:", failures, "Skipped widgets:", skipped);
+    return;
+  }
+
+  messageEl.textContent = resp.message || `${failures.length} widgets failed`;
+  const list = document.createElement("ul");
+  list.className = "batch-failures";
+  failures.forEach(f => {
+    const item = document.createElement("li");
+    const attempts = f.attempts > 1 ? ` after ${f.attempts} attempts` : "";
+    item.textContent = `${f.widget_type} ${f.widget_id.substring(0, 8)}${attempts}: ${f.error}`;
+    list.appendChild(item);
+  });
+  skipped.forEach(s => {
+    const item = document.createElement("li");
+    item.textContent = `Skipped ${s.widget_type} ${s.widget_id.substring(0, 8)}: ${s.reason}`;
+    list.appendChild(item);
+  });
+  messageEl.appendChild(list);
+  messageEl.className = failures.length > 0 ? "message error mt-md" : "message info mt-md";
+  messageEl.style.display = "block";
+}
@@ -11,9 +11,23 @@ function isColorDark(hexColor) {
     return (r * 0.299 + g * 0.587 + b * 0.114) / 255 < 0.5;
 }
 
+// Wall and workspace this page posts to, from the client_id and workspace
+// of the page URL (empty: the server's own client and its first workspace)
+const pageParams = new URLSearchParams(window.location.search);
+const rcuSelection = new URLSearchParams();
+['client_id', 'workspace'].forEach(param => {
+    if (pageParams.get(param)) rcuSelection.set(param, pageParams.get(param));
+});
+
+// Append the page's wall and workspace to a server path
+function withClient(path) {
+    const query = rcuSelection.toString();
+    if (!query) return path;
+    return `${path}${path.includes('?') ? '&' : '?'}${query}`;
+}
+
 document.addEventListener('DOMContentLoaded', () => {
     // User Identification Elements
-    const teamButtons = document.querySelectorAll('.team-button');
     const userNameInput = document.getElementById('username');
     const userIdentification = document.getElementById('user-identification');
     const identificationMessage = document.getElementById('message');
@@ -32,39 +46,39 @@ document.addEventListener('DOMContentLoaded', () => {
 
     // Variables to store user info
     let selectedTeam = null;
+    let selectedTeamName = null;
     let userName = null;
     let userColor = null;
 
-    // Team colors (ROYGBIV) - exact RGB values as specified
-    const teamColors = {
-        1: "rgb(255, 0, 0)",      // Red
-        2: "rgb(255, 127, 0)",    // Orange
-        3: "rgb(255, 255, 0)",    // Yellow
-        4: "rgb(0, 255, 0)",      // Green
-        5: "rgb(0, 0, 255)",      // Blue
-        6: "rgb(75, 0, 130)",     // Indigo
-        7: "rgb(139, 0, 255)"     // Violet
-    };
-
-    // Text colors for each team (matching the provided style)
-    const teamTextColors = {
-        1: "rgb(255, 255, 255)",  // White for Red
-        2: "rgb(0, 0, 0)",         // Black for Orange
-        3: "rgb(0, 0, 0)",         // Black for Yellow
-        4: "rgb(0, 0, 0)",         // Black for Green
-        5: "rgb(255, 255, 255)",  // White for Blue
-        6: "rgb(255, 255, 255)",  // White for Indigo
-        7: "rgb(255, 255, 255)"    // White for Violet
-    };
-
-    // Initialize team button colors - match exact style provided
-    teamButtons.forEach(button => {
-        const teamNumber = parseInt(button.dataset.team);
-        if (teamNumber && teamColors[teamNumber]) {
-            button.style.backgroundColor = teamColors[teamNumber];
-            button.style.color = teamTextColors[teamNumber];
+    // Team buttons, from the teams of the RCU session
+    let teamButtons = [];
+    async function loadTeams() {
+        const container = document.getElementById('teamButtons');
+        if (!container) return;
+        try {
+            const response = await fetch('/rcu-teams');
+            const data = await response.json();
+            if (!response.ok || !data.success) {
+                throw new Error(data.error || `HTTP ${response.status}`);
+            }
+            container.innerHTML = '';
+            data.teams.forEach(team => {
+                const button = document.createElement('button');
+                const color = team.color.slice(0, 7);
+                button.className = 'btn btn-secondary team-button';
+                button.dataset.team = team.id; // requests refer to teams by ID
+                button.textContent = team.name;
+                button.style.backgroundColor = color;
+                button.style.color = isColorDark(color) ? '#FFFFFF' : '#000000';
+                button.addEventListener('click', () => identify(button));
+                container.appendChild(button);
+            });
+            teamButtons = Array.from(container.querySelectorAll('.team-button'));
+        } catch (err) {
+            console.error('Failed to load teams:', err);
+            updateIdentificationMessage('Could not load the teams. Please reload the page.', 'error');
         }
-    });
+    }
 
     // Generate QR code - use actual server IP address (NOT localhost)
     async function generateQRCode() {
@@ -102,6 +116,9 @@ document.addEventListener('DOMContentLoaded', () => {
             return;
         }
 
+        // Phones scanning the code post to the same wall
+        qrUrl = withClient(qrUrl);
+
         // Clear existing QR code
         qrCodeDiv.innerHTML = '';
 
@@ -141,78 +158,80 @@ document.addEventListener('DOMContentLoaded', () => {
     }
 
     // Team selection and immediate identification
-    teamButtons.forEach(button => {
-        button.addEventListener('click', async () => {
-            const name = userNameInput.value.trim();
-            if (!name) {
-                updateIdentificationMessage("Please enter your name first.", "error");
-                return;
-            }
+    async function identify(button) {
+        const name = userNameInput.value.trim();
+        if (!name) {
+            updateIdentificationMessage("Please enter your name first.", "error");
+            return;
+        }
 
-            teamButtons.forEach(btn => btn.classList.remove('active'));
-            button.classList.add('active');
-            selectedTeam = parseInt(button.dataset.team);
-            userName = name;
+        teamButtons.forEach(btn => btn.classList.remove('active'));
+        button.classList.add('active');
+        selectedTeam = parseInt(button.dataset.team);
+        selectedTeamName = button.textContent;
+        userName = name;
 
-            try {
-                // Identify user - this endpoint should exist
-                const response = await fetch('/identify-user', {
-                    method: 'POST',
-                    headers: {
-                        'Content-Type': 'application/json'
-                    },
-                    body: JSON.stringify({ team: selectedTeam, name: userName })
-                });
-
-                const data = await response.json();
-                if (response.ok && data.success) {
-                    userColor = data.color;
+        try {
+            // Identify user - this endpoint should exist
+            const response = await fetch(withClient('/identify-user'), {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: JSON.stringify({ team: selectedTeam, name: userName })
+            });
 
-                    // Get canvas name from API
-                    try {
-                        const canvasResponse = await fetch('/api/canvas/info');
-                        const canvasData = await canvasResponse.json();
-                        const canvasName = canvasData.canvas_name || "Unknown Canvas";
-
-                        // Update visibility of sections
-                        if (userIdentification) userIdentification.style.display = "none";
-                        if (userDashboard) {
-                            userDashboard.style.display = "block";
-                            // Update welcome message and note square
-                            if (welcomeMessage) {
-                                welcomeMessage.textContent = `Welcome, ${userName}, you are currently posting to Team ${selectedTeam} on ${canvasName}.`;
-                            }
-                            if (noteSquare) {
-                                noteSquare.style.backgroundColor = userColor;
-                                noteSquare.style.color = isColorDark(userColor) ? '#FFFFFF' : '#000000';
-                            }
+            const data = await response.json();
+            if (response.ok && data.success) {
+                userColor = data.color;
+                selectedTeamName = data.team_name || selectedTeamName;
+
+                // Get canvas name from API
+                try {
+                    const canvasResponse = await fetch(withClient('/api/canvas/info'));
+                    const canvasData = await canvasResponse.json();
+                    const canvasName = canvasData.canvas_name || "Unknown Canvas";
+
+                    // Update visibility of sections
+                    if (userIdentification) userIdentification.style.display = "none";
+                    if (userDashboard) {
+                        userDashboard.style.display = "block";
+                        // Update welcome message and note square
+                        if (welcomeMessage) {
+                            welcomeMessage.textContent = `Welcome, ${userName}, you are currently posting to ${selectedTeamName} on ${canvasName}.`;
                         }
-                    } catch (err) {
-                        console.error('Error fetching canvas info:', err);
-                        // Continue anyway with default canvas name
-                        if (userIdentification) userIdentification.style.display = "none";
-                        if (userDashboard) {
-                            userDashboard.style.display = "block";
-                            if (welcomeMessage) {
-                                welcomeMessage.textContent = `Welcome, ${userName}, you are currently posting to Team ${selectedTeam}.`;
-                            }
-                            if (noteSquare) {
-                                noteSquare.style.backgroundColor = userColor;
-                                noteSquare.style.color = isColorDark(userColor) ? '#FFFFFF' : '#000000';
-                            }
+                        if (noteSquare) {
+                            noteSquare.style.backgroundColor = userColor;
+                            noteSquare.style.color = isColorDark(userColor) ? '#FFFFFF' : '#000000';
+                        }
+                    }
+                } catch (err) {
+                    console.error('Error fetching canvas info:', err);
+                    // Continue anyway with default canvas name
+                    if (userIdentification) userIdentification.style.display = "none";
+                    if (userDashboard) {
+                        userDashboard.style.display = "block";
+                        if (welcomeMessage) {
+                            welcomeMessage.textContent = `Welcome, ${userName}, you are currently posting to ${selectedTeamName}.`;
+                        }
+                        if (noteSquare) {
+                            noteSquare.style.backgroundColor = userColor;
+                            noteSquare.style.color = isColorDark(userColor) ? '#FFFFFF' : '#000000';
                         }
                     }
-
-                    updateIdentificationMessage("Identification successful!", "success");
-                } else {
-                    updateIdentificationMessage(data.error || "Identification failed.", "error");
                 }
-            } catch (error) {
-                console.error("Error identifying user:", error);
-                updateIdentificationMessage("An error occurred during identification.", "error");
+
+                updateIdentificationMessage("Identification successful!", "success");
+            } else {
+                updateIdentificationMessage(data.error || "Identification failed.", "error");
             }
-        });
-    });
+        } catch (error) {
+            console.error("Error identifying user:", error);
+            updateIdentificationMessage("An error occurred during identification.", "error");
+        }
+    }
+
+    loadTeams();
 
     // Handle post note button
     if (postNoteButton) {
@@ -231,7 +250,7 @@ document.addEventListener('DOMContentLoaded', () => {
             }
 
             try {
-                const response = await fetch('/create-note', {
+                const response = await fetch(withClient('/create-note'), {
                     method: 'POST',
                     headers: {
                         'Content-Type': 'application/json'
@@ -268,19 +287,26 @@ document.addEventListener('DOMContentLoaded', () => {
 
             const fileInput = document.createElement('input');
             fileInput.type = 'file';
-            fileInput.accept = '.jpg,.jpeg,.png,.gif,.bmp,.tiff,.mp4,.avi,.mov,.wmv,.pdf,.mkv';
+            fileInput.accept = '.jpg,.jpeg,.png,.gif,.bmp,.tiff,.webp,.mp4,.avi,.mov,.wmv,.pdf,.mkv,.webm,.txt,.md,.url,.webloc';
 
             fileInput.onchange = async () => {
                 const file = fileInput.files[0];
                 if (file) {
-                    const formData = new FormData();
-                    formData.append('team', selectedTeam);
-                    formData.append('name', userName);
-                    formData.append('file', file);
-
                     try {
-                        updateIdentificationMessage("Uploading file...", "loading");
-                        const response = await fetch('/upload-item', {
+                        // Sent in chunks: a dropped connection resumes where it stopped
+                        const upload = await new ChunkedUpload(file, {
+                            onProgress: (sent, total) => {
+                                updateIdentificationMessage(`Uploading file... ${Math.floor((sent / total) * 100)}%`, "loading");
+                            }
+                        }).start();
+
+                        const formData = new FormData();
+                        formData.append('team', selectedTeam);
+                        formData.append('name', userName);
+                        formData.append('staged_id', upload.id);
+
+                        updateIdentificationMessage("Placing file on the canvas...", "loading");
+                        const response = await fetch(withClient('/upload-item'), {
                             method: 'POST',
                             body: formData
                         });
@@ -293,7 +319,7 @@ document.addEventListener('DOMContentLoaded', () => {
                         }
                     } catch (error) {
                         console.error("Error uploading file:", error);
-                        updateIdentificationMessage("An error occurred while uploading the file.", "error");
+                        updateIdentificationMessage(error.message || "An error occurred while uploading the file.", "error");
                     }
                 }
             };
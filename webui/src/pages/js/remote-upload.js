@@ -1,61 +1,413 @@
 /**
  * RCU Admin Page JavaScript
- * Handles team target creation, test team notes, and user management
+ * Handles file uploads to the canvas, upload history, the RCU teams, team
+ * target creation, test team notes, and user management
  */
 
 document.addEventListener('DOMContentLoaded', () => {
-  initTeamButtonStyles();
+  initUpload();
+  initUploadHistory();
+  initRCUSession();
   initCreateTargets();
-  initTestTeamButtons();
   initUserManagement();
 });
 
 /**
- * Initialize team button styles (ROYGBIV colors) - FIXED SIZE
+ * Initialize file upload: sends the files in resumable chunks, then follows
+ * the server's upload events while it places them on the canvas
  */
-function initTeamButtonStyles() {
-  const teamButtons = document.querySelectorAll('[data-test-team]');
-
-  // Team colors (ROYGBIV) - exact RGB values as specified
-  const teamColors = {
-    1: "rgb(255, 0, 0)",      // Red
-    2: "rgb(255, 127, 0)",    // Orange
-    3: "rgb(255, 255, 0)",    // Yellow
-    4: "rgb(0, 255, 0)",      // Green
-    5: "rgb(0, 0, 255)",      // Blue
-    6: "rgb(75, 0, 130)",     // Indigo
-    7: "rgb(139, 0, 255)"     // Violet
+function initUpload() {
+  const form = document.getElementById('uploadForm');
+  const filesInput = document.getElementById('uploadFiles');
+  const placement = document.getElementById('uploadPlacement');
+  const zoneGroup = document.getElementById('uploadZoneGroup');
+  const zoneSelect = document.getElementById('uploadZone');
+  const uploadBtn = document.getElementById('uploadBtn');
+  const progress = document.getElementById('uploadProgress');
+  const progressFill = document.getElementById('uploadProgressFill');
+  const progressText = document.getElementById('uploadProgressText');
+  const fileList = document.getElementById('uploadFileList');
+  const uploadMessage = document.getElementById('uploadMessage');
+  if (!form) return;
+
+  let currentUploadId = '';
+  const fileItems = new Map();
+
+  function setProgress(percent, text) {
+    progressFill.style.width = `${percent}%`;
+    progressText.textContent = text;
+  }
+
+  function setFileStatus(name, status, className) {
+    const item = fileItems.get(name);
+    if (!item) return;
+    item.textContent = `${name}: ${status}`;
+    item.className = className || '';
+  }
+
+  // Placement events of the server, for the upload started by this page
+  const events = new WorkspaceClient();
+  events.clientId = selectedClientId();
+  events.workspace = selectedWorkspace();
+  events.on('upload_progress', (data) => {
+    if (data.upload_id !== currentUploadId) return;
+    const done = data.phase === 'uploading' ? data.index - 1 : data.index;
+    setProgress(Math.round((done / data.total) * 100), `Placing ${data.index} of ${data.total} on the canvas...`);
+    if (data.phase === 'uploaded') {
+      setFileStatus(data.file, 'placed', 'upload-file-success');
+    } else if (data.phase === 'failed') {
+      setFileStatus(data.file, `failed: ${data.error}`, 'upload-file-failure');
+    } else {
+      setFileStatus(data.file, 'placing...');
+    }
+  });
+  events.on('upload_finished', (data) => {
+    if (data.upload_id !== currentUploadId) return;
+    setProgress(100, `${data.total - data.failed} of ${data.total} files placed`);
+    loadUploadHistory(0);
+  });
+  events.connect(window.location.origin, ['upload']);
+
+  async function loadZones() {
+    try {
+      const response = await fetch('/get-zones');
+      const data = await response.json();
+      zoneSelect.innerHTML = '';
+      (data.zones || []).forEach(zone => {
+        if (!zone.id) return;
+        const option = document.createElement('option');
+        option.value = zone.id;
+        option.textContent = zone.anchor_name || zone.id;
+        zoneSelect.appendChild(option);
+      });
+      if (zoneSelect.options.length === 0) {
+        displayMessage(uploadMessage, data.error || 'No zones on this canvas', 'error');
+      }
+    } catch (error) {
+      console.error('Error loading zones:', error);
+      displayMessage(uploadMessage, 'An error occurred while loading zones', 'error');
+    }
+  }
+
+  // What the server places as it is and what it converts first
+  async function loadUploadTypes() {
+    const hint = document.getElementById('uploadTypes');
+    try {
+      const response = await fetch('/api/uploads/types');
+      const data = await response.json();
+      if (!response.ok || !data.success) return;
+      const conversions = data.conversions.map(conversion =>
+        `${conversion.name} (${conversion.from.join(', ')} to ${conversion.to})`);
+      hint.textContent = `Placed as they are: ${data.types.map(type => type.mime_type).join(', ')}. ` +
+        `Conversions: ${conversions.join('; ')}.`;
+    } catch (error) {
+      console.error('Error loading upload types:', error);
+    }
+  }
+  loadUploadTypes();
+
+  placement.addEventListener('change', () => {
+    const zone = placement.value === 'zone';
+    zoneGroup.style.display = zone ? 'block' : 'none';
+    if (zone) loadZones();
+  });
+
+  form.addEventListener('submit', async (event) => {
+    event.preventDefault();
+    const files = Array.from(filesInput.files);
+    if (files.length === 0) return;
+    if (placement.value === 'zone' && !zoneSelect.value) {
+      displayMessage(uploadMessage, 'Select a zone to upload into', 'error');
+      return;
+    }
+
+    currentUploadId = `${Date.now()}-${Math.random().toString(36).slice(2, 8)}`;
+    const formData = new FormData(form);
+    formData.delete('files');
+    formData.set('upload_id', currentUploadId);
+
+    fileItems.clear();
+    fileList.innerHTML = '';
+    files.forEach(file => {
+      const item = document.createElement('li');
+      fileList.appendChild(item);
+      fileItems.set(file.name, item);
+      setFileStatus(file.name, 'waiting');
+    });
+    progress.style.display = 'block';
+    setProgress(0, 'Sending files...');
+    uploadBtn.disabled = true;
+
+    try {
+      // Send every file in resumable chunks, then place them all at once
+      const totalBytes = files.reduce((sum, file) => sum + file.size, 0);
+      let doneBytes = 0;
+      for (const file of files) {
+        setFileStatus(file.name, 'sending...');
+        const upload = await new ChunkedUpload(file, {
+          onProgress: (sent) => {
+            const percent = Math.round(((doneBytes + sent) / totalBytes) * 100);
+            setProgress(percent, `Sending files... ${Math.round((doneBytes + sent) / 1048576)} of ${Math.round(totalBytes / 1048576)} MB`);
+          }
+        }).start();
+        doneBytes += file.size;
+        formData.append('staged', upload.id);
+        setFileStatus(file.name, 'sent');
+      }
+
+      setProgress(0, 'Placing files on the canvas...');
+      const response = await fetch('/api/remote-upload', {
+        method: 'POST',
+        body: formData
+      });
+      const data = await response.json();
+      (data.files || []).forEach(record => {
+        if (record.outcome === 'success') {
+          setFileStatus(record.filename, 'placed', 'upload-file-success');
+        } else {
+          setFileStatus(record.filename, `failed: ${record.error}`, 'upload-file-failure');
+        }
+      });
+      if (data.success) {
+        displayMessage(uploadMessage, `${data.files.length} files uploaded to the canvas`, 'success');
+        form.reset();
+        zoneGroup.style.display = 'none';
+      } else {
+        displayMessage(uploadMessage, data.error || 'Upload failed', 'error');
+      }
+      loadUploadHistory(0);
+    } catch (error) {
+      console.error('Error uploading files:', error);
+      displayMessage(uploadMessage, error.message || 'An error occurred while uploading', 'error');
+    } finally {
+      uploadBtn.disabled = false;
+    }
+  });
+}
+
+const uploadHistoryPageSize = 20;
+let uploadHistoryOffset = 0;
+
+/**
+ * Initialize upload history paging
+ */
+function initUploadHistory() {
+  const prev = document.getElementById('uploadHistoryPrev');
+  const next = document.getElementById('uploadHistoryNext');
+  if (!prev || !next) return;
+
+  prev.addEventListener('click', () => loadUploadHistory(Math.max(uploadHistoryOffset - uploadHistoryPageSize, 0)));
+  next.addEventListener('click', () => loadUploadHistory(uploadHistoryOffset + uploadHistoryPageSize));
+  loadUploadHistory(0);
+}
+
+/**
+ * Load a page of the upload history, newest first
+ */
+async function loadUploadHistory(offset) {
+  const tbody = document.querySelector('#uploadHistoryTable tbody');
+  const info = document.getElementById('uploadHistoryInfo');
+  if (!tbody) return;
+
+  try {
+    const response = await fetch(`/api/remote-upload/history?offset=${offset}&limit=${uploadHistoryPageSize}`);
+    const data = await response.json();
+    if (!response.ok || !data.success) {
+      info.textContent = data.error || 'Failed to load the upload history';
+      return;
+    }
+    uploadHistoryOffset = offset;
+
+    tbody.innerHTML = '';
+    data.records.forEach(record => {
+      const row = document.createElement('tr');
+      const outcome = record.error ? `${record.outcome}: ${record.error}` : record.outcome;
+      [
+        new Date(record.uploaded_at).toLocaleString(),
+        `${record.filename} (${Math.round(record.size / 1024)} KB${record.converter ? `, ${record.converter}` : ''})`,
+        record.uploader || record.remote_ip || '-',
+        record.canvas_id,
+        record.widget_id ? `${record.widget_type} ${record.widget_id}` : '-',
+        outcome
+      ].forEach((text, i) => {
+        const td = document.createElement('td');
+        td.textContent = text;
+        if (i === 5) td.className = `upload-file-${record.outcome}`;
+        row.appendChild(td);
+      });
+      tbody.appendChild(row);
+    });
+
+    info.textContent = data.total === 0 ? 'No uploads yet.' :
+      `${offset + 1}-${offset + data.records.length} of ${data.total}`;
+    document.getElementById('uploadHistoryPrev').disabled = offset === 0;
+    document.getElementById('uploadHistoryNext').disabled = offset + data.records.length >= data.total;
+  } catch (error) {
+    console.error('Error loading upload history:', error);
+    info.textContent = 'An error occurred while loading the upload history';
+  }
+}
+
+/**
+ * Initialize the RCU teams editor: the teams' names, colors and the
+ * anchor_index of the zone each team's target is created in
+ */
+function initRCUSession() {
+  const tbody = document.querySelector('#teamsTable tbody');
+  const addTeamBtn = document.getElementById('addTeamBtn');
+  const saveTeamsBtn = document.getElementById('saveTeamsBtn');
+  const teamsMessage = document.getElementById('teamsMessage');
+  if (!tbody) return;
+
+  // Read the teams back from the table rows; new teams have no ID yet
+  const readTeams = () => Array.from(tbody.querySelectorAll('tr')).map(row => ({
+    id: parseInt(row.dataset.id) || 0,
+    name: row.querySelector('[data-field="name"]').value,
+    color: row.querySelector('[data-field="color"]').value,
+    anchor_index: parseInt(row.querySelector('[data-field="anchor_index"]').value) || 0
+  }));
+
+  const addRow = team => {
+    const row = document.createElement('tr');
+    row.innerHTML = `
+      <td class="team-number"></td>
+      <td><input type="text" class="input" data-field="name"></td>
+      <td><input type="color" data-field="color"></td>
+      <td><input type="number" class="input" min="1" data-field="anchor_index" style="width: 6em;"></td>
+      <td><button type="button" class="btn btn-danger btn-sm">Remove</button></td>
+    `;
+    row.dataset.id = team.id || '';
+    row.querySelector('.team-number').textContent = team.id || 'New';
+    row.querySelector('[data-field="name"]').value = team.name;
+    row.querySelector('[data-field="color"]').value = team.color.slice(0, 7).toLowerCase();
+    row.querySelector('[data-field="anchor_index"]').value = team.anchor_index;
+    row.querySelector('button').addEventListener('click', () => row.remove());
+    tbody.appendChild(row);
   };
 
-  // Text colors for each team (matching the provided style exactly)
-  const teamTextColors = {
-    1: "rgb(255, 255, 255)",  // White for Red
-    2: "rgb(0, 0, 0)",         // Black for Orange
-    3: "rgb(0, 0, 0)",         // Black for Yellow
-    4: "rgb(0, 0, 0)",         // Black for Green
-    5: "rgb(255, 255, 255)",  // White for Blue
-    6: "rgb(255, 255, 255)",  // White for Indigo
-    7: "rgb(255, 255, 255)"    // White for Violet
+  const render = teams => {
+    tbody.innerHTML = '';
+    teams.forEach(addRow);
+    renderTestTeamButtons(teams);
   };
 
-  // Apply colors and FIXED sizing - exactly as specified
-  teamButtons.forEach(button => {
-    const teamNumber = parseInt(button.getAttribute('data-test-team'));
-    if (teamNumber && teamColors[teamNumber]) {
-      // Set fixed size - CRITICAL: these must be exactly 120px x 40px
-      button.style.width = '120px';
-      button.style.height = '40px';
-      button.style.flexShrink = '0';
-      button.style.minWidth = '120px';
-      button.style.maxWidth = '120px';
-
-      // Set colors
-      button.style.backgroundColor = teamColors[teamNumber];
-      button.style.color = teamTextColors[teamNumber];
+  const load = async () => {
+    try {
+      const response = await fetch('/api/admin/rcu-session');
+      const data = await response.json();
+      if (response.ok && data.success) {
+        render(data.session.teams);
+      } else {
+        displayMessage(teamsMessage, data.error || "Failed to load the teams", "error");
+      }
+    } catch (error) {
+      console.error('Error loading teams:', error);
+      displayMessage(teamsMessage, "An error occurred while loading the teams", "error");
     }
+  };
+
+  if (addTeamBtn) {
+    addTeamBtn.addEventListener('click', () => {
+      const teams = readTeams();
+      const anchorIndex = teams.reduce((max, team) => Math.max(max, team.anchor_index), 0) + 1;
+      addRow({ name: `Team ${teams.length + 1}`, color: '#808080', anchor_index: anchorIndex });
+    });
+  }
+
+  if (saveTeamsBtn) {
+    saveTeamsBtn.addEventListener('click', async () => {
+      try {
+        const response = await fetch('/api/admin/rcu-session', {
+          method: 'PUT',
+          headers: {
+            'Content-Type': 'application/json'
+          },
+          body: JSON.stringify({ teams: readTeams() })
+        });
+
+        const data = await response.json();
+        if (response.ok && data.success) {
+          render(data.session.teams);
+          displayMessage(teamsMessage, "Teams saved", "success");
+        } else {
+          displayMessage(teamsMessage, data.error || "Failed to save the teams", "error");
+        }
+      } catch (error) {
+        console.error('Error saving teams:', error);
+        displayMessage(teamsMessage, "An error occurred while saving the teams", "error");
+      }
+    });
+  }
+
+  load();
+}
+
+/**
+ * Build a test button for each team, in the team's color - FIXED SIZE
+ */
+function renderTestTeamButtons(teams) {
+  const container = document.getElementById('testTeamButtons');
+  const testTeamMessage = document.getElementById('testTeamMessage');
+  if (!container) return;
+
+  container.innerHTML = '';
+  teams.forEach(team => {
+    const color = team.color.slice(0, 7);
+    const button = document.createElement('button');
+    button.type = 'button';
+    button.className = 'btn btn-secondary';
+    button.textContent = `Test ${team.name}`;
+
+    // Set fixed size - CRITICAL: these must be exactly 120px x 40px
+    button.style.width = '120px';
+    button.style.height = '40px';
+    button.style.flexShrink = '0';
+    button.style.minWidth = '120px';
+    button.style.maxWidth = '120px';
+
+    // Set colors
+    button.style.backgroundColor = color;
+    button.style.color = isColorDark(color) ? '#FFFFFF' : '#000000';
+
+    button.addEventListener('click', async () => {
+      try {
+        // Send a test note from Admin to the team's target
+        const response = await fetch('/api/admin/test-team', {
+          method: 'POST',
+          headers: {
+            'Content-Type': 'application/json'
+          },
+          body: JSON.stringify({
+            team: team.id,
+            text: `Test note from Admin to ${team.name}`
+          })
+        });
+
+        const data = await response.json();
+        if (response.ok && data.success) {
+          displayMessage(testTeamMessage, `Test note sent to ${team.name} successfully`, "success");
+        } else {
+          displayMessage(testTeamMessage, data.error || `Failed to send test note to ${team.name}`, "error");
+        }
+      } catch (error) {
+        console.error(`Error sending test note to ${team.name}:`, error);
+        displayMessage(testTeamMessage, `An error occurred while sending test note to ${team.name}`, "error");
+      }
+    });
+    container.appendChild(button);
   });
 }
 
+/**
+ * Text color for a #RRGGBB background
+ */
+function isColorDark(hexColor) {
+  const r = parseInt(hexColor.slice(1, 3), 16);
+  const g = parseInt(hexColor.slice(3, 5), 16);
+  const b = parseInt(hexColor.slice(5, 7), 16);
+  return (r * 0.299 + g * 0.587 + b * 0.114) / 255 < 0.5;
+}
+
 /**
  * Initialize create targets functionality
  */
@@ -115,44 +467,6 @@ function initCreateTargets() {
   }
 }
 
-/**
- * Initialize test team buttons
- */
-function initTestTeamButtons() {
-  const testTeamButtons = document.querySelectorAll('[data-test-team]');
-  const testTeamMessage = document.getElementById('testTeamMessage');
-
-  testTeamButtons.forEach(button => {
-    button.addEventListener('click', async () => {
-      const teamNumber = parseInt(button.getAttribute('data-test-team'));
-
-      try {
-        // Send a test note from Admin to the team's target
-        const response = await fetch('/api/admin/test-team', {
-          method: 'POST',
-          headers: {
-            'Content-Type': 'application/json'
-          },
-          body: JSON.stringify({
-            team: teamNumber,
-            text: `Test note from Admin to Team ${teamNumber}`
-          })
-        });
-
-        const data = await response.json();
-        if (response.ok && data.success) {
-          displayMessage(testTeamMessage, `Test note sent to Team ${teamNumber} successfully`, "success");
-        } else {
-          displayMessage(testTeamMessage, data.error || `Failed to send test note to Team ${teamNumber}`, "error");
-        }
-      } catch (error) {
-        console.error(`Error sending test note to Team ${teamNumber}:`, error);
-        displayMessage(testTeamMessage, `An error occurred while sending test note to Team ${teamNumber}`, "error");
-      }
-    });
-  });
-}
-
 /**
  * Initialize user management
  */
@@ -237,7 +551,7 @@ function displayUserList(users, tbody, messageEl) {
 
   tbody.innerHTML = users.map(user => `
     <tr>
-      <td>Team ${user.team}</td>
+      <td>${user.team_name || `Team ${user.team}`}</td>
       <td>${user.name}</td>
       <td>${user.color ? user.color.toUpperCase() : 'N/A'}</td>
     </tr>
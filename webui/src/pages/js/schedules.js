@@ -0,0 +1,271 @@
+/**
+ * Schedule Tab JavaScript
+ * Creates, lists, pauses and deletes scheduled macro jobs and shows their
+ * recent runs. Uses postJson() from macros.js.
+ */
+
+const SCHEDULE_ZONE_PARAMS = {
+  "pin-all": [{ name: "zoneId", label: "Zone" }],
+  "unpin-all": [{ name: "zoneId", label: "Zone" }],
+  "auto-grid": [{ name: "zoneId", label: "Zone" }],
+  "group-color": [{ name: "zoneId", label: "Zone" }],
+  "group-title": [{ name: "zoneId", label: "Zone" }],
+  "move": [{ name: "sourceZoneId", label: "Source Zone" }, { name: "targetZoneId", label: "Target Zone" }],
+  "copy": [{ name: "sourceZoneId", label: "Source Zone" }, { name: "targetZoneId", label: "Target Zone" }],
+  "zone-sync": [{ name: "sourceZoneId", label: "Source Zone" }, { name: "targetZoneId", label: "Target Zone" }]
+};
+
+let scheduleZones = [];
+let scheduleParamSelects = {};
+
+document.addEventListener("DOMContentLoaded", () => {
+  console.log("[schedules.js] Initializing schedule tab");
+
+  const macroSelect = document.getElementById("jobMacro");
+  if (!macroSelect) return;
+
+  macroSelect.addEventListener("change", renderJobParams);
+  document.getElementById("jobTrigger").addEventListener("change", updateJobTriggerInput);
+  document.getElementById("createJobButton").addEventListener("click", () => {
+    console.log("[schedules.js] Schedule button clicked");
+    createJob();
+  });
+
+  updateJobTriggerInput();
+  loadScheduleOptions();
+  fetchJobs();
+});
+
+/* ------------------------------ FORM ------------------------------ */
+async function loadScheduleOptions() {
+  try {
+    const [macrosRes, zonesRes] = await Promise.all([
+      fetch("/api/macros/definitions"),
+      fetch("/get-zones", { headers: { 'Cache-Control': 'no-cache' } })
+    ]);
+    const zonesData = await zonesRes.json();
+    scheduleZones = [...(zonesData.zones || [])].sort((a, b) =>
+      (a.anchor_name || "").toLowerCase().localeCompare((b.anchor_name || "").toLowerCase(), undefined, { numeric: true }));
+
+    const data = await macrosRes.json();
+    if (macrosRes.ok && data.success) {
+      const group = document.getElementById("jobCustomMacros");
+      (data.macros || []).forEach(macro => {
+        SCHEDULE_ZONE_PARAMS[`custom:${macro.id}`] = (macro.params || []).map(param =>
+          ({ name: param.name, label: param.label || param.name }));
+        const option = document.createElement("option");
+        option.value = `custom:${macro.id}`;
+        option.textContent = macro.name;
+        group.appendChild(option);
+      });
+    }
+  } catch (err) {
+    console.error("[schedules.js] Failed to load macros and zones:", err);
+    displayScheduleMessage(err.message || "Failed to load macros and zones", "error");
+  }
+  renderJobParams();
+}
+
+function renderJobParams() {
+  const paramsEl = document.getElementById("jobParams");
+  const macro = document.getElementById("jobMacro").value;
+  paramsEl.innerHTML = "";
+  scheduleParamSelects = {};
+
+  (SCHEDULE_ZONE_PARAMS[macro] || []).forEach(param => {
+    const group = document.createElement("div");
+    group.className = "form-group";
+    const label = document.createElement("label");
+    label.className = "input-label";
+    label.textContent = `${param.label}:`;
+    const select = document.createElement("select");
+    select.className = "input select";
+    select.innerHTML = '<option value="">Select a zone...</option>';
+    scheduleZones.forEach(zone => {
+      const option = document.createElement("option");
+      option.value = zone.id;
+      option.textContent = zone.anchor_name || `Zone ${zone.id}`;
+      select.appendChild(option);
+    });
+    group.appendChild(label);
+    group.appendChild(select);
+    paramsEl.appendChild(group);
+    scheduleParamSelects[param.name] = select;
+  });
+}
+
+function updateJobTriggerInput() {
+  const trigger = document.getElementById("jobTrigger").value;
+  const input = document.getElementById("jobTriggerValue");
+  document.getElementById("jobTriggerValueGroup").style.display = trigger === "on" ? "none" : "block";
+  input.placeholder = trigger === "at" ? "18:00" : "10m";
+}
+
+async function createJob() {
+  const macroSelect = document.getElementById("jobMacro");
+  const trigger = document.getElementById("jobTrigger").value;
+  const triggerValue = document.getElementById("jobTriggerValue").value.trim();
+
+  const params = {};
+  for (const [name, select] of Object.entries(scheduleParamSelects)) {
+    if (!select.value) {
+      displayScheduleMessage("Please select a zone for every parameter.", "error");
+      return;
+    }
+    params[name] = select.value;
+  }
+
+  const job = {
+    name: document.getElementById("jobName").value,
+    macro: macroSelect.value,
+    params
+  };
+  if (trigger === "on") {
+    job.on = "canvas_changed";
+  } else if (!triggerValue) {
+    displayScheduleMessage(trigger === "at" ? "Please enter a time (HH:MM)." : "Please enter an interval (e.g. 10m).", "error");
+    return;
+  } else {
+    job[trigger] = triggerValue;
+  }
+
+  try {
+    const resp = await postJson("/api/macros/jobs", job);
+    displayScheduleMessage(resp.message || "Job scheduled", "success");
+    document.getElementById("jobName").value = "";
+    fetchJobs();
+  } catch (err) {
+    console.error("[schedules.js] Create failed:", err);
+    displayScheduleMessage(err.message || "Failed to schedule job", "error");
+  }
+}
+
+/* ------------------------------ LIST ------------------------------ */
+async function fetchJobs() {
+  try {
+    const res = await fetch("/api/macros/jobs");
+    const data = await res.json();
+    if (!res.ok || !data.success) {
+      throw new Error(data.error || `HTTP ${res.status}`);
+    }
+    renderJobList(data.jobs || []);
+    renderJobHistory(data.history || []);
+  } catch (err) {
+    console.error("[schedules.js] Failed to list jobs:", err);
+    displayScheduleMessage(err.message || "Failed to list jobs", "error");
+  }
+}
+
+function renderJobList(jobs) {
+  const listEl = document.getElementById("jobList");
+  listEl.innerHTML = "";
+
+  if (jobs.length === 0) {
+    listEl.textContent = "No scheduled macros yet.";
+    return;
+  }
+
+  jobs.forEach(job => {
+    const row = document.createElement("div");
+    row.className = job.paused ? "job-row job-paused" : "job-row";
+
+    const info = document.createElement("div");
+    info.className = "job-info";
+    const name = document.createElement("strong");
+    name.textContent = job.paused ? `${job.name} (paused)` : job.name;
+    const details = document.createElement("span");
+    let text = `${job.macro} - ${describeJobTrigger(job)}`;
+    if (job.last_run) text += ` - last run ${new Date(job.last_run).toLocaleString()}`;
+    if (job.next_run && !job.paused) text += ` - next run ${new Date(job.next_run).toLocaleString()}`;
+    details.textContent = text;
+    info.appendChild(name);
+    info.appendChild(details);
+    row.appendChild(info);
+
+    const actions = document.createElement("div");
+    actions.className = "form-actions";
+    actions.appendChild(jobButton(job.paused ? "Resume" : "Pause", "btn-primary", () => pauseJob(job, !job.paused)));
+    actions.appendChild(jobButton("Delete", "btn-secondary", () => deleteJob(job)));
+    row.appendChild(actions);
+
+    listEl.appendChild(row);
+  });
+}
+
+function renderJobHistory(history) {
+  const historyEl = document.getElementById("jobHistory");
+  historyEl.innerHTML = "";
+
+  if (history.length === 0) {
+    historyEl.textContent = "No runs yet.";
+    return;
+  }
+
+  history.forEach(run => {
+    const row = document.createElement("div");
+    row.className = run.error || run.failed > 0 ? "job-info job-run-failed" : "job-info";
+    const started = new Date(run.started_at).toLocaleString();
+    const result = run.error
+      ? run.error
+      : `${run.succeeded} changed, ${run.failed} failed, ${run.skipped} skipped`;
+    row.textContent = `${started} - ${run.job_name} (${run.trigger}) - ${result}`;
+    historyEl.appendChild(row);
+  });
+}
+
+function describeJobTrigger(job) {
+  if (job.every) return `every ${job.every}`;
+  if (job.at) return `daily at ${job.at}`;
+  if (job.on === "canvas_changed") return "when the canvas changes";
+  return job.on || "";
+}
+
+function jobButton(label, style, onClick) {
+  const button = document.createElement("button");
+  button.className = `btn ${style}`;
+  button.textContent = label;
+  button.addEventListener("click", onClick);
+  return button;
+}
+
+/* ------------------------------ ACTIONS ------------------------------ */
+async function pauseJob(job, paused) {
+  try {
+    const resp = await postJson("/api/macros/jobs/pause", { id: job.id, paused });
+    displayScheduleMessage(resp.message || (paused ? "Job paused" : "Job resumed"), "success");
+    fetchJobs();
+  } catch (err) {
+    console.error("[schedules.js] Pause failed:", err);
+    displayScheduleMessage(err.message || "Failed to update job", "error");
+  }
+}
+
+async function deleteJob(job) {
+  if (!confirm(`Delete scheduled macro "${job.name}"?`)) {
+    return;
+  }
+
+  try {
+    const res = await fetch(`/api/macros/jobs?id=${encodeURIComponent(job.id)}`, { method: "DELETE" });
+    const data = await res.json();
+    if (!res.ok || !data.success) {
+      throw new Error(data.error || `HTTP ${res.status}`);
+    }
+    displayScheduleMessage(data.message || "Job deleted", "success");
+    fetchJobs();
+  } catch (err) {
+    console.error("[schedules.js] Delete failed:", err);
+    displayScheduleMessage(err.message || "Failed to delete job", "error");
+  }
+}
+
+function displayScheduleMessage(text, type) {
+  const messageEl = document.getElementById("scheduleMessage");
+  if (!messageEl) {
+    console.log(`[${type}] ${text}`);
+    return;
+  }
+  messageEl.textContent = text;
+  messageEl.className = `message ${type} mt-md`;
+  messageEl.style.display = "block";
+}
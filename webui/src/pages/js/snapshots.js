@@ -0,0 +1,247 @@
+/**
+ * Snapshots Tab JavaScript
+ * Takes, lists, diffs, restores and deletes canvas snapshots.
+ * Uses postJson() from macros.js.
+ */
+
+document.addEventListener("DOMContentLoaded", () => {
+  console.log("[snapshots.js] Initializing snapshots tab");
+
+  const takeSnapshotButton = document.getElementById("takeSnapshotButton");
+  if (takeSnapshotButton) {
+    takeSnapshotButton.addEventListener("click", () => {
+      console.log("[snapshots.js] Take Snapshot button clicked");
+      takeSnapshot();
+    });
+  }
+
+  fetchSnapshots();
+  fetchRestoreCanvases();
+});
+
+/* ------------------------------ LIST ------------------------------ */
+async function fetchSnapshots() {
+  const listEl = document.getElementById("snapshotList");
+  if (!listEl) return;
+
+  try {
+    const res = await fetch("/api/snapshots");
+    const data = await res.json();
+    if (!res.ok || !data.success) {
+      throw new Error(data.error || `HTTP ${res.status}`);
+    }
+    renderSnapshotList(data.snapshots || []);
+  } catch (err) {
+    console.error("[snapshots.js] Failed to list snapshots:", err);
+    displaySnapshotMessage(err.message || "Failed to list snapshots", "error");
+  }
+}
+
+function renderSnapshotList(snapshots) {
+  const listEl = document.getElementById("snapshotList");
+  listEl.innerHTML = "";
+
+  if (snapshots.length === 0) {
+    listEl.textContent = "No snapshots yet.";
+    return;
+  }
+
+  snapshots.forEach(snapshot => {
+    const row = document.createElement("div");
+    row.className = "snapshot-row";
+
+    const info = document.createElement("div");
+    info.className = "snapshot-info";
+    const name = document.createElement("strong");
+    name.textContent = snapshot.name || snapshot.id;
+    const details = document.createElement("span");
+    const created = new Date(snapshot.created_at).toLocaleString();
+    details.textContent = `${created} - ${snapshot.canvas_name || snapshot.canvas_id} - ` +
+      `${snapshot.widget_count} widgets, ${snapshot.asset_count} files, ${formatSnapshotSize(snapshot.size)}`;
+    info.appendChild(name);
+    info.appendChild(details);
+    row.appendChild(info);
+
+    const actions = document.createElement("div");
+    actions.className = "form-actions";
+    actions.appendChild(snapshotButton("Diff", "btn-secondary", () => diffSnapshot(snapshot)));
+    actions.appendChild(snapshotButton("Restore", "btn-primary", () => restoreSnapshot(snapshot)));
+    actions.appendChild(snapshotButton("Delete", "btn-secondary", () => deleteSnapshot(snapshot)));
+    row.appendChild(actions);
+
+    listEl.appendChild(row);
+  });
+}
+
+function snapshotButton(label, style, onClick) {
+  const button = document.createElement("button");
+  button.className = `btn ${style}`;
+  button.textContent = label;
+  button.addEventListener("click", onClick);
+  return button;
+}
+
+function formatSnapshotSize(bytes) {
+  if (!bytes) return "0 B";
+  const units = ["B", "KB", "MB", "GB"];
+  let size = bytes;
+  let unit = 0;
+  while (size >= 1024 && unit < units.length - 1) {
+    size /= 1024;
+    unit++;
+  }
+  return `${size.toFixed(unit === 0 ? 0 : 1)} ${units[unit]}`;
+}
+
+async function fetchRestoreCanvases() {
+  const select = document.getElementById("restoreCanvas");
+  if (!select) return;
+
+  try {
+    const res = await fetch("/api/snapshots/canvases");
+    const data = await res.json();
+    if (!res.ok || !data.success) {
+      throw new Error(data.error || `HTTP ${res.status}`);
+    }
+    (data.canvases || [])
+      .filter(canvas => canvas.id !== data.current_canvas_id)
+      .forEach(canvas => {
+        const option = document.createElement("option");
+        option.value = canvas.id;
+        option.textContent = canvas.name || canvas.id;
+        select.appendChild(option);
+      });
+  } catch (err) {
+    // Restoring to the current canvas still works without the list.
+    console.warn("[snapshots.js] Failed to list canvases:", err);
+  }
+}
+
+/* ------------------------------ ACTIONS ------------------------------ */
+async function takeSnapshot() {
+  const nameInput = document.getElementById("snapshotName");
+  const button = document.getElementById("takeSnapshotButton");
+  if (button) button.disabled = true;
+  displaySnapshotMessage("Taking snapshot, downloading files...", "info");
+
+  try {
+    const resp = await postJson("/api/snapshots", { name: nameInput ? nameInput.value : "" });
+    displaySnapshotMessage(resp.message || "Snapshot saved", "success");
+    if (nameInput) nameInput.value = "";
+    fetchSnapshots();
+  } catch (err) {
+    console.error("[snapshots.js] Snapshot failed:", err);
+    displaySnapshotMessage(err.message || "Failed to take snapshot", "error");
+  } finally {
+    if (button) button.disabled = false;
+  }
+}
+
+async function diffSnapshot(snapshot) {
+  const diffEl = document.getElementById("snapshotDiff");
+  displaySnapshotMessage("Comparing with the current canvas...", "info");
+
+  try {
+    const res = await fetch(`/api/snapshots/diff?id=${encodeURIComponent(snapshot.id)}`);
+    const data = await res.json();
+    if (!res.ok || !data.success) {
+      throw new Error(data.error || `HTTP ${res.status}`);
+    }
+    displaySnapshotMessage(data.message, "info");
+    renderSnapshotDiff(diffEl, snapshot, data.diff);
+  } catch (err) {
+    console.error("[snapshots.js] Diff failed:", err);
+    displaySnapshotMessage(err.message || "Failed to compare snapshot", "error");
+  }
+}
+
+function renderSnapshotDiff(diffEl, snapshot, diff) {
+  if (!diffEl) return;
+  diffEl.innerHTML = "";
+
+  const title = document.createElement("h3");
+  title.textContent = `Changes since "${snapshot.name || snapshot.id}"`;
+  diffEl.appendChild(title);
+
+  const sections = [
+    ["Added", diff.added || []],
+    ["Removed", diff.removed || []],
+    ["Changed", diff.changed || []]
+  ];
+  sections.forEach(([label, widgets]) => {
+    if (widgets.length === 0) return;
+    const heading = document.createElement("h4");
+    heading.textContent = `${label} (${widgets.length})`;
+    diffEl.appendChild(heading);
+
+    const list = document.createElement("ul");
+    list.className = "batch-failures";
+    widgets.forEach(w => {
+      const item = document.createElement("li");
+      const fields = (w.fields || []).length > 0 ? `: ${w.fields.join(", ")}` : "";
+      item.textContent = `${w.widget_type} ${w.title || w.widget_id.substring(0, 8)}${fields}`;
+      list.appendChild(item);
+    });
+    diffEl.appendChild(list);
+  });
+}
+
+async function restoreSnapshot(snapshot) {
+  const canvasSelect = document.getElementById("restoreCanvas");
+  const replaceCheckbox = document.getElementById("restoreReplace");
+  const canvasId = canvasSelect ? canvasSelect.value : "";
+  const replace = replaceCheckbox ? replaceCheckbox.checked : false;
+  const target = canvasId ? canvasSelect.options[canvasSelect.selectedIndex].textContent : "the current canvas";
+
+  let question = `Restore ${snapshot.widget_count} widgets from "${snapshot.name || snapshot.id}" to ${target}?`;
+  if (replace) {
+    question += "\n\nAll widgets already on that canvas will be deleted first.";
+  }
+  if (!confirm(question)) {
+    return;
+  }
+
+  displaySnapshotMessage("Restoring snapshot, uploading files...", "info");
+  try {
+    const resp = await postJson("/api/snapshots/restore", {
+      id: snapshot.id,
+      canvas_id: canvasId,
+      replace: replace
+    });
+    const failed = resp.report?.failed || 0;
+    displaySnapshotMessage(resp.message || "Snapshot restored", failed > 0 ? "error" : "success");
+  } catch (err) {
+    console.error("[snapshots.js] Restore failed:", err);
+    displaySnapshotMessage(err.message || "Failed to restore snapshot", "error");
+  }
+}
+
+async function deleteSnapshot(snapshot) {
+  if (!confirm(`Delete snapshot "${snapshot.name || snapshot.id}"?`)) {
+    return;
+  }
+
+  try {
+    const res = await fetch(`/api/snapshots?id=${encodeURIComponent(snapshot.id)}`, { method: "DELETE" });
+    const data = await res.json();
+    if (!res.ok || !data.success) {
+      throw new Error(data.error || `HTTP ${res.status}`);
+    }
+    displaySnapshotMessage(data.message || "Snapshot deleted", "success");
+    fetchSnapshots();
+  } catch (err) {
+    console.error("[snapshots.js] Delete failed:", err);
+    displaySnapshotMessage(err.message || "Failed to delete snapshot", "error");
+  }
+}
+
+function displaySnapshotMessage(text, type) {
+  const messageEl = document.getElementById("snapshotsMessage");
+  if (!messageEl) {
+    console.log(`[${type}] ${text}`);
+    return;
+  }
+  messageEl.textContent = text;
+  messageEl.className = `message ${type} mt-md`;
+  messageEl.style.display = "block";
+}